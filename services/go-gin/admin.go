@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/api/idtoken"
+)
+
+// newAdminMux builds the handler for the admin endpoints: pprof, expvar,
+// metrics and, when auth is set, the /admin/ API.
+//
+// With auth nil the handler is only served on the admin listener, which is
+// separate from the interactions port so profiling endpoints are never
+// reachable through the public ingress. With auth set every request must
+// present a credential auth accepts, and the handler is also mounted on the
+// interactions port under ADMIN_PATH.
+func newAdminMux(cfg *Config, auth *adminAuthenticator) http.Handler {
+	mux := http.NewServeMux()
+
+	// Runtime profiling
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// Runtime stats (memstats, cmdline and any published expvars)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	// Prometheus metrics, for scrapers that can't reach the public /metrics
+	mux.Handle("/metrics", promhttp.Handler())
+
+	if auth == nil {
+		return mux
+	}
+	registerAdminAPI(mux, cfg)
+	return auth.require(mux)
+}
+
+// adminAuthenticator authenticates admin requests by their bearer token: either the
+// static ADMIN_TOKEN, or a Google-signed ID token for ADMIN_ID_TOKEN_AUDIENCE
+// whose verified email is one of ADMIN_ID_TOKEN_MEMBERS, such as a Cloud
+// Run invoker's service account.
+type adminAuthenticator struct {
+	token     []byte
+	audience  string
+	members   map[string]bool
+	validator *idtoken.Validator
+}
+
+// newAdminAuth returns the admin authenticator cfg configures, or nil if it
+// configures neither a static token nor ID tokens
+func newAdminAuth(ctx context.Context, cfg *Config) (*adminAuthenticator, error) {
+	if cfg.AdminToken == "" && cfg.AdminIDTokenAudience == "" {
+		return nil, nil
+	}
+	auth := &adminAuthenticator{token: []byte(cfg.AdminToken), audience: cfg.AdminIDTokenAudience}
+	if auth.audience != "" {
+		// Google's signing keys are public, so the validator needs no credentials
+		validator, err := idtoken.NewValidator(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("create ID token validator: %w", err)
+		}
+		auth.validator = validator
+		auth.members = make(map[string]bool, len(cfg.AdminIDTokenMembers))
+		for _, member := range cfg.AdminIDTokenMembers {
+			auth.members[strings.ToLower(member)] = true
+		}
+	}
+	return auth, nil
+}
+
+// allowed reports whether r carries a credential a accepts
+func (a *adminAuthenticator) allowed(r *http.Request) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || got == "" {
+		return false
+	}
+	if len(a.token) > 0 && subtle.ConstantTimeCompare([]byte(got), a.token) == 1 {
+		return true
+	}
+	if a.validator == nil {
+		return false
+	}
+	payload, err := a.validator.Validate(r.Context(), got, a.audience)
+	if err != nil {
+		return false
+	}
+	email, _ := payload.Claims["email"].(string)
+	verified, _ := payload.Claims["email_verified"].(bool)
+	return verified && a.members[strings.ToLower(email)]
+}
+
+// require rejects requests a doesn't authenticate
+func (a *adminAuthenticator) require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.allowed(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "unauthorized", Code: errorCodeUnauthorized})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startAdminServer serves the admin handler on cfg.AdminPort in the background
+func startAdminServer(cfg *Config, handler http.Handler) {
+	port := cfg.AdminPort
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	go func() {
+		logger.Info("Starting admin server", "port", port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Admin server failed", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Config fields never shown by /admin/config
+var secretConfigFields = map[string]bool{
+	"AdminToken":          true,
+	"WebhookSecret":       true,
+	"ShadowWebhookSecret": true,
+	"PublishSigningKey":   true,
+}
+
+// recent holds the last processed interactions for /admin/interactions
+var recent = newRecentInteractions(100)
+
+// inFlightPublishes counts interactions handed to the publish backend and
+// not yet answered by it
+var inFlightPublishes atomic.Int64
+
+// RecentInteraction is the redacted summary kept per processed interaction.
+// Tokens, options and message content are never kept.
+type RecentInteraction struct {
+	ID         string    `json:"id"`
+	Type       int       `json:"type"`
+	Command    string    `json:"command,omitempty"`
+	CustomID   string    `json:"custom_id,omitempty"`
+	GuildID    string    `json:"guild_id,omitempty"`
+	ChannelID  string    `json:"channel_id,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	Locale     string    `json:"locale,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+	Status     int       `json:"status"`
+	DurationMS float64   `json:"duration_ms"`
+}
+
+// recentInteractions is a fixed-size ring of the newest interactions
+type recentInteractions struct {
+	mu    sync.Mutex
+	items []RecentInteraction
+	next  int
+	full  bool
+}
+
+func newRecentInteractions(size int) *recentInteractions {
+	return &recentInteractions{items: make([]RecentInteraction, size)}
+}
+
+// Record adds the interaction handled by c, once its response is written
+func (r *recentInteractions) Record(c *gin.Context, interaction *Interaction, start time.Time) {
+	if len(r.items) == 0 {
+		return
+	}
+	entry := RecentInteraction{
+		ID:         interaction.ID,
+		Type:       interaction.Type,
+		Command:    interaction.CommandPath(),
+		CustomID:   interaction.CustomID(),
+		GuildID:    interaction.GuildID,
+		ChannelID:  interaction.ChannelID,
+		UserID:     interaction.UserID(),
+		Locale:     interaction.Locale,
+		ReceivedAt: start.UTC(),
+		Status:     c.Writer.Status(),
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	r.mu.Lock()
+	r.items[r.next] = entry
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+}
+
+// Latest returns up to n interactions, newest first
+func (r *recentInteractions) Latest(n int) []RecentInteraction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := r.next
+	if r.full {
+		count = len(r.items)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+	out := make([]RecentInteraction, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.items[(r.next-i+len(r.items))%len(r.items)])
+	}
+	return out
+}
+
+// registerAdminAPI adds the /admin/ endpoints to the admin listener. They
+// expose interaction metadata, so they are only served to requests bearing
+// ADMIN_TOKEN or an accepted ADMIN_ID_TOKEN_AUDIENCE ID token.
+func registerAdminAPI(mux *http.ServeMux, cfg *Config) {
+	mux.HandleFunc("GET /admin/interactions", func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		writeAdminJSON(w, map[string]interface{}{"interactions": recent.Latest(limit)})
+	})
+	mux.HandleFunc("GET /admin/config", func(w http.ResponseWriter, _ *http.Request) {
+		writeAdminJSON(w, configView(cfg))
+	})
+	mux.HandleFunc("GET /admin/state", func(w http.ResponseWriter, _ *http.Request) {
+		writeAdminJSON(w, serviceState())
+	})
+}
+
+// serviceState reports what's queued, the breakers, and the dedup state
+func serviceState() map[string]interface{} {
+	outbox := map[string]interface{}{"in_flight_publishes": inFlightPublishes.Load()}
+	if egress != nil {
+		egress.mu.Lock()
+		outbox["grpc_pending_acks"] = len(egress.pending)
+		egress.mu.Unlock()
+	}
+
+	breakers := map[string]string{"publish": publishBreaker.State().String()}
+	stateInfo := map[string]interface{}{
+		"backend":      "memory",
+		"dedup_window": dedupWindow.String(),
+	}
+	if state.redis != nil {
+		breakers["redis"] = state.breaker.State().String()
+		stateInfo["backend"] = "redis"
+	}
+	keys, counters := state.memory.Size()
+	stateInfo["memory_keys"] = keys
+	stateInfo["memory_counters"] = counters
+
+	return map[string]interface{}{
+		"version":  serviceVersion(),
+		"outbox":   outbox,
+		"breakers": breakers,
+		"state":    stateInfo,
+	}
+}
+
+// configView renders cfg for /admin/config with secrets removed, durations
+// in Go syntax and keys in hex
+func configView(cfg *Config) map[string]interface{} {
+	out := make(map[string]interface{})
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		switch value := v.Field(i).Interface().(type) {
+		case string:
+			switch {
+			case secretConfigFields[name] && value != "":
+				out[name] = redacted
+			case name == "RedisURL" && value != "":
+				// Redis URLs can carry a password
+				if u, err := url.Parse(value); err == nil {
+					out[name] = u.Redacted()
+				} else {
+					out[name] = redacted
+				}
+			default:
+				out[name] = value
+			}
+		case time.Duration:
+			out[name] = value.String()
+		case ed25519.PublicKey:
+			out[name] = hex.EncodeToString(value)
+		case *[32]byte:
+			if value != nil {
+				out[name] = hex.EncodeToString(value[:])
+			} else {
+				out[name] = nil
+			}
+		default:
+			out[name] = value
+		}
+	}
+	return out
+}
+
+func writeAdminJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// attachmentHosts are the Discord CDN hosts attachments are downloaded from.
+// Anything else is left alone so a crafted URL can't make the edge fetch
+// arbitrary (e.g. metadata server) addresses.
+var attachmentHosts = map[string]bool{
+	"cdn.discordapp.com":   true,
+	"media.discordapp.net": true,
+}
+
+// attachmentOffloader copies attachments to a GCS bucket before publishing,
+// because Discord CDN URLs expire before async workers get to them
+type attachmentOffloader struct {
+	bucket   *storage.BucketHandle
+	name     string
+	maxBytes int64
+	client   *http.Client
+}
+
+// offloader is nil unless ATTACHMENT_BUCKET is set
+var offloader *attachmentOffloader
+
+func newAttachmentOffloader(ctx context.Context, bucket string, maxBytes int64) (*attachmentOffloader, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &attachmentOffloader{
+		bucket:   client.Bucket(bucket),
+		name:     bucket,
+		maxBytes: maxBytes,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Offload stores the interaction's attachments and returns a copy of data
+// whose resolved attachments point at the GCS objects. Attachments that
+// can't be copied keep their CDN URL. data itself is never modified.
+func (o *attachmentOffloader) Offload(ctx context.Context, interaction *Interaction) map[string]interface{} {
+	data := interaction.Data
+	if interaction.Resolved == nil || len(interaction.Resolved.Attachments) == 0 {
+		return data
+	}
+	resolved, _ := data["resolved"].(map[string]interface{})
+	rawAttachments, _ := resolved["attachments"].(map[string]interface{})
+
+	attachments := make(map[string]interface{}, len(rawAttachments))
+	for id, raw := range rawAttachments {
+		attachments[id] = raw
+		a, ok := interaction.Resolved.Attachments[id]
+		entry, isMap := raw.(map[string]interface{})
+		if !ok || !isMap {
+			continue
+		}
+
+		ref, err := o.copy(ctx, interaction.ID, a)
+		if err != nil {
+			attachmentOffloadsTotal.WithLabelValues("error").Inc()
+			loggerFrom(ctx).Warn("Failed to offload attachment, publishing CDN URL",
+				"interaction_id", interaction.ID,
+				"attachment_id", id,
+				"error", err,
+			)
+			continue
+		}
+		attachmentOffloadsTotal.WithLabelValues("success").Inc()
+
+		replaced := make(map[string]interface{}, len(entry))
+		for k, v := range entry {
+			replaced[k] = v
+		}
+		replaced["url"] = ref
+		delete(replaced, "proxy_url")
+		attachments[id] = replaced
+	}
+
+	return withValue(data, "resolved", withValue(resolved, "attachments", attachments))
+}
+
+// copy streams one attachment from the CDN into the bucket and returns its gs:// reference
+func (o *attachmentOffloader) copy(ctx context.Context, interactionID string, a payload.Attachment) (string, error) {
+	if int64(a.Size) > o.maxBytes {
+		return "", fmt.Errorf("attachment is %d bytes, limit is %d", a.Size, o.maxBytes)
+	}
+	u, err := url.Parse(a.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid attachment URL: %w", err)
+	}
+	if u.Scheme != "https" || !attachmentHosts[u.Hostname()] {
+		return "", fmt.Errorf("refusing to download from %q", u.Hostname())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	// Cancelling the writer's context abandons the upload on error
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	object := path.Join("attachments", interactionID, a.ID, path.Base(a.Filename))
+	w := o.bucket.Object(object).NewWriter(wctx)
+	w.ContentType = a.ContentType
+	// Read one byte past the limit so an oversize body is detected rather than truncated
+	n, err := io.Copy(w, io.LimitReader(resp.Body, o.maxBytes+1))
+	if err == nil && n > o.maxBytes {
+		err = fmt.Errorf("attachment exceeds %d bytes", o.maxBytes)
+	}
+	if err != nil {
+		cancel()
+		_ = w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return "gs://" + o.name + "/" + object, nil
+}
+
+// withValue returns a shallow copy of m with key set to v
+func withValue(m map[string]interface{}, key string, v interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m)+1)
+	for k, val := range m {
+		out[k] = val
+	}
+	out[key] = v
+	return out
+}
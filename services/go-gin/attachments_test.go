@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// TestAttachmentCopy_RefusesURL checks URLs are refused before anything is
+// downloaded or written
+func TestAttachmentCopy_RefusesURL(t *testing.T) {
+	o := &attachmentOffloader{name: "bucket", maxBytes: 1 << 20}
+	for _, tc := range []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"unparseable", "https://cdn.discordapp.com/%zz", "invalid attachment URL"},
+		{"other host", "https://example.com/a.png", `refusing to download from "example.com"`},
+		{"plain http", "http://cdn.discordapp.com/a.png", `refusing to download from "cdn.discordapp.com"`},
+	} {
+		_, err := o.copy(context.Background(), "1", payload.Attachment{ID: "2", Filename: "a.png", URL: tc.url})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
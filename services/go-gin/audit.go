@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/gin-gonic/gin"
+)
+
+// Audit event types for security-relevant rejections
+const (
+	auditSignatureFailure       = "signature_failure"
+	auditOversizeBody           = "oversize_body"
+	auditUnknownInteractionType = "unknown_interaction_type"
+	auditRateLimited            = "rate_limited"
+)
+
+// auditRedactedHeaders are never copied into audit records
+var auditRedactedHeaders = map[string]bool{
+	"X-Signature-Ed25519":   true,
+	"X-Signature-Timestamp": true,
+	"Authorization":         true,
+	"Proxy-Authorization":   true,
+	"Cookie":                true,
+}
+
+// AuditEvent is a security-relevant event recorded for abuse investigation
+type AuditEvent struct {
+	Event      string            `json:"event"`
+	Reason     string            `json:"reason,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	SourceIP   string            `json:"source_ip"`
+	RemoteAddr string            `json:"remote_addr"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Headers    map[string]string `json:"headers"`
+	Timestamp  string            `json:"timestamp"`
+}
+
+// auditLogger writes audit events to a dedicated log stream and, when
+// configured, to a separate Pub/Sub topic
+type auditLogger struct {
+	topic *pubsub.Topic
+}
+
+var auditor = &auditLogger{}
+
+// Record writes an audit event for the current request
+func (a *auditLogger) Record(c *gin.Context, event, reason string) {
+	ev := AuditEvent{
+		Event:      event,
+		Reason:     reason,
+		RequestID:  requestIDFrom(c.Request.Context()),
+		SourceIP:   c.ClientIP(),
+		RemoteAddr: c.Request.RemoteAddr,
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Headers:    auditHeaders(c.Request.Header),
+		Timestamp:  time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	auditEventsTotal.WithLabelValues(event).Inc()
+
+	// The audit label lets operators filter the stream in Cloud Logging
+	loggerFrom(c.Request.Context()).Warn("audit event",
+		"logging.googleapis.com/labels", map[string]string{"stream": "audit"},
+		slog.Group("audit",
+			"event", ev.Event,
+			"reason", ev.Reason,
+			"source_ip", ev.SourceIP,
+			"remote_addr", ev.RemoteAddr,
+			"method", ev.Method,
+			"path", ev.Path,
+			"headers", ev.Headers,
+		),
+	)
+
+	if a.topic != nil {
+		a.publish(&ev)
+	}
+}
+
+// publish queues the event on the audit topic without waiting for the result
+func (a *auditLogger) publish(ev *AuditEvent) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		logger.Error("Failed to marshal audit event", "error", err)
+		return
+	}
+	result := a.topic.Publish(context.Background(), &pubsub.Message{
+		Data:       data,
+		Attributes: map[string]string{"event": ev.Event},
+	})
+	go func() {
+		if _, err := result.Get(context.Background()); err != nil {
+			logger.Error("Failed to publish audit event", "event", ev.Event, "error", err)
+		}
+	}()
+}
+
+// auditHeaders flattens request headers, dropping signature and credential headers
+func auditHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if auditRedactedHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
@@ -0,0 +1,249 @@
+package main
+
+// Benchmarks for the interaction hot path. Compare a change against its base
+// with benchstat:
+//
+//	go test -run '^$' -bench . -benchmem -count 10 > old.txt
+//	(apply the change)
+//	go test -run '^$' -bench . -benchmem -count 10 > new.txt
+//	benchstat old.txt new.txt
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// benchKey signs the benchmarks' requests; setupBenchmark makes it the key
+// the service verifies with
+var benchKey = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+
+// benchPing is a ping as Discord sends it
+func benchPing(b *testing.B) []byte {
+	return benchJSON(b, Interaction{Type: InteractionTypePing, ID: "bench-ping", ApplicationID: "bench-app"})
+}
+
+// benchCommand is a slash command with a subcommand, options, a member and
+// resolved users, about the size of a typical one
+func benchCommand(b *testing.B) []byte {
+	user := map[string]interface{}{"id": "300000000000000001", "username": "bench", "global_name": "Bench"}
+	return benchJSON(b, Interaction{
+		Type:          InteractionTypeApplicationCommand,
+		ID:            "100000000000000001",
+		ApplicationID: "200000000000000001",
+		Token:         "bench-interaction-token",
+		GuildID:       "400000000000000001",
+		ChannelID:     "500000000000000001",
+		Locale:        "en-US",
+		GuildLocale:   "en-US",
+		Member: map[string]interface{}{
+			"user":        user,
+			"roles":       []string{"600000000000000001", "600000000000000002"},
+			"permissions": "2147483647",
+		},
+		Data: map[string]interface{}{
+			"id":   "700000000000000001",
+			"name": "config",
+			"type": 1,
+			"options": []map[string]interface{}{{
+				"name": "set",
+				"type": 1,
+				"options": []map[string]interface{}{
+					{"name": "key", "type": 3, "value": "greeting"},
+					{"name": "value", "type": 3, "value": "Hello, world! 👋"},
+					{"name": "target", "type": 6, "value": "300000000000000001"},
+				},
+			}},
+			"resolved": map[string]interface{}{
+				"users": map[string]interface{}{"300000000000000001": user},
+			},
+		},
+	})
+}
+
+func benchJSON(b *testing.B, v interface{}) []byte {
+	body, err := json.Marshal(v)
+	if err != nil {
+		b.Fatalf("Failed to marshal fixture: %v", err)
+	}
+	return body
+}
+
+// benchRequest returns a request for body signed with benchKey. Its
+// timestamp is only accepted for a few seconds, so keep -benchtime short.
+func benchRequest(body []byte) *http.Request {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := ed25519.Sign(benchKey, append([]byte(timestamp), body...))
+	r := httptest.NewRequest(http.MethodPost, "/interactions", nil)
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Signature-Ed25519", hex.EncodeToString(signature))
+	r.Header.Set("X-Signature-Timestamp", timestamp)
+	return r
+}
+
+// setupBenchmark configures the service as main does with no optional
+// features enabled, and silences logging
+func setupBenchmark(b *testing.B) {
+	b.Helper()
+
+	publicKey = benchKey.Public().(ed25519.PublicKey)
+	maxBodyBytes = 64 << 10
+	logger = newLogger(io.Discard)
+	guilds = newGuildPolicy(nil, nil)
+	commands = newCommandPolicy(nil)
+	permissions = &permissionPolicy{}
+	premium = &premiumPolicy{}
+	contexts = &contextPolicy{}
+	cmdMetrics = newCommandMetrics(100, false, 100)
+	publishBreaker = newCircuitBreaker(5, 30*time.Second)
+	var err error
+	if messages, err = loadMessageCatalog(defaultLocale); err != nil {
+		b.Fatalf("Failed to load message catalog: %v", err)
+	}
+}
+
+// BenchmarkValidateSignature measures verifying a signed request
+func BenchmarkValidateSignature(b *testing.B) {
+	setupBenchmark(b)
+	for _, bench := range []struct {
+		name string
+		body []byte
+	}{{"ping", benchPing(b)}, {"command", benchCommand(b)}} {
+		b.Run("body="+bench.name, func(b *testing.B) {
+			r := benchRequest(bench.body)
+			b.SetBytes(int64(len(bench.body)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := validateSignature(r, bench.body); err != nil {
+					b.Fatalf("Signature rejected: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDecodeInteraction measures checking and parsing a verified body
+func BenchmarkDecodeInteraction(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		body []byte
+	}{{"ping", benchPing(b)}, {"command", benchCommand(b)}} {
+		b.Run("body="+bench.name, func(b *testing.B) {
+			b.SetBytes(int64(len(bench.body)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := decodeInteraction(bench.body); err != nil {
+					b.Fatalf("Body rejected: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSanitize measures building the published message from a parsed
+// slash command
+func BenchmarkSanitize(b *testing.B) {
+	setupBenchmark(b)
+	interaction, err := decodeInteraction(benchCommand(b))
+	if err != nil {
+		b.Fatalf("Body rejected: %v", err)
+	}
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := newInteractionMessage(ctx, interaction); err != nil {
+			b.Fatalf("Failed to build message: %v", err)
+		}
+	}
+}
+
+// BenchmarkHandler measures a signed request through the router, with the
+// middleware main installs, to its response. Slash commands are deferred
+// without a publish backend, so the publish itself isn't measured.
+func BenchmarkHandler(b *testing.B) {
+	setupBenchmark(b)
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(securityHeaders())
+	r.Use(accessLog("", 0))
+	r.Use(requestLogger(""))
+	r.Use(recovery())
+	r.Use(requestTimeout(2500 * time.Millisecond))
+	r.POST("/interactions", handleInteraction)
+
+	for _, bench := range []struct {
+		name         string
+		body         []byte
+		responseType int
+	}{
+		{"ping", benchPing(b), ResponseTypePong},
+		{"command", benchCommand(b), ResponseTypeDeferredChannelMessage},
+	} {
+		b.Run("body="+bench.name, func(b *testing.B) {
+			req := benchRequest(bench.body)
+			serve := func() *httptest.ResponseRecorder {
+				req.Body = io.NopCloser(bytes.NewReader(bench.body))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w
+			}
+
+			var resp InteractionResponse
+			if w := serve(); json.Unmarshal(w.Body.Bytes(), &resp) != nil || resp.Type != bench.responseType {
+				b.Fatalf("Expected response type %d, got status %d: %s", bench.responseType, w.Code, w.Body)
+			}
+			b.SetBytes(int64(len(bench.body)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if w := serve(); w.Code != http.StatusOK {
+					b.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+				}
+			}
+		})
+	}
+}
+
+// discardWriter is a ResponseWriter that keeps nothing, so benchmarks
+// measure writing a response rather than recording it
+type discardWriter struct{ header http.Header }
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// BenchmarkRespond measures writing the hottest responses, precomputed as
+// the service writes them and marshaled as c.JSON would
+func BenchmarkRespond(b *testing.B) {
+	gin.SetMode(gin.ReleaseMode)
+	for _, tc := range precomputedCases[:3] {
+		for _, write := range []struct {
+			name    string
+			respond func(c *gin.Context)
+		}{
+			{"precomputed", tc.respond},
+			{"json", func(c *gin.Context) { c.JSON(http.StatusOK, tc.want) }},
+		} {
+			b.Run("response="+tc.name+"/write="+write.name, func(b *testing.B) {
+				r := gin.New()
+				r.POST("/interactions", write.respond)
+				req := httptest.NewRequest(http.MethodPost, "/interactions", nil)
+				w := &discardWriter{header: http.Header{}}
+				b.ReportAllocs()
+				for b.Loop() {
+					clear(w.header)
+					r.ServeHTTP(w, req)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/internal/sinkpb"
+)
+
+// archive streams published interactions into BigQuery alongside the
+// publish backend. Nil unless BIGQUERY_TABLE is set.
+var archive *bigQueryArchive
+
+// parseTableName accepts "dataset.table" (in defaultProject) or
+// "project.dataset.table" and returns the Storage Write API table path
+func parseTableName(name, defaultProject string) (string, error) {
+	parts := strings.Split(name, ".")
+	for _, p := range parts {
+		if p == "" {
+			return "", fmt.Errorf("invalid table %q (want [project.]dataset.table)", name)
+		}
+	}
+	switch len(parts) {
+	case 2:
+		if defaultProject == "" {
+			return "", fmt.Errorf("table %q needs a project (GOOGLE_CLOUD_PROJECT or project.dataset.table)", name)
+		}
+		return managedwriter.TableParentFromParts(defaultProject, parts[0], parts[1]), nil
+	case 3:
+		return managedwriter.TableParentFromParts(parts[0], parts[1], parts[2]), nil
+	default:
+		return "", fmt.Errorf("invalid table %q (want [project.]dataset.table)", name)
+	}
+}
+
+// bigQueryArchive appends one row per interaction to the table's default
+// stream, so rows are queryable within seconds without a consumer service
+type bigQueryArchive struct {
+	client *managedwriter.Client
+	stream *managedwriter.ManagedStream
+}
+
+func newBigQueryArchive(ctx context.Context, projectID, table string) (*bigQueryArchive, error) {
+	descriptor, err := adapt.NormalizeDescriptor((&sinkpb.ArchivedInteraction{}).ProtoReflect().Descriptor())
+	if err != nil {
+		return nil, err
+	}
+	client, err := managedwriter.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := client.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(table),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(descriptor),
+		managedwriter.EnableWriteRetries(true),
+	)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &bigQueryArchive{client: client, stream: stream}, nil
+}
+
+// archiveRow builds the table row for a published message
+func archiveRow(msg *pubsub.Message, interaction *Interaction) *sinkpb.ArchivedInteraction {
+	return &sinkpb.ArchivedInteraction{
+		InteractionId:   interaction.ID,
+		InteractionType: int64(interaction.Type),
+		ApplicationId:   interaction.ApplicationID,
+		GuildId:         interaction.GuildID,
+		ChannelId:       interaction.ChannelID,
+		UserId:          interaction.UserID(),
+		CommandName:     msg.Attributes["command_name"],
+		CommandPath:     msg.Attributes["command_path"],
+		CustomId:        msg.Attributes["custom_id"],
+		Context:         msg.Attributes["context"],
+		Locale:          interaction.Locale,
+		ServiceVersion:  msg.Attributes["service_version"],
+		ReceivedAt:      time.Now().UnixMicro(),
+		Payload:         string(msg.Data),
+	}
+}
+
+// Append queues the interaction's row without waiting for BigQuery, so the
+// archive never delays the response. Failures are logged and counted.
+func (a *bigQueryArchive) Append(ctx context.Context, msg *pubsub.Message, interaction *Interaction) {
+	log := loggerFrom(ctx)
+
+	row, err := proto.Marshal(archiveRow(msg, interaction))
+	if err != nil {
+		log.Error("Failed to encode archive row", "interaction_id", interaction.ID, "error", err)
+		return
+	}
+	// The append outlives the request
+	result, err := a.stream.AppendRows(context.WithoutCancel(ctx), [][]byte{row})
+	if err != nil {
+		archiveRowsTotal.WithLabelValues("error").Inc()
+		log.Error("Failed to archive interaction to BigQuery", "interaction_id", interaction.ID, "error", err)
+		return
+	}
+	go func() {
+		if _, err := result.GetResult(context.Background()); err != nil {
+			archiveRowsTotal.WithLabelValues("error").Inc()
+			log.Error("Failed to archive interaction to BigQuery", "interaction_id", interaction.ID, "error", err)
+			return
+		}
+		archiveRowsTotal.WithLabelValues("success").Inc()
+	}()
+}
+
+// checkArchiveTable verifies the archive table exists and has a column for
+// every row field
+func checkArchiveTable(ctx context.Context, cfg *Config) error {
+	if cfg.BigQueryTable == "" {
+		return nil
+	}
+	table, err := parseTableName(cfg.BigQueryTable, cfg.ProjectID)
+	if err != nil {
+		return err
+	}
+	client, err := managedwriter.NewClient(ctx, cfg.ProjectID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.GetWriteStream(ctx, &storagepb.GetWriteStreamRequest{
+		Name: table + "/streams/_default",
+		View: storagepb.WriteStreamView_FULL,
+	})
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]bool)
+	for _, field := range stream.GetTableSchema().GetFields() {
+		columns[field.GetName()] = true
+	}
+	fields := (&sinkpb.ArchivedInteraction{}).ProtoReflect().Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if name := string(fields.Get(i).Name()); !columns[name] {
+			return fmt.Errorf("table %s has no %s column", cfg.BigQueryTable, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// breakerState is the state of a circuit breaker
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// errBreakerOpen is returned by Execute when the breaker rejects a call
+var errBreakerOpen = errors.New("circuit breaker is open")
+
+// circuitBreaker is a minimal gobreaker-style circuit breaker.
+//
+// It opens after maxFailures consecutive failures, rejects calls while open,
+// and after openTimeout lets a single probe call through (half-open). A
+// successful probe closes the breaker; a failed probe re-opens it.
+type circuitBreaker struct {
+	maxFailures   int
+	openTimeout   time.Duration
+	onStateChange func(from, to breakerState)
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(maxFailures int, openTimeout time.Duration) *circuitBreaker {
+	if maxFailures < 1 {
+		maxFailures = 1
+	}
+	return &circuitBreaker{
+		maxFailures: maxFailures,
+		openTimeout: openTimeout,
+	}
+}
+
+// State returns the current breaker state
+func (b *circuitBreaker) State() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(time.Now())
+	return b.state
+}
+
+// Execute runs fn if the breaker allows it and records the outcome
+func (b *circuitBreaker) Execute(fn func() error) error {
+	if !b.allow() {
+		return errBreakerOpen
+	}
+	err := fn()
+	b.record(err == nil)
+	return err
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(time.Now())
+
+	switch b.state {
+	case breakerOpen:
+		return false
+	case breakerHalfOpen:
+		// Only one probe at a time while half-open
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+func (b *circuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.probing = false
+		if success {
+			b.setState(breakerClosed)
+		} else {
+			b.setState(breakerOpen)
+		}
+		return
+	}
+
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerClosed && b.failures >= b.maxFailures {
+		b.setState(breakerOpen)
+	}
+}
+
+// refresh moves an open breaker to half-open once the open timeout elapses.
+// Callers must hold b.mu.
+func (b *circuitBreaker) refresh(now time.Time) {
+	if b.state == breakerOpen && now.Sub(b.openedAt) >= b.openTimeout {
+		b.setState(breakerHalfOpen)
+	}
+}
+
+// setState transitions the breaker. Callers must hold b.mu.
+func (b *circuitBreaker) setState(to breakerState) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	b.failures = 0
+	if to == breakerOpen {
+		b.openedAt = time.Now()
+	}
+	if b.onStateChange != nil {
+		b.onStateChange(from, to)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// captureWriteTimeout bounds the upload, which runs after the response
+const captureWriteTimeout = 10 * time.Second
+
+// redacted replaces sensitive values in captures
+const redacted = "[REDACTED]"
+
+// capturedSecretHeaders are never written to the bucket. The signature
+// headers are kept out like everywhere else; their length is recorded so
+// truncation or re-encoding by a proxy still shows up.
+var capturedSecretHeaders = map[string]bool{
+	"X-Signature-Ed25519":   true,
+	"X-Signature-Timestamp": true,
+	"Authorization":         true,
+	"Proxy-Authorization":   true,
+	"Cookie":                true,
+}
+
+// tokenField matches every "token" string member in the body, so it can be
+// redacted without re-encoding (and so reformatting) the rest of the bytes
+var tokenField = regexp.MustCompile(`("token"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// capturer writes raw requests to a GCS bucket for debugging. Nil unless
+// PAYLOAD_CAPTURE_BUCKET is set.
+var capturer *payloadCapturer
+
+// payloadCapturer records what the edge actually received, so signature
+// discrepancies between implementations can be diagnosed from real traffic
+type payloadCapturer struct {
+	bucket     *storage.BucketHandle
+	sampleRate float64
+}
+
+// CapturedRequest is the JSON object written per captured request
+type CapturedRequest struct {
+	ReceivedAt     time.Time           `json:"received_at"`
+	Method         string              `json:"method"`
+	Path           string              `json:"path"`
+	Headers        map[string][]string `json:"headers"`
+	Body           string              `json:"body"`
+	BodyBytes      int                 `json:"body_bytes"`
+	BodySHA256     string              `json:"body_sha256"`
+	SignatureValid bool                `json:"signature_valid"`
+	SignatureError string              `json:"signature_error,omitempty"`
+	ServiceVersion string              `json:"service_version"`
+}
+
+func newPayloadCapturer(ctx context.Context, bucket string, sampleRate float64) (*payloadCapturer, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &payloadCapturer{bucket: client.Bucket(bucket), sampleRate: sampleRate}, nil
+}
+
+// newCapturedRequest builds the capture for a request. Body and header
+// bytes are kept as received apart from the redacted values; the hash and
+// length are of the unredacted body.
+func newCapturedRequest(r *http.Request, body []byte, sigErr error) *CapturedRequest {
+	sum := sha256.Sum256(body)
+	captured := &CapturedRequest{
+		ReceivedAt:     time.Now().UTC(),
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		Headers:        make(map[string][]string, len(r.Header)),
+		Body:           string(tokenField.ReplaceAll(body, []byte(`${1}"`+redacted+`"`))),
+		BodyBytes:      len(body),
+		BodySHA256:     hex.EncodeToString(sum[:]),
+		SignatureValid: sigErr == nil,
+		ServiceVersion: serviceVersion(),
+	}
+	if sigErr != nil {
+		captured.SignatureError = sigErr.Error()
+	}
+	for name, values := range r.Header {
+		if !capturedSecretHeaders[name] {
+			captured.Headers[name] = values
+			continue
+		}
+		masked := make([]string, len(values))
+		for i, v := range values {
+			masked[i] = redacted + " (" + strconv.Itoa(len(v)) + " bytes)"
+		}
+		captured.Headers[name] = masked
+	}
+	return captured
+}
+
+// Capture writes a sample of requests to the bucket without delaying the
+// response. Failures are logged and counted, never surfaced.
+func (p *payloadCapturer) Capture(c *gin.Context, body []byte, sigErr error) {
+	if rand.Float64() >= p.sampleRate {
+		return
+	}
+	captured := newCapturedRequest(c.Request, body, sigErr)
+	log := loggerFrom(c.Request.Context())
+
+	data, err := json.Marshal(captured)
+	if err != nil {
+		log.Error("Failed to encode payload capture", "error", err)
+		return
+	}
+	// Objects sort by arrival time and stay unique across instances
+	object := path.Join("captures", captured.ReceivedAt.Format("2006/01/02/15"),
+		fmt.Sprintf("%d-%s.json", captured.ReceivedAt.UnixNano(), captured.BodySHA256[:16]))
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), captureWriteTimeout)
+	go func() {
+		defer cancel()
+		w := p.bucket.Object(object).NewWriter(ctx)
+		w.ContentType = "application/json"
+		_, err := w.Write(data)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			payloadCapturesTotal.WithLabelValues("error").Inc()
+			log.Error("Failed to write payload capture", "object", object, "error", err)
+			return
+		}
+		payloadCapturesTotal.WithLabelValues("success").Inc()
+	}()
+}
+
+// checkCaptureBucket verifies the capture bucket is reachable and deletes
+// captures within the configured retention
+func checkCaptureBucket(ctx context.Context, cfg *Config) error {
+	if cfg.PayloadCaptureBucket == "" {
+		return nil
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	attrs, err := client.Bucket(cfg.PayloadCaptureBucket).Attrs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range attrs.Lifecycle.Rules {
+		if rule.Action.Type == storage.DeleteAction && rule.Condition.AgeInDays > 0 &&
+			rule.Condition.AgeInDays <= int64(cfg.PayloadCaptureRetentionDays) {
+			return nil
+		}
+	}
+	return fmt.Errorf("bucket %s has no lifecycle rule deleting objects within %d days",
+		cfg.PayloadCaptureBucket, cfg.PayloadCaptureRetentionDays)
+}
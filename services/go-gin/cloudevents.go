@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// CloudEvents context attributes in binary content mode, where each is a
+// "ce-" prefixed message attribute. The same names are valid HTTP binding
+// headers, so webhook and Cloud Tasks deliveries carry them unchanged.
+const (
+	ceSpecVersion     = "ce-specversion"
+	ceID              = "ce-id"
+	ceSource          = "ce-source"
+	ceType            = "ce-type"
+	ceSubject         = "ce-subject"
+	ceTime            = "ce-time"
+	ceContentType     = "content-type"
+	cloudEventsPrefix = "ce-"
+)
+
+// Event types of published interactions
+const (
+	eventTypeApplicationCommand = "com.discord.interaction.application_command.v1"
+	eventTypeMessageComponent   = "com.discord.interaction.message_component.v1"
+)
+
+// cloudEvents adds CloudEvents attributes to published messages so Eventarc
+// and other CloudEvents consumers can route them without parsing the body
+var cloudEvents bool
+
+// addCloudEventAttributes marks msg as a CloudEvent describing interaction
+func addCloudEventAttributes(msg *pubsub.Message, interaction *Interaction) {
+	eventType := eventTypeApplicationCommand
+	subject := interaction.CommandPath()
+	if interaction.Type == InteractionTypeMessageComponent {
+		eventType = eventTypeMessageComponent
+		subject = interaction.CustomID()
+	}
+
+	msg.Attributes[ceSpecVersion] = "1.0"
+	msg.Attributes[ceID] = interaction.ID
+	msg.Attributes[ceSource] = "//discord.com/applications/" + interaction.ApplicationID
+	msg.Attributes[ceType] = eventType
+	msg.Attributes[ceTime] = time.Now().UTC().Format(time.RFC3339Nano)
+	msg.Attributes[ceContentType] = "application/json"
+	if subject != "" {
+		msg.Attributes[ceSubject] = subject
+	}
+}
+
+// isCloudEventAttribute reports whether name is a CloudEvents attribute that
+// maps to an HTTP header of the same name
+func isCloudEventAttribute(name string) bool {
+	return strings.HasPrefix(name, cloudEventsPrefix) || name == ceContentType
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
+	"cloud.google.com/go/cloudtasks/apiv2/cloudtaskspb"
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// taskDispatcher creates a Cloud Task per interaction. Nil unless
+// PUBLISH_BACKEND is "cloudtasks".
+var taskDispatcher *cloudTasksDispatcher
+
+// queueNamePattern matches a full Cloud Tasks queue resource name
+var queueNamePattern = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/queues/[^/]+$`)
+
+// errDuplicateTask means a task was already created for the interaction
+var errDuplicateTask = errors.New("task already exists for interaction")
+
+// parseQueueName checks name is projects/<project>/locations/<location>/queues/<queue>
+func parseQueueName(name string) error {
+	if !queueNamePattern.MatchString(name) {
+		return fmt.Errorf("%q is not of the form projects/<project>/locations/<location>/queues/<queue>", name)
+	}
+	return nil
+}
+
+// cloudTasksDispatcher hands interactions to a worker URL through a Cloud
+// Tasks queue, which then owns retries and backoff, so no subscriber is needed
+type cloudTasksDispatcher struct {
+	client         *cloudtasks.Client
+	queue          string
+	targetURL      string
+	delay          time.Duration
+	serviceAccount string
+}
+
+func newCloudTasksDispatcher(ctx context.Context, cfg *Config) (*cloudTasksDispatcher, error) {
+	client, err := cloudtasks.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudTasksDispatcher{
+		client:         client,
+		queue:          cfg.CloudTasksQueue,
+		targetURL:      cfg.CloudTasksTargetURL,
+		delay:          cfg.CloudTasksScheduleDelay,
+		serviceAccount: cfg.CloudTasksServiceAccount,
+	}, nil
+}
+
+// taskName derives the task name from the interaction ID, so Cloud Tasks
+// rejects a second task for the same interaction
+func (d *cloudTasksDispatcher) taskName(interactionID string) string {
+	return d.queue + "/tasks/interaction-" + interactionID
+}
+
+// Dispatch creates the task. A task that already exists for the interaction
+// is reported as errDuplicateTask.
+func (d *cloudTasksDispatcher) Dispatch(ctx context.Context, msg *pubsub.Message, interactionID string) error {
+	headers := attributeHeaders(msg.Attributes)
+	headers["Content-Type"] = "application/json"
+
+	req := &cloudtaskspb.HttpRequest{
+		Url:        d.targetURL,
+		HttpMethod: cloudtaskspb.HttpMethod_POST,
+		Headers:    headers,
+		Body:       msg.Data,
+	}
+	// Cloud Run targets authenticate the queue with an OIDC token
+	if d.serviceAccount != "" {
+		req.AuthorizationHeader = &cloudtaskspb.HttpRequest_OidcToken{
+			OidcToken: &cloudtaskspb.OidcToken{ServiceAccountEmail: d.serviceAccount},
+		}
+	}
+
+	task := &cloudtaskspb.Task{
+		Name:        d.taskName(interactionID),
+		MessageType: &cloudtaskspb.Task_HttpRequest{HttpRequest: req},
+	}
+	if d.delay > 0 {
+		task.ScheduleTime = timestamppb.New(time.Now().Add(d.delay))
+	}
+
+	_, err := d.client.CreateTask(ctx, &cloudtaskspb.CreateTaskRequest{Parent: d.queue, Task: task})
+	if status.Code(err) == codes.AlreadyExists {
+		return errDuplicateTask
+	}
+	return err
+}
+
+// dispatchTask creates the interaction's task through the circuit breaker.
+// Duplicates don't count as breaker failures.
+func dispatchTask(ctx context.Context, msg *pubsub.Message, interaction *Interaction) error {
+	log := loggerFrom(ctx)
+
+	var duplicate bool
+	err := publishBreaker.Execute(func() error {
+		err := taskDispatcher.Dispatch(ctx, msg, interaction.ID)
+		if errors.Is(err, errDuplicateTask) {
+			duplicate = true
+			return nil
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, errBreakerOpen):
+		cloudTasksCreatedTotal.WithLabelValues("rejected").Inc()
+		log.Warn("Cloud Tasks circuit breaker open", "interaction_id", interaction.ID)
+	case errors.Is(err, context.DeadlineExceeded), status.Code(err) == codes.DeadlineExceeded:
+		cloudTasksCreatedTotal.WithLabelValues("timeout").Inc()
+		log.Warn("Cloud Task was not created before its deadline", "interaction_id", interaction.ID)
+	case err != nil:
+		cloudTasksCreatedTotal.WithLabelValues("error").Inc()
+		log.Error("Failed to create Cloud Task", "interaction_id", interaction.ID, "error", err)
+		errorReporter.Report(fmt.Errorf("cloud tasks create failed: %w", err), nil, interactionFields(interaction))
+	case duplicate:
+		cloudTasksCreatedTotal.WithLabelValues("duplicate").Inc()
+		log.Info("Cloud Task already exists for interaction", "interaction_id", interaction.ID)
+	default:
+		cloudTasksCreatedTotal.WithLabelValues("success").Inc()
+	}
+	return err
+}
+
+// checkTaskQueue verifies the Cloud Tasks queue exists and is reachable
+func checkTaskQueue(ctx context.Context, cfg *Config) error {
+	if cfg.PublishBackend != backendCloudTasks {
+		return nil
+	}
+	client, err := cloudtasks.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	queue, err := client.GetQueue(ctx, &cloudtaskspb.GetQueueRequest{Name: cfg.CloudTasksQueue})
+	if err != nil {
+		return err
+	}
+	if queue.GetState() != cloudtaskspb.Queue_RUNNING {
+		return fmt.Errorf("queue %s is %s", cfg.CloudTasksQueue, queue.GetState())
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// InteractionTypeMessageComponent is sent when a user clicks a button or uses a select menu
+const InteractionTypeMessageComponent = 3
+
+// ResponseTypeDeferredUpdateMessage acknowledges a component interaction and
+// shows a loading state on the original message instead of posting a reply
+const ResponseTypeDeferredUpdateMessage = 6
+
+// CustomID returns the custom_id of the component that was used, or "" if there is none
+func (i *Interaction) CustomID() string {
+	id, _ := i.Data["custom_id"].(string)
+	return id
+}
+
+// componentPolicy decides how component interactions are acknowledged
+type componentPolicy struct {
+	// updatePrefixes selects custom_ids answered with DEFERRED_UPDATE_MESSAGE
+	updatePrefixes []string
+	// activityPrefixes selects custom_ids answered with LAUNCH_ACTIVITY
+	activityPrefixes []string
+	// immediate holds the enabled immediate handlers by custom_id prefix
+	immediate map[string]immediateHandler
+}
+
+// responseType returns the response type for a component's custom_id.
+// Components default to a new deferred reply, like slash commands.
+func (p *componentPolicy) responseType(customID string) int {
+	for _, prefix := range p.activityPrefixes {
+		if strings.HasPrefix(customID, prefix) {
+			return ResponseTypeLaunchActivity
+		}
+	}
+	for _, prefix := range p.updatePrefixes {
+		if strings.HasPrefix(customID, prefix) {
+			return ResponseTypeDeferredUpdateMessage
+		}
+	}
+	return ResponseTypeDeferredChannelMessage
+}
+
+// componentRegistry holds immediate handlers by custom_id prefix. They
+// resolve simple flows at the edge, usually with an UPDATE_MESSAGE response,
+// and only run for prefixes enabled through IMMEDIATE_COMPONENTS.
+var componentRegistry = map[string]immediateHandler{}
+
+// registerImmediateComponent makes an immediate handler available for a custom_id prefix
+func registerImmediateComponent(prefix string, h immediateHandler) {
+	componentRegistry[prefix] = h
+}
+
+func init() {
+	registerImmediateComponent("cancel:", immediateCancel)
+}
+
+// enabledComponentHandlers returns the registered handlers for the given prefixes
+func enabledComponentHandlers(prefixes []string) (map[string]immediateHandler, error) {
+	enabled := make(map[string]immediateHandler, len(prefixes))
+	for _, prefix := range prefixes {
+		h, ok := componentRegistry[prefix]
+		if !ok {
+			return nil, fmt.Errorf("no immediate component handler registered for %q", prefix)
+		}
+		enabled[prefix] = h
+	}
+	return enabled, nil
+}
+
+// immediateHandler returns the enabled handler whose prefix matches customID.
+// When prefixes overlap, the longest match wins, so a specific flow can
+// override a general one whatever order the map is ranged in.
+func (p *componentPolicy) immediateHandler(customID string) (prefix string, h immediateHandler, ok bool) {
+	for candidate, handler := range p.immediate {
+		if strings.HasPrefix(customID, candidate) && (!ok || len(candidate) > len(prefix)) {
+			prefix, h, ok = candidate, handler, true
+		}
+	}
+	return prefix, h, ok
+}
+
+// immediateCancel resolves the cancel half of a confirm/cancel prompt by
+// replacing the prompt and removing its buttons
+func immediateCancel(interaction *Interaction) InteractionResponse {
+	return payload.NewMessage().
+		Content(localize(interaction, msgCancelled)).
+		ClearComponents().
+		Update()
+}
+
+func handleMessageComponent(c *gin.Context, interaction *Interaction) {
+	if !guilds.Allowed(interaction.GuildID) {
+		policyRejectionsTotal.WithLabelValues("guild").Inc()
+		respondEphemeral(c, localize(interaction, msgCommandNotAvailable))
+		return
+	}
+
+	// Simple button flows are resolved at the edge without publishing
+	if prefix, h, ok := componentResponses.immediateHandler(interaction.CustomID()); ok {
+		immediateResponsesTotal.WithLabelValues(prefix).Inc()
+		c.JSON(http.StatusOK, h(interaction))
+		return
+	}
+
+	// Launching an Activity needs no follow-up, so nothing is published
+	responseType := componentResponses.responseType(interaction.CustomID())
+	if responseType != ResponseTypeLaunchActivity {
+		startPublish(c.Request.Context(), interaction)
+	}
+
+	respondType(c, responseType)
+}
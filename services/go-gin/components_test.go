@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+// TestImmediateHandler_LongestPrefix checks overlapping prefixes resolve to
+// the most specific handler, however the map happens to be ordered
+func TestImmediateHandler_LongestPrefix(t *testing.T) {
+	handler := func(content string) immediateHandler {
+		return func(*Interaction) InteractionResponse {
+			return InteractionResponse{Type: ResponseTypeChannelMessage, Data: map[string]interface{}{"content": content}}
+		}
+	}
+	policy := &componentPolicy{immediate: map[string]immediateHandler{
+		"confirm:":       handler("confirm"),
+		"confirm:purge:": handler("purge"),
+		"confirm:p":      handler("p"),
+		"cancel:":        handler("cancel"),
+	}}
+
+	for _, tc := range []struct {
+		customID string
+		want     string
+	}{
+		{"confirm:purge:42", "confirm:purge:"},
+		{"confirm:pin:1", "confirm:p"},
+		{"confirm:ban:1", "confirm:"},
+		{"cancel:purge", "cancel:"},
+		{"other:1", ""},
+	} {
+		// Map iteration order is random; repeat so an order-dependent match
+		// would show up
+		for range 50 {
+			prefix, h, ok := policy.immediateHandler(tc.customID)
+			if ok != (tc.want != "") || prefix != tc.want {
+				t.Fatalf("%s: expected prefix %q, got %q (ok=%v)", tc.customID, tc.want, prefix, ok)
+			}
+			if ok && h(nil).Data["content"] != policy.immediate[tc.want](nil).Data["content"] {
+				t.Fatalf("%s: prefix %q returned another prefix's handler", tc.customID, prefix)
+			}
+		}
+	}
+}
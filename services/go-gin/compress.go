@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// Message data compression. Disabled when compression is empty.
+var (
+	compression          string
+	compressionThreshold int
+)
+
+// compressMessage returns msg with its data compressed and the
+// content_encoding attribute set when the data exceeds the threshold. msg is
+// not modified, and is returned as is if compression fails.
+func compressMessage(ctx context.Context, msg *pubsub.Message, interaction *Interaction) *pubsub.Message {
+	if compression == "" || len(msg.Data) <= compressionThreshold {
+		return msg
+	}
+	data, err := payload.Encode(msg.Data, compression)
+	if err != nil {
+		loggerFrom(ctx).Error("Failed to compress message, publishing uncompressed",
+			"interaction_id", interaction.ID, "error", err)
+		return msg
+	}
+	compressedMessagesTotal.WithLabelValues(compression).Inc()
+	compressionSavedBytes.Add(float64(len(msg.Data) - len(data)))
+
+	attrs := make(map[string]string, len(msg.Attributes)+1)
+	for k, v := range msg.Attributes {
+		attrs[k] = v
+	}
+	attrs[payload.AttributeContentEncoding] = compression
+	return &pubsub.Message{Data: data, Attributes: attrs}
+}
@@ -0,0 +1,746 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// Publish backends selected by PUBLISH_BACKEND
+const (
+	backendPubSub     = "pubsub"
+	backendWebhook    = "webhook"
+	backendCloudTasks = "cloudtasks"
+	backendGRPC       = "grpc"
+)
+
+// Config holds the service configuration loaded from the environment
+type Config struct {
+	Port        string
+	PublicKey   ed25519.PublicKey
+	ProjectID   string
+	PubSubTopic string
+
+	// Where interactions are published: "pubsub", "webhook", "cloudtasks" or
+	// "grpc"
+	PublishBackend string
+
+	// Add CloudEvents attributes to published messages (for Eventarc triggers)
+	CloudEvents bool
+
+	// Webhook backend: HTTPS endpoint, HMAC signing secret, and retry policy
+	WebhookURL         string
+	WebhookSecret      string
+	WebhookMaxAttempts int
+	WebhookTimeout     time.Duration
+
+	// Cloud Tasks backend: queue (projects/<p>/locations/<l>/queues/<q>), the
+	// worker URL tasks target, dispatch delay, and the service account whose
+	// OIDC token authenticates dispatches (none when empty)
+	CloudTasksQueue          string
+	CloudTasksTargetURL      string
+	CloudTasksScheduleDelay  time.Duration
+	CloudTasksServiceAccount string
+
+	// gRPC egress: processor address (host:port) and whether to skip TLS
+	GRPCEgressTarget    string
+	GRPCEgressPlaintext bool
+
+	// BigQuery table ([project.]dataset.table) every published interaction is
+	// also archived to (disabled when empty)
+	BigQueryTable string
+
+	// Firestore collection recording accepted interactions for dedup and
+	// audit (disabled when empty), and how long documents are kept
+	InteractionStoreCollection string
+	InteractionStoreTTL        time.Duration
+	FirestoreDatabase          string
+
+	// JSON file of feature flag rules, re-read every FeatureFlagsRefresh
+	// (flags evaluate to their defaults when empty)
+	FeatureFlagsFile    string
+	FeatureFlagsRefresh time.Duration
+
+	// Redis shared by all instances for dedup and rate limiting (in-memory
+	// per instance when empty or unavailable)
+	RedisURL string
+
+	// How long interaction IDs are remembered to drop redeliveries (0 = off)
+	DedupWindow time.Duration
+
+	// Commands each user, and each guild, may run per window (0 = unlimited)
+	UserRateLimit   int
+	GuildRateLimit  int
+	RateLimitWindow time.Duration
+
+	// Optional Pub/Sub topic receiving audit events
+	AuditPubSubTopic string
+
+	// Secondary topic used when publishing to PubSubTopic fails; a bare name
+	// or projects/<project>/topics/<topic> for another project
+	FailoverPubSubTopic string
+
+	// Compress message data larger than the threshold with gzip or zstd
+	// (disabled when empty)
+	PublishCompression          string
+	PublishCompressionThreshold int
+
+	// HMAC-SHA256 key published message data is signed with (unsigned when
+	// empty), and the key ID sent alongside for rotation
+	PublishSigningKey   string
+	PublishSigningKeyID string
+
+	// Shadow destination a sample of interactions is mirrored to: a topic
+	// (bare name or projects/<project>/topics/<topic>) or a signed webhook
+	ShadowPubSubTopic   string
+	ShadowWebhookURL    string
+	ShadowWebhookSecret string
+	ShadowSampleRate    float64
+
+	// Guilds the service serves (empty allowlist = all guilds)
+	GuildAllowlist []string
+	GuildDenylist  []string
+
+	// Command names accepted for publishing (empty = all commands)
+	AllowedCommands []string
+
+	// Where each command may be used (command name -> guild, dm, or any)
+	CommandContexts map[string]string
+
+	// Member permissions required per command (command name -> bitfield)
+	CommandPermissions map[string]uint64
+
+	// SKUs unlocking monetized commands (command name -> set of SKU IDs)
+	CommandSKUs map[string]map[string]bool
+
+	// Per-command response policies (deferred, ephemeral, static, or launch_activity)
+	CommandResponses map[string]ResponsePolicy
+
+	// Who answers the primary entry point command: "app" or "discord"
+	EntryPointHandler string
+
+	// Commands answered by built-in immediate handlers, and the /help catalog
+	ImmediateCommands []string
+	HelpCatalogFile   string
+
+	// Locale used when neither the user's nor the guild's locale has a translation
+	DefaultLocale string
+
+	// Component interactions (type 3) are rejected unless enabled. The
+	// prefixes select custom_ids answered with DEFERRED_UPDATE_MESSAGE or
+	// LAUNCH_ACTIVITY, or resolved by immediate component handlers.
+	ComponentInteractions     bool
+	ComponentUpdatePrefixes   []string
+	ComponentActivityPrefixes []string
+	ImmediateComponents       []string
+
+	// Worker public key interaction tokens are sealed to (tokens are dropped when nil)
+	TokenSealKey *[32]byte
+
+	// GCS bucket attachments are copied to before publishing (disabled when empty)
+	AttachmentBucket   string
+	AttachmentMaxBytes int64
+
+	// Debugging: GCS bucket raw (token-redacted) requests are written to
+	// (disabled when empty), the fraction captured, and the retention the
+	// bucket's lifecycle rule must enforce
+	PayloadCaptureBucket        string
+	PayloadCaptureSampleRate    float64
+	PayloadCaptureRetentionDays int
+
+	// TLS for the interactions listener (plain HTTP when TLSCertFile is empty).
+	// A client CA bundle enables mutual TLS with the given verification mode.
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+	TLSClientAuth   string
+
+	// Let's Encrypt certificates for the listed domains, instead of cert files
+	AutocertDomains  []string
+	AutocertCacheDir string
+	AutocertEmail    string
+
+	// Plain HTTP listener redirecting to HTTPS (disabled when empty)
+	HTTPRedirectPort string
+
+	// HTTP paths, all mounted under BasePath (e.g. "/bots/myapp")
+	BasePath         string
+	InteractionPaths []string
+	HealthPath       string
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For is believed for the
+	// client address; none by default
+	TrustedProxies []string
+
+	// Client addresses interaction requests are accepted from; all when empty
+	AllowedSources []netip.Prefix
+
+	// Largest accepted request body
+	MaxBodyBytes int64
+
+	// How deeply objects and arrays may nest in a request body
+	JSONMaxDepth int
+
+	// Admin listener for pprof/expvar (disabled when AdminPort is empty)
+	AdminPort  string
+	AdminToken string
+
+	// Google ID tokens accepted for the admin endpoints: the audience they
+	// must be issued for and the emails allowed to present them
+	AdminIDTokenAudience string
+	AdminIDTokenMembers  []string
+
+	// Where the admin endpoints are mounted on the interactions port, when
+	// AdminToken or AdminIDTokenAudience is set
+	AdminPath string
+
+	// Whether /metrics is served on the interactions port without
+	// authentication (default: only when admin auth isn't configured)
+	PublicMetrics bool
+
+	// Interactions kept for the admin API's /admin/interactions
+	AdminRecentInteractions int
+
+	// Fraction of successful requests written to the access log (errors are always logged)
+	AccessLogSampleRate float64
+
+	// Label cardinality limits for per-command and per-guild metrics
+	MetricsMaxCommands int
+	MetricsGuildLabels bool
+	MetricsMaxGuilds   int
+
+	// Overall deadline for handling a request (Discord allows 3s)
+	RequestTimeout time.Duration
+
+	// Load shedding: interaction requests beyond MaxConcurrentRequests in
+	// flight get 503 with Retry-After (0 disables)
+	MaxConcurrentRequests int
+	ShedRetryAfter        time.Duration
+
+	// Listener timeouts: how long a client may take to send a request's
+	// headers and its whole request, and how long an idle keep-alive
+	// connection is kept. Clients trickling a request are cut off rather
+	// than holding a connection open indefinitely.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	IdleTimeout       time.Duration
+
+	// How long startup may spend on Pub/Sub round trips before serving
+	WarmupTimeout time.Duration
+
+	// Circuit breaker around Pub/Sub publishing
+	BreakerMaxFailures int
+	BreakerOpenTimeout time.Duration
+}
+
+// dotEnvFiles are loaded for local development, earlier files taking precedence
+var dotEnvFiles = []string{".env.local", ".env"}
+
+// loadDotEnv sets environment variables from the dotenv files in the working
+// directory. Variables already in the environment are never overridden and
+// missing files are skipped, so deployments without the files are unaffected.
+func loadDotEnv() (loaded []string, err error) {
+	for _, name := range dotEnvFiles {
+		if _, err := os.Stat(name); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := godotenv.Load(name); err != nil {
+			return loaded, fmt.Errorf("failed to load %s: %w", name, err)
+		}
+		loaded = append(loaded, name)
+	}
+	return loaded, nil
+}
+
+// loadConfig reads the service configuration from environment variables
+func loadConfig() (*Config, error) {
+	cfg := &Config{
+		Port:        envString("PORT", "8080"),
+		ProjectID:   os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		PubSubTopic: os.Getenv("PUBSUB_TOPIC"),
+		AdminPort:   os.Getenv("ADMIN_PORT"),
+		AdminToken:  os.Getenv("ADMIN_TOKEN"),
+
+		AuditPubSubTopic: os.Getenv("AUDIT_PUBSUB_TOPIC"),
+		GuildAllowlist:   envList("GUILD_ALLOWLIST"),
+		GuildDenylist:    envList("GUILD_DENYLIST"),
+		AllowedCommands:  envList("ALLOWED_COMMANDS"),
+
+		ImmediateCommands: envList("IMMEDIATE_COMMANDS"),
+		HelpCatalogFile:   os.Getenv("HELP_CATALOG_FILE"),
+		DefaultLocale:     envString("DEFAULT_LOCALE", "en-US"),
+	}
+
+	publicKeyHex := os.Getenv("DISCORD_PUBLIC_KEY")
+	if publicKeyHex == "" {
+		return nil, errors.New("DISCORD_PUBLIC_KEY environment variable is required")
+	}
+	key, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DISCORD_PUBLIC_KEY: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid DISCORD_PUBLIC_KEY: want %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	cfg.PublicKey = key
+
+	cfg.TLSCertFile = os.Getenv("TLS_CERT_FILE")
+	cfg.TLSKeyFile = os.Getenv("TLS_KEY_FILE")
+	cfg.TLSClientCAFile = os.Getenv("TLS_CLIENT_CA_FILE")
+	cfg.TLSClientAuth = envString("TLS_CLIENT_AUTH", clientAuthRequire)
+	cfg.AutocertDomains = envList("TLS_AUTOCERT_DOMAINS")
+	cfg.AutocertCacheDir = envString("TLS_AUTOCERT_CACHE_DIR", "autocert-cache")
+	cfg.AutocertEmail = os.Getenv("TLS_AUTOCERT_EMAIL")
+	cfg.HTTPRedirectPort = os.Getenv("HTTP_REDIRECT_PORT")
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLSCertFile != "" && len(cfg.AutocertDomains) > 0 {
+		return nil, errors.New("TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS are mutually exclusive")
+	}
+	tlsEnabled := cfg.TLSCertFile != "" || len(cfg.AutocertDomains) > 0
+	if cfg.TLSClientCAFile != "" && !tlsEnabled {
+		return nil, errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+	}
+	if cfg.HTTPRedirectPort != "" && !tlsEnabled {
+		return nil, errors.New("HTTP_REDIRECT_PORT requires TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+	}
+	if cfg.HTTPRedirectPort != "" && (cfg.HTTPRedirectPort == cfg.Port || cfg.HTTPRedirectPort == cfg.AdminPort) {
+		return nil, errors.New("HTTP_REDIRECT_PORT must differ from PORT and ADMIN_PORT")
+	}
+	if cfg.TLSClientAuth != clientAuthRequire && cfg.TLSClientAuth != clientAuthOptional {
+		return nil, fmt.Errorf("TLS_CLIENT_AUTH must be %q or %q", clientAuthRequire, clientAuthOptional)
+	}
+
+	cfg.BasePath = strings.TrimSuffix(os.Getenv("BASE_PATH"), "/")
+	cfg.InteractionPaths = envList("INTERACTIONS_PATHS")
+	if len(cfg.InteractionPaths) == 0 {
+		cfg.InteractionPaths = []string{"/", "/interactions"}
+	}
+	cfg.HealthPath = envString("HEALTH_PATH", "/health")
+	cfg.TrustedProxies = envList("TRUSTED_PROXIES")
+	if cfg.AllowedSources, err = parseSourcePrefixes(envList("ALLOWED_SOURCE_CIDRS")); err != nil {
+		return nil, err
+	}
+	for _, p := range append([]string{cfg.BasePath, cfg.HealthPath}, cfg.InteractionPaths...) {
+		if p != "" && !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("path %q must start with /", p)
+		}
+	}
+
+	cfg.FailoverPubSubTopic = os.Getenv("PUBSUB_FAILOVER_TOPIC")
+	if cfg.FailoverPubSubTopic != "" {
+		if cfg.PubSubTopic == "" {
+			return nil, errors.New("PUBSUB_FAILOVER_TOPIC requires PUBSUB_TOPIC")
+		}
+		if _, _, err := parseTopicName(cfg.FailoverPubSubTopic, cfg.ProjectID); err != nil {
+			return nil, fmt.Errorf("invalid PUBSUB_FAILOVER_TOPIC: %w", err)
+		}
+	}
+
+	cfg.PublishCompression = os.Getenv("PUBLISH_COMPRESSION")
+	switch cfg.PublishCompression {
+	case "", payload.EncodingGzip, payload.EncodingZstd:
+	default:
+		return nil, fmt.Errorf("invalid PUBLISH_COMPRESSION %q (want gzip or zstd)", cfg.PublishCompression)
+	}
+	if cfg.PublishCompressionThreshold, err = envInt("PUBLISH_COMPRESSION_THRESHOLD", 16<<10); err != nil {
+		return nil, err
+	}
+	if cfg.PublishCompressionThreshold < 0 {
+		return nil, errors.New("PUBLISH_COMPRESSION_THRESHOLD must not be negative")
+	}
+
+	cfg.PublishSigningKey = os.Getenv("PUBLISH_SIGNING_KEY")
+	cfg.PublishSigningKeyID = os.Getenv("PUBLISH_SIGNING_KEY_ID")
+	if cfg.PublishSigningKey != "" && len(cfg.PublishSigningKey) < 32 {
+		return nil, errors.New("PUBLISH_SIGNING_KEY must be at least 32 bytes")
+	}
+	if cfg.PublishSigningKeyID != "" && cfg.PublishSigningKey == "" {
+		return nil, errors.New("PUBLISH_SIGNING_KEY_ID requires PUBLISH_SIGNING_KEY")
+	}
+
+	cfg.ShadowPubSubTopic = os.Getenv("SHADOW_PUBSUB_TOPIC")
+	cfg.ShadowWebhookURL = os.Getenv("SHADOW_WEBHOOK_URL")
+	cfg.ShadowWebhookSecret = os.Getenv("SHADOW_WEBHOOK_SECRET")
+	if cfg.ShadowSampleRate, err = envFloat("SHADOW_SAMPLE_RATE", 1); err != nil {
+		return nil, err
+	}
+	if cfg.ShadowSampleRate < 0 || cfg.ShadowSampleRate > 1 {
+		return nil, errors.New("SHADOW_SAMPLE_RATE must be between 0 and 1")
+	}
+	switch {
+	case cfg.ShadowPubSubTopic != "" && cfg.ShadowWebhookURL != "":
+		return nil, errors.New("set only one of SHADOW_PUBSUB_TOPIC and SHADOW_WEBHOOK_URL")
+	case cfg.ShadowPubSubTopic != "":
+		if cfg.ProjectID == "" {
+			return nil, errors.New("SHADOW_PUBSUB_TOPIC requires GOOGLE_CLOUD_PROJECT")
+		}
+		if _, _, err := parseTopicName(cfg.ShadowPubSubTopic, cfg.ProjectID); err != nil {
+			return nil, fmt.Errorf("invalid SHADOW_PUBSUB_TOPIC: %w", err)
+		}
+	case cfg.ShadowWebhookURL != "":
+		if cfg.ShadowWebhookSecret == "" {
+			return nil, errors.New("SHADOW_WEBHOOK_URL requires SHADOW_WEBHOOK_SECRET")
+		}
+		if err := parseWebhookURL(cfg.ShadowWebhookURL); err != nil {
+			return nil, fmt.Errorf("invalid SHADOW_WEBHOOK_URL: %w", err)
+		}
+	}
+
+	cfg.PublishBackend = envString("PUBLISH_BACKEND", backendPubSub)
+	if cfg.CloudEvents, err = envBool("PUBLISH_CLOUDEVENTS", false); err != nil {
+		return nil, err
+	}
+	cfg.WebhookURL = os.Getenv("WEBHOOK_URL")
+	cfg.WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+	if cfg.WebhookMaxAttempts, err = envInt("WEBHOOK_MAX_ATTEMPTS", 3); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookTimeout, err = envDuration("WEBHOOK_TIMEOUT", time.Second); err != nil {
+		return nil, err
+	}
+	cfg.CloudTasksQueue = os.Getenv("CLOUD_TASKS_QUEUE")
+	cfg.CloudTasksTargetURL = os.Getenv("CLOUD_TASKS_TARGET_URL")
+	cfg.CloudTasksServiceAccount = os.Getenv("CLOUD_TASKS_SERVICE_ACCOUNT")
+	if cfg.CloudTasksScheduleDelay, err = envDuration("CLOUD_TASKS_SCHEDULE_DELAY", 0); err != nil {
+		return nil, err
+	}
+	cfg.GRPCEgressTarget = os.Getenv("GRPC_EGRESS_TARGET")
+	if cfg.GRPCEgressPlaintext, err = envBool("GRPC_EGRESS_PLAINTEXT", false); err != nil {
+		return nil, err
+	}
+	if cfg.PublishBackend != backendWebhook && cfg.WebhookURL != "" {
+		return nil, errors.New("WEBHOOK_URL requires PUBLISH_BACKEND=webhook")
+	}
+	if cfg.PublishBackend != backendCloudTasks && cfg.CloudTasksQueue != "" {
+		return nil, errors.New("CLOUD_TASKS_QUEUE requires PUBLISH_BACKEND=cloudtasks")
+	}
+	if cfg.PublishBackend != backendGRPC && cfg.GRPCEgressTarget != "" {
+		return nil, errors.New("GRPC_EGRESS_TARGET requires PUBLISH_BACKEND=grpc")
+	}
+	if cfg.PublishBackend != backendPubSub && cfg.PubSubTopic != "" {
+		return nil, fmt.Errorf("PUBSUB_TOPIC is not used with PUBLISH_BACKEND=%s", cfg.PublishBackend)
+	}
+	switch cfg.PublishBackend {
+	case backendPubSub:
+	case backendWebhook:
+		if cfg.WebhookURL == "" || cfg.WebhookSecret == "" {
+			return nil, errors.New("PUBLISH_BACKEND=webhook requires WEBHOOK_URL and WEBHOOK_SECRET")
+		}
+		if err := parseWebhookURL(cfg.WebhookURL); err != nil {
+			return nil, fmt.Errorf("invalid WEBHOOK_URL: %w", err)
+		}
+		if cfg.WebhookMaxAttempts < 1 {
+			return nil, errors.New("WEBHOOK_MAX_ATTEMPTS must be positive")
+		}
+	case backendCloudTasks:
+		if cfg.CloudTasksQueue == "" || cfg.CloudTasksTargetURL == "" {
+			return nil, errors.New("PUBLISH_BACKEND=cloudtasks requires CLOUD_TASKS_QUEUE and CLOUD_TASKS_TARGET_URL")
+		}
+		if err := parseQueueName(cfg.CloudTasksQueue); err != nil {
+			return nil, fmt.Errorf("invalid CLOUD_TASKS_QUEUE: %w", err)
+		}
+		if err := parseWebhookURL(cfg.CloudTasksTargetURL); err != nil {
+			return nil, fmt.Errorf("invalid CLOUD_TASKS_TARGET_URL: %w", err)
+		}
+		if cfg.CloudTasksScheduleDelay < 0 {
+			return nil, errors.New("CLOUD_TASKS_SCHEDULE_DELAY must not be negative")
+		}
+	case backendGRPC:
+		if cfg.GRPCEgressTarget == "" {
+			return nil, errors.New("PUBLISH_BACKEND=grpc requires GRPC_EGRESS_TARGET")
+		}
+	default:
+		return nil, fmt.Errorf("PUBLISH_BACKEND must be one of %q, %q, %q, %q",
+			backendPubSub, backendWebhook, backendCloudTasks, backendGRPC)
+	}
+
+	cfg.BigQueryTable = os.Getenv("BIGQUERY_TABLE")
+	if cfg.BigQueryTable != "" {
+		if cfg.ProjectID == "" {
+			return nil, errors.New("BIGQUERY_TABLE requires GOOGLE_CLOUD_PROJECT")
+		}
+		if _, err := parseTableName(cfg.BigQueryTable, cfg.ProjectID); err != nil {
+			return nil, fmt.Errorf("invalid BIGQUERY_TABLE: %w", err)
+		}
+	}
+
+	cfg.InteractionStoreCollection = os.Getenv("INTERACTION_STORE_COLLECTION")
+	cfg.FirestoreDatabase = envString("FIRESTORE_DATABASE", "(default)")
+	if cfg.InteractionStoreTTL, err = envDuration("INTERACTION_STORE_TTL", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.InteractionStoreCollection != "" && cfg.ProjectID == "" {
+		return nil, errors.New("INTERACTION_STORE_COLLECTION requires GOOGLE_CLOUD_PROJECT")
+	}
+	if cfg.InteractionStoreTTL <= 0 {
+		return nil, errors.New("INTERACTION_STORE_TTL must be positive")
+	}
+
+	cfg.FeatureFlagsFile = os.Getenv("FEATURE_FLAGS_FILE")
+	if cfg.FeatureFlagsRefresh, err = envDuration("FEATURE_FLAGS_REFRESH", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.FeatureFlagsRefresh <= 0 {
+		return nil, errors.New("FEATURE_FLAGS_REFRESH must be positive")
+	}
+
+	cfg.RedisURL = os.Getenv("REDIS_URL")
+	if cfg.DedupWindow, err = envDuration("DEDUP_WINDOW", 0); err != nil {
+		return nil, err
+	}
+	if cfg.UserRateLimit, err = envInt("USER_RATE_LIMIT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.GuildRateLimit, err = envInt("GUILD_RATE_LIMIT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimitWindow, err = envDuration("RATE_LIMIT_WINDOW", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.DedupWindow < 0 || cfg.UserRateLimit < 0 || cfg.GuildRateLimit < 0 {
+		return nil, errors.New("DEDUP_WINDOW, USER_RATE_LIMIT and GUILD_RATE_LIMIT must not be negative")
+	}
+	if cfg.RateLimitWindow <= 0 {
+		return nil, errors.New("RATE_LIMIT_WINDOW must be positive")
+	}
+
+	if cfg.AdminRecentInteractions, err = envInt("ADMIN_RECENT_INTERACTIONS", 100); err != nil {
+		return nil, err
+	}
+	if cfg.AdminRecentInteractions < 0 {
+		return nil, errors.New("ADMIN_RECENT_INTERACTIONS must not be negative")
+	}
+
+	if cfg.AdminPort != "" && cfg.AdminPort == cfg.Port {
+		return nil, errors.New("ADMIN_PORT must differ from PORT")
+	}
+
+	// Any Google account can mint an ID token for any audience, so the
+	// audience alone admits nobody in particular
+	cfg.AdminIDTokenAudience = os.Getenv("ADMIN_ID_TOKEN_AUDIENCE")
+	cfg.AdminIDTokenMembers = envList("ADMIN_ID_TOKEN_MEMBERS")
+	if (cfg.AdminIDTokenAudience == "") != (len(cfg.AdminIDTokenMembers) == 0) {
+		return nil, errors.New("ADMIN_ID_TOKEN_AUDIENCE and ADMIN_ID_TOKEN_MEMBERS must be set together")
+	}
+	cfg.AdminPath = strings.TrimSuffix(envString("ADMIN_PATH", "/_ops"), "/")
+	if !strings.HasPrefix(cfg.AdminPath, "/") {
+		return nil, errors.New("ADMIN_PATH must start with / and not be the root")
+	}
+	// Once the ops endpoints need credentials, metrics do too unless asked
+	adminAuth := cfg.AdminToken != "" || cfg.AdminIDTokenAudience != ""
+	if cfg.PublicMetrics, err = envBool("PUBLIC_METRICS", !adminAuth); err != nil {
+		return nil, err
+	}
+
+	if cfg.CommandContexts, err = parseCommandContexts(envList("COMMAND_CONTEXTS")); err != nil {
+		return nil, fmt.Errorf("invalid COMMAND_CONTEXTS: %w", err)
+	}
+
+	if cfg.CommandPermissions, err = parseCommandPermissions(envList("COMMAND_PERMISSIONS")); err != nil {
+		return nil, fmt.Errorf("invalid COMMAND_PERMISSIONS: %w", err)
+	}
+
+	if cfg.CommandSKUs, err = parseCommandSKUs(envList("COMMAND_SKUS")); err != nil {
+		return nil, fmt.Errorf("invalid COMMAND_SKUS: %w", err)
+	}
+
+	if cfg.CommandResponses, err = parseResponsePolicies(os.Getenv("COMMAND_RESPONSES")); err != nil {
+		return nil, fmt.Errorf("invalid COMMAND_RESPONSES: %w", err)
+	}
+
+	if cfg.EntryPointHandler, err = parseEntryPointHandler(envString("ENTRY_POINT_HANDLER", entryPointHandlerApp)); err != nil {
+		return nil, err
+	}
+
+	if cfg.ComponentInteractions, err = envBool("COMPONENT_INTERACTIONS", false); err != nil {
+		return nil, err
+	}
+	cfg.ComponentUpdatePrefixes = envList("COMPONENT_DEFERRED_UPDATE_PREFIXES")
+	cfg.ImmediateComponents = envList("IMMEDIATE_COMPONENTS")
+	cfg.ComponentActivityPrefixes = envList("COMPONENT_LAUNCH_ACTIVITY_PREFIXES")
+
+	if raw := os.Getenv("TOKEN_SEAL_PUBLIC_KEY"); raw != "" {
+		if cfg.TokenSealKey, err = parseSealKey(raw); err != nil {
+			return nil, fmt.Errorf("invalid TOKEN_SEAL_PUBLIC_KEY: %w", err)
+		}
+	}
+
+	cfg.AttachmentBucket = os.Getenv("ATTACHMENT_BUCKET")
+	maxAttachment, err := envInt("ATTACHMENT_MAX_BYTES", 25<<20)
+	if err != nil {
+		return nil, err
+	}
+	if maxAttachment < 1 {
+		return nil, errors.New("ATTACHMENT_MAX_BYTES must be positive")
+	}
+	cfg.AttachmentMaxBytes = int64(maxAttachment)
+
+	cfg.PayloadCaptureBucket = os.Getenv("PAYLOAD_CAPTURE_BUCKET")
+	if cfg.PayloadCaptureSampleRate, err = envFloat("PAYLOAD_CAPTURE_SAMPLE_RATE", 1); err != nil {
+		return nil, err
+	}
+	if cfg.PayloadCaptureSampleRate < 0 || cfg.PayloadCaptureSampleRate > 1 {
+		return nil, errors.New("PAYLOAD_CAPTURE_SAMPLE_RATE must be between 0 and 1")
+	}
+	if cfg.PayloadCaptureRetentionDays, err = envInt("PAYLOAD_CAPTURE_RETENTION_DAYS", 7); err != nil {
+		return nil, err
+	}
+	if cfg.PayloadCaptureRetentionDays < 1 {
+		return nil, errors.New("PAYLOAD_CAPTURE_RETENTION_DAYS must be positive")
+	}
+
+	maxBody, err := envInt("MAX_BODY_BYTES", 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	if maxBody < 1 {
+		return nil, errors.New("MAX_BODY_BYTES must be positive")
+	}
+	cfg.MaxBodyBytes = int64(maxBody)
+	if cfg.JSONMaxDepth, err = envInt("JSON_MAX_DEPTH", payload.DefaultMaxDepth); err != nil {
+		return nil, err
+	}
+	if cfg.JSONMaxDepth < 1 {
+		return nil, errors.New("JSON_MAX_DEPTH must be positive")
+	}
+
+	if cfg.AccessLogSampleRate, err = envFloat("ACCESS_LOG_SAMPLE_RATE", 1); err != nil {
+		return nil, err
+	}
+	if cfg.AccessLogSampleRate < 0 || cfg.AccessLogSampleRate > 1 {
+		return nil, errors.New("ACCESS_LOG_SAMPLE_RATE must be between 0 and 1")
+	}
+	if cfg.MetricsMaxCommands, err = envInt("METRICS_MAX_COMMANDS", 100); err != nil {
+		return nil, err
+	}
+	if cfg.MetricsGuildLabels, err = envBool("METRICS_GUILD_LABELS", false); err != nil {
+		return nil, err
+	}
+	if cfg.MetricsMaxGuilds, err = envInt("METRICS_MAX_GUILDS", 100); err != nil {
+		return nil, err
+	}
+	if cfg.RequestTimeout, err = envDuration("REQUEST_TIMEOUT", 2500*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrentRequests, err = envInt("MAX_CONCURRENT_REQUESTS", 0); err != nil {
+		return nil, err
+	}
+	if cfg.ShedRetryAfter, err = envDuration("SHED_RETRY_AFTER", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrentRequests < 0 || cfg.ShedRetryAfter < 0 {
+		return nil, errors.New("MAX_CONCURRENT_REQUESTS and SHED_RETRY_AFTER must not be negative")
+	}
+	if cfg.ReadHeaderTimeout, err = envDuration("READ_HEADER_TIMEOUT", 2*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.ReadTimeout, err = envDuration("READ_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+	// Longer than Google Cloud load balancers keep idle connections (600s),
+	// so the balancer closes them first and never reuses one being closed
+	if cfg.IdleTimeout, err = envDuration("IDLE_TIMEOUT", 620*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.ReadHeaderTimeout < 0 || cfg.ReadTimeout < 0 || cfg.IdleTimeout < 0 {
+		return nil, errors.New("READ_HEADER_TIMEOUT, READ_TIMEOUT and IDLE_TIMEOUT must not be negative")
+	}
+	if cfg.WarmupTimeout, err = envDuration("WARMUP_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.WarmupTimeout <= 0 {
+		return nil, errors.New("WARMUP_TIMEOUT must be positive")
+	}
+	if cfg.BreakerMaxFailures, err = envInt("PUBSUB_BREAKER_MAX_FAILURES", 5); err != nil {
+		return nil, err
+	}
+	if cfg.BreakerOpenTimeout, err = envDuration("PUBSUB_BREAKER_OPEN_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// envString returns the value of an environment variable or a default
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envList parses a comma-separated environment variable, dropping empty entries
+func envList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// envInt parses an integer environment variable, returning def when unset
+func envInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// envBool parses a boolean environment variable (true/false/1/0), returning def when unset
+func envBool(key string, def bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// envFloat parses a floating point environment variable, returning def when unset
+func envFloat(key string, def float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// envDuration parses a duration environment variable (e.g. "30s"), returning def when unset
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
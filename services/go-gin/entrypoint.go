@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CommandTypePrimaryEntryPoint is the data.type of an app's primary entry
+// point command, the one that launches its Activity from the App Launcher
+const CommandTypePrimaryEntryPoint = 4
+
+// Entry point handlers, matching the command's registered handler type
+const (
+	// entryPointHandlerApp (APP_HANDLER) means the app answers the callback
+	entryPointHandlerApp = "app"
+	// entryPointHandlerDiscord (DISCORD_LAUNCH_ACTIVITY) means Discord
+	// launches the Activity itself without waiting for the app
+	entryPointHandlerDiscord = "discord"
+)
+
+// entryPointHandler is the configured ENTRY_POINT_HANDLER
+var entryPointHandler = entryPointHandlerApp
+
+// parseEntryPointHandler validates an ENTRY_POINT_HANDLER value
+func parseEntryPointHandler(s string) (string, error) {
+	switch s {
+	case entryPointHandlerApp, entryPointHandlerDiscord:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown entry point handler %q (want %q or %q)", s, entryPointHandlerApp, entryPointHandlerDiscord)
+	}
+}
+
+// IsEntryPoint reports whether the interaction invokes the primary entry point command
+func (i *Interaction) IsEntryPoint() bool {
+	t, _ := i.Data["type"].(float64)
+	return int(t) == CommandTypePrimaryEntryPoint
+}
+
+// entryPointResponsePolicy returns the policy for an entry point command.
+// Unless overridden in COMMAND_RESPONSES the app launches its Activity.
+func entryPointResponsePolicy(policies map[string]ResponsePolicy, command string) ResponsePolicy {
+	if p, ok := policies[command]; ok {
+		return p
+	}
+	return ResponsePolicy{Mode: responseLaunchActivity}
+}
+
+// handleDiscordEntryPoint answers an entry point interaction the app wasn't
+// expected to receive because Discord handles the launch. Launching again is
+// harmless, and nothing is published since no follow-up is needed.
+func handleDiscordEntryPoint(c *gin.Context, interaction *Interaction) {
+	loggerFrom(c.Request.Context()).Warn("Received entry point interaction handled by Discord",
+		"interaction_id", interaction.ID,
+		"command_name", interaction.CommandName(),
+	)
+	c.JSON(http.StatusOK, InteractionResponse{Type: ResponseTypeLaunchActivity})
+}
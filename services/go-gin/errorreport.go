@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// errorSink receives failures that should be visible outside the request logs
+type errorSink interface {
+	// Report records err with optional stack trace and context fields.
+	// Fields must never contain the interaction token or signature material.
+	Report(err error, stack []byte, fields map[string]string)
+	// Flush waits for buffered reports to be delivered
+	Flush(timeout time.Duration)
+}
+
+// errorReporter is the active error sink; a no-op unless configured
+var errorReporter errorSink = noopErrorSink{}
+
+// initErrorReporting selects the error sink from the environment.
+//
+// SENTRY_DSN enables Sentry; ERROR_REPORTING=gcp enables Google Cloud Error
+// Reporting via structured log entries. This reads the environment directly
+// so configuration errors can themselves be reported.
+func initErrorReporting() error {
+	if dsn := os.Getenv("SENTRY_DSN"); dsn != "" {
+		if err := sentry.Init(sentry.ClientOptions{
+			Dsn:        dsn,
+			ServerName: serviceName(),
+			Release:    serviceVersion(),
+		}); err != nil {
+			return fmt.Errorf("failed to initialize Sentry: %w", err)
+		}
+		errorReporter = sentryErrorSink{}
+		return nil
+	}
+
+	switch mode := os.Getenv("ERROR_REPORTING"); mode {
+	case "", "none":
+	case "gcp":
+		errorReporter = gcpErrorSink{service: serviceName(), version: serviceVersion()}
+	default:
+		return fmt.Errorf("invalid ERROR_REPORTING %q (want gcp or none)", mode)
+	}
+	return nil
+}
+
+// serviceName returns the Cloud Run service name, falling back to go-gin
+func serviceName() string {
+	return envString("K_SERVICE", "go-gin")
+}
+
+// interactionFields returns the non-sensitive context attached to error reports
+func interactionFields(interaction *Interaction) map[string]string {
+	if interaction == nil {
+		return nil
+	}
+	fields := map[string]string{
+		"interaction_id":   interaction.ID,
+		"interaction_type": strconv.Itoa(interaction.Type),
+		"application_id":   interaction.ApplicationID,
+		"guild_id":         interaction.GuildID,
+		"channel_id":       interaction.ChannelID,
+	}
+	if name := interaction.CommandName(); name != "" {
+		fields["command_name"] = name
+	}
+	return fields
+}
+
+type noopErrorSink struct{}
+
+func (noopErrorSink) Report(error, []byte, map[string]string) {}
+func (noopErrorSink) Flush(time.Duration)                     {}
+
+// sentryErrorSink reports to Sentry using the globally initialized client
+type sentryErrorSink struct{}
+
+func (sentryErrorSink) Report(err error, stack []byte, fields map[string]string) {
+	hub := sentry.CurrentHub().Clone()
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(fields)
+		if len(stack) > 0 {
+			scope.SetExtra("stack", string(stack))
+		}
+		hub.CaptureException(err)
+	})
+}
+
+func (sentryErrorSink) Flush(timeout time.Duration) {
+	sentry.Flush(timeout)
+}
+
+// gcpErrorSink writes ReportedErrorEvent log entries to stderr, which Cloud
+// Logging forwards to Error Reporting without needing an API client
+type gcpErrorSink struct {
+	service string
+	version string
+}
+
+func (s gcpErrorSink) Report(err error, stack []byte, fields map[string]string) {
+	// Error Reporting groups Go errors by the stack trace in the message
+	message := err.Error()
+	if len(stack) > 0 {
+		message += "\n\n" + string(stack)
+	}
+
+	entry := map[string]interface{}{
+		"severity":       "ERROR",
+		"@type":          "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent",
+		"message":        message,
+		"serviceContext": map[string]string{"service": s.service, "version": s.version},
+		"eventTime":      time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if len(fields) > 0 {
+		entry["interaction"] = fields
+	}
+
+	line, mErr := json.Marshal(entry)
+	if mErr != nil {
+		logger.Error("Failed to marshal error report", "error", mErr)
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(line))
+}
+
+func (gcpErrorSink) Flush(time.Duration) {}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// failoverTopic receives interactions the primary topic couldn't take. It
+// may live in another region or project. Nil when failover is disabled.
+var failoverTopic *pubsub.Topic
+
+// parseTopicName accepts a bare topic name (in defaultProject) or a full
+// "projects/<project>/topics/<topic>" resource name
+func parseTopicName(name, defaultProject string) (project, topic string, err error) {
+	if !strings.HasPrefix(name, "projects/") {
+		return defaultProject, name, nil
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) != 4 || parts[2] != "topics" || parts[1] == "" || parts[3] == "" {
+		return "", "", fmt.Errorf("invalid topic %q (want projects/<project>/topics/<topic>)", name)
+	}
+	return parts[1], parts[3], nil
+}
+
+// publishFailover republishes msg to the failover topic after the primary
+// failed for reason. The copy is marked so consumers can tell it was rerouted.
+func publishFailover(ctx context.Context, msg *pubsub.Message, reason string, interaction *Interaction) error {
+	log := loggerFrom(ctx)
+
+	attrs := make(map[string]string, len(msg.Attributes)+2)
+	for k, v := range msg.Attributes {
+		attrs[k] = v
+	}
+	attrs["failover"] = "true"
+	attrs["failover_reason"] = reason
+
+	result := failoverTopic.Publish(ctx, &pubsub.Message{Data: msg.Data, Attributes: attrs})
+	if _, err := result.Get(ctx); err != nil {
+		failoverTotal.WithLabelValues("error").Inc()
+		log.Error("Failed to publish to failover topic", "interaction_id", interaction.ID, "error", err)
+		errorReporter.Report(fmt.Errorf("pubsub failover publish failed: %w", err), nil, interactionFields(interaction))
+		return err
+	}
+	failoverTotal.WithLabelValues("success").Inc()
+	log.Warn("Published interaction to failover topic", "interaction_id", interaction.ID, "reason", reason)
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+// Feature flags evaluated per interaction. Without a provider every flag
+// evaluates to its default, which matches the static configuration.
+const (
+	// Publish before sending the deferred response (default false). When off,
+	// the response goes out first and the publish finishes in the background.
+	flagSyncPublish = "sync-publish"
+
+	// Drop interactions seen within the dedup window (default: DEDUP_WINDOW > 0)
+	flagDedup = "dedup"
+
+	// Pub/Sub topic to publish the interaction to instead of PUBSUB_TOPIC
+	// (default "": no override)
+	flagPublishTopic = "publish-topic"
+)
+
+// asyncPublishTimeout bounds publishes that run after the response. It's
+// independent of the request, whose context is done once the response is sent.
+const asyncPublishTimeout = 10 * time.Second
+
+// flags evaluates feature flags through the registered OpenFeature provider
+var flags = openfeature.NewClient("discord-edge")
+
+// flagContext is the evaluation context for an interaction. The user ID is
+// the targeting key; rules can also match on command and guild.
+func flagContext(interaction *Interaction) openfeature.EvaluationContext {
+	return openfeature.NewEvaluationContext(interaction.UserID(), map[string]any{
+		"interaction_id":   interaction.ID,
+		"interaction_type": interaction.Type,
+		"application_id":   interaction.ApplicationID,
+		"command":          interaction.CommandName(),
+		"command_path":     interaction.CommandPath(),
+		"custom_id":        interaction.CustomID(),
+		"guild_id":         interaction.GuildID,
+	})
+}
+
+// flagEnabled evaluates a boolean flag for the interaction
+func flagEnabled(ctx context.Context, key string, def bool, interaction *Interaction) bool {
+	v, _ := flags.BooleanValue(ctx, key, def, flagContext(interaction))
+	return v
+}
+
+// flagString evaluates a string flag for the interaction
+func flagString(ctx context.Context, key, def string, interaction *Interaction) string {
+	v, _ := flags.StringValue(ctx, key, def, flagContext(interaction))
+	return v
+}
+
+// flagAuditHook logs and counts every flag evaluation, so a behavior change
+// can be traced to the flag (and rule) that caused it. Evaluations that fell
+// back to the default are logged at debug level only.
+type flagAuditHook struct {
+	openfeature.UnimplementedHook
+}
+
+func (flagAuditHook) After(ctx context.Context, hc openfeature.HookContext, details openfeature.InterfaceEvaluationDetails, _ openfeature.HookHints) error {
+	level := slog.LevelInfo
+	if details.Reason == openfeature.DefaultReason {
+		level = slog.LevelDebug
+	}
+	flagEvaluationsTotal.WithLabelValues(hc.FlagKey(), details.Variant).Inc()
+	loggerFrom(ctx).Log(ctx, level, "Feature flag evaluated",
+		"flag", hc.FlagKey(),
+		"value", details.Value,
+		"variant", details.Variant,
+		"reason", string(details.Reason),
+		"provider", hc.ProviderMetadata().Name,
+		"interaction_id", hc.EvaluationContext().Attribute("interaction_id"),
+	)
+	return nil
+}
+
+func (flagAuditHook) Error(ctx context.Context, hc openfeature.HookContext, err error, _ openfeature.HookHints) {
+	flagEvaluationsTotal.WithLabelValues(hc.FlagKey(), "error").Inc()
+	loggerFrom(ctx).Warn("Feature flag evaluation failed, using default",
+		"flag", hc.FlagKey(),
+		"default", hc.DefaultValue(),
+		"provider", hc.ProviderMetadata().Name,
+		"error", err,
+	)
+}
+
+// flagRule is one flag in FEATURE_FLAGS_FILE. The first match wins: the
+// guild, then the full command path, then the top-level command, then value.
+type flagRule struct {
+	Value    any            `json:"value"`
+	Guilds   map[string]any `json:"guilds,omitempty"`
+	Commands map[string]any `json:"commands,omitempty"`
+}
+
+// resolve picks the rule's value for the flattened evaluation context
+func (r flagRule) resolve(flatCtx openfeature.FlattenedContext) (any, string, openfeature.Reason) {
+	if guild, _ := flatCtx["guild_id"].(string); guild != "" {
+		if v, ok := r.Guilds[guild]; ok {
+			return v, "guild:" + guild, openfeature.TargetingMatchReason
+		}
+	}
+	for _, attr := range []string{"command_path", "command"} {
+		if cmd, _ := flatCtx[attr].(string); cmd != "" {
+			if v, ok := r.Commands[cmd]; ok {
+				return v, "command:" + cmd, openfeature.TargetingMatchReason
+			}
+		}
+	}
+	return r.Value, "default", openfeature.StaticReason
+}
+
+// fileFlagProvider is an OpenFeature provider backed by a JSON file, for
+// example a Secret Manager secret mounted as a volume. The file is re-read
+// when it changes, so flags flip without a redeploy. Any other OpenFeature
+// provider (flagd, a vendor SDK) can be registered in its place.
+type fileFlagProvider struct {
+	path string
+
+	mu      sync.RWMutex
+	rules   map[string]flagRule
+	modTime time.Time
+}
+
+func newFileFlagProvider(path string, refresh time.Duration) (*fileFlagProvider, error) {
+	p := &fileFlagProvider{path: path}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	go func() {
+		for range time.Tick(refresh) {
+			if err := p.reload(); err != nil {
+				logger.Error("Failed to reload feature flags, keeping previous values", "file", path, "error", err)
+			}
+		}
+	}()
+	return p, nil
+}
+
+// reload re-reads the file if it changed since the last read
+func (p *fileFlagProvider) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	p.mu.RLock()
+	unchanged := info.ModTime().Equal(p.modTime)
+	p.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	rules, err := loadFlagRules(p.path)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	initial := p.rules == nil
+	p.rules, p.modTime = rules, info.ModTime()
+	p.mu.Unlock()
+
+	keys := make([]string, 0, len(rules))
+	for k := range rules {
+		keys = append(keys, k)
+	}
+	if initial {
+		logger.Info("Feature flags loaded", "file", p.path, "flags", keys)
+	} else {
+		logger.Info("Feature flags reloaded", "file", p.path, "flags", keys)
+	}
+	return nil
+}
+
+// loadFlagRules parses a flags file
+func loadFlagRules(path string) (map[string]flagRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules map[string]flagRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func (p *fileFlagProvider) Metadata() openfeature.Metadata {
+	return openfeature.Metadata{Name: "file"}
+}
+
+func (p *fileFlagProvider) Hooks() []openfeature.Hook {
+	return nil
+}
+
+// evaluate resolves key and checks the value has the wanted type
+func evaluate[T any](p *fileFlagProvider, key string, def T, flatCtx openfeature.FlattenedContext) (T, openfeature.ProviderResolutionDetail) {
+	p.mu.RLock()
+	rule, ok := p.rules[key]
+	p.mu.RUnlock()
+	if !ok {
+		// Flags left out of the file keep their defaults
+		return def, openfeature.ProviderResolutionDetail{Variant: "default", Reason: openfeature.DefaultReason}
+	}
+	raw, variant, reason := rule.resolve(flatCtx)
+	v, ok := raw.(T)
+	if !ok {
+		return def, openfeature.ProviderResolutionDetail{
+			ResolutionError: openfeature.NewTypeMismatchResolutionError(fmt.Sprintf("%s is %T", key, raw)),
+			Reason:          openfeature.ErrorReason,
+		}
+	}
+	return v, openfeature.ProviderResolutionDetail{Variant: variant, Reason: reason}
+}
+
+func (p *fileFlagProvider) BooleanEvaluation(_ context.Context, key string, def bool, flatCtx openfeature.FlattenedContext) openfeature.BoolResolutionDetail {
+	v, detail := evaluate(p, key, def, flatCtx)
+	return openfeature.BoolResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+func (p *fileFlagProvider) StringEvaluation(_ context.Context, key string, def string, flatCtx openfeature.FlattenedContext) openfeature.StringResolutionDetail {
+	v, detail := evaluate(p, key, def, flatCtx)
+	return openfeature.StringResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+func (p *fileFlagProvider) FloatEvaluation(_ context.Context, key string, def float64, flatCtx openfeature.FlattenedContext) openfeature.FloatResolutionDetail {
+	v, detail := evaluate(p, key, def, flatCtx)
+	return openfeature.FloatResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+func (p *fileFlagProvider) IntEvaluation(_ context.Context, key string, def int64, flatCtx openfeature.FlattenedContext) openfeature.IntResolutionDetail {
+	// JSON numbers decode as float64
+	v, detail := evaluate(p, key, float64(def), flatCtx)
+	return openfeature.IntResolutionDetail{Value: int64(v), ProviderResolutionDetail: detail}
+}
+
+func (p *fileFlagProvider) ObjectEvaluation(_ context.Context, key string, def any, flatCtx openfeature.FlattenedContext) openfeature.InterfaceResolutionDetail {
+	v, detail := evaluate(p, key, def, flatCtx)
+	return openfeature.InterfaceResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+// routedTopics caches topic handles for publish-topic overrides
+var routedTopics sync.Map // name -> *pubsub.Topic
+
+// topicFor returns the topic the interaction is published to: the
+// publish-topic flag's topic if set, otherwise PUBSUB_TOPIC
+func topicFor(ctx context.Context, interaction *Interaction) *pubsub.Topic {
+	name := flagString(ctx, flagPublishTopic, "", interaction)
+	if name == "" {
+		return pubsubTopic
+	}
+	if t, ok := routedTopics.Load(name); ok {
+		return t.(*pubsub.Topic)
+	}
+	project, topic, err := parseTopicName(name, pubsubClient.Project())
+	if err != nil {
+		loggerFrom(ctx).Warn("Ignoring invalid publish-topic flag", "topic", name, "error", err)
+		return pubsubTopic
+	}
+	t, _ := routedTopics.LoadOrStore(name, pubsubClient.TopicInProject(topic, project))
+	return t.(*pubsub.Topic)
+}
+
+// publishAsync reports whether the publish should run after the response
+func publishAsync(ctx context.Context, interaction *Interaction) bool {
+	return !flagEnabled(ctx, flagSyncPublish, false, interaction)
+}
+
+// startPublish hands the interaction to the publish backend in the
+// background, bounded by asyncPublishTimeout, so the response isn't held up.
+// With sync-publish on it publishes inline, bounded by the request deadline.
+func startPublish(ctx context.Context, interaction *Interaction) {
+	if !publishAsync(ctx, interaction) {
+		publishInteraction(ctx, interaction)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncPublishTimeout)
+	go func() {
+		defer cancel()
+		defer recoverPublish(ctx, interaction)
+		publishInteraction(ctx, interaction)
+	}()
+}
+
+// recoverPublish stops a panic in a background publish from taking the
+// process down with it: the interaction isn't published, and the panic is
+// reported like one in a handler
+func recoverPublish(ctx context.Context, interaction *Interaction) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	stack := debug.Stack()
+	fields := interactionFields(interaction)
+	loggerFrom(ctx).Error("panic recovered in background publish",
+		"panic", fmt.Sprint(recovered),
+		"stack", string(stack),
+		"interaction_id", fields["interaction_id"],
+		"command_name", fields["command_name"],
+	)
+	panicsTotal.Inc()
+	errorReporter.Report(fmt.Errorf("panic: %v", recovered), stack, fields)
+}
+
+// checkFeatureFlags verifies the flags file parses
+func checkFeatureFlags(_ context.Context, cfg *Config) error {
+	if cfg.FeatureFlagsFile == "" {
+		return nil
+	}
+	_, err := loadFlagRules(cfg.FeatureFlagsFile)
+	return err
+}
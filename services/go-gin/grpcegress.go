@@ -0,0 +1,220 @@
+package main
+
+//go:generate protoc -I proto --go_out=. --go_opt=module=github.com/pmgledhill102/discord-bot-test-suite/services/go-gin --go-grpc_out=. --go-grpc_opt=module=github.com/pmgledhill102/discord-bot-test-suite/services/go-gin interactions/v1/sink.proto interactions/v1/archive.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/internal/sinkpb"
+)
+
+// egress streams interactions to a downstream processor. Nil unless
+// PUBLISH_BACKEND is "grpc".
+var egress *grpcEgress
+
+// errProcessorUnavailable is returned while the processor can't be reached
+var errProcessorUnavailable = errors.New("processor unavailable")
+
+// grpcEgress keeps one bidirectional InteractionSink stream open to the
+// processor and matches its acks to the requests waiting on them. A broken
+// stream fails everything in flight and is reopened by the next send.
+type grpcEgress struct {
+	conn   *grpc.ClientConn
+	client sinkpb.InteractionSinkClient
+
+	mu      sync.Mutex
+	stream  sinkpb.InteractionSink_StreamClient
+	cancel  context.CancelFunc
+	pending map[string]chan error
+
+	// gRPC streams don't allow concurrent sends
+	sendMu sync.Mutex
+}
+
+func newGRPCEgress(target string, plaintext bool) (*grpcEgress, error) {
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if plaintext {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(creds),
+		// Keep the idle stream alive through proxies and load balancers
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                30 * time.Second,
+			Timeout:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// Connect now so the first interaction doesn't pay for the handshake
+	conn.Connect()
+	return &grpcEgress{
+		conn:    conn,
+		client:  sinkpb.NewInteractionSinkClient(conn),
+		pending: make(map[string]chan error),
+	}, nil
+}
+
+// openStream returns the current stream, opening one if there is none. The
+// wait for a connection is bounded by ctx, but the stream itself outlives
+// the request. Callers hold e.mu.
+func (e *grpcEgress) openStream(ctx context.Context) (sinkpb.InteractionSink_StreamClient, error) {
+	if e.stream != nil {
+		return e.stream, nil
+	}
+	for state := e.conn.GetState(); state != connectivity.Ready; state = e.conn.GetState() {
+		switch state {
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			e.conn.Connect()
+			return nil, errProcessorUnavailable
+		case connectivity.Idle:
+			e.conn.Connect()
+		}
+		if !e.conn.WaitForStateChange(ctx, state) {
+			return nil, ctx.Err()
+		}
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stream, err := e.client.Stream(streamCtx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	e.stream, e.cancel = stream, cancel
+	go e.receive(stream)
+	return stream, nil
+}
+
+// receive delivers acks to the senders waiting on them until the stream breaks
+func (e *grpcEgress) receive(stream sinkpb.InteractionSink_StreamClient) {
+	for {
+		ack, err := stream.Recv()
+		if err != nil {
+			e.reset(stream, err)
+			return
+		}
+		e.mu.Lock()
+		ch, ok := e.pending[ack.GetId()]
+		delete(e.pending, ack.GetId())
+		e.mu.Unlock()
+		if !ok {
+			continue
+		}
+		if ack.GetError() != "" {
+			ch <- fmt.Errorf("processor rejected interaction: %s", ack.GetError())
+		} else {
+			ch <- nil
+		}
+	}
+}
+
+// reset drops a broken stream and fails every send still waiting for an ack
+func (e *grpcEgress) reset(stream sinkpb.InteractionSink_StreamClient, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream != stream {
+		return
+	}
+	e.cancel()
+	e.stream = nil
+	for id, ch := range e.pending {
+		ch <- fmt.Errorf("stream closed before ack: %w", err)
+		delete(e.pending, id)
+	}
+}
+
+// Send streams msg to the processor and waits for its ack or for ctx to end
+func (e *grpcEgress) Send(ctx context.Context, msg *pubsub.Message, interactionID string) error {
+	ack := make(chan error, 1)
+
+	e.mu.Lock()
+	stream, err := e.openStream(ctx)
+	if err == nil {
+		e.pending[interactionID] = ack
+	}
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	e.sendMu.Lock()
+	err = stream.Send(&sinkpb.Interaction{Id: interactionID, Data: msg.Data, Attributes: msg.Attributes})
+	e.sendMu.Unlock()
+	if err != nil {
+		// The stream is broken; receive sees the cause and resets it
+		e.forget(interactionID)
+		return fmt.Errorf("stream send failed: %w", err)
+	}
+
+	select {
+	case err := <-ack:
+		return err
+	case <-ctx.Done():
+		e.forget(interactionID)
+		return ctx.Err()
+	}
+}
+
+// forget stops waiting for an interaction's ack
+func (e *grpcEgress) forget(interactionID string) {
+	e.mu.Lock()
+	delete(e.pending, interactionID)
+	e.mu.Unlock()
+}
+
+// streamToProcessor sends msg over the egress stream through the circuit breaker
+func streamToProcessor(ctx context.Context, msg *pubsub.Message, interaction *Interaction) error {
+	log := loggerFrom(ctx)
+
+	err := publishBreaker.Execute(func() error {
+		return egress.Send(ctx, msg, interaction.ID)
+	})
+	switch {
+	case errors.Is(err, errBreakerOpen):
+		grpcEgressTotal.WithLabelValues("rejected").Inc()
+		log.Warn("gRPC egress circuit breaker open", "interaction_id", interaction.ID)
+	case errors.Is(err, context.DeadlineExceeded):
+		grpcEgressTotal.WithLabelValues("timeout").Inc()
+		log.Warn("Processor did not ack before its deadline", "interaction_id", interaction.ID)
+	case err != nil:
+		grpcEgressTotal.WithLabelValues("error").Inc()
+		log.Error("Failed to stream interaction to processor", "interaction_id", interaction.ID, "error", err)
+		errorReporter.Report(fmt.Errorf("grpc egress failed: %w", err), nil, interactionFields(interaction))
+	default:
+		grpcEgressTotal.WithLabelValues("success").Inc()
+	}
+	return err
+}
+
+// checkEgress verifies the processor accepts connections
+func checkEgress(ctx context.Context, cfg *Config) error {
+	if cfg.PublishBackend != backendGRPC {
+		return nil
+	}
+	e, err := newGRPCEgress(cfg.GRPCEgressTarget, cfg.GRPCEgressPlaintext)
+	if err != nil {
+		return err
+	}
+	defer e.conn.Close()
+	for state := e.conn.GetState(); state != connectivity.Ready; state = e.conn.GetState() {
+		if !e.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("%s: not ready (%s)", cfg.GRPCEgressTarget, state)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Message keys for user-facing responses
+const (
+	msgCommandNotAvailable = "command_not_available"
+	msgCommandNotSupported = "command_not_supported"
+	msgMissingPermissions  = "missing_permissions"
+	msgPong                = "pong"
+	msgCancelled           = "cancelled"
+	msgGuildOnly           = "guild_only"
+	msgDMOnly              = "dm_only"
+	msgRateLimited         = "rate_limited"
+	msgGuildRateLimited    = "guild_rate_limited"
+)
+
+// localeFiles holds one JSON catalog per Discord locale, named <locale>.json
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// messageCatalog maps locale -> message key -> text
+type messageCatalog map[string]map[string]string
+
+// messages is the catalog used for responses; defaultLocale is the final fallback
+var (
+	messages      messageCatalog
+	defaultLocale = "en-US"
+)
+
+// loadMessageCatalog reads every embedded locale and checks that fallback is complete
+func loadMessageCatalog(fallback string) (messageCatalog, error) {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := make(messageCatalog, len(entries))
+	for _, entry := range entries {
+		raw, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var msgs map[string]string
+		if err := json.Unmarshal(raw, &msgs); err != nil {
+			return nil, fmt.Errorf("invalid locale %s: %w", entry.Name(), err)
+		}
+		catalog[strings.TrimSuffix(entry.Name(), ".json")] = msgs
+	}
+
+	// Regional locales also answer for their base language, so "es-419" can
+	// fall back to "es-ES". ReadDir is sorted, so the first region wins.
+	for _, entry := range entries {
+		locale := strings.TrimSuffix(entry.Name(), ".json")
+		if lang, _, ok := strings.Cut(locale, "-"); ok {
+			if _, exists := catalog[lang]; !exists {
+				catalog[lang] = catalog[locale]
+			}
+		}
+	}
+
+	base, ok := catalog[fallback]
+	if !ok {
+		return nil, fmt.Errorf("no catalog for default locale %q", fallback)
+	}
+	for _, key := range []string{
+		msgCommandNotAvailable, msgCommandNotSupported, msgMissingPermissions, msgPong,
+		msgCancelled, msgGuildOnly, msgDMOnly, msgRateLimited, msgGuildRateLimited,
+	} {
+		if base[key] == "" {
+			return nil, fmt.Errorf("default locale %q is missing %q", fallback, key)
+		}
+	}
+	return catalog, nil
+}
+
+// localize returns the message for key in the invoking user's language.
+//
+// The fallback chain is the user's locale, then its base language (so
+// "es-419" uses "es-ES"), then the same for the guild locale, and finally
+// the default locale.
+func localize(interaction *Interaction, key string) string {
+	var candidates []string
+	if interaction != nil {
+		candidates = append(candidates, interaction.Locale, interaction.GuildLocale)
+	}
+	for _, locale := range candidates {
+		if locale == "" {
+			continue
+		}
+		if msg, ok := messages.lookup(locale, key); ok {
+			return msg
+		}
+	}
+	return messages[defaultLocale][key]
+}
+
+// lookup finds key for an exact locale or, failing that, its base language
+func (m messageCatalog) lookup(locale, key string) (string, bool) {
+	if msg := m[locale][key]; msg != "" {
+		return msg, true
+	}
+	lang, _, _ := strings.Cut(locale, "-")
+	if msg := m[lang][key]; msg != "" {
+		return msg, true
+	}
+	return "", false
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// immediateHandler answers a command directly at the edge without a Pub/Sub
+// round trip. Handlers must be fast and side-effect free.
+type immediateHandler func(interaction *Interaction) InteractionResponse
+
+// immediateRegistry holds every available immediate handler by command name.
+// Handlers only run for commands enabled through IMMEDIATE_COMMANDS.
+var immediateRegistry = map[string]immediateHandler{}
+
+// registerImmediate makes an immediate handler available under name
+func registerImmediate(name string, h immediateHandler) {
+	immediateRegistry[name] = h
+}
+
+func init() {
+	registerImmediate("ping", immediatePing)
+	registerImmediate("help", immediateHelp)
+}
+
+// enabledImmediateHandlers returns the registered handlers for the named commands
+func enabledImmediateHandlers(names []string) (map[string]immediateHandler, error) {
+	enabled := make(map[string]immediateHandler, len(names))
+	for _, name := range names {
+		h, ok := immediateRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("no immediate handler registered for %q", name)
+		}
+		enabled[name] = h
+	}
+	return enabled, nil
+}
+
+func immediatePing(interaction *Interaction) InteractionResponse {
+	return payload.NewMessage().Content(localize(interaction, msgPong)).Reply()
+}
+
+// HelpCatalog is the static list of commands shown by /help
+type HelpCatalog struct {
+	Title    string `json:"title"`
+	Commands []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"commands"`
+}
+
+//go:embed catalog/help.json
+var defaultHelpCatalog []byte
+
+// helpText is rendered once at startup from the help catalog
+var helpText string
+
+// loadHelpCatalog renders the help text from path, or the embedded catalog when path is empty
+func loadHelpCatalog(path string) error {
+	raw := defaultHelpCatalog
+	if path != "" {
+		var err error
+		if raw, err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("failed to read help catalog: %w", err)
+		}
+	}
+
+	var catalog HelpCatalog
+	if err := json.Unmarshal(raw, &catalog); err != nil {
+		return fmt.Errorf("invalid help catalog: %w", err)
+	}
+	sort.Slice(catalog.Commands, func(i, j int) bool {
+		return catalog.Commands[i].Name < catalog.Commands[j].Name
+	})
+
+	var b strings.Builder
+	if catalog.Title != "" {
+		fmt.Fprintf(&b, "**%s**\n", catalog.Title)
+	}
+	for _, cmd := range catalog.Commands {
+		fmt.Fprintf(&b, "`/%s` - %s\n", cmd.Name, cmd.Description)
+	}
+	helpText = strings.TrimSuffix(b.String(), "\n")
+	return nil
+}
+
+func immediateHelp(*Interaction) InteractionResponse {
+	return payload.NewMessage().Content(helpText).Ephemeral().Reply()
+}
@@ -0,0 +1,246 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.29.3
+// source: interactions/v1/archive.proto
+
+package sinkpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ArchivedInteraction is one row of the BigQuery interactions archive. Field
+// names match the table's column names (see bigquery/interactions.json).
+type ArchivedInteraction struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	InteractionId   string                 `protobuf:"bytes,1,opt,name=interaction_id,json=interactionId,proto3" json:"interaction_id,omitempty"`
+	InteractionType int64                  `protobuf:"varint,2,opt,name=interaction_type,json=interactionType,proto3" json:"interaction_type,omitempty"`
+	ApplicationId   string                 `protobuf:"bytes,3,opt,name=application_id,json=applicationId,proto3" json:"application_id,omitempty"`
+	GuildId         string                 `protobuf:"bytes,4,opt,name=guild_id,json=guildId,proto3" json:"guild_id,omitempty"`
+	ChannelId       string                 `protobuf:"bytes,5,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	UserId          string                 `protobuf:"bytes,6,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	CommandName     string                 `protobuf:"bytes,7,opt,name=command_name,json=commandName,proto3" json:"command_name,omitempty"`
+	CommandPath     string                 `protobuf:"bytes,8,opt,name=command_path,json=commandPath,proto3" json:"command_path,omitempty"`
+	CustomId        string                 `protobuf:"bytes,9,opt,name=custom_id,json=customId,proto3" json:"custom_id,omitempty"`
+	Context         string                 `protobuf:"bytes,10,opt,name=context,proto3" json:"context,omitempty"`
+	Locale          string                 `protobuf:"bytes,11,opt,name=locale,proto3" json:"locale,omitempty"`
+	ServiceVersion  string                 `protobuf:"bytes,12,opt,name=service_version,json=serviceVersion,proto3" json:"service_version,omitempty"`
+	// TIMESTAMP column, in microseconds since the Unix epoch.
+	ReceivedAt int64 `protobuf:"varint,13,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"`
+	// JSON column holding the sanitized interaction.
+	Payload       string `protobuf:"bytes,14,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArchivedInteraction) Reset() {
+	*x = ArchivedInteraction{}
+	mi := &file_interactions_v1_archive_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArchivedInteraction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArchivedInteraction) ProtoMessage() {}
+
+func (x *ArchivedInteraction) ProtoReflect() protoreflect.Message {
+	mi := &file_interactions_v1_archive_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArchivedInteraction.ProtoReflect.Descriptor instead.
+func (*ArchivedInteraction) Descriptor() ([]byte, []int) {
+	return file_interactions_v1_archive_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ArchivedInteraction) GetInteractionId() string {
+	if x != nil {
+		return x.InteractionId
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetInteractionType() int64 {
+	if x != nil {
+		return x.InteractionType
+	}
+	return 0
+}
+
+func (x *ArchivedInteraction) GetApplicationId() string {
+	if x != nil {
+		return x.ApplicationId
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetGuildId() string {
+	if x != nil {
+		return x.GuildId
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetChannelId() string {
+	if x != nil {
+		return x.ChannelId
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetCommandName() string {
+	if x != nil {
+		return x.CommandName
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetCommandPath() string {
+	if x != nil {
+		return x.CommandPath
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetCustomId() string {
+	if x != nil {
+		return x.CustomId
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetContext() string {
+	if x != nil {
+		return x.Context
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetServiceVersion() string {
+	if x != nil {
+		return x.ServiceVersion
+	}
+	return ""
+}
+
+func (x *ArchivedInteraction) GetReceivedAt() int64 {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return 0
+}
+
+func (x *ArchivedInteraction) GetPayload() string {
+	if x != nil {
+		return x.Payload
+	}
+	return ""
+}
+
+var File_interactions_v1_archive_proto protoreflect.FileDescriptor
+
+const file_interactions_v1_archive_proto_rawDesc = "" +
+	"\n" +
+	"\x1dinteractions/v1/archive.proto\x12\x17discord.interactions.v1\"\xda\x03\n" +
+	"\x13ArchivedInteraction\x12%\n" +
+	"\x0einteraction_id\x18\x01 \x01(\tR\rinteractionId\x12)\n" +
+	"\x10interaction_type\x18\x02 \x01(\x03R\x0finteractionType\x12%\n" +
+	"\x0eapplication_id\x18\x03 \x01(\tR\rapplicationId\x12\x19\n" +
+	"\bguild_id\x18\x04 \x01(\tR\aguildId\x12\x1d\n" +
+	"\n" +
+	"channel_id\x18\x05 \x01(\tR\tchannelId\x12\x17\n" +
+	"\auser_id\x18\x06 \x01(\tR\x06userId\x12!\n" +
+	"\fcommand_name\x18\a \x01(\tR\vcommandName\x12!\n" +
+	"\fcommand_path\x18\b \x01(\tR\vcommandPath\x12\x1b\n" +
+	"\tcustom_id\x18\t \x01(\tR\bcustomId\x12\x18\n" +
+	"\acontext\x18\n" +
+	" \x01(\tR\acontext\x12\x16\n" +
+	"\x06locale\x18\v \x01(\tR\x06locale\x12'\n" +
+	"\x0fservice_version\x18\f \x01(\tR\x0eserviceVersion\x12\x1f\n" +
+	"\vreceived_at\x18\r \x01(\x03R\n" +
+	"receivedAt\x12\x18\n" +
+	"\apayload\x18\x0e \x01(\tR\apayloadBQZOgithub.com/pmgledhill102/discord-bot-test-suite/services/go-gin/internal/sinkpbb\x06proto3"
+
+var (
+	file_interactions_v1_archive_proto_rawDescOnce sync.Once
+	file_interactions_v1_archive_proto_rawDescData []byte
+)
+
+func file_interactions_v1_archive_proto_rawDescGZIP() []byte {
+	file_interactions_v1_archive_proto_rawDescOnce.Do(func() {
+		file_interactions_v1_archive_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_interactions_v1_archive_proto_rawDesc), len(file_interactions_v1_archive_proto_rawDesc)))
+	})
+	return file_interactions_v1_archive_proto_rawDescData
+}
+
+var file_interactions_v1_archive_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_interactions_v1_archive_proto_goTypes = []any{
+	(*ArchivedInteraction)(nil), // 0: discord.interactions.v1.ArchivedInteraction
+}
+var file_interactions_v1_archive_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_interactions_v1_archive_proto_init() }
+func file_interactions_v1_archive_proto_init() {
+	if File_interactions_v1_archive_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_interactions_v1_archive_proto_rawDesc), len(file_interactions_v1_archive_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_interactions_v1_archive_proto_goTypes,
+		DependencyIndexes: file_interactions_v1_archive_proto_depIdxs,
+		MessageInfos:      file_interactions_v1_archive_proto_msgTypes,
+	}.Build()
+	File_interactions_v1_archive_proto = out.File
+	file_interactions_v1_archive_proto_goTypes = nil
+	file_interactions_v1_archive_proto_depIdxs = nil
+}
@@ -0,0 +1,216 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.29.3
+// source: interactions/v1/sink.proto
+
+package sinkpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Interaction is one sanitized interaction, exactly as it would be published
+// to Pub/Sub (see docs/PUBSUB-SCHEMA.md).
+type Interaction struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Interaction ID, echoed in the Ack.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Sanitized interaction JSON. Never contains the interaction token.
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	// Message attributes (interaction_type, command_name, ...).
+	Attributes    map[string]string `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Interaction) Reset() {
+	*x = Interaction{}
+	mi := &file_interactions_v1_sink_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Interaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Interaction) ProtoMessage() {}
+
+func (x *Interaction) ProtoReflect() protoreflect.Message {
+	mi := &file_interactions_v1_sink_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Interaction.ProtoReflect.Descriptor instead.
+func (*Interaction) Descriptor() ([]byte, []int) {
+	return file_interactions_v1_sink_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Interaction) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Interaction) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Interaction) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+// Ack acknowledges one Interaction.
+type Ack struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ID of the acknowledged interaction.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Empty when the processor accepted the interaction, otherwise why it
+	// was rejected.
+	Error         string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_interactions_v1_sink_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_interactions_v1_sink_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_interactions_v1_sink_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Ack) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Ack) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_interactions_v1_sink_proto protoreflect.FileDescriptor
+
+const file_interactions_v1_sink_proto_rawDesc = "" +
+	"\n" +
+	"\x1ainteractions/v1/sink.proto\x12\x17discord.interactions.v1\"\xc6\x01\n" +
+	"\vInteraction\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12T\n" +
+	"\n" +
+	"attributes\x18\x03 \x03(\v24.discord.interactions.v1.Interaction.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"+\n" +
+	"\x03Ack\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error2c\n" +
+	"\x0fInteractionSink\x12P\n" +
+	"\x06Stream\x12$.discord.interactions.v1.Interaction\x1a\x1c.discord.interactions.v1.Ack(\x010\x01BQZOgithub.com/pmgledhill102/discord-bot-test-suite/services/go-gin/internal/sinkpbb\x06proto3"
+
+var (
+	file_interactions_v1_sink_proto_rawDescOnce sync.Once
+	file_interactions_v1_sink_proto_rawDescData []byte
+)
+
+func file_interactions_v1_sink_proto_rawDescGZIP() []byte {
+	file_interactions_v1_sink_proto_rawDescOnce.Do(func() {
+		file_interactions_v1_sink_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_interactions_v1_sink_proto_rawDesc), len(file_interactions_v1_sink_proto_rawDesc)))
+	})
+	return file_interactions_v1_sink_proto_rawDescData
+}
+
+var file_interactions_v1_sink_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_interactions_v1_sink_proto_goTypes = []any{
+	(*Interaction)(nil), // 0: discord.interactions.v1.Interaction
+	(*Ack)(nil),         // 1: discord.interactions.v1.Ack
+	nil,                 // 2: discord.interactions.v1.Interaction.AttributesEntry
+}
+var file_interactions_v1_sink_proto_depIdxs = []int32{
+	2, // 0: discord.interactions.v1.Interaction.attributes:type_name -> discord.interactions.v1.Interaction.AttributesEntry
+	0, // 1: discord.interactions.v1.InteractionSink.Stream:input_type -> discord.interactions.v1.Interaction
+	1, // 2: discord.interactions.v1.InteractionSink.Stream:output_type -> discord.interactions.v1.Ack
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_interactions_v1_sink_proto_init() }
+func file_interactions_v1_sink_proto_init() {
+	if File_interactions_v1_sink_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_interactions_v1_sink_proto_rawDesc), len(file_interactions_v1_sink_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_interactions_v1_sink_proto_goTypes,
+		DependencyIndexes: file_interactions_v1_sink_proto_depIdxs,
+		MessageInfos:      file_interactions_v1_sink_proto_msgTypes,
+	}.Build()
+	File_interactions_v1_sink_proto = out.File
+	file_interactions_v1_sink_proto_goTypes = nil
+	file_interactions_v1_sink_proto_depIdxs = nil
+}
@@ -0,0 +1,125 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: interactions/v1/sink.proto
+
+package sinkpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	InteractionSink_Stream_FullMethodName = "/discord.interactions.v1.InteractionSink/Stream"
+)
+
+// InteractionSinkClient is the client API for InteractionSink service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// InteractionSink receives sanitized interactions from the edge service over
+// a long-lived stream, for processors that can't afford a broker hop.
+type InteractionSinkClient interface {
+	// Stream carries interactions to the processor, which acknowledges each
+	// one by interaction ID. Acks may arrive in any order.
+	Stream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Interaction, Ack], error)
+}
+
+type interactionSinkClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInteractionSinkClient(cc grpc.ClientConnInterface) InteractionSinkClient {
+	return &interactionSinkClient{cc}
+}
+
+func (c *interactionSinkClient) Stream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Interaction, Ack], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &InteractionSink_ServiceDesc.Streams[0], InteractionSink_Stream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Interaction, Ack]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InteractionSink_StreamClient = grpc.BidiStreamingClient[Interaction, Ack]
+
+// InteractionSinkServer is the server API for InteractionSink service.
+// All implementations must embed UnimplementedInteractionSinkServer
+// for forward compatibility.
+//
+// InteractionSink receives sanitized interactions from the edge service over
+// a long-lived stream, for processors that can't afford a broker hop.
+type InteractionSinkServer interface {
+	// Stream carries interactions to the processor, which acknowledges each
+	// one by interaction ID. Acks may arrive in any order.
+	Stream(grpc.BidiStreamingServer[Interaction, Ack]) error
+	mustEmbedUnimplementedInteractionSinkServer()
+}
+
+// UnimplementedInteractionSinkServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedInteractionSinkServer struct{}
+
+func (UnimplementedInteractionSinkServer) Stream(grpc.BidiStreamingServer[Interaction, Ack]) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedInteractionSinkServer) mustEmbedUnimplementedInteractionSinkServer() {}
+func (UnimplementedInteractionSinkServer) testEmbeddedByValue()                         {}
+
+// UnsafeInteractionSinkServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InteractionSinkServer will
+// result in compilation errors.
+type UnsafeInteractionSinkServer interface {
+	mustEmbedUnimplementedInteractionSinkServer()
+}
+
+func RegisterInteractionSinkServer(s grpc.ServiceRegistrar, srv InteractionSinkServer) {
+	// If the following call pancis, it indicates UnimplementedInteractionSinkServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&InteractionSink_ServiceDesc, srv)
+}
+
+func _InteractionSink_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(InteractionSinkServer).Stream(&grpc.GenericServerStream[Interaction, Ack]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type InteractionSink_StreamServer = grpc.BidiStreamingServer[Interaction, Ack]
+
+// InteractionSink_ServiceDesc is the grpc.ServiceDesc for InteractionSink service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var InteractionSink_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "discord.interactions.v1.InteractionSink",
+	HandlerType: (*InteractionSinkServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _InteractionSink_Stream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "interactions/v1/sink.proto",
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// Bodies JSON parsers disagree about. Some keep the first of a repeated key
+// and some the last; some skip a byte order mark or stop at the end of the
+// first document. A body one implementation reads one way could be read
+// differently by another, so every service rejects them.
+var (
+	errNotObject     = errors.New("body is not a JSON object")
+	errByteOrderMark = errors.New("body starts with a byte order mark")
+	errDuplicateKey  = errors.New("duplicate key in JSON object")
+	errTrailingData  = errors.New("data after the JSON object")
+)
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// maxListedKeys is how many keys an object's keys are compared against one
+// by one before they are moved into a map
+const maxListedKeys = 16
+
+// openValue is an object or array the scan is inside
+type openValue struct {
+	object bool
+	// The object's keys are keys[start:], or set once there are too many
+	start int
+	set   map[string]bool
+}
+
+// keyScanner tracks the keys of every open object, as slices of the body
+// where they need no decoding, so a typical body is scanned without
+// allocating per key
+type keyScanner struct {
+	open []openValue
+	keys [][]byte
+}
+
+func (s *keyScanner) push(object bool) {
+	s.open = append(s.open, openValue{object: object, start: len(s.keys)})
+}
+
+func (s *keyScanner) pop() {
+	top := s.open[len(s.open)-1]
+	s.keys = s.keys[:top.start]
+	s.open = s.open[:len(s.open)-1]
+}
+
+// inObject reports whether the innermost open value is an object
+func (s *keyScanner) inObject() bool {
+	return len(s.open) > 0 && s.open[len(s.open)-1].object
+}
+
+// add records a key in the innermost object, reporting false if the object
+// already has it
+func (s *keyScanner) add(key []byte) bool {
+	top := &s.open[len(s.open)-1]
+	if top.set != nil {
+		if top.set[string(key)] {
+			return false
+		}
+		top.set[string(key)] = true
+		return true
+	}
+	for _, seen := range s.keys[top.start:] {
+		if bytes.Equal(seen, key) {
+			return false
+		}
+	}
+	s.keys = append(s.keys, key)
+	if len(s.keys)-top.start > maxListedKeys {
+		top.set = make(map[string]bool, 2*maxListedKeys)
+		for _, seen := range s.keys[top.start:] {
+			top.set[string(seen)] = true
+		}
+		s.keys = s.keys[:top.start]
+	}
+	return true
+}
+
+// checkJSONObject checks a body is a single JSON object, nested no deeper
+// than payload.MaxDepth and with no key repeated in any object, before it is
+// parsed. It makes one pass over the bytes, following only the structure;
+// syntax errors are left to the parser.
+func checkJSONObject(body []byte) error {
+	if bytes.HasPrefix(body, utf8BOM) {
+		return errByteOrderMark
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '{' {
+		return errNotObject
+	}
+
+	var (
+		scan keyScanner
+		// expectKey is whether the next string in the innermost object is a key
+		expectKey bool
+		// closed is whether the outermost object has ended
+		closed bool
+		// A repeated key or trailing data, reported unless the body is also
+		// nested too deeply, which is checked to the end as CheckDepth does
+		found error
+		depth int
+	)
+	for i := 0; i < len(body); i++ {
+		b := body[i]
+		if closed && found == nil && !isJSONSpace(b) {
+			found = errTrailingData
+		}
+		switch b {
+		case '"':
+			end, escaped := scanString(body, i+1)
+			if found == nil && !closed && expectKey && scan.inObject() {
+				if !scan.add(objectKey(body[i+1:end], escaped)) {
+					found = errDuplicateKey
+				}
+				expectKey = false
+			}
+			i = end
+		case '{', '[':
+			if depth++; depth > payload.MaxDepth {
+				return payload.ErrTooDeep
+			}
+			scan.push(b == '{')
+			expectKey = b == '{'
+		case '}', ']':
+			depth--
+			if len(scan.open) > 0 {
+				scan.pop()
+				closed = len(scan.open) == 0
+			}
+		case ',':
+			expectKey = scan.inObject()
+		case ':':
+			expectKey = false
+		}
+	}
+	return found
+}
+
+// scanString returns the index of the quote ending the string that starts
+// at i, or len(body) if it's unterminated, and whether it has escapes
+func scanString(body []byte, i int) (end int, escaped bool) {
+	for ; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			escaped = true
+			i++
+		case '"':
+			return i, escaped
+		}
+	}
+	return len(body), escaped
+}
+
+// objectKey returns a key as the parser will read it. Escapes are rare in
+// keys, so only keys with them are decoded.
+func objectKey(raw []byte, escaped bool) []byte {
+	if !escaped {
+		return raw
+	}
+	var key string
+	quoted := make([]byte, 0, len(raw)+2)
+	quoted = append(append(append(quoted, '"'), raw...), '"')
+	if err := jsonUnmarshal(quoted, &key); err != nil {
+		return raw // the parser reports the syntax error
+	}
+	return []byte(key)
+}
+
+// isJSONSpace reports whether b is whitespace between JSON tokens
+func isJSONSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
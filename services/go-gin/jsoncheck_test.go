@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// TestCheckJSONObject checks which bodies are rejected before parsing, and
+// that syntax errors are left to the parser
+func TestCheckJSONObject(t *testing.T) {
+	deep := strings.Repeat("[", payload.MaxDepth) + strings.Repeat("]", payload.MaxDepth)
+	many := `{"k0":0`
+	for i := 1; i <= 2*maxListedKeys; i++ {
+		many += `,"k` + strings.Repeat("x", i) + `":0`
+	}
+
+	for _, tc := range []struct {
+		name string
+		body string
+		want error
+	}{
+		{"object", `{"type":1,"data":{"name":"a","options":[{"name":"a"}]}}`, nil},
+		{"trailing whitespace", "{\"type\":1}\r\n\t ", nil},
+		{"same key in sibling objects", `{"a":{"b":1},"c":{"b":2},"d":[{"b":3},{"b":4}]}`, nil},
+		{"key text inside strings", `{"a":"\"a\":1,\"a\":2","b":"{\"a\"}"}`, nil},
+		{"many keys", many + "}", nil},
+		{"nested to the limit", `{"a":` + deep[1:len(deep)-1] + `}`, nil},
+		{"syntax error", `{"a":1,}`, nil},
+
+		{"byte order mark", "\xef\xbb\xbf{}", errByteOrderMark},
+		{"empty", " ", errNotObject},
+		{"array", `[{"a":1}]`, errNotObject},
+		{"null", "null", errNotObject},
+		{"duplicate key", `{"type":1,"type":2}`, errDuplicateKey},
+		{"duplicate nested key", `{"data":{"options":[{"name":"a","name":"b"}]}}`, errDuplicateKey},
+		{"duplicate escaped key", `{"type":1,"\u0074ype":2}`, errDuplicateKey},
+		{"duplicate among many keys", many + `,"k0":1}`, errDuplicateKey},
+		{"trailing object", `{"type":1}{"type":2}`, errTrailingData},
+		{"trailing text", `{"type":1} trailing`, errTrailingData},
+		{"nested too deeply", `{"a":` + deep + `}`, payload.ErrTooDeep},
+		{"too deep after a duplicate", `{"a":1,"a":` + deep + `}`, payload.ErrTooDeep},
+	} {
+		if got := checkJSONObject([]byte(tc.body)); !errors.Is(got, tc.want) || (got == nil) != (tc.want == nil) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
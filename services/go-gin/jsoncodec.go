@@ -0,0 +1,16 @@
+//go:build !go_json
+
+package main
+
+import "encoding/json"
+
+// The JSON codec for the interaction path: decoding request bodies and
+// encoding published messages. Build with -tags go_json to use goccy/go-json
+// instead, which also switches Gin's response encoding (see jsoncodec_gojson.go).
+
+// jsonCodec names the codec, as reported by /version
+const jsonCodec = "encoding/json"
+
+func jsonMarshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func jsonUnmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
@@ -0,0 +1,20 @@
+//go:build go_json
+
+package main
+
+import json "github.com/goccy/go-json"
+
+// goccy/go-json decodes a typical slash command about a third faster than
+// encoding/json (see BenchmarkDecodeInteraction). Gin uses it for responses
+// under the same tag, and checkJSONObject's byte scan decodes escaped keys
+// with it, so one build flag switches decoding, publishing and responding.
+// Configuration files and the optional audit and capture records still use
+// encoding/json. TestJSONCodec_ContractFixtures must pass under the tag
+// before a build with it is deployed.
+
+// jsonCodec names the codec, as reported by /version
+const jsonCodec = "goccy/go-json"
+
+func jsonMarshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func jsonUnmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// conformanceSpec is the contract tests' specification, whose fixtures are
+// the request bodies every service is tested with
+const conformanceSpec = "../../tests/contract/spec/conformance.yaml"
+
+// contractBodies returns every request body the conformance specification
+// sends, by requirement ID: each fixture with its patch applied, or the
+// literal body
+func contractBodies(t *testing.T) map[string][]byte {
+	t.Helper()
+
+	raw, err := os.ReadFile(conformanceSpec)
+	if err != nil {
+		t.Skipf("Conformance specification not available: %v", err)
+	}
+	var spec struct {
+		Fixtures     map[string]map[string]any `yaml:"fixtures"`
+		Requirements []struct {
+			ID      string `yaml:"id"`
+			Request *struct {
+				Fixture string         `yaml:"fixture"`
+				Patch   map[string]any `yaml:"patch"`
+				Body    *string        `yaml:"body"`
+			} `yaml:"request"`
+		} `yaml:"requirements"`
+	}
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		t.Fatalf("Invalid conformance specification: %v", err)
+	}
+
+	bodies := make(map[string][]byte)
+	for name, fixture := range spec.Fixtures {
+		bodies["fixture "+name] = marshalFixture(t, fixture)
+	}
+	for _, r := range spec.Requirements {
+		switch {
+		case r.Request == nil:
+		case r.Request.Body != nil:
+			bodies[r.ID] = []byte(*r.Request.Body)
+		default:
+			bodies[r.ID] = marshalFixture(t, mergeFixture(spec.Fixtures[r.Request.Fixture], r.Request.Patch))
+		}
+	}
+	return bodies
+}
+
+// mergeFixture applies a patch to a fixture as the contract tests do:
+// objects are merged field by field, anything else is replaced
+func mergeFixture(base, patch map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(patch))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range patch {
+		baseObj, ok1 := out[k].(map[string]any)
+		patchObj, ok2 := v.(map[string]any)
+		if ok1 && ok2 {
+			out[k] = mergeFixture(baseObj, patchObj)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func marshalFixture(t *testing.T, fixture map[string]any) []byte {
+	t.Helper()
+
+	body, err := json.Marshal(fixture)
+	if err != nil {
+		t.Fatalf("Failed to marshal fixture: %v", err)
+	}
+	return bytes.ReplaceAll(body, []byte("{{interaction_id}}"), []byte("100000000000000001"))
+}
+
+// referenceDecode is decodeInteraction with encoding/json
+func referenceDecode(body []byte) (*Interaction, error) {
+	if err := checkJSONObject(body); err != nil {
+		return nil, err
+	}
+	var interaction Interaction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		return nil, err
+	}
+	resolved := &payload.Resolved{}
+	if raw, ok := interaction.Data["resolved"]; ok {
+		b, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, resolved); err != nil {
+			return nil, err
+		}
+	}
+	interaction.Resolved = resolved
+	return &interaction, nil
+}
+
+// TestJSONCodec_ContractFixtures checks the codec the service is built with
+// decodes every contract body as encoding/json does, accepting and rejecting
+// the same ones, and encodes what it decoded to the same bytes. Run it with
+// -tags go_json before building the service with that tag.
+func TestJSONCodec_ContractFixtures(t *testing.T) {
+	bodies := contractBodies(t)
+	if len(bodies) == 0 {
+		t.Fatal("Expected request bodies in the conformance specification")
+	}
+	t.Logf("Comparing %s with encoding/json on %d bodies", jsonCodec, len(bodies))
+
+	for id, body := range bodies {
+		want, wantErr := referenceDecode(body)
+		got, gotErr := decodeInteraction(body)
+		if (gotErr == nil) != (wantErr == nil) {
+			t.Errorf("%s: %s error %v, encoding/json error %v", id, jsonCodec, gotErr, wantErr)
+			continue
+		}
+		if wantErr != nil {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %s decoded %+v, encoding/json %+v", id, jsonCodec, got, want)
+			continue
+		}
+
+		gotJSON, err := jsonMarshal(got)
+		if err != nil {
+			t.Errorf("%s: %s failed to encode: %v", id, jsonCodec, err)
+			continue
+		}
+		wantJSON, _ := json.Marshal(want)
+		if !bytes.Equal(gotJSON, wantJSON) {
+			t.Errorf("%s: %s encoded %s, encoding/json %s", id, jsonCodec, gotJSON, wantJSON)
+		}
+	}
+}
+
+// TestJSONCodec_Responses checks the responses the service sends encode the
+// same with either codec
+func TestJSONCodec_Responses(t *testing.T) {
+	for _, resp := range []InteractionResponse{
+		{Type: ResponseTypePong},
+		{Type: ResponseTypeDeferredChannelMessage},
+		{Type: ResponseTypeDeferredChannelMessage, Data: map[string]interface{}{"flags": MessageFlagEphemeral}},
+		{Type: ResponseTypeChannelMessage, Data: map[string]interface{}{"content": "<b>\"héllo\" & 👋</b> "}},
+	} {
+		got, err := jsonMarshal(resp)
+		if err != nil {
+			t.Fatalf("%s failed to encode %+v: %v", jsonCodec, resp, err)
+		}
+		want, _ := json.Marshal(resp)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s encoded %s, encoding/json %s", jsonCodec, got, want)
+		}
+	}
+}
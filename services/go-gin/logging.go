@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logger writes structured JSON log entries to stderr using the field names
+// Cloud Logging recognizes (severity, message, logging.googleapis.com/trace)
+var logger = newLogger(os.Stderr)
+
+// Cloud Logging special fields
+const (
+	traceKey        = "logging.googleapis.com/trace"
+	spanIDKey       = "logging.googleapis.com/spanId"
+	traceSampledKey = "logging.googleapis.com/trace_sampled"
+)
+
+type loggerContextKey struct{}
+
+func newLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{ReplaceAttr: cloudLoggingAttr}))
+}
+
+// cloudLoggingAttr renames slog's built-in keys to Cloud Logging's
+func cloudLoggingAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		level, _ := a.Value.Any().(slog.Level)
+		return slog.String("severity", severity(level))
+	case slog.MessageKey:
+		a.Key = "message"
+	}
+	return a
+}
+
+// severity maps slog levels to Cloud Logging LogSeverity names
+func severity(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARNING"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// requestLogger attaches a logger carrying the request's trace and
+// httpRequest fields to the request context, so every entry logged while
+// handling the request is grouped under it in the Cloud Run log explorer
+func requestLogger(projectID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := requestIDFor(c.Request.Header)
+		c.Header(requestIDHeader, id)
+		l := logger.With(traceAttrs(c.Request, projectID)...).With(
+			"request_id", id,
+			slog.Group("httpRequest",
+				"requestMethod", c.Request.Method,
+				"requestUrl", c.Request.URL.RequestURI(),
+				"userAgent", c.Request.UserAgent(),
+				"remoteIp", c.ClientIP(),
+				"protocol", c.Request.Proto,
+			),
+		)
+		ctx := context.WithValue(c.Request.Context(), loggerContextKey{}, l)
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+		if tc, ok := parseTraceContext(c.Request.Header); ok {
+			ctx = context.WithValue(ctx, traceContextKey{}, tc)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// accessLog records every request in the latency histogram and writes a
+// sampled access log entry. Server errors are always logged regardless of
+// the sample rate.
+func accessLog(projectID string, sampleRate float64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		latency := time.Since(start)
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		interactionType, command := "", ""
+		if interaction := interactionFromContext(c); interaction != nil {
+			interactionType = strconv.Itoa(interaction.Type)
+			command = interaction.CommandPath()
+		}
+
+		requestDuration.WithLabelValues(route, c.Request.Method, strconv.Itoa(status), interactionType).
+			Observe(latency.Seconds())
+
+		if status < http.StatusInternalServerError && rand.Float64() >= sampleRate {
+			return
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.With(traceAttrs(c.Request, projectID)...).Log(c.Request.Context(), level, "request completed",
+			slog.Group("httpRequest",
+				"requestMethod", c.Request.Method,
+				"requestUrl", c.Request.URL.RequestURI(),
+				"status", status,
+				"requestSize", strconv.FormatInt(c.Request.ContentLength, 10),
+				"responseSize", strconv.Itoa(c.Writer.Size()),
+				"userAgent", c.Request.UserAgent(),
+				"remoteIp", c.ClientIP(),
+				"protocol", c.Request.Proto,
+				"latency", fmt.Sprintf("%.9fs", latency.Seconds()),
+			),
+			"request_id", requestIDFrom(c.Request.Context()),
+			"interaction_type", interactionType,
+			"command_name", command,
+			"duration_ms", float64(latency.Microseconds())/1000,
+		)
+	}
+}
+
+// fatal logs an error entry and exits
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+// loggerFrom returns the request-scoped logger, or the base logger outside a request
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return logger
+}
+
+// requestIDHeader carries a request's ID from the ingress, and back in the
+// response
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds an ID taken from a request header
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key holding the request's ID
+type requestIDKey struct{}
+
+// requestIDFor returns the ID a request is known by in logs, its response
+// and published messages, so a failure a user reports can be followed
+// through every hop: the ingress's X-Request-ID, else the trace ID from
+// traceparent or X-Cloud-Trace-Context, else a new one. A header value that
+// isn't safe to log verbatim is ignored.
+func requestIDFor(h http.Header) string {
+	if id := h.Get(requestIDHeader); validRequestID(id) {
+		return id
+	}
+	if traceID, _, _ := parseTraceHeaders(h); validRequestID(traceID) {
+		return traceID
+	}
+	return newRequestID()
+}
+
+// validRequestID reports whether id is a non-empty, bounded run of the
+// characters request and trace IDs are made of
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-_.:/+=", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random ID shaped like a trace ID
+func newRequestID() string {
+	var b [16]byte
+	_, _ = cryptorand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// requestIDFrom returns the ID stored by requestLogger, or "" outside a
+// request
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// traceContextKey is the context key holding the request's traceContext
+type traceContextKey struct{}
+
+// traceContext is the W3C trace context of a request, propagated to
+// published messages so subscribers can continue the trace
+type traceContext struct {
+	parent string // traceparent
+	state  string // tracestate, if any
+}
+
+// parseTraceContext returns the request's W3C trace context, converting an
+// X-Cloud-Trace-Context header when there is no traceparent
+func parseTraceContext(h http.Header) (traceContext, bool) {
+	traceID, spanID, sampled := parseTraceHeaders(h)
+	if len(traceID) != 32 || len(spanID) != 16 {
+		return traceContext{}, false
+	}
+	flags := "00"
+	if sampled {
+		flags = "01"
+	}
+	tc := traceContext{parent: "00-" + strings.ToLower(traceID) + "-" + spanID + "-" + flags}
+	if h.Get("traceparent") != "" {
+		tc.state = h.Get("tracestate")
+	}
+	return tc, true
+}
+
+// traceContextFrom returns the trace context stored by requestLogger
+func traceContextFrom(ctx context.Context) (traceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(traceContext)
+	return tc, ok
+}
+
+// traceAttrs extracts the trace from X-Cloud-Trace-Context or W3C traceparent
+func traceAttrs(r *http.Request, projectID string) []any {
+	traceID, spanID, sampled := parseTraceHeaders(r.Header)
+	if traceID == "" || projectID == "" {
+		return nil
+	}
+	attrs := []any{traceKey, "projects/" + projectID + "/traces/" + traceID}
+	if spanID != "" {
+		attrs = append(attrs, spanIDKey, spanID)
+	}
+	return append(attrs, traceSampledKey, sampled)
+}
+
+// parseTraceHeaders returns the trace ID, span ID, and sampling decision
+func parseTraceHeaders(h http.Header) (traceID, spanID string, sampled bool) {
+	// traceparent: 00-<32 hex trace id>-<16 hex span id>-<2 hex flags>
+	if tp := h.Get("traceparent"); tp != "" {
+		parts := strings.Split(tp, "-")
+		if len(parts) == 4 && len(parts[1]) == 32 && len(parts[2]) == 16 {
+			flags, _ := strconv.ParseUint(parts[3], 16, 8)
+			return parts[1], parts[2], flags&0x01 == 1
+		}
+	}
+
+	// X-Cloud-Trace-Context: TRACE_ID/SPAN_ID;o=OPTIONS
+	if xc := h.Get("X-Cloud-Trace-Context"); xc != "" {
+		rest, opts, _ := strings.Cut(xc, ";")
+		traceID, spanID, _ = strings.Cut(rest, "/")
+		// The legacy header carries a decimal span ID; Cloud Logging wants hex
+		if n, err := strconv.ParseUint(spanID, 10, 64); err == nil {
+			spanID = fmt.Sprintf("%016x", n)
+		} else {
+			spanID = ""
+		}
+		return traceID, spanID, opts == "o=1"
+	}
+	return "", "", false
+}
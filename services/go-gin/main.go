@@ -5,22 +5,31 @@
 // - Responds to Ping (type=1) with Pong (type=1)
 // - Responds to Slash commands (type=2) with Deferred (type=5)
 // - Publishes sanitized slash command payloads to Pub/Sub
+// - Guards publishing with a circuit breaker so a degraded Pub/Sub fails fast
 package main
 
 import (
 	"context"
 	"crypto/ed25519"
-	"encoding/hex"
-	"encoding/json"
-	"io"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/gin-gonic/gin"
+	"github.com/open-feature/go-sdk/openfeature"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
 )
 
 // Interaction types
@@ -32,199 +41,857 @@ const (
 // Response types
 const (
 	ResponseTypePong                   = 1
+	ResponseTypeChannelMessage         = payload.ResponseTypeChannelMessage
 	ResponseTypeDeferredChannelMessage = 5
 )
 
+// Message flags
+const (
+	MessageFlagEphemeral = payload.MessageFlagEphemeral
+)
+
 // Interaction represents a Discord interaction request
 type Interaction struct {
-	Type          int                    `json:"type"`
-	ID            string                 `json:"id,omitempty"`
-	ApplicationID string                 `json:"application_id,omitempty"`
-	Token         string                 `json:"token,omitempty"`
-	Data          map[string]interface{} `json:"data,omitempty"`
-	GuildID       string                 `json:"guild_id,omitempty"`
-	ChannelID     string                 `json:"channel_id,omitempty"`
-	Member        map[string]interface{} `json:"member,omitempty"`
-	User          map[string]interface{} `json:"user,omitempty"`
-	Locale        string                 `json:"locale,omitempty"`
-	GuildLocale   string                 `json:"guild_locale,omitempty"`
+	Type          int                      `json:"type"`
+	ID            string                   `json:"id,omitempty"`
+	ApplicationID string                   `json:"application_id,omitempty"`
+	Token         string                   `json:"token,omitempty"`
+	Data          map[string]interface{}   `json:"data,omitempty"`
+	GuildID       string                   `json:"guild_id,omitempty"`
+	ChannelID     string                   `json:"channel_id,omitempty"`
+	Member        map[string]interface{}   `json:"member,omitempty"`
+	User          map[string]interface{}   `json:"user,omitempty"`
+	Locale        string                   `json:"locale,omitempty"`
+	GuildLocale   string                   `json:"guild_locale,omitempty"`
+	Entitlements  []map[string]interface{} `json:"entitlements,omitempty"`
+
+	// Resolved is the typed form of data.resolved, populated after parsing
+	Resolved *payload.Resolved `json:"-"`
+}
+
+// CommandName returns the invoked command name, or "" if there is none
+func (i *Interaction) CommandName() string {
+	return payload.CommandName(i.Data)
+}
+
+// CommandPath returns the fully-qualified command including any subcommand
+// group and subcommand, e.g. "config permission set"
+func (i *Interaction) CommandPath() string {
+	return payload.CommandPath(i.Data)
 }
 
+// UserID returns the invoking user's ID, from member.user in guilds or user in DMs
+func (i *Interaction) UserID() string {
+	return payload.UserID(i.Member, i.User)
+}
+
+// interactionKey is the gin context key holding the parsed *Interaction
+const interactionKey = "interaction"
+
 // InteractionResponse represents a Discord interaction response
-type InteractionResponse struct {
-	Type int                    `json:"type"`
-	Data map[string]interface{} `json:"data,omitempty"`
+type InteractionResponse = payload.Response
+
+// ErrorResponse is the body of every failed request: a message for people
+// and a stable code for programs
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// Error codes, as the conformance specification defines them
+const (
+	errorCodeInvalidSignature           = "invalid_signature"
+	errorCodeInvalidBody                = "invalid_body"
+	errorCodeUnsupportedInteractionType = "unsupported_interaction_type"
+	errorCodeUnsupportedContentType     = "unsupported_content_type"
+	errorCodeUnsupportedEncoding        = "unsupported_content_encoding"
+	errorCodeBodyTooLarge               = "body_too_large"
+	errorCodeOverloaded                 = "overloaded"
+	errorCodeNotFound                   = "not_found"
+	errorCodeMethodNotAllowed           = "method_not_allowed"
+	errorCodeSourceNotAllowed           = "source_not_allowed"
+	errorCodeUnauthorized               = "unauthorized"
+	errorCodeInternal                   = "internal_error"
+)
+
+// abortWithError answers with an error response and stops the handler chain
+func abortWithError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Error: message, Code: code})
 }
 
 var (
-	publicKey    ed25519.PublicKey
-	pubsubClient *pubsub.Client
-	pubsubTopic  *pubsub.Topic
-	projectID    string
+	publicKey      ed25519.PublicKey
+	pubsubClient   *pubsub.Client
+	pubsubTopic    *pubsub.Topic
+	projectID      string
+	publishBreaker *circuitBreaker
+	cmdMetrics     *commandMetrics
+	maxBodyBytes   int64
+	guilds         *guildPolicy
+	commands       *commandPolicy
+	permissions    *permissionPolicy
+	premium        *premiumPolicy
+	contexts       *contextPolicy
+	responses      map[string]ResponsePolicy
+	immediate      map[string]immediateHandler
+
+	// componentResponses is nil unless component interactions are enabled
+	componentResponses *componentPolicy
 )
 
 func main() {
-	// Load configuration from environment
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Local development settings, if present. Loaded before anything reads the environment.
+	dotEnv, err := loadDotEnv()
+	if err != nil {
+		fatal("Invalid .env file", "error", err)
+	}
+	if len(dotEnv) > 0 {
+		logger.Info("Loaded environment from files", "files", dotEnv)
 	}
 
-	publicKeyHex := os.Getenv("DISCORD_PUBLIC_KEY")
-	if publicKeyHex == "" {
-		log.Fatal("DISCORD_PUBLIC_KEY environment variable is required")
+	validate := flag.Bool("validate-config", false, "validate configuration and dependencies, then exit")
+	flag.Parse()
+	if *validate {
+		os.Exit(validateConfig(os.Stdout))
 	}
 
-	var err error
-	publicKey, err = hex.DecodeString(publicKeyHex)
+	// Error reporting is configured next so config errors can be reported
+	if err := initErrorReporting(); err != nil {
+		fatal("Invalid error reporting configuration", "error", err)
+	}
+
+	// Route the standard library logger (used by dependencies) through slog
+	slog.SetDefault(logger)
+
+	// Load configuration from environment
+	cfg, err := loadConfig()
 	if err != nil {
-		log.Fatalf("Invalid DISCORD_PUBLIC_KEY: %v", err)
+		errorReporter.Report(err, nil, nil)
+		errorReporter.Flush(2 * time.Second)
+		fatal("Invalid configuration", "error", err)
+	}
+	startupMilestone(milestoneConfigLoaded)
+	port := cfg.Port
+	publicKey = cfg.PublicKey
+	maxBodyBytes = cfg.MaxBodyBytes
+	payload.MaxDepth = cfg.JSONMaxDepth
+
+	guilds = newGuildPolicy(cfg.GuildAllowlist, cfg.GuildDenylist)
+	commands = newCommandPolicy(cfg.AllowedCommands)
+	permissions = &permissionPolicy{required: cfg.CommandPermissions}
+	premium = &premiumPolicy{required: cfg.CommandSKUs}
+	contexts = &contextPolicy{allowed: cfg.CommandContexts}
+	entryPointHandler = cfg.EntryPointHandler
+	if cfg.ComponentInteractions {
+		handlers, err := enabledComponentHandlers(cfg.ImmediateComponents)
+		if err != nil {
+			fatal("Invalid IMMEDIATE_COMPONENTS", "error", err)
+		}
+		componentResponses = &componentPolicy{
+			updatePrefixes:   cfg.ComponentUpdatePrefixes,
+			activityPrefixes: cfg.ComponentActivityPrefixes,
+			immediate:        handlers,
+		}
+	}
+	responses = cfg.CommandResponses
+	if immediate, err = enabledImmediateHandlers(cfg.ImmediateCommands); err != nil {
+		fatal("Invalid IMMEDIATE_COMMANDS", "error", err)
+	}
+	defaultLocale = cfg.DefaultLocale
+	if messages, err = loadMessageCatalog(defaultLocale); err != nil {
+		fatal("Invalid message catalog", "error", err)
+	}
+	if err := loadHelpCatalog(cfg.HelpCatalogFile); err != nil {
+		fatal("Invalid help catalog", "error", err)
+	}
+	cmdMetrics = newCommandMetrics(cfg.MetricsMaxCommands, cfg.MetricsGuildLabels, cfg.MetricsMaxGuilds)
+
+	// Circuit breaker around the publish path
+	publishBreaker = newCircuitBreaker(cfg.BreakerMaxFailures, cfg.BreakerOpenTimeout)
+	publishBreaker.onStateChange = func(from, to breakerState) {
+		logger.Warn("Pub/Sub circuit breaker state changed", "from", from.String(), "to", to.String())
+		breakerStateGauge.Set(float64(to))
 	}
 
 	// Initialize Pub/Sub client
-	projectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
-	topicName := os.Getenv("PUBSUB_TOPIC")
+	projectID = cfg.ProjectID
 
-	if projectID != "" && topicName != "" {
-		ctx := context.Background()
-		pubsubClient, err = pubsub.NewClient(ctx, projectID)
+	if projectID != "" && (cfg.PubSubTopic != "" || cfg.AuditPubSubTopic != "" || cfg.ShadowPubSubTopic != "") {
+		pubsubClient, err = pubsub.NewClient(context.Background(), projectID)
 		if err != nil {
-			log.Printf("Warning: Failed to create Pub/Sub client: %v", err)
+			logger.Warn("Failed to create Pub/Sub client", "error", err)
 		} else {
-			pubsubTopic = pubsubClient.Topic(topicName)
-			// Ensure topic exists (for emulator, create if not exists)
-			exists, err := pubsubTopic.Exists(ctx)
-			if err != nil {
-				log.Printf("Warning: Failed to check topic existence: %v", err)
-			} else if !exists {
-				pubsubTopic, err = pubsubClient.CreateTopic(ctx, topicName)
-				if err != nil {
-					log.Printf("Warning: Failed to create topic: %v", err)
-				}
+			// Checking the topics opens the client's connection and fetches
+			// its credentials now, rather than during the first publish
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.WarmupTimeout)
+			if cfg.PubSubTopic != "" {
+				pubsubTopic = openTopic(ctx, cfg.PubSubTopic)
 			}
+			if cfg.FailoverPubSubTopic != "" {
+				// Not created on demand: it may be in a project this service can't administer
+				project, topic, _ := parseTopicName(cfg.FailoverPubSubTopic, projectID)
+				failoverTopic = pubsubClient.TopicInProject(topic, project)
+			}
+			if cfg.AuditPubSubTopic != "" {
+				auditor.topic = openTopic(ctx, cfg.AuditPubSubTopic)
+			}
+			if cfg.ShadowPubSubTopic != "" {
+				// Like the failover topic, the new pipeline's topic may be in another project
+				project, topic, _ := parseTopicName(cfg.ShadowPubSubTopic, projectID)
+				shadow = &shadowPublisher{topic: pubsubClient.TopicInProject(topic, project), sampleRate: cfg.ShadowSampleRate}
+			}
+			cancel()
+			startupMilestone(milestonePubSubReady)
+		}
+	}
+
+	cloudEvents = cfg.CloudEvents
+	compression, compressionThreshold = cfg.PublishCompression, cfg.PublishCompressionThreshold
+	signingKey, signingKeyID = []byte(cfg.PublishSigningKey), cfg.PublishSigningKeyID
+	if cfg.ShadowWebhookURL != "" {
+		shadow = &shadowPublisher{
+			webhook:    newWebhookForwarder(cfg.ShadowWebhookURL, cfg.ShadowWebhookSecret, cfg.WebhookMaxAttempts, cfg.WebhookTimeout),
+			sampleRate: cfg.ShadowSampleRate,
+		}
+	}
+	switch cfg.PublishBackend {
+	case backendWebhook:
+		webhook = newWebhookForwarder(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookMaxAttempts, cfg.WebhookTimeout)
+	case backendCloudTasks:
+		if taskDispatcher, err = newCloudTasksDispatcher(context.Background(), cfg); err != nil {
+			fatal("Failed to create Cloud Tasks client", "error", err)
+		}
+	case backendGRPC:
+		if egress, err = newGRPCEgress(cfg.GRPCEgressTarget, cfg.GRPCEgressPlaintext); err != nil {
+			fatal("Invalid GRPC_EGRESS_TARGET", "error", err)
+		}
+	}
+
+	if cfg.BigQueryTable != "" {
+		table, _ := parseTableName(cfg.BigQueryTable, projectID)
+		if archive, err = newBigQueryArchive(context.Background(), projectID, table); err != nil {
+			fatal("Failed to create BigQuery writer", "error", err)
+		}
+	}
+
+	if cfg.FeatureFlagsFile != "" {
+		provider, err := newFileFlagProvider(cfg.FeatureFlagsFile, cfg.FeatureFlagsRefresh)
+		if err != nil {
+			fatal("Failed to load feature flags", "error", err)
+		}
+		if err := openfeature.SetProviderAndWait(provider); err != nil {
+			fatal("Failed to register feature flag provider", "error", err)
+		}
+		openfeature.AddHooks(flagAuditHook{})
+	}
+
+	if state, err = newSharedState(cfg.RedisURL); err != nil {
+		fatal("Invalid REDIS_URL", "error", err)
+	}
+	dedupWindow = cfg.DedupWindow
+	if cfg.UserRateLimit > 0 {
+		userRateLimit = newUserRateLimiter(cfg.UserRateLimit, cfg.RateLimitWindow)
+	}
+	if cfg.GuildRateLimit > 0 {
+		guildRateLimit = newGuildRateLimiter(cfg.GuildRateLimit, cfg.RateLimitWindow)
+	}
+
+	if cfg.InteractionStoreCollection != "" {
+		if store, err = newInteractionStore(context.Background(), projectID, cfg.FirestoreDatabase,
+			cfg.InteractionStoreCollection, cfg.InteractionStoreTTL); err != nil {
+			fatal("Failed to create Firestore client", "error", err)
+		}
+	}
+
+	if cfg.TokenSealKey != nil {
+		sealer = &tokenSealer{recipient: cfg.TokenSealKey}
+	}
+
+	if cfg.AttachmentBucket != "" {
+		if offloader, err = newAttachmentOffloader(context.Background(), cfg.AttachmentBucket, cfg.AttachmentMaxBytes); err != nil {
+			fatal("Failed to create Cloud Storage client", "error", err)
+		}
+	}
+
+	if cfg.PayloadCaptureBucket != "" {
+		if capturer, err = newPayloadCapturer(context.Background(), cfg.PayloadCaptureBucket, cfg.PayloadCaptureSampleRate); err != nil {
+			fatal("Failed to create Cloud Storage client", "error", err)
 		}
+		logger.Warn("Payload capture enabled; raw requests are written to Cloud Storage",
+			"bucket", cfg.PayloadCaptureBucket, "sample_rate", cfg.PayloadCaptureSampleRate)
+	}
+
+	// Admin endpoints (pprof, expvar, metrics), on a separate port and, once
+	// authenticated, on the interactions port too
+	adminAuth, err := newAdminAuth(context.Background(), cfg)
+	if err != nil {
+		fatal("Failed to set up admin authentication", "error", err)
+	}
+	adminHandler := newAdminMux(cfg, adminAuth)
+	if cfg.AdminPort != "" || adminAuth != nil {
+		recent = newRecentInteractions(cfg.AdminRecentInteractions)
+	}
+	if cfg.AdminPort != "" {
+		startAdminServer(cfg, adminHandler)
 	}
 
 	// Set up Gin router
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
-	r.Use(gin.Recovery())
+	// Gin trusts X-Forwarded-For from any peer unless told otherwise; client
+	// addresses in logs and audit events come from it only via a listed proxy
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		fatal("Invalid TRUSTED_PROXIES", "error", err)
+	}
+	r.Use(securityHeaders())
+	r.Use(accessLog(projectID, cfg.AccessLogSampleRate))
+	r.Use(requestLogger(projectID))
+	r.Use(recovery())
+
+	// Authenticated admin endpoints, registered ahead of the request
+	// deadline so a CPU profile or trace runs for as long as it is asked to
+	if adminAuth != nil {
+		prefix := routePath(cfg.BasePath, cfg.AdminPath)
+		r.Any(prefix+"/*path", gin.WrapH(http.StripPrefix(prefix, adminHandler)))
+	}
+	r.Use(requestTimeout(cfg.RequestTimeout))
+
+	// Unknown paths and methods get the same JSON errors as everything else
+	// rather than the framework's plain-text pages
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(func(c *gin.Context) {
+		abortWithError(c, http.StatusNotFound, errorCodeNotFound, "not found")
+	})
+	r.NoMethod(func(c *gin.Context) {
+		abortWithError(c, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "method not allowed")
+	})
+
+	// Build information
+	r.GET(routePath(cfg.BasePath, "/version"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, buildInfo())
+	})
 
 	// Health check endpoint
-	r.GET("/health", func(c *gin.Context) {
+	r.GET(routePath(cfg.BasePath, cfg.HealthPath), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
-	// Discord interactions endpoint
-	r.POST("/", handleInteraction)
-	r.POST("/interactions", handleInteraction)
+	// Readiness endpoint reflecting downstream dependency health
+	r.GET(routePath(cfg.BasePath, "/readyz"), handleReadyz)
+
+	// Prometheus metrics, unless they are only served with the admin endpoints
+	if cfg.PublicMetrics {
+		r.GET(routePath(cfg.BasePath, "/metrics"), gin.WrapH(promhttp.Handler()))
+	}
+
+	// Discord interactions endpoint(s), filtering sources and shedding load
+	// before any signature is checked; health and metrics stay available
+	// to every source, under any load
+	filter := sourceFilter(cfg.AllowedSources)
+	shed := loadShedder(cfg.MaxConcurrentRequests, cfg.ShedRetryAfter)
+	for _, path := range cfg.InteractionPaths {
+		r.POST(routePath(cfg.BasePath, path), filter, shed, handleInteraction)
+	}
+
+	tlsConfig, certManager, err := serverTLSConfig(cfg)
+	if err != nil {
+		fatal("Invalid TLS configuration", "error", err)
+	}
+	if tlsConfig != nil && cfg.HTTPRedirectPort != "" {
+		startRedirectServer(cfg.HTTPRedirectPort, port, certManager)
+	}
+
+	// Nothing listens until the service is warm, so whatever probe the
+	// platform uses, no interaction is routed here before then
+	warmSignature()
+	startupMilestone(milestoneSignatureWarm)
 
 	// Start server
-	log.Printf("Starting server on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	info := buildInfo()
+	logger.Info("Starting server",
+		"port", port,
+		"tls", tlsConfig != nil,
+		"autocert", certManager != nil,
+		"mtls", tlsConfig != nil && tlsConfig.ClientCAs != nil,
+		"version", info.Version,
+		"commit", info.Commit,
+		"build_time", info.BuildTime,
+	)
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		fatal("Failed to start server", "error", err)
+	}
+	startupMilestone(milestoneListening)
+	if tlsConfig != nil {
+		// Certificates are already loaded into TLSConfig
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		err = srv.Serve(ln)
+	}
+	if err != nil {
+		fatal("Failed to start server", "error", err)
 	}
 }
 
+// openTopic returns a handle to the named topic, creating it if it doesn't
+// exist (for the emulator). Returns nil if the topic can't be created.
+func openTopic(ctx context.Context, name string) *pubsub.Topic {
+	topic := pubsubClient.Topic(name)
+	exists, err := topic.Exists(ctx)
+	if err != nil {
+		logger.Warn("Failed to check topic existence", "topic", name, "error", err)
+		return topic
+	}
+	if !exists {
+		topic, err = pubsubClient.CreateTopic(ctx, name)
+		if err != nil {
+			logger.Warn("Failed to create topic", "topic", name, "error", err)
+			return nil
+		}
+	}
+	return topic
+}
+
+// routePath mounts path under the base path. The root path maps to the base
+// itself, so Discord can be given "/bots/myapp" without a trailing slash.
+func routePath(base, path string) string {
+	if base != "" && path == "/" {
+		return base
+	}
+	return base + path
+}
+
 func handleInteraction(c *gin.Context) {
-	// Read body
-	body, err := io.ReadAll(c.Request.Body)
+	start := time.Now()
+	firstInteraction.Do(func() { startupMilestone(milestoneFirstInteraction) })
+
+	// Read body, decompressed and bounded by the configured limit
+	body, err := readBody(c.Writer, c.Request, maxBodyBytes)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			auditor.Record(c, auditOversizeBody, fmt.Sprintf("body exceeds %d bytes", tooLarge.Limit))
+			abortWithError(c, http.StatusRequestEntityTooLarge, errorCodeBodyTooLarge, "request body too large")
+			return
+		}
+		if errors.Is(err, errUnsupportedEncoding) {
+			abortWithError(c, http.StatusUnsupportedMediaType, errorCodeUnsupportedEncoding, "Content-Encoding must be gzip or identity")
+			return
+		}
+		abortWithError(c, http.StatusBadRequest, errorCodeInvalidBody, "failed to read body")
 		return
 	}
 
 	// Validate signature
-	if !validateSignature(c.Request, body) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+	err = validateSignature(c.Request, body)
+	if capturer != nil {
+		capturer.Capture(c, body, err)
+	}
+	if err != nil {
+		auditor.Record(c, auditSignatureFailure, err.Error())
+		abortWithError(c, http.StatusUnauthorized, errorCodeInvalidSignature, "invalid signature")
 		return
 	}
 
-	// Parse interaction
-	var interaction Interaction
-	if err := json.Unmarshal(body, &interaction); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON"})
+	// Only JSON is parsed as an interaction: form, multipart and
+	// octet-stream bodies are never handed to any parser, even when signed
+	if contentType := c.GetHeader("Content-Type"); !isJSONContentType(contentType) {
+		unsupportedContentTypesTotal.WithLabelValues(contentTypeLabel(contentType)).Inc()
+		abortWithError(c, http.StatusBadRequest, errorCodeUnsupportedContentType, "Content-Type must be application/json")
+		return
+	}
+
+	interaction, err := decodeInteraction(body)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, errorCodeInvalidBody, err.Error())
 		return
 	}
 
+	// Make the interaction available to middleware (e.g. panic reporting)
+	c.Set(interactionKey, interaction)
+	defer recent.Record(c, interaction, start)
+
 	// Handle by type
 	switch interaction.Type {
 	case InteractionTypePing:
 		handlePing(c)
 	case InteractionTypeApplicationCommand:
-		handleApplicationCommand(c, &interaction)
+		handleApplicationCommand(c, interaction)
+	case InteractionTypeMessageComponent:
+		if componentResponses == nil {
+			auditor.Record(c, auditUnknownInteractionType, fmt.Sprintf("interaction type %d", interaction.Type))
+			abortWithError(c, http.StatusBadRequest, errorCodeUnsupportedInteractionType, "unsupported interaction type")
+			return
+		}
+		handleMessageComponent(c, interaction)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported interaction type"})
+		auditor.Record(c, auditUnknownInteractionType, fmt.Sprintf("interaction type %d", interaction.Type))
+		abortWithError(c, http.StatusBadRequest, errorCodeUnsupportedInteractionType, "unsupported interaction type")
+	}
+}
+
+// errInvalidJSON is returned for a body the parser rejects
+var errInvalidJSON = errors.New("invalid JSON")
+
+// decodeInteraction parses a verified body, which must be a single object
+// read the same way by any parser: null would unmarshal into an empty one.
+// Its errors are fit to return to the client.
+func decodeInteraction(body []byte) (*Interaction, error) {
+	if err := checkJSONObject(body); err != nil {
+		return nil, err
+	}
+	var interaction Interaction
+	if err := jsonUnmarshal(body, &interaction); err != nil {
+		return nil, errInvalidJSON
 	}
+	var err error
+	if interaction.Resolved, err = parseResolved(interaction.Data); err != nil {
+		return nil, errInvalidJSON
+	}
+	return &interaction, nil
+}
+
+// isJSONContentType reports whether a Content-Type header is application/json,
+// in UTF-8 if it names a charset
+func isJSONContentType(header string) bool {
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil || mediaType != "application/json" {
+		return false
+	}
+	charset, ok := params["charset"]
+	return !ok || strings.EqualFold(charset, "utf-8")
 }
 
-func validateSignature(r *http.Request, body []byte) bool {
+// contentTypeLabel buckets a rejected Content-Type header into a metric
+// label: the media types a misdirected form or upload is sent with, "none",
+// or "other", so clients can't create series at will
+func contentTypeLabel(header string) string {
+	if strings.TrimSpace(header) == "" {
+		return "none"
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return overflowLabel
+	}
+	switch mediaType {
+	case "application/x-www-form-urlencoded", "multipart/form-data", "application/octet-stream", "text/plain":
+		return mediaType
+	}
+	return overflowLabel
+}
+
+// Signature validation failures (reasons are audited, never returned to clients)
+var (
+	errMissingSignature   = errors.New("missing signature headers")
+	errMalformedSignature = errors.New("malformed signature")
+	errInvalidTimestamp   = errors.New("invalid timestamp")
+	errExpiredTimestamp   = errors.New("expired timestamp")
+	errSignatureMismatch  = errors.New("signature mismatch")
+)
+
+func validateSignature(r *http.Request, body []byte) error {
 	signature := r.Header.Get("X-Signature-Ed25519")
 	timestamp := r.Header.Get("X-Signature-Timestamp")
 
 	if signature == "" || timestamp == "" {
-		return false
+		return errMissingSignature
 	}
 
-	// Decode signature
-	sigBytes, err := hex.DecodeString(signature)
-	if err != nil {
-		return false
+	// Decode signature onto the stack; valid hex of the wrong length can't
+	// verify
+	var sig [ed25519.SignatureSize]byte
+	n, ok := decodeHexString(sig[:], signature)
+	if !ok {
+		return errMalformedSignature
+	}
+	if n != len(sig) {
+		return errSignatureMismatch
 	}
 
 	// Check timestamp (must be within 5 seconds)
 	ts, err := strconv.ParseInt(timestamp, 10, 64)
 	if err != nil {
-		return false
+		return errInvalidTimestamp
 	}
 	if time.Now().Unix()-ts > 5 {
-		return false
+		return errExpiredTimestamp
+	}
+
+	// Verify signature: sign(timestamp + body), assembled in a pooled buffer
+	buf := messageBuffers.Get().(*[]byte)
+	message := append(append((*buf)[:0], timestamp...), body...)
+	verified := ed25519.Verify(publicKey, message, sig[:])
+	if cap(message) <= maxPooledMessage {
+		*buf = message
+		messageBuffers.Put(buf)
+	}
+	if !verified {
+		return errSignatureMismatch
+	}
+	return nil
+}
+
+// messageBuffers holds buffers for assembling signed messages, so verifying
+// a request doesn't allocate a copy of its body. Buffers grown past
+// maxPooledMessage by an unusually large body are left to the collector
+// rather than kept.
+var messageBuffers = sync.Pool{New: func() any { return new([]byte) }}
+
+const maxPooledMessage = 64 << 10
+
+// decodeHexString decodes hex from s into dst without converting s to a
+// byte slice, returning how many bytes s holds. It reports false if s isn't
+// valid hex; bytes beyond dst's length are checked but not kept.
+func decodeHexString(dst []byte, s string) (int, bool) {
+	if len(s)%2 != 0 {
+		return 0, false
+	}
+	for i := 0; i < len(s); i += 2 {
+		hi, ok1 := fromHexChar(s[i])
+		lo, ok2 := fromHexChar(s[i+1])
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		if i/2 < len(dst) {
+			dst[i/2] = hi<<4 | lo
+		}
+	}
+	return len(s) / 2, true
+}
+
+// fromHexChar converts a hex digit, in either case, to its value
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
 	}
+	return 0, false
+}
 
-	// Verify signature: sign(timestamp + body)
-	message := append([]byte(timestamp), body...)
-	return ed25519.Verify(publicKey, message, sigBytes)
+func handleReadyz(c *gin.Context) {
+	state := publishBreaker.State()
+	if state == breakerOpen {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "pubsub_breaker": state.String()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "pubsub_breaker": state.String()})
 }
 
 func handlePing(c *gin.Context) {
 	// Respond with Pong - do NOT publish to Pub/Sub
-	c.JSON(http.StatusOK, InteractionResponse{Type: ResponseTypePong})
+	respondType(c, ResponseTypePong)
 }
 
 func handleApplicationCommand(c *gin.Context, interaction *Interaction) {
-	// Publish to Pub/Sub (if configured)
-	if pubsubTopic != nil {
-		go publishToPubSub(interaction)
+	cmdMetrics.Observe(interaction.CommandPath(), interaction.GuildID)
+
+	if interaction.IsEntryPoint() && entryPointHandler == entryPointHandlerDiscord {
+		handleDiscordEntryPoint(c, interaction)
+		return
+	}
+
+	// Reject guilds the bot shouldn't serve before anything is published
+	if !guilds.Allowed(interaction.GuildID) {
+		policyRejectionsTotal.WithLabelValues("guild").Inc()
+		respondEphemeral(c, localize(interaction, msgCommandNotAvailable))
+		return
+	}
+
+	// Guild-only and DM-only commands explain where they can be used
+	if msg := contexts.Check(interaction); msg != "" {
+		policyRejectionsTotal.WithLabelValues("context").Inc()
+		respondEphemeral(c, localize(interaction, msg))
+		return
+	}
+
+	// Keep commands the workers don't know about out of the pipeline
+	if !commands.Allowed(interaction.CommandName()) {
+		policyRejectionsTotal.WithLabelValues("command").Inc()
+		respondEphemeral(c, localize(interaction, msgCommandNotSupported))
+		return
+	}
+
+	// Authorize at the edge so workers don't each reimplement it
+	if !permissions.Allowed(interaction) {
+		policyRejectionsTotal.WithLabelValues("permission").Inc()
+		respondEphemeral(c, localize(interaction, msgMissingPermissions))
+		return
+	}
+
+	// Monetized commands show Discord's upsell instead of being deferred
+	if !premium.Allowed(interaction) {
+		policyRejectionsTotal.WithLabelValues("premium").Inc()
+		respondType(c, ResponseTypePremiumRequired)
+		return
+	}
+
+	// Trivially fast commands are answered at the edge without publishing
+	if h, ok := immediate[interaction.CommandName()]; ok {
+		immediateResponsesTotal.WithLabelValues(interaction.CommandName()).Inc()
+		c.JSON(http.StatusOK, h(interaction))
+		return
+	}
+
+	// Per-user limits keep one user from flooding the workers
+	if userRateLimit != nil && !userRateLimit.Allow(c.Request.Context(), interaction) {
+		policyRejectionsTotal.WithLabelValues("rate_limit").Inc()
+		auditor.Record(c, auditRateLimited, fmt.Sprintf("user %s over USER_RATE_LIMIT", interaction.UserID()))
+		respondEphemeral(c, localize(interaction, msgRateLimited))
+		return
+	}
+
+	// Per-guild limits keep one guild from taking the whole service's
+	// capacity. A user already over their own limit isn't counted here.
+	if guildRateLimit != nil && !guildRateLimit.Allow(c.Request.Context(), interaction) {
+		policyRejectionsTotal.WithLabelValues("guild_rate_limit").Inc()
+		auditor.Record(c, auditRateLimited, fmt.Sprintf("guild %s over GUILD_RATE_LIMIT", interaction.GuildID))
+		respondEphemeral(c, localize(interaction, msgGuildRateLimited))
+		return
+	}
+
+	policy := responsePolicyFor(responses, interaction.CommandName())
+	if interaction.IsEntryPoint() {
+		policy = entryPointResponsePolicy(responses, interaction.CommandName())
+	}
+
+	// Static responses and Activity launches are answered entirely at the edge
+	switch policy.Mode {
+	case responseStatic:
+		respondMessage(c, policy.Content, policy.Ephemeral)
+		return
+	case responseLaunchActivity:
+		respondType(c, ResponseTypeLaunchActivity)
+		return
+	}
+
+	// Publish to the configured backend, if any. The publish runs in the
+	// background unless sync-publish is on, so the deferred response goes out
+	// without waiting for it.
+	startPublish(c.Request.Context(), interaction)
+
+	// Respond with deferred response (non-ephemeral unless configured)
+	if policy.Mode == responseDeferredEphemeral {
+		respondPrecomputed(c, deferredEphemeralBody)
+		return
+	}
+	respondType(c, ResponseTypeDeferredChannelMessage)
+}
+
+// respondEphemeral answers immediately with a message only the invoker can see
+func respondEphemeral(c *gin.Context, content string) {
+	respondMessage(c, content, true)
+}
+
+// respondMessage answers immediately with a channel message
+func respondMessage(c *gin.Context, content string, ephemeral bool) {
+	data := map[string]interface{}{"content": content}
+	if ephemeral {
+		data["flags"] = MessageFlagEphemeral
 	}
+	c.JSON(http.StatusOK, InteractionResponse{Type: ResponseTypeChannelMessage, Data: data})
+}
 
-	// Respond with deferred response (non-ephemeral)
-	c.JSON(http.StatusOK, InteractionResponse{Type: ResponseTypeDeferredChannelMessage})
+// publishInteraction hands the interaction to the configured backend, if any
+func publishInteraction(ctx context.Context, interaction *Interaction) {
+	// Another request (or instance) may already have accepted this interaction
+	if !firstDelivery(ctx, interaction) {
+		duplicatesTotal.Inc()
+		loggerFrom(ctx).Warn("Duplicate interaction not published", "interaction_id", interaction.ID)
+		return
+	}
+	if store != nil && !store.Claim(ctx, interaction) {
+		return
+	}
+	if webhook == nil && taskDispatcher == nil && egress == nil && pubsubTopic == nil && archive == nil && shadow == nil {
+		return
+	}
+	inFlightPublishes.Add(1)
+	defer inFlightPublishes.Add(-1)
+
+	msg, err := newInteractionMessage(ctx, interaction)
+	if err != nil {
+		loggerFrom(ctx).Error("Failed to marshal interaction for publishing", "error", err)
+		return
+	}
+	// The archive keeps the JSON queryable; everything else gets the
+	// compressed copy
+	if archive != nil {
+		archive.Append(ctx, msg, interaction)
+	}
+	msg = signMessage(compressMessage(ctx, msg, interaction))
+	if shadow != nil {
+		shadow.Mirror(ctx, msg, interaction)
+	}
+	switch {
+	case webhook != nil:
+		err = forwardToWebhook(ctx, msg, interaction)
+	case taskDispatcher != nil:
+		err = dispatchTask(ctx, msg, interaction)
+	case egress != nil:
+		err = streamToProcessor(ctx, msg, interaction)
+	case pubsubTopic != nil:
+		err = publishToPubSub(ctx, msg, interaction)
+	default:
+		return
+	}
+	if store != nil {
+		store.Finish(ctx, interaction.ID, err)
+	}
 }
 
-func publishToPubSub(interaction *Interaction) {
-	// Create sanitized copy (remove sensitive fields)
-	sanitized := &Interaction{
+// newInteractionMessage builds the published message: the sanitized
+// interaction as data, with routing attributes
+func newInteractionMessage(ctx context.Context, interaction *Interaction) (*pubsub.Message, error) {
+	log := loggerFrom(ctx)
+
+	// Create sanitized copy in the published schema, which has no token
+	// field - sensitive data
+	sanitized := &payload.Interaction{
 		Type:          interaction.Type,
 		ID:            interaction.ID,
 		ApplicationID: interaction.ApplicationID,
-		// Token is intentionally NOT copied - sensitive data
-		Data:        interaction.Data,
-		GuildID:     interaction.GuildID,
-		ChannelID:   interaction.ChannelID,
-		Member:      interaction.Member,
-		User:        interaction.User,
-		Locale:      interaction.Locale,
-		GuildLocale: interaction.GuildLocale,
+		Data:          interaction.Data,
+		GuildID:       interaction.GuildID,
+		ChannelID:     interaction.ChannelID,
+		Member:        interaction.Member,
+		User:          interaction.User,
+		Locale:        interaction.Locale,
+		GuildLocale:   interaction.GuildLocale,
+		// Entitlements are passed through so workers can make their own checks
+		Entitlements: interaction.Entitlements,
 	}
 
-	data, err := json.Marshal(sanitized)
-	if err != nil {
-		log.Printf("Failed to marshal interaction for Pub/Sub: %v", err)
-		return
+	// The plaintext token never leaves the edge; a sealed copy lets the
+	// worker complete the deferred response
+	if sealer != nil && interaction.Token != "" {
+		sealed, err := sealer.Seal(interaction.Token)
+		if err != nil {
+			log.Error("Failed to seal interaction token", "interaction_id", interaction.ID, "error", err)
+		}
+		sanitized.SealedToken = sealed
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Copy attachments out of Discord's CDN before their URLs expire
+	if offloader != nil {
+		sanitized.Data = offloader.Offload(ctx, interaction)
+	}
+
+	data, err := jsonMarshal(sanitized)
+	if err != nil {
+		return nil, err
+	}
 
 	// Build message with attributes
 	msg := &pubsub.Message{
@@ -235,19 +902,84 @@ func publishToPubSub(interaction *Interaction) {
 			"application_id":   interaction.ApplicationID,
 			"guild_id":         interaction.GuildID,
 			"channel_id":       interaction.ChannelID,
+			"context":          interactionContext(interaction),
 			"timestamp":        time.Now().UTC().Format(time.RFC3339),
+			"service_version":  serviceVersion(),
 		},
 	}
+	// Subscribers pick the type to decode the data into by this
+	msg.Attributes[payload.AttributeSchemaVersion] = payload.SchemaVersion
 
 	// Add command name if available
-	if interaction.Data != nil {
-		if name, ok := interaction.Data["name"].(string); ok {
-			msg.Attributes["command_name"] = name
+	if name := interaction.CommandName(); name != "" {
+		msg.Attributes["command_name"] = name
+		msg.Attributes["command_path"] = interaction.CommandPath()
+	}
+	if customID := interaction.CustomID(); customID != "" {
+		msg.Attributes["custom_id"] = customID
+	}
+
+	// Who invoked it, in which language, and the trace it belongs to, so
+	// subscribers can filter and correlate without parsing the data
+	if userID := interaction.UserID(); userID != "" {
+		msg.Attributes["user_id"] = userID
+	}
+	if interaction.Locale != "" {
+		msg.Attributes["locale"] = interaction.Locale
+	}
+	if interaction.GuildLocale != "" {
+		msg.Attributes["guild_locale"] = interaction.GuildLocale
+	}
+	if id := requestIDFrom(ctx); id != "" {
+		msg.Attributes["request_id"] = id
+	}
+	if tc, ok := traceContextFrom(ctx); ok {
+		msg.Attributes["traceparent"] = tc.parent
+		if tc.state != "" {
+			msg.Attributes["tracestate"] = tc.state
 		}
 	}
 
-	result := pubsubTopic.Publish(ctx, msg)
-	if _, err := result.Get(ctx); err != nil {
-		log.Printf("Failed to publish to Pub/Sub: %v", err)
+	if cloudEvents {
+		addCloudEventAttributes(msg, interaction)
+	}
+	return msg, nil
+}
+
+func publishToPubSub(ctx context.Context, msg *pubsub.Message, interaction *Interaction) error {
+	log := loggerFrom(ctx)
+
+	// Publish through the circuit breaker so a degraded Pub/Sub fails fast
+	// instead of tying up a goroutine for the full timeout on every request
+	err := publishBreaker.Execute(func() error {
+		result := topicFor(ctx, interaction).Publish(ctx, msg)
+		_, err := result.Get(ctx)
+		return err
+	})
+	var reason string
+	switch {
+	case errors.Is(err, errBreakerOpen):
+		publishTotal.WithLabelValues("rejected").Inc()
+		log.Warn("Pub/Sub circuit breaker open", "interaction_id", interaction.ID, "failover", failoverTopic != nil)
+		reason = "breaker_open"
+	case errors.Is(err, context.DeadlineExceeded):
+		// No time left to try another topic
+		publishTotal.WithLabelValues("timeout").Inc()
+		log.Warn("Pub/Sub publish did not complete before its deadline", "interaction_id", interaction.ID)
+		return err
+	case err != nil:
+		// The client has already exhausted its retries at this point
+		publishTotal.WithLabelValues("error").Inc()
+		log.Error("Failed to publish to Pub/Sub", "interaction_id", interaction.ID, "error", err)
+		errorReporter.Report(fmt.Errorf("pubsub publish failed: %w", err), nil, interactionFields(interaction))
+		reason = "publish_error"
+	default:
+		publishTotal.WithLabelValues("success").Inc()
+		return nil
+	}
+
+	if failoverTopic != nil {
+		return publishFailover(ctx, msg, reason, interaction)
 	}
+	return err
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus metrics exposed at /metrics
+var (
+	publishTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_pubsub_publish_total",
+		Help: "Pub/Sub publish attempts by result (success, error, timeout, rejected).",
+	}, []string{"result"})
+
+	breakerStateGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "discord_pubsub_breaker_state",
+		Help: "Pub/Sub circuit breaker state (0=closed, 1=half-open, 2=open).",
+	})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "discord_http_request_duration_seconds",
+		Help:    "HTTP request latency by route, method, status, and interaction type.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"route", "method", "status", "interaction_type"})
+
+	commandsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_commands_total",
+		Help: "Slash commands received by full command path.",
+	}, []string{"command"})
+
+	guildInteractionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_guild_interactions_total",
+		Help: "Slash commands received by guild ID (only when METRICS_GUILD_LABELS is enabled).",
+	}, []string{"guild_id"})
+
+	policyRejectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_policy_rejections_total",
+		Help: "Interactions answered with a rejection instead of being deferred, by policy.",
+	}, []string{"policy"})
+
+	immediateResponsesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_immediate_responses_total",
+		Help: "Commands (or component custom_id prefixes) answered by an immediate handler at the edge.",
+	}, []string{"command"})
+
+	attachmentOffloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_attachment_offloads_total",
+		Help: "Attachments copied to Cloud Storage before publishing, by result.",
+	}, []string{"result"})
+
+	failoverTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_pubsub_failover_total",
+		Help: "Interactions republished to the failover topic after the primary failed, by result.",
+	}, []string{"result"})
+
+	webhookDeliveriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_webhook_deliveries_total",
+		Help: "Webhook backend deliveries by result (success, error, timeout, rejected).",
+	}, []string{"result"})
+
+	cloudTasksCreatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_cloud_tasks_created_total",
+		Help: "Cloud Tasks backend task creations by result (success, duplicate, error, timeout, rejected).",
+	}, []string{"result"})
+
+	grpcEgressTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_grpc_egress_total",
+		Help: "Interactions streamed to the gRPC processor by result (success, error, timeout, rejected).",
+	}, []string{"result"})
+
+	archiveRowsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_bigquery_archive_rows_total",
+		Help: "Interactions archived to BigQuery by result (success, error).",
+	}, []string{"result"})
+
+	storeClaimsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_interaction_store_claims_total",
+		Help: "Interactions recorded in the Firestore store by result (claimed, duplicate, error).",
+	}, []string{"result"})
+
+	payloadCapturesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_payload_captures_total",
+		Help: "Raw requests written to the payload capture bucket.",
+	}, []string{"result"})
+
+	flagEvaluationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_feature_flag_evaluations_total",
+		Help: "Feature flag evaluations by flag and variant (error when the default was used).",
+	}, []string{"flag", "variant"})
+
+	shadowPublishTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_shadow_publish_total",
+		Help: "Interactions mirrored to the shadow destination.",
+	}, []string{"result"})
+
+	compressedMessagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_compressed_messages_total",
+		Help: "Published messages whose data was compressed.",
+	}, []string{"encoding"})
+
+	compressionSavedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "discord_compression_saved_bytes_total",
+		Help: "Bytes of message data saved by compression.",
+	})
+
+	duplicatesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "discord_duplicate_interactions_total",
+		Help: "Interactions seen again within DEDUP_WINDOW and not republished.",
+	})
+
+	stateFallbacksTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "discord_state_fallbacks_total",
+		Help: "Dedup and rate-limit operations served from in-memory state because Redis was unavailable.",
+	})
+
+	unsupportedContentTypesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_unsupported_content_types_total",
+		Help: "Signed requests rejected for a Content-Type other than application/json, by media type.",
+	}, []string{"media_type"})
+
+	auditEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_audit_events_total",
+		Help: "Security-relevant audit events by type.",
+	}, []string{"event"})
+
+	sourceRejectionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "discord_source_rejections_total",
+		Help: "Interaction requests answered 403 because the client address isn't in ALLOWED_SOURCE_CIDRS.",
+	})
+
+	shedRequestsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "discord_shed_requests_total",
+		Help: "Interaction requests answered 503 because MAX_CONCURRENT_REQUESTS were already in flight.",
+	})
+
+	startupMilestoneSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "discord_startup_milestone_seconds",
+		Help: "Seconds from process start to each startup milestone.",
+	}, []string{"milestone"})
+
+	panicsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "discord_handler_panics_total",
+		Help: "Panics recovered in handlers and background publishes.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		publishTotal,
+		breakerStateGauge,
+		requestDuration,
+		commandsTotal,
+		guildInteractionsTotal,
+		policyRejectionsTotal,
+		immediateResponsesTotal,
+		attachmentOffloadsTotal,
+		failoverTotal,
+		webhookDeliveriesTotal,
+		cloudTasksCreatedTotal,
+		grpcEgressTotal,
+		archiveRowsTotal,
+		storeClaimsTotal,
+		duplicatesTotal,
+		payloadCapturesTotal,
+		flagEvaluationsTotal,
+		shadowPublishTotal,
+		compressedMessagesTotal,
+		compressionSavedBytes,
+		stateFallbacksTotal,
+		unsupportedContentTypesTotal,
+		auditEventsTotal,
+		panicsTotal,
+		shedRequestsTotal,
+		sourceRejectionsTotal,
+		startupMilestoneSeconds,
+	)
+}
+
+// overflowLabel replaces label values beyond a labelGuard's limit
+const overflowLabel = "other"
+
+// labelGuard caps the number of distinct values used for a metric label so
+// user-controlled values (guild IDs, command names) can't blow up cardinality.
+// The first limit distinct values are kept; later ones collapse to "other".
+type labelGuard struct {
+	limit int
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newLabelGuard(limit int) *labelGuard {
+	return &labelGuard{limit: limit, seen: make(map[string]struct{})}
+}
+
+// Value returns v if it is (or can become) a tracked label value, else "other"
+func (g *labelGuard) Value(v string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.seen[v]; ok {
+		return v
+	}
+	if len(g.seen) >= g.limit {
+		return overflowLabel
+	}
+	g.seen[v] = struct{}{}
+	return v
+}
+
+// commandMetrics records per-command and (optionally) per-guild counters
+type commandMetrics struct {
+	commands *labelGuard
+	guilds   *labelGuard // nil when guild labels are disabled
+}
+
+func newCommandMetrics(maxCommands int, guildLabels bool, maxGuilds int) *commandMetrics {
+	m := &commandMetrics{commands: newLabelGuard(maxCommands)}
+	if guildLabels {
+		m.guilds = newLabelGuard(maxGuilds)
+	}
+	return m
+}
+
+// Observe counts one slash command
+func (m *commandMetrics) Observe(command, guildID string) {
+	commandsTotal.WithLabelValues(m.commands.Value(command)).Inc()
+	if m.guilds == nil {
+		return
+	}
+	if guildID == "" {
+		guildID = "dm"
+	}
+	guildInteractionsTotal.WithLabelValues(m.guilds.Value(guildID)).Inc()
+}
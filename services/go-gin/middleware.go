@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestTimeout bounds the total time spent handling a request.
+//
+// The deadline is attached to the request context so downstream work (such as
+// the Pub/Sub publish) is cancelled when it expires, and applied as a read
+// deadline on the connection so a slow client cannot stall the body read.
+// Handlers are expected to still write a complete response once the context
+// is done.
+func requestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		deadline := time.Now().Add(timeout)
+		ctx, cancel := context.WithDeadline(c.Request.Context(), deadline)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		// Not every ResponseWriter supports deadlines; the context still applies
+		_ = http.NewResponseController(c.Writer).SetReadDeadline(deadline)
+
+		c.Next()
+	}
+}
+
+// securityHeaders sets the headers every response gets. Responses are JSON
+// for Discord, never pages: nosniff and the content security policy stop a
+// browser treating one as anything else, and no-store keeps interaction
+// responses, which carry user data, out of caches between Discord and the
+// service. HSTS is only meaningful, and only sent, over TLS.
+func securityHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Cache-Control", "no-store")
+		h.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+		h.Set("Referrer-Policy", "no-referrer")
+		if c.Request.TLS != nil {
+			h.Set("Strict-Transport-Security", "max-age=31536000")
+		}
+		c.Next()
+	}
+}
+
+// loadShedder answers requests beyond limit in flight at once with 503 and a
+// Retry-After header rather than queueing them. Signature verification is
+// CPU-bound, so during a spike every queued request would miss Discord's
+// deadline; shedding the excess up front keeps the rest within it. A limit
+// of zero or less disables shedding.
+func loadShedder(limit int, retryAfter time.Duration) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	slots := make(chan struct{}, limit)
+	retry := strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds()))))
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			c.Next()
+		default:
+			shedRequestsTotal.Inc()
+			c.Header("Retry-After", retry)
+			abortWithError(c, http.StatusServiceUnavailable, errorCodeOverloaded, "too many requests in flight")
+		}
+	}
+}
+
+// recovery replaces gin.Recovery. A panicking handler is logged as a
+// structured entry with its stack and interaction context, counted, reported
+// to the error sink, and answered with a JSON 500 rather than a framework page.
+func recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// Deliberate abort; let net/http handle it
+				panic(recovered)
+			}
+
+			stack := debug.Stack()
+			fields := interactionFields(interactionFromContext(c))
+			loggerFrom(c.Request.Context()).Error("panic recovered",
+				"panic", fmt.Sprint(recovered),
+				"stack", string(stack),
+				"interaction_id", fields["interaction_id"],
+				"command_name", fields["command_name"],
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+			)
+			panicsTotal.Inc()
+			errorReporter.Report(fmt.Errorf("panic: %v", recovered), stack, fields)
+
+			if c.Writer.Written() {
+				// Too late to change the response
+				c.Abort()
+				return
+			}
+			abortWithError(c, http.StatusInternalServerError, errorCodeInternal, "internal server error")
+		}()
+		c.Next()
+	}
+}
+
+// interactionFromContext returns the interaction parsed by the handler, if any
+func interactionFromContext(c *gin.Context) *Interaction {
+	if v, ok := c.Get(interactionKey); ok {
+		if interaction, ok := v.(*Interaction); ok {
+			return interaction
+		}
+	}
+	return nil
+}
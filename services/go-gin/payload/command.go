@@ -0,0 +1,50 @@
+package payload
+
+// CommandName returns the invoked command's name from an interaction's data,
+// or "" if there is none
+func CommandName(data map[string]any) string {
+	name, _ := data["name"].(string)
+	return name
+}
+
+// CommandPath returns the fully-qualified command including any subcommand
+// group and subcommand, e.g. "config permission set"
+func CommandPath(data map[string]any) string {
+	path := CommandName(data)
+	for _, sub := range Subcommands(data) {
+		name, _ := sub["name"].(string)
+		path += " " + name
+	}
+	return path
+}
+
+// Subcommands returns the subcommand group and subcommand an interaction's
+// data invokes, outermost first. The last one's "options" are the options of
+// the command that ran.
+func Subcommands(data map[string]any) []map[string]any {
+	var path []map[string]any
+	options := data["options"]
+	for {
+		list, _ := options.([]any)
+		if len(list) != 1 {
+			return path
+		}
+		opt, _ := list[0].(map[string]any)
+		t, _ := opt["type"].(float64)
+		if int(t) != OptionTypeSubCommand && int(t) != OptionTypeSubCommandGroup {
+			return path
+		}
+		path = append(path, opt)
+		options = opt["options"]
+	}
+}
+
+// UserID returns the invoking user's ID given an interaction's member and
+// user: member.user in guilds, user in DMs
+func UserID(member, user map[string]any) string {
+	if u, _ := member["user"].(map[string]any); u != nil {
+		user = u
+	}
+	id, _ := user["id"].(string)
+	return id
+}
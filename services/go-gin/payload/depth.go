@@ -0,0 +1,49 @@
+package payload
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxDepth is well above Discord's deepest interactions, a
+// subcommand group's options or a modal's components, at under ten levels
+const DefaultMaxDepth = 32
+
+// MaxDepth is how deeply objects and arrays may nest in interaction JSON,
+// the outermost object being depth 1. Services set it from their
+// configuration at startup; Parse rejects deeper data.
+var MaxDepth = DefaultMaxDepth
+
+// ErrTooDeep is returned for JSON nested deeper than the limit
+var ErrTooDeep = errors.New("JSON nested too deeply")
+
+// CheckDepth returns ErrTooDeep if objects and arrays in data nest deeper
+// than limit. It scans the bytes without parsing them, stopping at the first
+// level over the limit, so hostile nesting is rejected before a parser sees
+// it. Syntax errors are left to the parser.
+func CheckDepth(data []byte, limit int) error {
+	depth := 0
+	inString, escaped := false, false
+	for i, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch b {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			if depth++; depth > limit {
+				return fmt.Errorf("%w: over %d levels at byte %d", ErrTooDeep, limit, i)
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package payload
+
+import "time"
+
+// Interaction response types a MessageBuilder builds
+const (
+	// ResponseTypeChannelMessage replies with a new message
+	ResponseTypeChannelMessage = 4
+
+	// ResponseTypeUpdateMessage edits the message a component is attached to
+	ResponseTypeUpdateMessage = 7
+)
+
+// MessageFlagEphemeral makes a message visible only to the invoker
+const MessageFlagEphemeral = 1 << 6
+
+// Response is the body of an interaction response
+type Response struct {
+	Type int            `json:"type"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Component types
+const (
+	ComponentTypeActionRow         = 1
+	ComponentTypeButton            = 2
+	ComponentTypeStringSelect      = 3
+	ComponentTypeUserSelect        = 5
+	ComponentTypeRoleSelect        = 6
+	ComponentTypeMentionableSelect = 7
+	ComponentTypeChannelSelect     = 8
+)
+
+// Button styles
+const (
+	ButtonStylePrimary   = 1
+	ButtonStyleSecondary = 2
+	ButtonStyleSuccess   = 3
+	ButtonStyleDanger    = 4
+	ButtonStyleLink      = 5
+)
+
+// Embed is a rich embed attached to a message
+type Embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Color       int          `json:"color,omitempty"`
+	Timestamp   string       `json:"timestamp,omitempty"`
+	Fields      []EmbedField `json:"fields,omitempty"`
+	Footer      *EmbedFooter `json:"footer,omitempty"`
+	Author      *EmbedAuthor `json:"author,omitempty"`
+	Image       *EmbedMedia  `json:"image,omitempty"`
+	Thumbnail   *EmbedMedia  `json:"thumbnail,omitempty"`
+}
+
+// EmbedField is a name/value pair shown in an embed
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// EmbedFooter is the small text at the bottom of an embed
+type EmbedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// EmbedAuthor is shown above an embed's title
+type EmbedAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// EmbedMedia is an embed's image or thumbnail
+type EmbedMedia struct {
+	URL string `json:"url"`
+}
+
+// EmbedBuilder assembles an Embed:
+//
+//	NewEmbed().Title("Results").Field("Wins", "3", true).Color(0x5865F2)
+type EmbedBuilder struct {
+	embed Embed
+}
+
+// NewEmbed starts an empty embed
+func NewEmbed() *EmbedBuilder {
+	return &EmbedBuilder{}
+}
+
+// Title sets the embed title
+func (b *EmbedBuilder) Title(title string) *EmbedBuilder {
+	b.embed.Title = title
+	return b
+}
+
+// Description sets the embed body text
+func (b *EmbedBuilder) Description(description string) *EmbedBuilder {
+	b.embed.Description = description
+	return b
+}
+
+// URL makes the title a link
+func (b *EmbedBuilder) URL(url string) *EmbedBuilder {
+	b.embed.URL = url
+	return b
+}
+
+// Color sets the embed's side bar color (0xRRGGBB)
+func (b *EmbedBuilder) Color(color int) *EmbedBuilder {
+	b.embed.Color = color
+	return b
+}
+
+// Timestamp shows t in the embed footer
+func (b *EmbedBuilder) Timestamp(t time.Time) *EmbedBuilder {
+	b.embed.Timestamp = t.UTC().Format(time.RFC3339)
+	return b
+}
+
+// Field appends a field
+func (b *EmbedBuilder) Field(name, value string, inline bool) *EmbedBuilder {
+	b.embed.Fields = append(b.embed.Fields, EmbedField{Name: name, Value: value, Inline: inline})
+	return b
+}
+
+// Footer sets the footer text
+func (b *EmbedBuilder) Footer(text string) *EmbedBuilder {
+	b.embed.Footer = &EmbedFooter{Text: text}
+	return b
+}
+
+// Author sets the author line
+func (b *EmbedBuilder) Author(name string) *EmbedBuilder {
+	b.embed.Author = &EmbedAuthor{Name: name}
+	return b
+}
+
+// Image sets the large image
+func (b *EmbedBuilder) Image(url string) *EmbedBuilder {
+	b.embed.Image = &EmbedMedia{URL: url}
+	return b
+}
+
+// Thumbnail sets the small image in the corner
+func (b *EmbedBuilder) Thumbnail(url string) *EmbedBuilder {
+	b.embed.Thumbnail = &EmbedMedia{URL: url}
+	return b
+}
+
+// Embed returns the embed built so far
+func (b *EmbedBuilder) Embed() Embed {
+	return b.embed
+}
+
+// Component is a message component placed in an action row: a Button or a
+// SelectMenu
+type Component interface {
+	componentType() int
+}
+
+// Button is a clickable message component. Link buttons set URL instead of CustomID.
+type Button struct {
+	Type     int    `json:"type"`
+	Style    int    `json:"style"`
+	Label    string `json:"label,omitempty"`
+	CustomID string `json:"custom_id,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+func (Button) componentType() int { return ComponentTypeButton }
+
+// NewButton returns an interactive button
+func NewButton(style int, label, customID string) Button {
+	return Button{Type: ComponentTypeButton, Style: style, Label: label, CustomID: customID}
+}
+
+// NewLinkButton returns a button that opens url
+func NewLinkButton(label, url string) Button {
+	return Button{Type: ComponentTypeButton, Style: ButtonStyleLink, Label: label, URL: url}
+}
+
+// SelectMenu is a dropdown. String selects list their Options; user, role,
+// mentionable, and channel selects are populated by Discord.
+type SelectMenu struct {
+	Type        int            `json:"type"`
+	CustomID    string         `json:"custom_id"`
+	Options     []SelectOption `json:"options,omitempty"`
+	Placeholder string         `json:"placeholder,omitempty"`
+	MinValues   *int           `json:"min_values,omitempty"`
+	MaxValues   *int           `json:"max_values,omitempty"`
+	Disabled    bool           `json:"disabled,omitempty"`
+}
+
+func (s SelectMenu) componentType() int { return s.Type }
+
+// SelectOption is one choice in a string select
+type SelectOption struct {
+	Label       string `json:"label"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Default     bool   `json:"default,omitempty"`
+}
+
+// NewSelectMenu returns a string select offering options
+func NewSelectMenu(customID string, options ...SelectOption) SelectMenu {
+	return SelectMenu{Type: ComponentTypeStringSelect, CustomID: customID, Options: options}
+}
+
+// NewEntitySelect returns a user, role, mentionable, or channel select
+func NewEntitySelect(componentType int, customID string) SelectMenu {
+	return SelectMenu{Type: componentType, CustomID: customID}
+}
+
+// Values sets how many choices the user must pick
+func (s SelectMenu) Values(minValues, maxValues int) SelectMenu {
+	s.MinValues, s.MaxValues = &minValues, &maxValues
+	return s
+}
+
+// ActionRow holds up to five buttons or a single select menu
+type ActionRow struct {
+	Type       int         `json:"type"`
+	Components []Component `json:"components"`
+}
+
+// MessageBuilder assembles the data of a message response, for the edge to
+// answer with directly or a consumer to send as a follow-up
+type MessageBuilder struct {
+	content    *string
+	embeds     []Embed
+	components []ActionRow
+	flags      int
+}
+
+// NewMessage starts an empty message
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{}
+}
+
+// Content sets the message text
+func (b *MessageBuilder) Content(content string) *MessageBuilder {
+	b.content = &content
+	return b
+}
+
+// Embed appends an embed
+func (b *MessageBuilder) Embed(embed Embed) *MessageBuilder {
+	b.embeds = append(b.embeds, embed)
+	return b
+}
+
+// Row appends an action row
+func (b *MessageBuilder) Row(components ...Component) *MessageBuilder {
+	b.components = append(b.components, ActionRow{Type: ComponentTypeActionRow, Components: components})
+	return b
+}
+
+// ClearComponents removes every component from the message being updated
+func (b *MessageBuilder) ClearComponents() *MessageBuilder {
+	b.components = []ActionRow{}
+	return b
+}
+
+// Ephemeral makes a new message visible only to the invoker
+func (b *MessageBuilder) Ephemeral() *MessageBuilder {
+	b.flags |= MessageFlagEphemeral
+	return b
+}
+
+// Data returns the response data. Fields that were never set are omitted so
+// an update leaves them unchanged on the original message.
+func (b *MessageBuilder) Data() map[string]any {
+	data := map[string]any{}
+	if b.content != nil {
+		data["content"] = *b.content
+	}
+	if b.embeds != nil {
+		data["embeds"] = b.embeds
+	}
+	if b.components != nil {
+		data["components"] = b.components
+	}
+	if b.flags != 0 {
+		data["flags"] = b.flags
+	}
+	return data
+}
+
+// Reply builds a CHANNEL_MESSAGE_WITH_SOURCE response
+func (b *MessageBuilder) Reply() Response {
+	return Response{Type: ResponseTypeChannelMessage, Data: b.Data()}
+}
+
+// Update builds an UPDATE_MESSAGE response for a component interaction
+func (b *MessageBuilder) Update() Response {
+	return Response{Type: ResponseTypeUpdateMessage, Data: b.Data()}
+}
@@ -0,0 +1,110 @@
+// Package payload defines, compresses, decompresses and verifies published
+// interaction data, and holds the Discord models the edge and its consumers
+// share: the typed resolved data (Resolved), and the message model of
+// embeds and components with builders for both (MessageBuilder, EmbedBuilder).
+//
+// The data's schema is versioned: each revision has its own type
+// (InteractionV1, ...) and messages name theirs in the schema_version
+// attribute. Parse decodes any supported revision into the current one.
+//
+// Messages whose data exceeds the service's threshold are published
+// compressed, with the encoding named in the content_encoding attribute.
+// Consumers pass the attribute and data to Decode to get the interaction
+// JSON back, whether or not the message was compressed.
+//
+// When the service has a signing key, each message also carries an
+// HMAC-SHA256 of its data in the data_signature attribute. Consumers that
+// share the key check it with VerifyMessage before decoding.
+package payload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// AttributeContentEncoding is the message attribute naming the encoding.
+// It is absent when the data is not compressed.
+const AttributeContentEncoding = "content_encoding"
+
+// Supported encodings
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// MaxDecodedBytes caps decompressed output so a corrupt or hostile message
+// can't exhaust memory. Pub/Sub messages are at most 10MB decompressed here.
+const MaxDecodedBytes = 10 << 20
+
+// zstd encoders and decoders are expensive to create and safe for
+// concurrent EncodeAll/DecodeAll calls, so one of each is shared
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func initZstd() {
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecodedBytes))
+}
+
+// Encode compresses data with encoding ("gzip" or "zstd")
+func Encode(data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case EncodingGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case EncodingZstd:
+		zstdOnce.Do(initZstd)
+		return zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// Decode returns the uncompressed data of a message whose content_encoding
+// attribute is encoding. An empty encoding means data is not compressed.
+func Decode(data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return data, nil
+	case EncodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		out, err := io.ReadAll(io.LimitReader(r, MaxDecodedBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > MaxDecodedBytes {
+			return nil, fmt.Errorf("decoded data exceeds %d bytes", MaxDecodedBytes)
+		}
+		return out, nil
+	case EncodingZstd:
+		zstdOnce.Do(initZstd)
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// DecodeMessage returns the uncompressed data of a message given its
+// attributes, for example a Pub/Sub message's Attributes and Data
+func DecodeMessage(attributes map[string]string, data []byte) ([]byte, error) {
+	return Decode(data, attributes[AttributeContentEncoding])
+}
@@ -0,0 +1,109 @@
+package payload
+
+import "encoding/json"
+
+// Application command option types, see
+// https://discord.com/developers/docs/interactions/application-commands#application-command-object-application-command-option-type
+const (
+	OptionTypeSubCommand      = 1
+	OptionTypeSubCommandGroup = 2
+	OptionTypeString          = 3
+	OptionTypeInteger         = 4
+	OptionTypeBoolean         = 5
+	OptionTypeUser            = 6
+	OptionTypeChannel         = 7
+	OptionTypeRole            = 8
+	OptionTypeMentionable     = 9
+	OptionTypeNumber          = 10
+	OptionTypeAttachment      = 11
+)
+
+// Resolved holds the entities referenced by an interaction's options, keyed by ID
+type Resolved struct {
+	Users       map[string]User       `json:"users,omitempty"`
+	Members     map[string]Member     `json:"members,omitempty"`
+	Roles       map[string]Role       `json:"roles,omitempty"`
+	Channels    map[string]Channel    `json:"channels,omitempty"`
+	Attachments map[string]Attachment `json:"attachments,omitempty"`
+	Messages    map[string]Message    `json:"messages,omitempty"`
+}
+
+// User is a Discord user
+type User struct {
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	Discriminator string `json:"discriminator,omitempty"`
+	GlobalName    string `json:"global_name,omitempty"`
+	Avatar        string `json:"avatar,omitempty"`
+	Bot           bool   `json:"bot,omitempty"`
+}
+
+// Member is a guild member. Resolved members omit the user, which is in Users.
+type Member struct {
+	Nick        string   `json:"nick,omitempty"`
+	Roles       []string `json:"roles"`
+	JoinedAt    string   `json:"joined_at,omitempty"`
+	Permissions string   `json:"permissions,omitempty"`
+}
+
+// Role is a guild role
+type Role struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Color       int    `json:"color"`
+	Position    int    `json:"position"`
+	Permissions string `json:"permissions"`
+	Managed     bool   `json:"managed,omitempty"`
+}
+
+// Channel is a partial channel as sent in resolved data
+type Channel struct {
+	ID          string `json:"id"`
+	Type        int    `json:"type"`
+	Name        string `json:"name,omitempty"`
+	ParentID    string `json:"parent_id,omitempty"`
+	Permissions string `json:"permissions,omitempty"`
+}
+
+// Attachment is a file uploaded through an ATTACHMENT option. Services that
+// offload attachments replace URL with a gs:// reference and drop ProxyURL.
+type Attachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+	URL         string `json:"url"`
+	ProxyURL    string `json:"proxy_url"`
+}
+
+// Message is a message targeted by a message context menu command
+type Message struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Content   string `json:"content"`
+	Author    User   `json:"author"`
+}
+
+// ParseResolved decodes an interaction's data.resolved into typed entities.
+// It returns an empty Resolved when the interaction references none, and an
+// error when the entities don't have Discord's shapes.
+func ParseResolved(data map[string]any) (*Resolved, error) {
+	return ParseResolvedWith(data, json.Marshal, json.Unmarshal)
+}
+
+// ParseResolvedWith is ParseResolved with another JSON codec
+func ParseResolvedWith(data map[string]any, marshal func(any) ([]byte, error), unmarshal func([]byte, any) error) (*Resolved, error) {
+	resolved := &Resolved{}
+	raw, ok := data["resolved"]
+	if !ok {
+		return resolved, nil
+	}
+	b, err := marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshal(b, resolved); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
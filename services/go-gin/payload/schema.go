@@ -0,0 +1,73 @@
+package payload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// AttributeSchemaVersion is the message attribute naming the revision of
+// the data schema. Messages published before the attribute existed have
+// version 1 data.
+const AttributeSchemaVersion = "schema_version"
+
+// Schema revisions. Add a constant, a type and an upgrade step in Parse for
+// each new revision; never change a published type.
+const (
+	SchemaV1 = "1"
+
+	// SchemaVersion is the revision the service publishes
+	SchemaVersion = SchemaV1
+)
+
+// ErrUnsupportedSchemaVersion is returned for messages newer than this
+// package. Consumers should nack them (or route them to a dead letter topic)
+// until they are upgraded, rather than guess at the format.
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// InteractionV1 is the data of a schema version 1 message: the interaction
+// Discord sent, without its token
+type InteractionV1 struct {
+	Type          int              `json:"type"`
+	ID            string           `json:"id,omitempty"`
+	ApplicationID string           `json:"application_id,omitempty"`
+	Data          map[string]any   `json:"data,omitempty"`
+	GuildID       string           `json:"guild_id,omitempty"`
+	ChannelID     string           `json:"channel_id,omitempty"`
+	Member        map[string]any   `json:"member,omitempty"`
+	User          map[string]any   `json:"user,omitempty"`
+	Locale        string           `json:"locale,omitempty"`
+	GuildLocale   string           `json:"guild_locale,omitempty"`
+	Entitlements  []map[string]any `json:"entitlements,omitempty"`
+
+	// SealedToken is the interaction token sealed to the worker's key, when
+	// the service has one
+	SealedToken string `json:"sealed_token,omitempty"`
+}
+
+// Interaction is the current schema revision's type
+type Interaction = InteractionV1
+
+// Parse decodes a message's data into the current schema revision. It
+// decompresses the data and upgrades older revisions, so consumers handle a
+// single type whatever version the publisher was running. Data nested deeper
+// than MaxDepth is rejected with ErrTooDeep.
+func Parse(attributes map[string]string, data []byte) (*Interaction, error) {
+	raw, err := DecodeMessage(attributes, data)
+	if err != nil {
+		return nil, err
+	}
+	switch version := attributes[AttributeSchemaVersion]; version {
+	case "", SchemaV1:
+		if err := CheckDepth(raw, MaxDepth); err != nil {
+			return nil, fmt.Errorf("schema version 1: %w", err)
+		}
+		var v1 InteractionV1
+		if err := json.Unmarshal(raw, &v1); err != nil {
+			return nil, fmt.Errorf("schema version 1: %w", err)
+		}
+		return &v1, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedSchemaVersion, version)
+	}
+}
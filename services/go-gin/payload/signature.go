@@ -0,0 +1,52 @@
+package payload
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Signature attributes, present when the service signs published messages.
+// The signature covers the data exactly as published, so it is checked
+// before decompressing.
+const (
+	// AttributeSignature is "sha256=" followed by the hex HMAC-SHA256 of
+	// the message data
+	AttributeSignature = "data_signature"
+
+	// AttributeSignatureKeyID names the key that made the signature, so
+	// keys can be rotated. Absent when the service has no key ID set.
+	AttributeSignatureKeyID = "data_signature_key_id"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign returns the data_signature attribute value for data
+func Sign(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid data_signature of data under
+// key. The comparison takes constant time.
+func Verify(key, data []byte, signature string) bool {
+	sig, ok := strings.CutPrefix(signature, signaturePrefix)
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// VerifyMessage checks a message's data_signature attribute, for example on
+// a Pub/Sub message's Attributes and Data. Unsigned messages fail.
+func VerifyMessage(key []byte, attributes map[string]string, data []byte) bool {
+	return Verify(key, data, attributes[AttributeSignature])
+}
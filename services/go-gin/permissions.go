@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Discord permission bits, see
+// https://discord.com/developers/docs/topics/permissions#permissions-bitwise-permission-flags
+var permissionBits = map[string]uint64{
+	"CREATE_INSTANT_INVITE":               1 << 0,
+	"KICK_MEMBERS":                        1 << 1,
+	"BAN_MEMBERS":                         1 << 2,
+	"ADMINISTRATOR":                       1 << 3,
+	"MANAGE_CHANNELS":                     1 << 4,
+	"MANAGE_GUILD":                        1 << 5,
+	"ADD_REACTIONS":                       1 << 6,
+	"VIEW_AUDIT_LOG":                      1 << 7,
+	"PRIORITY_SPEAKER":                    1 << 8,
+	"STREAM":                              1 << 9,
+	"VIEW_CHANNEL":                        1 << 10,
+	"SEND_MESSAGES":                       1 << 11,
+	"SEND_TTS_MESSAGES":                   1 << 12,
+	"MANAGE_MESSAGES":                     1 << 13,
+	"EMBED_LINKS":                         1 << 14,
+	"ATTACH_FILES":                        1 << 15,
+	"READ_MESSAGE_HISTORY":                1 << 16,
+	"MENTION_EVERYONE":                    1 << 17,
+	"USE_EXTERNAL_EMOJIS":                 1 << 18,
+	"VIEW_GUILD_INSIGHTS":                 1 << 19,
+	"CONNECT":                             1 << 20,
+	"SPEAK":                               1 << 21,
+	"MUTE_MEMBERS":                        1 << 22,
+	"DEAFEN_MEMBERS":                      1 << 23,
+	"MOVE_MEMBERS":                        1 << 24,
+	"USE_VAD":                             1 << 25,
+	"CHANGE_NICKNAME":                     1 << 26,
+	"MANAGE_NICKNAMES":                    1 << 27,
+	"MANAGE_ROLES":                        1 << 28,
+	"MANAGE_WEBHOOKS":                     1 << 29,
+	"MANAGE_GUILD_EXPRESSIONS":            1 << 30,
+	"USE_APPLICATION_COMMANDS":            1 << 31,
+	"REQUEST_TO_SPEAK":                    1 << 32,
+	"MANAGE_EVENTS":                       1 << 33,
+	"MANAGE_THREADS":                      1 << 34,
+	"CREATE_PUBLIC_THREADS":               1 << 35,
+	"CREATE_PRIVATE_THREADS":              1 << 36,
+	"USE_EXTERNAL_STICKERS":               1 << 37,
+	"SEND_MESSAGES_IN_THREADS":            1 << 38,
+	"USE_EMBEDDED_ACTIVITIES":             1 << 39,
+	"MODERATE_MEMBERS":                    1 << 40,
+	"VIEW_CREATOR_MONETIZATION_ANALYTICS": 1 << 41,
+	"USE_SOUNDBOARD":                      1 << 42,
+	"CREATE_GUILD_EXPRESSIONS":            1 << 43,
+	"CREATE_EVENTS":                       1 << 44,
+	"USE_EXTERNAL_SOUNDS":                 1 << 45,
+	"SEND_VOICE_MESSAGES":                 1 << 46,
+	"SEND_POLLS":                          1 << 49,
+	"USE_EXTERNAL_APPS":                   1 << 50,
+}
+
+// parsePermissions parses "NAME+NAME" (or a numeric bitfield) into a bitfield
+func parsePermissions(s string) (uint64, error) {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n, nil
+	}
+	var bits uint64
+	for _, name := range strings.Split(s, "+") {
+		bit, ok := permissionBits[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("unknown permission %q", name)
+		}
+		bits |= bit
+	}
+	return bits, nil
+}
+
+// parseCommandPermissions parses entries of the form "command=PERM+PERM"
+func parseCommandPermissions(entries []string) (map[string]uint64, error) {
+	out := make(map[string]uint64, len(entries))
+	for _, entry := range entries {
+		command, perms, ok := strings.Cut(entry, "=")
+		if !ok || command == "" || perms == "" {
+			return nil, fmt.Errorf("invalid command permission %q (want command=PERMISSION)", entry)
+		}
+		bits, err := parsePermissions(perms)
+		if err != nil {
+			return nil, fmt.Errorf("command %q: %w", command, err)
+		}
+		out[command] = bits
+	}
+	return out, nil
+}
+
+// memberPermissions returns the invoking member's resolved permissions.
+// ok is false when the interaction carries no member permissions (e.g. DMs).
+func memberPermissions(interaction *Interaction) (perms uint64, ok bool) {
+	raw, _ := interaction.Member["permissions"].(string)
+	if raw == "" {
+		return 0, false
+	}
+	perms, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return perms, true
+}
+
+// permissionPolicy gates commands on the invoker's permissions
+type permissionPolicy struct {
+	required map[string]uint64
+}
+
+// Allowed reports whether the invoker has every permission the command requires.
+// ADMINISTRATOR implies all permissions.
+func (p *permissionPolicy) Allowed(interaction *Interaction) bool {
+	// A subcommand's own requirement takes precedence over its command's
+	required, gated := p.required[interaction.CommandPath()]
+	if !gated {
+		required, gated = p.required[interaction.CommandName()]
+	}
+	if !gated || required == 0 {
+		return true
+	}
+	perms, ok := memberPermissions(interaction)
+	if !ok {
+		return false
+	}
+	if perms&permissionBits["ADMINISTRATOR"] != 0 {
+		return true
+	}
+	return perms&required == required
+}
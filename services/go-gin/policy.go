@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// guildPolicy decides which guilds the service serves.
+//
+// A non-empty allowlist restricts guild interactions to the listed guilds;
+// the denylist is applied on top. Interactions without a guild (DMs) are
+// not affected by either list.
+type guildPolicy struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+func newGuildPolicy(allow, deny []string) *guildPolicy {
+	return &guildPolicy{allow: stringSet(allow), deny: stringSet(deny)}
+}
+
+// Allowed reports whether interactions from guildID should be served
+func (p *guildPolicy) Allowed(guildID string) bool {
+	if guildID == "" {
+		return true
+	}
+	if p.deny[guildID] {
+		return false
+	}
+	return len(p.allow) == 0 || p.allow[guildID]
+}
+
+// commandPolicy restricts which command names are accepted. An empty
+// allowlist accepts every command.
+type commandPolicy struct {
+	allow map[string]bool
+}
+
+func newCommandPolicy(allow []string) *commandPolicy {
+	return &commandPolicy{allow: stringSet(allow)}
+}
+
+// Allowed reports whether the named command should be deferred and published
+func (p *commandPolicy) Allowed(name string) bool {
+	return len(p.allow) == 0 || p.allow[name]
+}
+
+// Interaction contexts a command may be restricted to
+const (
+	contextAny   = "any"
+	contextGuild = "guild"
+	contextDM    = "dm"
+)
+
+// interactionContext reports whether the interaction came from a guild or a DM
+func interactionContext(interaction *Interaction) string {
+	if interaction.GuildID != "" {
+		return contextGuild
+	}
+	return contextDM
+}
+
+// contextPolicy restricts commands to guilds or DMs. Commands without an
+// entry are allowed in both.
+type contextPolicy struct {
+	allowed map[string]string
+}
+
+// parseCommandContexts parses entries of the form "command=guild|dm|any"
+func parseCommandContexts(entries []string) (map[string]string, error) {
+	out := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		command, ctx, ok := strings.Cut(entry, "=")
+		if !ok || command == "" {
+			return nil, fmt.Errorf("invalid command context %q (want command=guild|dm|any)", entry)
+		}
+		switch ctx {
+		case contextAny, contextGuild, contextDM:
+			out[command] = ctx
+		default:
+			return nil, fmt.Errorf("command %q: unknown context %q", command, ctx)
+		}
+	}
+	return out, nil
+}
+
+// Check returns "" when the command may run in the interaction's context,
+// otherwise the message key explaining where it can be used
+func (p *contextPolicy) Check(interaction *Interaction) string {
+	want, ok := p.allowed[interaction.CommandName()]
+	if !ok || want == contextAny || want == interactionContext(interaction) {
+		return ""
+	}
+	if want == contextGuild {
+		return msgGuildOnly
+	}
+	return msgDMOnly
+}
+
+// stringSet builds a lookup set from a list
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResponseTypePremiumRequired tells Discord to show the app's premium upsell
+const ResponseTypePremiumRequired = 10
+
+// parseCommandSKUs parses entries of the form "command=SKU". A command may be
+// listed more than once; any one of its SKUs unlocks it.
+func parseCommandSKUs(entries []string) (map[string]map[string]bool, error) {
+	out := make(map[string]map[string]bool, len(entries))
+	for _, entry := range entries {
+		command, sku, ok := strings.Cut(entry, "=")
+		if !ok || command == "" || sku == "" {
+			return nil, fmt.Errorf("invalid command SKU %q (want command=SKU_ID)", entry)
+		}
+		if out[command] == nil {
+			out[command] = make(map[string]bool)
+		}
+		out[command][sku] = true
+	}
+	return out, nil
+}
+
+// premiumPolicy gates monetized commands on the interaction's entitlements
+type premiumPolicy struct {
+	required map[string]map[string]bool
+}
+
+// Allowed reports whether the interaction carries an active entitlement for
+// one of the SKUs the command requires
+func (p *premiumPolicy) Allowed(interaction *Interaction) bool {
+	skus, gated := p.required[interaction.CommandName()]
+	if !gated {
+		return true
+	}
+	now := time.Now()
+	for _, entitlement := range interaction.Entitlements {
+		sku, _ := entitlement["sku_id"].(string)
+		if skus[sku] && entitlementActive(entitlement, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// entitlementActive reports whether an entitlement is neither deleted nor expired.
+// Entitlements without an end date (e.g. test entitlements) never expire.
+func entitlementActive(entitlement map[string]interface{}, now time.Time) bool {
+	if deleted, _ := entitlement["deleted"].(bool); deleted {
+		return false
+	}
+	endsAt, _ := entitlement["ends_at"].(string)
+	if endsAt == "" {
+		return true
+	}
+	end, err := time.Parse(time.RFC3339, endsAt)
+	return err == nil && now.Before(end)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// errUnsupportedEncoding is returned for a Content-Encoding other than gzip
+var errUnsupportedEncoding = errors.New("unsupported Content-Encoding")
+
+// readBody reads a request body of at most limit bytes. Discord never
+// compresses bodies, but proxies in front of the service sometimes do: a
+// gzip body is decompressed, with the limit applying to its decompressed
+// size too, so a small body can't expand without bound. Discord signed the
+// body before any proxy compressed it, so the signature is checked against
+// what this returns. Other encodings, and gzip applied more than once, are
+// rejected with errUnsupportedEncoding.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	body := http.MaxBytesReader(w, r.Body, limit)
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return io.ReadAll(body)
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		data, err := io.ReadAll(io.LimitReader(zr, limit+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > limit {
+			return nil, &http.MaxBytesError{Limit: limit}
+		}
+		return data, nil
+	default:
+		return nil, errUnsupportedEncoding
+	}
+}
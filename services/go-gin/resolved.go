@@ -0,0 +1,110 @@
+package main
+
+import "github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+
+// parseResolved decodes data.resolved with the service's JSON codec
+func parseResolved(data map[string]interface{}) (*payload.Resolved, error) {
+	return payload.ParseResolvedWith(data, jsonMarshal, jsonUnmarshal)
+}
+
+// Option returns the named option, searching inside subcommands and groups
+func (i *Interaction) Option(name string) (map[string]interface{}, bool) {
+	return findOption(i.Data["options"], name)
+}
+
+func findOption(options interface{}, name string) (map[string]interface{}, bool) {
+	list, _ := options.([]interface{})
+	for _, o := range list {
+		opt, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		t, _ := opt["type"].(float64)
+		if int(t) == payload.OptionTypeSubCommand || int(t) == payload.OptionTypeSubCommandGroup {
+			if found, ok := findOption(opt["options"], name); ok {
+				return found, true
+			}
+			continue
+		}
+		if opt["name"] == name {
+			return opt, true
+		}
+	}
+	return nil, false
+}
+
+// optionID returns the snowflake value of the named option if it has one of the given types
+func (i *Interaction) optionID(name string, types ...int) (string, bool) {
+	opt, ok := i.Option(name)
+	if !ok {
+		return "", false
+	}
+	t, _ := opt["type"].(float64)
+	for _, want := range types {
+		if int(t) == want {
+			id, ok := opt["value"].(string)
+			return id, ok
+		}
+	}
+	return "", false
+}
+
+// OptionUser resolves a USER (or MENTIONABLE) option to its user
+func (i *Interaction) OptionUser(name string) (payload.User, bool) {
+	id, ok := i.optionID(name, payload.OptionTypeUser, payload.OptionTypeMentionable)
+	if !ok || i.Resolved == nil {
+		return payload.User{}, false
+	}
+	u, ok := i.Resolved.Users[id]
+	return u, ok
+}
+
+// OptionMember resolves a USER (or MENTIONABLE) option to the guild member, if invoked in a guild
+func (i *Interaction) OptionMember(name string) (payload.Member, bool) {
+	id, ok := i.optionID(name, payload.OptionTypeUser, payload.OptionTypeMentionable)
+	if !ok || i.Resolved == nil {
+		return payload.Member{}, false
+	}
+	m, ok := i.Resolved.Members[id]
+	return m, ok
+}
+
+// OptionRole resolves a ROLE (or MENTIONABLE) option to its role
+func (i *Interaction) OptionRole(name string) (payload.Role, bool) {
+	id, ok := i.optionID(name, payload.OptionTypeRole, payload.OptionTypeMentionable)
+	if !ok || i.Resolved == nil {
+		return payload.Role{}, false
+	}
+	r, ok := i.Resolved.Roles[id]
+	return r, ok
+}
+
+// OptionChannel resolves a CHANNEL option to its channel
+func (i *Interaction) OptionChannel(name string) (payload.Channel, bool) {
+	id, ok := i.optionID(name, payload.OptionTypeChannel)
+	if !ok || i.Resolved == nil {
+		return payload.Channel{}, false
+	}
+	ch, ok := i.Resolved.Channels[id]
+	return ch, ok
+}
+
+// OptionAttachment resolves an ATTACHMENT option to its attachment
+func (i *Interaction) OptionAttachment(name string) (payload.Attachment, bool) {
+	id, ok := i.optionID(name, payload.OptionTypeAttachment)
+	if !ok || i.Resolved == nil {
+		return payload.Attachment{}, false
+	}
+	a, ok := i.Resolved.Attachments[id]
+	return a, ok
+}
+
+// TargetMessage returns the message a message context menu command was used on
+func (i *Interaction) TargetMessage() (payload.Message, bool) {
+	id, _ := i.Data["target_id"].(string)
+	if id == "" || i.Resolved == nil {
+		return payload.Message{}, false
+	}
+	m, ok := i.Resolved.Messages[id]
+	return m, ok
+}
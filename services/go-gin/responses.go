@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response modes for slash commands
+const (
+	// responseDeferred defers publicly and publishes (the default)
+	responseDeferred = "deferred"
+	// responseDeferredEphemeral defers with the ephemeral flag and publishes
+	responseDeferredEphemeral = "ephemeral"
+	// responseStatic answers immediately with a fixed message and doesn't publish
+	responseStatic = "static"
+	// responseLaunchActivity launches the app's Activity and doesn't publish
+	responseLaunchActivity = "launch_activity"
+)
+
+// ResponseTypeLaunchActivity launches the Activity associated with the app
+const ResponseTypeLaunchActivity = 12
+
+// ResponsePolicy declares how the service answers a command
+type ResponsePolicy struct {
+	Mode      string `json:"mode"`
+	Content   string `json:"content,omitempty"`
+	Ephemeral bool   `json:"ephemeral,omitempty"`
+}
+
+// defaultResponsePolicy is used for commands without an explicit policy
+var defaultResponsePolicy = ResponsePolicy{Mode: responseDeferred}
+
+// parseResponsePolicies parses a JSON object mapping command names to policies, e.g.
+//
+//	{"help": {"mode": "static", "content": "Try /ping"}, "secret": {"mode": "ephemeral"}}
+func parseResponsePolicies(raw string) (map[string]ResponsePolicy, error) {
+	policies := map[string]ResponsePolicy{}
+	if raw == "" {
+		return policies, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &policies); err != nil {
+		return nil, err
+	}
+	for command, p := range policies {
+		switch p.Mode {
+		case responseDeferred, responseDeferredEphemeral, responseLaunchActivity:
+		case responseStatic:
+			if p.Content == "" {
+				return nil, fmt.Errorf("command %q: static responses need content", command)
+			}
+		default:
+			return nil, fmt.Errorf("command %q: unknown mode %q", command, p.Mode)
+		}
+	}
+	return policies, nil
+}
+
+// responsePolicyFor returns the policy for the named command
+func responsePolicyFor(policies map[string]ResponsePolicy, command string) ResponsePolicy {
+	if p, ok := policies[command]; ok {
+		return p
+	}
+	return defaultResponsePolicy
+}
+
+// Response bodies that never change, encoded once at startup with the same
+// codec Gin uses, so the hottest responses are written without marshaling
+var (
+	// precomputedTypes holds the bodies of responses that are only a type
+	precomputedTypes = map[int][]byte{
+		ResponseTypePong:                   mustMarshalResponse(InteractionResponse{Type: ResponseTypePong}),
+		ResponseTypeDeferredChannelMessage: mustMarshalResponse(InteractionResponse{Type: ResponseTypeDeferredChannelMessage}),
+		ResponseTypeDeferredUpdateMessage:  mustMarshalResponse(InteractionResponse{Type: ResponseTypeDeferredUpdateMessage}),
+		ResponseTypePremiumRequired:        mustMarshalResponse(InteractionResponse{Type: ResponseTypePremiumRequired}),
+		ResponseTypeLaunchActivity:         mustMarshalResponse(InteractionResponse{Type: ResponseTypeLaunchActivity}),
+	}
+	deferredEphemeralBody = mustMarshalResponse(InteractionResponse{
+		Type: ResponseTypeDeferredChannelMessage,
+		Data: map[string]interface{}{"flags": MessageFlagEphemeral},
+	})
+
+	// jsonContentType is the Content-Type Gin's c.JSON sets. Header values
+	// are only read once set, so every response can share the slice.
+	jsonContentType = []string{"application/json; charset=utf-8"}
+)
+
+func mustMarshalResponse(resp InteractionResponse) []byte {
+	body, err := jsonMarshal(resp)
+	if err != nil {
+		panic(fmt.Sprintf("marshal response type %d: %v", resp.Type, err))
+	}
+	return body
+}
+
+// respondType answers with a response that is only a type
+func respondType(c *gin.Context, responseType int) {
+	if body, ok := precomputedTypes[responseType]; ok {
+		respondPrecomputed(c, body)
+		return
+	}
+	c.JSON(http.StatusOK, InteractionResponse{Type: responseType})
+}
+
+// respondPrecomputed writes a 200 with an already encoded JSON body, with
+// the same headers as c.JSON
+func respondPrecomputed(c *gin.Context, body []byte) {
+	c.Writer.Header()["Content-Type"] = jsonContentType
+	c.Writer.WriteHeader(http.StatusOK)
+	_, _ = c.Writer.Write(body)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// precomputedCases are the precomputed responses, each with what c.JSON
+// would have written instead
+var precomputedCases = []struct {
+	name    string
+	respond func(c *gin.Context)
+	want    InteractionResponse
+}{
+	{"pong", func(c *gin.Context) { respondType(c, ResponseTypePong) }, InteractionResponse{Type: ResponseTypePong}},
+	{"deferred", func(c *gin.Context) { respondType(c, ResponseTypeDeferredChannelMessage) },
+		InteractionResponse{Type: ResponseTypeDeferredChannelMessage}},
+	{"deferred_ephemeral", func(c *gin.Context) { respondPrecomputed(c, deferredEphemeralBody) },
+		InteractionResponse{Type: ResponseTypeDeferredChannelMessage, Data: map[string]interface{}{"flags": MessageFlagEphemeral}}},
+	{"deferred_update", func(c *gin.Context) { respondType(c, ResponseTypeDeferredUpdateMessage) },
+		InteractionResponse{Type: ResponseTypeDeferredUpdateMessage}},
+	{"premium_required", func(c *gin.Context) { respondType(c, ResponseTypePremiumRequired) },
+		InteractionResponse{Type: ResponseTypePremiumRequired}},
+	{"launch_activity", func(c *gin.Context) { respondType(c, ResponseTypeLaunchActivity) },
+		InteractionResponse{Type: ResponseTypeLaunchActivity}},
+}
+
+// record runs respond in a Gin context and returns what it wrote
+func record(respond func(c *gin.Context)) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	respond(c)
+	return w
+}
+
+// TestPrecomputedResponses_MatchJSON checks each precomputed response is
+// written with the status, headers and body c.JSON would have written
+func TestPrecomputedResponses_MatchJSON(t *testing.T) {
+	for _, tc := range precomputedCases {
+		got := record(tc.respond)
+		want := record(func(c *gin.Context) { c.JSON(http.StatusOK, tc.want) })
+		if got.Code != want.Code {
+			t.Errorf("%s: expected status %d, got %d", tc.name, want.Code, got.Code)
+		}
+		if !reflect.DeepEqual(got.Header(), want.Header()) {
+			t.Errorf("%s: expected headers %v, got %v", tc.name, want.Header(), got.Header())
+		}
+		if !bytes.Equal(got.Body.Bytes(), want.Body.Bytes()) {
+			t.Errorf("%s: expected body %s, got %s", tc.name, want.Body, got.Body)
+		}
+	}
+}
+
+// TestRespondType_NotPrecomputed checks a type without a precomputed body
+// is still answered
+func TestRespondType_NotPrecomputed(t *testing.T) {
+	w := record(func(c *gin.Context) { respondType(c, ResponseTypeChannelMessage) })
+	if w.Code != http.StatusOK || w.Body.String() != `{"type":4}` {
+		t.Errorf("Expected 200 {\"type\":4}, got %d %s", w.Code, w.Body)
+	}
+}
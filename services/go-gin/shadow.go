@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// shadow mirrors a sample of published interactions to a second destination
+// so a new pipeline can be validated on real traffic. Nil unless
+// SHADOW_PUBSUB_TOPIC or SHADOW_WEBHOOK_URL is set.
+var shadow *shadowPublisher
+
+// shadowPublisher sends copies marked with shadow=true. Shadow publishes run
+// in the background and outside the circuit breaker, so a failing shadow
+// never affects the primary publish or the response.
+type shadowPublisher struct {
+	topic      *pubsub.Topic
+	webhook    *webhookForwarder
+	sampleRate float64
+}
+
+// sampled picks interactions by ID rather than at random, so redeliveries
+// of an interaction are mirrored (or not) consistently
+func (s *shadowPublisher) sampled(interactionID string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(interactionID))
+	return float64(h.Sum32()%10000) < s.sampleRate*10000
+}
+
+// Mirror sends a copy of msg to the shadow destination if the interaction
+// is in the sample
+func (s *shadowPublisher) Mirror(ctx context.Context, msg *pubsub.Message, interaction *Interaction) {
+	if !s.sampled(interaction.ID) {
+		return
+	}
+	attrs := make(map[string]string, len(msg.Attributes)+1)
+	for k, v := range msg.Attributes {
+		attrs[k] = v
+	}
+	attrs["shadow"] = "true"
+	mirrored := &pubsub.Message{Data: msg.Data, Attributes: attrs}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncPublishTimeout)
+	go func() {
+		defer cancel()
+		var err error
+		if s.topic != nil {
+			_, err = s.topic.Publish(ctx, mirrored).Get(ctx)
+		} else {
+			err = s.webhook.Deliver(ctx, mirrored)
+		}
+		if err != nil {
+			shadowPublishTotal.WithLabelValues("error").Inc()
+			loggerFrom(ctx).Warn("Failed to publish shadow copy", "interaction_id", interaction.ID, "error", err)
+			return
+		}
+		shadowPublishTotal.WithLabelValues("success").Inc()
+	}()
+}
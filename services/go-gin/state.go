@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis calls sit on the request path, so they fail fast and fall back to
+// in-memory state rather than eat into Discord's 3s budget
+const (
+	redisDialTimeout = 200 * time.Millisecond
+	redisIOTimeout   = 100 * time.Millisecond
+	redisKeyPrefix   = "discord-bot:"
+)
+
+// memorySweepInterval is how often expired in-memory entries are dropped
+const memorySweepInterval = time.Minute
+
+// stateBackend stores the short-lived keys behind dedup and rate limiting
+type stateBackend interface {
+	// SetIfAbsent records key for ttl and reports whether it was newly set
+	SetIfAbsent(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Increment counts one event for key in the current fixed window and
+	// returns the window's count so far
+	Increment(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+// sharedState is shared across instances through Redis when configured. When
+// Redis is unset or failing, each instance enforces the same rules on its own
+// in-memory state, so limits loosen instead of requests failing. A breaker
+// keeps a dead Redis from costing a timeout on every request.
+type sharedState struct {
+	redis   stateBackend // nil when REDIS_URL is unset
+	memory  *memoryState
+	breaker *circuitBreaker
+}
+
+// state backs dedup and rate limiting. In-memory unless REDIS_URL is set.
+var state = &sharedState{memory: newMemoryState()}
+
+func newSharedState(redisURL string) (*sharedState, error) {
+	s := &sharedState{memory: newMemoryState()}
+	if redisURL == "" {
+		return s, nil
+	}
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	opts.DialTimeout = redisDialTimeout
+	opts.ReadTimeout = redisIOTimeout
+	opts.WriteTimeout = redisIOTimeout
+	s.redis = &redisState{client: redis.NewClient(opts)}
+	s.breaker = newCircuitBreaker(3, 10*time.Second)
+	s.breaker.onStateChange = func(from, to breakerState) {
+		logger.Warn("Redis circuit breaker state changed", "from", from.String(), "to", to.String())
+	}
+	return s, nil
+}
+
+// SetIfAbsent records key for ttl and reports whether it was newly set
+func (s *sharedState) SetIfAbsent(ctx context.Context, key string, ttl time.Duration) bool {
+	if s.redis != nil {
+		var set bool
+		err := s.breaker.Execute(func() (err error) {
+			set, err = s.redis.SetIfAbsent(ctx, key, ttl)
+			return err
+		})
+		if err == nil {
+			return set
+		}
+		s.degraded(ctx, err)
+	}
+	set, _ := s.memory.SetIfAbsent(ctx, key, ttl)
+	return set
+}
+
+// Increment counts one event for key in the current window
+func (s *sharedState) Increment(ctx context.Context, key string, window time.Duration) int64 {
+	if s.redis != nil {
+		var n int64
+		err := s.breaker.Execute(func() (err error) {
+			n, err = s.redis.Increment(ctx, key, window)
+			return err
+		})
+		if err == nil {
+			return n
+		}
+		s.degraded(ctx, err)
+	}
+	n, _ := s.memory.Increment(ctx, key, window)
+	return n
+}
+
+// degraded records a fallback to in-memory state
+func (s *sharedState) degraded(ctx context.Context, err error) {
+	stateFallbacksTotal.Inc()
+	// The breaker already logged opening; don't repeat it for every request
+	if !errors.Is(err, errBreakerOpen) {
+		loggerFrom(ctx).Warn("Redis unavailable, using in-memory state", "error", err)
+	}
+}
+
+// windowKey names the counter for the fixed window containing now
+func windowKey(key string, window time.Duration, now time.Time) string {
+	return key + ":" + strconv.FormatInt(now.UnixNano()/int64(window), 10)
+}
+
+// redisState keeps state in Redis, shared by every instance
+type redisState struct {
+	client *redis.Client
+}
+
+func (r *redisState) SetIfAbsent(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, redisKeyPrefix+key, 1, ttl).Result()
+}
+
+func (r *redisState) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	k := redisKeyPrefix + windowKey(key, window, time.Now())
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, k)
+	pipe.Expire(ctx, k, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// memoryState keeps state in this instance only
+type memoryState struct {
+	mu        sync.Mutex
+	keys      map[string]time.Time // key -> expiry
+	counters  map[string]*memoryCounter
+	lastSweep time.Time
+}
+
+type memoryCounter struct {
+	count   int64
+	expires time.Time
+}
+
+func newMemoryState() *memoryState {
+	return &memoryState{
+		keys:      make(map[string]time.Time),
+		counters:  make(map[string]*memoryCounter),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *memoryState) SetIfAbsent(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	if expires, ok := m.keys[key]; ok && now.Before(expires) {
+		return false, nil
+	}
+	m.keys[key] = now.Add(ttl)
+	return true, nil
+}
+
+func (m *memoryState) Increment(_ context.Context, key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	k := windowKey(key, window, now)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	c, ok := m.counters[k]
+	if !ok {
+		c = &memoryCounter{expires: now.Add(window)}
+		m.counters[k] = c
+	}
+	c.count++
+	return c.count, nil
+}
+
+// Size returns how many dedup keys and rate-limit counters are held
+func (m *memoryState) Size() (keys, counters int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.keys), len(m.counters)
+}
+
+// sweep drops expired entries at most once per memorySweepInterval. Callers hold m.mu.
+func (m *memoryState) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < memorySweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for k, expires := range m.keys {
+		if !now.Before(expires) {
+			delete(m.keys, k)
+		}
+	}
+	for k, c := range m.counters {
+		if !now.Before(c.expires) {
+			delete(m.counters, k)
+		}
+	}
+}
+
+// rateLimiter caps how many commands each user, or each guild, can run per
+// window
+type rateLimiter struct {
+	scope  string                    // "user" or "guild", part of the state key
+	key    func(*Interaction) string // the user or guild ID; "" is never limited
+	limit  int64
+	window time.Duration
+}
+
+// Per-user and per-guild limiters, nil unless USER_RATE_LIMIT and
+// GUILD_RATE_LIMIT are set
+var userRateLimit, guildRateLimit *rateLimiter
+
+func newUserRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{scope: "user", key: (*Interaction).UserID, limit: int64(limit), window: window}
+}
+
+// newGuildRateLimiter limits guilds; commands from DMs have no guild and
+// are only subject to the per-user limit
+func newGuildRateLimiter(limit int, window time.Duration) *rateLimiter {
+	guildID := func(i *Interaction) string { return i.GuildID }
+	return &rateLimiter{scope: "guild", key: guildID, limit: int64(limit), window: window}
+}
+
+// Allow counts the interaction against its user's or guild's limit
+func (l *rateLimiter) Allow(ctx context.Context, interaction *Interaction) bool {
+	id := l.key(interaction)
+	if id == "" {
+		return true
+	}
+	return state.Increment(ctx, "ratelimit:"+l.scope+":"+id, l.window) <= l.limit
+}
+
+// dedupWindow is how long interaction IDs are remembered (0 disables dedup
+// unless the dedup flag turns it on)
+var dedupWindow time.Duration
+
+// defaultDedupWindow applies when the dedup flag is on without DEDUP_WINDOW
+const defaultDedupWindow = 10 * time.Minute
+
+// firstDelivery reports whether this is the first time the interaction has
+// been seen within the dedup window
+func firstDelivery(ctx context.Context, interaction *Interaction) bool {
+	if !flagEnabled(ctx, flagDedup, dedupWindow > 0, interaction) {
+		return true
+	}
+	window := dedupWindow
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+	return state.SetIfAbsent(ctx, "dedup:"+interaction.ID, window)
+}
+
+// checkRedis verifies Redis answers a PING
+func checkRedis(ctx context.Context, cfg *Config) error {
+	if cfg.RedisURL == "" {
+		return nil
+	}
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return err
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+	return client.Ping(ctx).Err()
+}
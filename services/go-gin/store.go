@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Processing status recorded for each stored interaction
+const (
+	statusReceived  = "received"
+	statusPublished = "published"
+	statusFailed    = "failed"
+)
+
+// storeWriteTimeout bounds the status update, which runs after the publish
+// and may no longer have any of the request deadline left
+const storeWriteTimeout = time.Second
+
+// store records accepted interactions in Firestore. Nil unless
+// INTERACTION_STORE_COLLECTION is set.
+var store *interactionStore
+
+// StoredInteraction is the Firestore document kept per accepted interaction
+type StoredInteraction struct {
+	InteractionID   string    `firestore:"interaction_id"`
+	InteractionType int       `firestore:"interaction_type"`
+	Command         string    `firestore:"command,omitempty"`
+	CustomID        string    `firestore:"custom_id,omitempty"`
+	GuildID         string    `firestore:"guild_id"`
+	ChannelID       string    `firestore:"channel_id"`
+	UserID          string    `firestore:"user_id"`
+	ReceivedAt      time.Time `firestore:"received_at"`
+	Status          string    `firestore:"status"`
+	Error           string    `firestore:"error,omitempty"`
+	UpdatedAt       time.Time `firestore:"updated_at"`
+	ServiceVersion  string    `firestore:"service_version"`
+
+	// Firestore TTL policies delete documents once this time has passed
+	ExpireAt time.Time `firestore:"expire_at"`
+}
+
+// interactionStore keeps one document per interaction, keyed by interaction
+// ID. Creating the document is the dedup check: only the first instance to
+// accept an interaction publishes it. The documents double as an audit
+// trail operators can query in the console.
+type interactionStore struct {
+	client     *firestore.Client
+	collection *firestore.CollectionRef
+	ttl        time.Duration
+}
+
+func newInteractionStore(ctx context.Context, projectID, database, collection string, ttl time.Duration) (*interactionStore, error) {
+	client, err := firestore.NewClientWithDatabase(ctx, projectID, database)
+	if err != nil {
+		return nil, err
+	}
+	return &interactionStore{client: client, collection: client.Collection(collection), ttl: ttl}, nil
+}
+
+// Claim records the interaction as received. It returns false when another
+// request (on this or any instance) already recorded it. Store errors fail
+// open: the interaction is published rather than dropped.
+func (s *interactionStore) Claim(ctx context.Context, interaction *Interaction) bool {
+	now := time.Now().UTC()
+	doc := StoredInteraction{
+		InteractionID:   interaction.ID,
+		InteractionType: interaction.Type,
+		Command:         interaction.CommandPath(),
+		CustomID:        interaction.CustomID(),
+		GuildID:         interaction.GuildID,
+		ChannelID:       interaction.ChannelID,
+		UserID:          interaction.UserID(),
+		ReceivedAt:      now,
+		Status:          statusReceived,
+		UpdatedAt:       now,
+		ServiceVersion:  serviceVersion(),
+		ExpireAt:        now.Add(s.ttl),
+	}
+	_, err := s.collection.Doc(interaction.ID).Create(ctx, doc)
+	switch {
+	case status.Code(err) == codes.AlreadyExists:
+		storeClaimsTotal.WithLabelValues("duplicate").Inc()
+		loggerFrom(ctx).Warn("Duplicate interaction not published", "interaction_id", interaction.ID)
+		return false
+	case err != nil:
+		storeClaimsTotal.WithLabelValues("error").Inc()
+		loggerFrom(ctx).Error("Failed to record interaction", "interaction_id", interaction.ID, "error", err)
+		return true
+	default:
+		storeClaimsTotal.WithLabelValues("claimed").Inc()
+		return true
+	}
+}
+
+// Finish records the outcome of publishing a claimed interaction
+func (s *interactionStore) Finish(ctx context.Context, interactionID string, publishErr error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), storeWriteTimeout)
+	defer cancel()
+
+	updates := []firestore.Update{
+		{Path: "status", Value: statusPublished},
+		{Path: "updated_at", Value: time.Now().UTC()},
+	}
+	if publishErr != nil {
+		updates[0].Value = statusFailed
+		updates = append(updates, firestore.Update{Path: "error", Value: publishErr.Error()})
+	}
+	if _, err := s.collection.Doc(interactionID).Update(ctx, updates); err != nil {
+		loggerFrom(ctx).Error("Failed to update interaction status", "interaction_id", interactionID, "error", err)
+	}
+}
+
+// checkInteractionStore verifies the collection can be read
+func checkInteractionStore(ctx context.Context, cfg *Config) error {
+	if cfg.InteractionStoreCollection == "" {
+		return nil
+	}
+	s, err := newInteractionStore(ctx, cfg.ProjectID, cfg.FirestoreDatabase, cfg.InteractionStoreCollection, cfg.InteractionStoreTTL)
+	if err != nil {
+		return err
+	}
+	defer s.client.Close()
+	if _, err := s.collection.Limit(1).Documents(ctx).GetAll(); err != nil {
+		return fmt.Errorf("collection %s: %w", cfg.InteractionStoreCollection, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Client certificate verification modes for mutual TLS
+const (
+	// clientAuthRequire rejects connections without a valid client certificate
+	clientAuthRequire = "require"
+	// clientAuthOptional verifies a client certificate only if one is presented
+	clientAuthOptional = "optional"
+)
+
+// modernTLSConfig returns TLS settings limited to forward-secret AEAD cipher
+// suites. TLS 1.3 suites aren't configurable and are all acceptable.
+func modernTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+}
+
+// serverTLSConfig builds the interactions listener's TLS configuration, or
+// returns nil when the listener serves plain HTTP. With autocert, the returned
+// manager must also answer HTTP-01 challenges on the redirect listener.
+func serverTLSConfig(cfg *Config) (*tls.Config, *autocert.Manager, error) {
+	var tlsConfig *tls.Config
+	var manager *autocert.Manager
+
+	switch {
+	case len(cfg.AutocertDomains) > 0:
+		manager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		tlsConfig = modernTLSConfig()
+		tlsConfig.GetCertificate = manager.GetCertificate
+		// acme-tls/1 lets Let's Encrypt validate over the TLS listener itself
+		tlsConfig.NextProtos = []string{"h2", "http/1.1", "acme-tls/1"}
+	case cfg.TLSCertFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		tlsConfig = modernTLSConfig()
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	default:
+		return nil, nil, nil
+	}
+
+	if cfg.TLSClientCAFile == "" {
+		return tlsConfig, manager, nil
+	}
+
+	// Mutual TLS: clients (e.g. an authenticating proxy) present a
+	// certificate signed by one of these CAs
+	pem, err := os.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, nil, fmt.Errorf("no certificates found in %s", cfg.TLSClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	switch cfg.TLSClientAuth {
+	case clientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, manager, nil
+}
+
+// startRedirectServer serves plain HTTP on port, redirecting every request to
+// the HTTPS listener on httpsPort. With autocert it also answers ACME HTTP-01
+// challenges.
+func startRedirectServer(port, httpsPort string, manager *autocert.Manager) {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+	if manager != nil {
+		handler = manager.HTTPHandler(handler)
+	}
+
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		logger.Info("Starting HTTP redirect server", "port", port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("HTTP redirect server failed", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// tokenSealer encrypts interaction tokens for the worker with a sealed box
+// (libsodium crypto_box_seal). Only the holder of the matching private key
+// can open them, so the edge can hand the token off without storing state
+// and without it being readable in Pub/Sub.
+type tokenSealer struct {
+	recipient *[32]byte
+}
+
+// sealer is nil unless TOKEN_SEAL_PUBLIC_KEY is set; without it the token is dropped rather than sealed into sealed_token.
+var sealer *tokenSealer
+
+// parseSealKey decodes a hex or base64 X25519 public key
+func parseSealKey(s string) (*[32]byte, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		if raw, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("key is neither hex nor base64")
+		}
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(raw))
+	}
+	var key [32]byte
+	copy(key[:], raw)
+	return &key, nil
+}
+
+// Seal returns the base64 sealed box of token
+func (s *tokenSealer) Seal(token string) (string, error) {
+	sealed, err := box.SealAnonymous(nil, []byte(token), s.recipient, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/storage"
+)
+
+// validationCheck is one step of --validate-config
+type validationCheck struct {
+	name string
+	run  func(ctx context.Context, cfg *Config) error
+}
+
+// validateConfig loads and checks the configuration without serving traffic,
+// writing a report to w. It returns the process exit code: 0 when every
+// check passed, 1 otherwise. Used as a deploy-time gate before traffic is
+// shifted to a new revision.
+func validateConfig(w io.Writer) int {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(w, "FAIL config: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(w, "ok   config (public key %d bytes)\n", len(cfg.PublicKey))
+
+	checks := []validationCheck{
+		{"immediate handlers", func(_ context.Context, cfg *Config) error {
+			if _, err := enabledImmediateHandlers(cfg.ImmediateCommands); err != nil {
+				return err
+			}
+			_, err := enabledComponentHandlers(cfg.ImmediateComponents)
+			return err
+		}},
+		{"message catalog", func(_ context.Context, cfg *Config) error {
+			_, err := loadMessageCatalog(cfg.DefaultLocale)
+			return err
+		}},
+		{"help catalog", func(_ context.Context, cfg *Config) error {
+			return loadHelpCatalog(cfg.HelpCatalogFile)
+		}},
+		{"tls", func(_ context.Context, cfg *Config) error {
+			_, _, err := serverTLSConfig(cfg)
+			return err
+		}},
+		{"pubsub topics", checkTopics},
+		{"attachment bucket", checkAttachmentBucket},
+		{"payload capture bucket", checkCaptureBucket},
+		{"cloud tasks queue", checkTaskQueue},
+		{"grpc egress", checkEgress},
+		{"bigquery archive table", checkArchiveTable},
+		{"interaction store", checkInteractionStore},
+		{"redis", checkRedis},
+		{"feature flags", checkFeatureFlags},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	code := 0
+	for _, check := range checks {
+		if err := check.run(ctx, cfg); err != nil {
+			fmt.Fprintf(w, "FAIL %s: %v\n", check.name, err)
+			code = 1
+			continue
+		}
+		fmt.Fprintf(w, "ok   %s\n", check.name)
+	}
+	return code
+}
+
+// checkTopics verifies the configured topics exist and are reachable. Unlike
+// startup, validation doesn't create missing topics.
+func checkTopics(ctx context.Context, cfg *Config) error {
+	var names []string
+	for _, name := range []string{cfg.PubSubTopic, cfg.AuditPubSubTopic, cfg.ShadowPubSubTopic} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if cfg.ProjectID == "" {
+		return fmt.Errorf("GOOGLE_CLOUD_PROJECT is required to publish to %v", names)
+	}
+
+	client, err := pubsub.NewClient(ctx, cfg.ProjectID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if cfg.FailoverPubSubTopic != "" {
+		names = append(names, cfg.FailoverPubSubTopic)
+	}
+	for _, name := range names {
+		project, topic, err := parseTopicName(name, cfg.ProjectID)
+		if err != nil {
+			return err
+		}
+		exists, err := client.TopicInProject(topic, project).Exists(ctx)
+		if err != nil {
+			return fmt.Errorf("topic %s: %w", name, err)
+		}
+		if !exists {
+			return fmt.Errorf("topic %s does not exist in project %s", topic, project)
+		}
+	}
+	return nil
+}
+
+// checkAttachmentBucket verifies the attachment bucket is reachable
+func checkAttachmentBucket(ctx context.Context, cfg *Config) error {
+	if cfg.AttachmentBucket == "" {
+		return nil
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	_, err = client.Bucket(cfg.AttachmentBucket).Attrs(ctx)
+	return err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Build information, set at build time via:
+//
+//	go build -ldflags "-X main.version=1.2.3 -X main.commit=abc123 -X main.buildTime=2026-01-01T00:00:00Z"
+var (
+	version   = "dev"
+	commit    = ""
+	buildTime = ""
+)
+
+// BuildInfo describes the running build
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	JSONCodec string `json:"json_codec"`
+}
+
+// buildInfo returns the build information, falling back to the VCS metadata
+// the Go toolchain embeds when the ldflags weren't provided. It's read once:
+// debug.ReadBuildInfo is too slow to call for every published message.
+var buildInfo = sync.OnceValue(func() BuildInfo {
+	info := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		JSONCodec: jsonCodec,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = s.Value
+				}
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+})
+
+// serviceVersion is the compact version string used in logs and attributes
+var serviceVersion = sync.OnceValue(func() string {
+	info := buildInfo()
+	if info.Commit == "unknown" {
+		return info.Version
+	}
+	short := info.Commit
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	return info.Version + "+" + short
+})
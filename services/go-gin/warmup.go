@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// processStart approximates when the process started: package variables are
+// initialized before main, after little more than the runtime's own setup
+var processStart = time.Now()
+
+// Startup milestones, in the order they are reached
+const (
+	milestoneConfigLoaded     = "config_loaded"
+	milestonePubSubReady      = "pubsub_ready"
+	milestoneSignatureWarm    = "signature_warm"
+	milestoneListening        = "listening"
+	milestoneFirstInteraction = "first_interaction"
+)
+
+// startupMilestone logs how long after process start a step of startup
+// finished and exports it as discord_startup_milestone_seconds, so the cost
+// of a scale-from-zero start can be broken down from the logs or a dashboard
+func startupMilestone(name string) {
+	elapsed := time.Since(processStart)
+	startupMilestoneSeconds.WithLabelValues(name).Set(elapsed.Seconds())
+	logger.Info("Startup milestone", "milestone", name, "elapsed_ms", elapsed.Milliseconds())
+}
+
+// firstInteraction marks the first interaction request an instance receives
+var firstInteraction sync.Once
+
+// warmupSeed derives the key warmSignature signs with. Any key works: the
+// point is to run the same code as a real verification.
+var warmupSeed = make([]byte, ed25519.SeedSize)
+
+// warmSignature verifies a canned signed ping. The first ed25519
+// verification in a process builds the curve's precomputed tables, which
+// would otherwise be paid for by the first interaction after a cold start.
+func warmSignature() {
+	key := ed25519.NewKeyFromSeed(warmupSeed)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	message := append([]byte(timestamp), `{"type":1,"id":"warmup","application_id":"warmup"}`...)
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), message, ed25519.Sign(key, message)) {
+		logger.Warn("Warmup signature did not verify")
+	}
+}
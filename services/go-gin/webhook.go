@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// Headers on webhook deliveries. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with WEBHOOK_SECRET.
+const (
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+)
+
+// attributeHeaderPrefix carries message attributes on HTTP deliveries,
+// e.g. interaction_id as X-Attribute-Interaction-Id
+const attributeHeaderPrefix = "X-Attribute-"
+
+// webhookRetryBackoff is the delay before the first retry; it doubles after each attempt
+const webhookRetryBackoff = 100 * time.Millisecond
+
+// webhook forwards interactions over HTTPS instead of Pub/Sub. Nil unless
+// PUBLISH_BACKEND is "webhook".
+var webhook *webhookForwarder
+
+// webhookForwarder POSTs sanitized interactions to an HTTPS endpoint, for
+// downstream systems that aren't on a message bus
+type webhookForwarder struct {
+	url         string
+	secret      []byte
+	maxAttempts int
+	client      *http.Client
+}
+
+func newWebhookForwarder(rawURL, secret string, maxAttempts int, timeout time.Duration) *webhookForwarder {
+	return &webhookForwarder{
+		url:         rawURL,
+		secret:      []byte(secret),
+		maxAttempts: maxAttempts,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// webhookStatusError is a delivery rejected by the endpoint
+type webhookStatusError struct {
+	status int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook returned status %d", e.status)
+}
+
+// retryable reports whether the endpoint may accept the same delivery later
+func (e *webhookStatusError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= http.StatusInternalServerError
+}
+
+// parseWebhookURL requires HTTPS, except for loopback hosts used in local development
+func parseWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+		return errors.New("plain http is only allowed for localhost")
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+}
+
+// attributeHeaders maps message attributes to HTTP headers. CloudEvents
+// attributes keep their names, as the CloudEvents HTTP binding expects, and
+// so does the W3C trace context, so the receiver's tracing picks it up.
+func attributeHeaders(attrs map[string]string) map[string]string {
+	headers := make(map[string]string, len(attrs))
+	for name, value := range attrs {
+		if isCloudEventAttribute(name) || name == "traceparent" || name == "tracestate" {
+			headers[http.CanonicalHeaderKey(name)] = value
+			continue
+		}
+		headers[http.CanonicalHeaderKey(attributeHeaderPrefix+strings.ReplaceAll(name, "_", "-"))] = value
+	}
+	return headers
+}
+
+// sign returns the delivery signature for body sent at timestamp
+func (w *webhookForwarder) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, w.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver POSTs msg to the endpoint, retrying network errors, 429s and 5xx
+// responses with exponential backoff until the attempts or ctx run out.
+// Every attempt carries the same timestamp and signature so the receiver
+// can deduplicate on interaction_id.
+func (w *webhookForwarder) Deliver(ctx context.Context, msg *pubsub.Message) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := w.sign(timestamp, msg.Data)
+
+	backoff := webhookRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.post(ctx, msg, timestamp, signature)
+		var statusErr *webhookStatusError
+		if err == nil || attempt >= w.maxAttempts || (errors.As(err, &statusErr) && !statusErr.retryable()) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (after %d attempts, last error: %v)", ctx.Err(), attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a single delivery attempt
+func (w *webhookForwarder) post(ctx context.Context, msg *pubsub.Message, timestamp, signature string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(msg.Data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+signature)
+	for name, value := range attributeHeaders(msg.Attributes) {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &webhookStatusError{status: resp.StatusCode}
+	}
+	return nil
+}
+
+// forwardToWebhook delivers msg through the circuit breaker
+func forwardToWebhook(ctx context.Context, msg *pubsub.Message, interaction *Interaction) error {
+	log := loggerFrom(ctx)
+
+	err := publishBreaker.Execute(func() error {
+		return webhook.Deliver(ctx, msg)
+	})
+	switch {
+	case errors.Is(err, errBreakerOpen):
+		webhookDeliveriesTotal.WithLabelValues("rejected").Inc()
+		log.Warn("Webhook circuit breaker open", "interaction_id", interaction.ID)
+	case errors.Is(err, context.DeadlineExceeded):
+		webhookDeliveriesTotal.WithLabelValues("timeout").Inc()
+		log.Warn("Webhook delivery did not complete before its deadline", "interaction_id", interaction.ID, "error", err)
+	case err != nil:
+		webhookDeliveriesTotal.WithLabelValues("error").Inc()
+		log.Error("Failed to deliver interaction to webhook", "interaction_id", interaction.ID, "error", err)
+		errorReporter.Report(fmt.Errorf("webhook delivery failed: %w", err), nil, interactionFields(interaction))
+	default:
+		webhookDeliveriesTotal.WithLabelValues("success").Inc()
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// maxFiles is how many files Discord accepts on one message
+const maxFiles = 10
+
+// attachmentHosts are the Discord CDN hosts files are downloaded from, as on
+// the edge. Anything else is refused so a crafted URL can't make the worker
+// fetch arbitrary (e.g. metadata server) addresses.
+var attachmentHosts = map[string]bool{
+	"cdn.discordapp.com":   true,
+	"media.discordapp.net": true,
+}
+
+// Attachment is a file the user attached to the command. URL is a
+// gs://bucket/object reference when the edge offloaded it (ATTACHMENT_BUCKET
+// on the edge), otherwise the Discord CDN URL.
+type Attachment = payload.Attachment
+
+// AttachmentOption returns the attachment supplied for an attachment option
+func (i Interaction) AttachmentOption(name string) (Attachment, bool) {
+	id, ok := i.Options().ID(name)
+	if !ok {
+		return Attachment{}, false
+	}
+	a, ok := i.Resolved().Attachments[id]
+	return a, ok
+}
+
+// File is a file uploaded with a response. Set Data, or Source to have the
+// worker fetch it: a gs:// reference in ATTACHMENT_BUCKET or a Discord CDN
+// URL, such as an Attachment's URL.
+type File struct {
+	Name        string
+	Description string
+	ContentType string
+	Data        []byte
+	Source      string
+}
+
+// attachments fetches File sources. It is always set; gs:// references
+// need ATTACHMENT_BUCKET.
+var attachments *attachmentFetcher
+
+// attachmentFetcher downloads the files responses reference
+type attachmentFetcher struct {
+	storage  *storage.Client // nil unless ATTACHMENT_BUCKET is set
+	bucket   string
+	client   *http.Client
+	maxBytes int64
+}
+
+func newAttachmentFetcher(ctx context.Context, bucket string, maxBytes int64) (*attachmentFetcher, error) {
+	f := &attachmentFetcher{
+		bucket:   bucket,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		maxBytes: maxBytes,
+	}
+	if bucket != "" {
+		var err error
+		if f.storage, err = storage.NewClient(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Load fetches the Source of every file in resp and its follow-ups
+func (f *attachmentFetcher) Load(ctx context.Context, resp *Response) error {
+	for i := range resp.Files {
+		file := &resp.Files[i]
+		if file.Source == "" {
+			if int64(len(file.Data)) > f.maxBytes {
+				return fmt.Errorf("file %q exceeds ATTACHMENT_MAX_BYTES (%d)", file.Name, f.maxBytes)
+			}
+			continue
+		}
+		data, contentType, err := f.fetch(ctx, file.Source)
+		if err != nil {
+			return fmt.Errorf("file %q: %w", file.Name, err)
+		}
+		file.Data = data
+		if file.ContentType == "" {
+			file.ContentType = contentType
+		}
+	}
+	for i := range resp.FollowUps {
+		if err := f.Load(ctx, &resp.FollowUps[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch downloads one gs:// reference or CDN URL, up to maxBytes
+func (f *attachmentFetcher) fetch(ctx context.Context, source string) ([]byte, string, error) {
+	if rest, ok := strings.CutPrefix(source, "gs://"); ok {
+		bucket, object, _ := strings.Cut(rest, "/")
+		if f.storage == nil || bucket != f.bucket || object == "" {
+			return nil, "", fmt.Errorf("refusing to read %q: not in ATTACHMENT_BUCKET", "gs://"+bucket)
+		}
+		r, err := f.storage.Bucket(bucket).Object(object).NewReader(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		defer r.Close()
+		data, err := f.read(r)
+		return data, r.Attrs.ContentType, err
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "https" || !attachmentHosts[u.Hostname()] {
+		return nil, "", errors.New("refusing to download from a host other than Discord's CDN")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// CDN URLs expire; that's what the edge's offload is for
+		return nil, "", fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	data, err := f.read(resp.Body)
+	return data, resp.Header.Get("Content-Type"), err
+}
+
+// read reads one byte past the limit so an oversize file is detected rather
+// than truncated
+func (f *attachmentFetcher) read(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, f.maxBytes+1))
+	if err == nil && int64(len(data)) > f.maxBytes {
+		err = fmt.Errorf("file exceeds ATTACHMENT_MAX_BYTES (%d)", f.maxBytes)
+	}
+	return data, err
+}
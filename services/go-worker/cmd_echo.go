@@ -0,0 +1,13 @@
+package main
+
+import "context"
+
+// /echo text:<string> replies with the text. Mentions in it stay
+// suppressed, so echoing "@everyone" pings no one.
+func init() {
+	register("echo", HandlerFunc(echo))
+}
+
+func echo(_ context.Context, interaction Interaction) (Response, error) {
+	return Response{Content: interaction.Options().StringOr("text", interaction.Localize(msgEchoEmpty))}, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// Ways messages are delivered to the worker
+const (
+	deliveryPull = "pull"
+	deliveryPush = "push"
+)
+
+// Config holds the worker configuration loaded from the environment
+type Config struct {
+	// Health listener port (no listener when empty)
+	Port string
+
+	// How messages arrive: pulled from Subscription, or pushed to POST /
+	// on Port by a push subscription or Eventarc trigger. Subscription is
+	// optional for push and only labels spans.
+	Delivery       string
+	ProjectID      string
+	Subscription   string
+	MaxConcurrency int
+
+	// How long in-flight messages may run after SIGTERM before they are
+	// cut off and returned to the subscription
+	DrainTimeout time.Duration
+
+	// Deliveries before a failing message is dead-lettered, and the topic
+	// it goes to (logged and dropped when empty)
+	MaxDeliveryAttempts int
+	DeadLetterTopic     string
+
+	// Topic every delivery's outcome is published to (off when empty)
+	ResultsTopic string
+
+	// Where handled interaction IDs are recorded so duplicate deliveries
+	// aren't answered twice: memory, redis, firestore, or none. Claims last
+	// IdempotencyLease while a delivery runs; handled IDs IdempotencyTTL.
+	IdempotencyBackend    string
+	IdempotencyTTL        time.Duration
+	IdempotencyLease      time.Duration
+	RedisURL              string
+	IdempotencyCollection string
+	FirestoreDatabase     string
+
+	// Bucket gs:// file references may be read from (the edge's
+	// ATTACHMENT_BUCKET), and the largest file uploaded with a response
+	AttachmentBucket   string
+	AttachmentMaxBytes int64
+
+	// Locale whose message catalog is the final fallback
+	DefaultLocale string
+
+	// X25519 private key opening sealed interaction tokens (hex or base64)
+	TokenSealPrivateKey string
+
+	// Discord REST API base URL, per-request timeout, and how many times a
+	// rate limited (429) request is retried
+	DiscordAPIURL           string
+	DiscordAPITimeout       time.Duration
+	DiscordRateLimitRetries int
+
+	// How deeply objects and arrays may nest in a message's interaction
+	JSONMaxDepth int
+}
+
+func loadConfig() (*Config, error) {
+	cfg := &Config{
+		Port:                os.Getenv("PORT"),
+		ProjectID:           os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		Subscription:        os.Getenv("PUBSUB_SUBSCRIPTION"),
+		TokenSealPrivateKey: os.Getenv("TOKEN_SEAL_PRIVATE_KEY"),
+		DiscordAPIURL:       envString("DISCORD_API_URL", "https://discord.com/api/v10"),
+		DefaultLocale:       envString("DEFAULT_LOCALE", "en-US"),
+	}
+	var err error
+	switch cfg.Delivery = envString("DELIVERY", deliveryPull); cfg.Delivery {
+	case deliveryPull:
+		if cfg.Subscription == "" {
+			return nil, errors.New("PUBSUB_SUBSCRIPTION is required with DELIVERY=pull")
+		}
+	case deliveryPush:
+		if cfg.Port == "" {
+			return nil, errors.New("PORT is required with DELIVERY=push")
+		}
+	default:
+		return nil, fmt.Errorf("DELIVERY must be pull or push, not %q", cfg.Delivery)
+	}
+	if cfg.ProjectID == "" {
+		return nil, errors.New("GOOGLE_CLOUD_PROJECT is required")
+	}
+	if cfg.TokenSealPrivateKey == "" {
+		return nil, errors.New("TOKEN_SEAL_PRIVATE_KEY is required to respond to interactions")
+	}
+	if cfg.MaxConcurrency, err = envInt("MAX_CONCURRENCY", 10); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrency < 1 {
+		return nil, errors.New("MAX_CONCURRENCY must be positive")
+	}
+	if cfg.DrainTimeout, err = envDuration("DRAIN_TIMEOUT", 8*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.DrainTimeout <= 0 {
+		return nil, errors.New("DRAIN_TIMEOUT must be positive")
+	}
+	cfg.DeadLetterTopic = os.Getenv("DEAD_LETTER_TOPIC")
+	cfg.ResultsTopic = os.Getenv("RESULTS_TOPIC")
+	if cfg.MaxDeliveryAttempts, err = envInt("MAX_DELIVERY_ATTEMPTS", 5); err != nil {
+		return nil, err
+	}
+	if cfg.MaxDeliveryAttempts < 1 {
+		return nil, errors.New("MAX_DELIVERY_ATTEMPTS must be positive")
+	}
+	cfg.IdempotencyBackend = envString("IDEMPOTENCY_BACKEND", idempotencyMemory)
+	cfg.RedisURL = os.Getenv("REDIS_URL")
+	cfg.IdempotencyCollection = envString("IDEMPOTENCY_COLLECTION", "worker-idempotency")
+	cfg.FirestoreDatabase = envString("FIRESTORE_DATABASE", "(default)")
+	if cfg.IdempotencyTTL, err = envDuration("IDEMPOTENCY_TTL", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.IdempotencyLease, err = envDuration("IDEMPOTENCY_LEASE", 2*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.IdempotencyTTL <= 0 || cfg.IdempotencyLease <= 0 {
+		return nil, errors.New("IDEMPOTENCY_TTL and IDEMPOTENCY_LEASE must be positive")
+	}
+	switch cfg.IdempotencyBackend {
+	case idempotencyMemory, idempotencyFirestore, idempotencyNone:
+	case idempotencyRedis:
+		if cfg.RedisURL == "" {
+			return nil, errors.New("IDEMPOTENCY_BACKEND=redis requires REDIS_URL")
+		}
+	default:
+		return nil, fmt.Errorf("IDEMPOTENCY_BACKEND must be memory, redis, firestore, or none, not %q", cfg.IdempotencyBackend)
+	}
+	cfg.AttachmentBucket = os.Getenv("ATTACHMENT_BUCKET")
+	maxAttachment, err := envInt("ATTACHMENT_MAX_BYTES", 10<<20)
+	if err != nil {
+		return nil, err
+	}
+	if maxAttachment <= 0 {
+		return nil, errors.New("ATTACHMENT_MAX_BYTES must be positive")
+	}
+	cfg.AttachmentMaxBytes = int64(maxAttachment)
+	if cfg.DiscordAPITimeout, err = envDuration("DISCORD_API_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.DiscordRateLimitRetries, err = envInt("DISCORD_RATE_LIMIT_RETRIES", 3); err != nil {
+		return nil, err
+	}
+	if cfg.DiscordRateLimitRetries < 0 {
+		return nil, errors.New("DISCORD_RATE_LIMIT_RETRIES must not be negative")
+	}
+	if cfg.JSONMaxDepth, err = envInt("JSON_MAX_DEPTH", payload.DefaultMaxDepth); err != nil {
+		return nil, err
+	}
+	if cfg.JSONMaxDepth < 1 {
+		return nil, errors.New("JSON_MAX_DEPTH must be positive")
+	}
+	return cfg, nil
+}
+
+// envString returns the value of an environment variable or a default
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envInt parses an integer environment variable, returning def when unset
+func envInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// envDuration parses a duration environment variable (e.g. "30s"), returning def when unset
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cloud.google.com/go/pubsub"
+	pubsubapi "cloud.google.com/go/pubsub/apiv1"
+	"cloud.google.com/go/pubsub/apiv1/pubsubpb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// Attributes added to dead-lettered messages. "worker reprocess" strips
+// them before republishing.
+const (
+	deadLetterAttributePrefix = "dead_letter_"
+
+	attrDeadLetterReason       = "dead_letter_reason"
+	attrDeadLetterError        = "dead_letter_error"
+	attrDeadLetterAttempts     = "dead_letter_attempts"
+	attrDeadLetterSubscription = "dead_letter_subscription"
+	attrDeadLetterMessageID    = "dead_letter_message_id"
+	attrDeadLetterTime         = "dead_letter_time"
+)
+
+// maxAttributeValueBytes is Pub/Sub's limit on an attribute value
+const maxAttributeValueBytes = 1024
+
+// Reprocess pulls this many messages at a time, and holds skipped ones for
+// up to Pub/Sub's maximum ack deadline
+const (
+	reprocessBatchSize    = 100
+	maxAckDeadlineSeconds = 600
+)
+
+// deadLetterer routes messages the worker gave up on to a dead-letter topic,
+// unchanged apart from attributes describing the failure
+type deadLetterer struct {
+	topic        *pubsub.Topic
+	subscription string
+}
+
+func newDeadLetterer(client *pubsub.Client, topic, subscription string) *deadLetterer {
+	return &deadLetterer{topic: client.Topic(topic), subscription: subscription}
+}
+
+// Publish sends msg to the dead-letter topic and waits for the result
+func (d *deadLetterer) Publish(ctx context.Context, msg *pubsub.Message, failure *processError, attempts int) error {
+	attrs := make(map[string]string, len(msg.Attributes)+6)
+	for k, v := range msg.Attributes {
+		attrs[k] = v
+	}
+	attrs[attrDeadLetterReason] = failure.reason
+	attrs[attrDeadLetterError] = truncate(failure.err.Error(), maxAttributeValueBytes)
+	attrs[attrDeadLetterAttempts] = strconv.Itoa(attempts)
+	attrs[attrDeadLetterSubscription] = d.subscription
+	attrs[attrDeadLetterMessageID] = msg.ID
+	attrs[attrDeadLetterTime] = time.Now().UTC().Format(time.RFC3339)
+
+	_, err := d.topic.Publish(ctx, &pubsub.Message{Data: msg.Data, Attributes: attrs}).Get(ctx)
+	return err
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// reprocess implements "worker reprocess": it pulls messages from a
+// subscription to the dead-letter topic and republishes them, without the
+// dead-letter attributes, to the topic the worker consumes. Messages it
+// skips are returned to the subscription when it finishes. It returns the
+// exit code.
+func reprocess(args []string) int {
+	fs := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	subscription := fs.String("subscription", "", "subscription to the dead-letter topic (required)")
+	topic := fs.String("topic", "", "topic to republish to, normally the edge's PUBSUB_TOPIC (required)")
+	reason := fs.String("reason", "", "only republish messages dead-lettered for this reason")
+	limit := fs.Int("limit", 0, "stop after this many messages (0: no limit)")
+	dryRun := fs.Bool("dry-run", false, "list the messages without republishing or acking them")
+	idle := fs.Duration("idle-timeout", 10*time.Second, "stop once no message arrives for this long")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	project := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if *subscription == "" || *topic == "" || project == "" {
+		fmt.Fprintln(os.Stderr, "reprocess: -subscription, -topic and GOOGLE_CLOUD_PROJECT are required")
+		return 2
+	}
+
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, project)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "reprocess:", err)
+		return 1
+	}
+	defer client.Close()
+	target := client.Topic(*topic)
+	defer target.Stop()
+
+	// Synchronous pull, so skipped messages can be held until the end and
+	// each message is looked at once
+	subc, err := pubsubapi.NewSubscriberClient(ctx, emulatorOptions()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "reprocess:", err)
+		return 1
+	}
+	defer subc.Close()
+	subName := *subscription
+	if !strings.HasPrefix(subName, "projects/") {
+		subName = "projects/" + project + "/subscriptions/" + subName
+	}
+
+	var republished, fails int
+	var held []string
+	defer func() {
+		// Release skipped messages for redelivery
+		for len(held) > 0 {
+			n := min(len(held), reprocessBatchSize)
+			_ = subc.ModifyAckDeadline(ctx, &pubsubpb.ModifyAckDeadlineRequest{
+				Subscription: subName, AckIds: held[:n], AckDeadlineSeconds: 0,
+			})
+			held = held[n:]
+		}
+	}()
+
+	for *limit == 0 || republished < *limit {
+		pullCtx, cancel := context.WithTimeout(ctx, *idle)
+		resp, err := subc.Pull(pullCtx, &pubsubpb.PullRequest{Subscription: subName, MaxMessages: reprocessBatchSize})
+		cancel()
+		if status.Code(err) == codes.DeadlineExceeded || (err == nil && len(resp.ReceivedMessages) == 0) {
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "reprocess:", err)
+			return 1
+		}
+		var acks []string
+		for _, rm := range resp.ReceivedMessages {
+			msg := rm.Message
+			if (*reason != "" && msg.Attributes[attrDeadLetterReason] != *reason) ||
+				(*limit > 0 && republished >= *limit) {
+				held = append(held, rm.AckId)
+				continue
+			}
+			fmt.Printf("%s interaction=%s reason=%s attempts=%s error=%q\n",
+				msg.Attributes[attrDeadLetterMessageID], msg.Attributes["interaction_id"],
+				msg.Attributes[attrDeadLetterReason], msg.Attributes[attrDeadLetterAttempts],
+				msg.Attributes[attrDeadLetterError])
+			if *dryRun {
+				held = append(held, rm.AckId)
+				republished++
+				continue
+			}
+			attrs := make(map[string]string, len(msg.Attributes))
+			for k, v := range msg.Attributes {
+				if !strings.HasPrefix(k, deadLetterAttributePrefix) {
+					attrs[k] = v
+				}
+			}
+			if _, err := target.Publish(ctx, &pubsub.Message{Data: msg.Data, Attributes: attrs}).Get(ctx); err != nil {
+				fmt.Fprintln(os.Stderr, "reprocess: republish failed:", err)
+				fails++
+				held = append(held, rm.AckId)
+				continue
+			}
+			republished++
+			acks = append(acks, rm.AckId)
+		}
+		if len(acks) > 0 {
+			if err := subc.Acknowledge(ctx, &pubsubpb.AcknowledgeRequest{Subscription: subName, AckIds: acks}); err != nil {
+				// Republished but still in the dead-letter topic; a later
+				// run would republish them again
+				fmt.Fprintln(os.Stderr, "reprocess: ack failed:", err)
+				return 1
+			}
+		}
+		if len(held) > 0 {
+			// Keep held messages from being redelivered to this run
+			_ = subc.ModifyAckDeadline(ctx, &pubsubpb.ModifyAckDeadlineRequest{
+				Subscription: subName, AckIds: held, AckDeadlineSeconds: maxAckDeadlineSeconds,
+			})
+		}
+	}
+
+	verb := "republished"
+	if *dryRun {
+		verb = "would republish"
+	}
+	fmt.Printf("%s %d messages, %d failed\n", verb, republished, fails)
+	if fails > 0 {
+		return 1
+	}
+	return 0
+}
+
+// emulatorOptions connects API clients to PUBSUB_EMULATOR_HOST when set,
+// like pubsub.NewClient does
+func emulatorOptions() []option.ClientOption {
+	addr := os.Getenv("PUBSUB_EMULATOR_HOST")
+	if addr == "" {
+		return nil
+	}
+	return []option.ClientOption{
+		option.WithEndpoint(addr),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		option.WithoutAuthentication(),
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exact", 5, "exact"},
+		{"truncated", 5, "trunc"},
+		{"", 0, ""},
+		{"abc", 0, ""},
+		// "é" is two bytes and "€" three; neither is split
+		{"café", 4, "caf"},
+		{"café", 5, "café"},
+		{"€€", 4, "€"},
+		{"€€", 2, ""},
+	} {
+		if got := truncate(tc.s, tc.n); got != tc.want {
+			t.Errorf("truncate(%q, %d): expected %q, got %q", tc.s, tc.n, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// discord is the Discord REST API client responses are sent with
+var discord *discordClient
+
+// discordClient calls the interaction webhook endpoints. Interaction
+// webhooks are authenticated by the token in the path, so no bot token is
+// needed.
+type discordClient struct {
+	baseURL    string
+	client     *http.Client
+	limits     *rateLimits
+	maxRetries int
+}
+
+func newDiscordClient(baseURL string, timeout time.Duration, maxRetries int) *discordClient {
+	return &discordClient{
+		baseURL:    baseURL,
+		client:     &http.Client{Timeout: timeout},
+		limits:     newRateLimits(),
+		maxRetries: maxRetries,
+	}
+}
+
+// discordAPIError is a request Discord rejected
+type discordAPIError struct {
+	Status int
+	Body   string
+}
+
+func (e *discordAPIError) Error() string {
+	return fmt.Sprintf("discord API returned %d: %s", e.Status, e.Body)
+}
+
+// EditOriginal replaces the deferred response's placeholder with resp
+func (d *discordClient) EditOriginal(ctx context.Context, applicationID, token string, resp Response) error {
+	return d.do(ctx, "edit_original", http.MethodPatch, webhookPath(applicationID, token)+"/messages/@original", withoutMentions(resp))
+}
+
+// CreateFollowup sends resp as a new message in the interaction's channel
+func (d *discordClient) CreateFollowup(ctx context.Context, applicationID, token string, resp Response) error {
+	return d.do(ctx, "create_followup", http.MethodPost, webhookPath(applicationID, token), withoutMentions(resp))
+}
+
+// withoutMentions stops resp's mentions notifying anyone its handler didn't
+// allow. Parse is always sent, as [] rather than null when it lists nothing.
+func withoutMentions(resp Response) Response {
+	m := AllowedMentions{}
+	if resp.AllowedMentions != nil {
+		m = *resp.AllowedMentions
+	}
+	if m.Parse == nil {
+		m.Parse = []string{}
+	}
+	resp.AllowedMentions = &m
+	return resp
+}
+
+func webhookPath(applicationID, token string) string {
+	return "/webhooks/" + url.PathEscape(applicationID) + "/" + url.PathEscape(token)
+}
+
+// messageAttachment describes an uploaded file in payload_json; ID is the
+// index of its files[n] part
+type messageAttachment struct {
+	ID          int    `json:"id"`
+	Filename    string `json:"filename"`
+	Description string `json:"description,omitempty"`
+}
+
+// encodeMessage encodes resp as JSON, or as multipart/form-data with the
+// message in payload_json and each file in a files[n] part when it has files.
+// File data must already be loaded.
+func encodeMessage(resp Response) ([]byte, string, error) {
+	if len(resp.Files) == 0 {
+		data, err := json.Marshal(resp)
+		return data, "application/json", err
+	}
+	payload := struct {
+		Response
+		Attachments []messageAttachment `json:"attachments"`
+	}{Response: resp}
+	for i, f := range resp.Files {
+		payload.Attachments = append(payload.Attachments, messageAttachment{ID: i, Filename: f.Name, Description: f.Description})
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="payload_json"`},
+		"Content-Type":        {"application/json"},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	_, _ = part.Write(payloadJSON)
+	for i, f := range resp.Files {
+		contentType := f.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Disposition": {mime.FormatMediaType("form-data", map[string]string{
+				"name": fmt.Sprintf("files[%d]", i), "filename": f.Name,
+			})},
+			"Content-Type": {contentType},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		_, _ = part.Write(f.Data)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
+
+// do sends a message, waiting out rate limits: it holds requests until
+// their bucket resets, and retries 429s up to maxRetries times. Errors never
+// include the URL, which carries the interaction token. operation names the
+// call in metrics and spans.
+func (d *discordClient) do(ctx context.Context, operation, method, path string, resp Response) error {
+	data, contentType, err := encodeMessage(resp)
+	if err != nil {
+		return err
+	}
+	route := routeKey(method, path)
+	for attempt := 1; ; attempt++ {
+		if err := d.limits.Wait(ctx, route); err != nil {
+			return err
+		}
+		status, msg, err := d.send(ctx, operation, method, path, route, contentType, data)
+		if err != nil || status != http.StatusTooManyRequests {
+			return err
+		}
+		if attempt > d.maxRetries {
+			return &discordAPIError{Status: status, Body: msg}
+		}
+	}
+}
+
+// send makes one request. It returns the status and body of a 429, which
+// the caller retries, and an error for any other failure.
+func (d *discordClient) send(ctx context.Context, operation, method, path, route, contentType string, data []byte) (status int, body string, err error) {
+	// The span never records the URL, which carries the token
+	ctx, span := tracer.Start(ctx, "discord "+operation, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("http.request.method", method)))
+	start := time.Now()
+	defer func() {
+		label := "error"
+		if status != 0 {
+			label = strconv.Itoa(status)
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+		}
+		if err != nil || status == http.StatusTooManyRequests {
+			span.SetStatus(codes.Error, label)
+		}
+		span.End()
+		discordRequestDuration.WithLabelValues(operation, label).Observe(time.Since(start).Seconds())
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, method, d.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, "", errors.New("invalid discord API request")
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return 0, "", fmt.Errorf("%s %s: %w", method, d.baseURL, urlErr.Err)
+		}
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	var msg []byte
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ = io.ReadAll(io.LimitReader(resp.Body, 1024))
+	} else {
+		_, _ = io.Copy(io.Discard, resp.Body)
+	}
+
+	retryAfter := d.limits.Update(route, resp, msg, time.Now())
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		scope := resp.Header.Get(headerRateLimitScope)
+		if scope == "" {
+			scope = "unknown"
+		}
+		discordRateLimitedTotal.WithLabelValues(scope).Inc()
+		loggerFrom(ctx).Warn("Discord rate limited request",
+			"retry_after", retryAfter.String(),
+			"scope", resp.Header.Get(headerRateLimitScope),
+			"global", resp.Header.Get(headerRateLimitGlobal) == "true",
+		)
+		return resp.StatusCode, string(msg), nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return resp.StatusCode, "", &discordAPIError{Status: resp.StatusCode, Body: string(msg)}
+	}
+	return resp.StatusCode, "", nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// Handler runs one bot command. The response replaces the deferred
+// "thinking..." message. An error is logged and answered with a generic
+// failure message; the interaction is not retried.
+type Handler interface {
+	Handle(ctx context.Context, interaction Interaction) (Response, error)
+}
+
+// HandlerFunc adapts a function to Handler
+type HandlerFunc func(ctx context.Context, interaction Interaction) (Response, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, interaction Interaction) (Response, error) {
+	return f(ctx, interaction)
+}
+
+// Response is the message a command responds with. Build one with
+// NewMessage, or check a literal with Validate.
+type Response struct {
+	Content    string              `json:"content"`
+	Embeds     []payload.Embed     `json:"embeds,omitempty"`
+	Components []payload.ActionRow `json:"components,omitempty"`
+
+	// AllowedMentions says which mentions in the message notify anyone.
+	// Unset, none do: the content often repeats what users typed.
+	AllowedMentions *AllowedMentions `json:"allowed_mentions,omitempty"`
+
+	// Files are uploaded with the message
+	Files []File `json:"-"`
+
+	// FollowUps are sent as new messages after the deferred response is
+	// edited. They can't have follow-ups of their own.
+	FollowUps []Response `json:"-"`
+}
+
+// AllowedMentions lists the mentions a message may notify. Parse takes
+// "users", "roles" and "everyone"; Users and Roles allow individual IDs of
+// a kind Parse doesn't list.
+type AllowedMentions struct {
+	Parse       []string `json:"parse"`
+	Users       []string `json:"users,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	RepliedUser bool     `json:"replied_user,omitempty"`
+}
+
+// handlers holds every command. Each command registers itself from its own
+// file's init, so adding a command is a single file.
+var handlers = newRegistry(HandlerFunc(unknownCommand))
+
+// register adds a command handler at init time. name is a top-level command
+// name, or a command path like "config permission set" to handle one
+// subcommand separately.
+func register(name string, h Handler) {
+	handlers.Register(name, h)
+}
+
+// Registry maps command names to handlers
+type Registry struct {
+	handlers map[string]Handler
+	fallback Handler
+}
+
+func newRegistry(fallback Handler) *Registry {
+	return &Registry{handlers: make(map[string]Handler), fallback: fallback}
+}
+
+// Register adds h for name. Registering a name twice is a programming error.
+func (r *Registry) Register(name string, h Handler) {
+	if name == "" || h == nil {
+		panic("register: empty command name or nil handler")
+	}
+	if _, dup := r.handlers[name]; dup {
+		panic(fmt.Sprintf("register: command %q registered twice", name))
+	}
+	r.handlers[name] = h
+}
+
+// Lookup returns the handler for the interaction's command path, then its
+// top-level command, then the fallback
+func (r *Registry) Lookup(interaction Interaction) Handler {
+	_, h := r.Route(interaction)
+	return h
+}
+
+// Route is Lookup that also returns the name the handler was registered
+// under, or "" for the fallback
+func (r *Registry) Route(interaction Interaction) (string, Handler) {
+	for _, name := range []string{interaction.CommandPath(), interaction.CommandName()} {
+		if h, ok := r.handlers[name]; ok {
+			return name, h
+		}
+	}
+	return "", r.fallback
+}
+
+// Names returns the registered command names, sorted
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unknownCommand answers commands registered with Discord but not here, so
+// the user isn't left with a spinner
+func unknownCommand(ctx context.Context, interaction Interaction) (Response, error) {
+	loggerFrom(ctx).Warn("No handler for command", "command", interaction.CommandPath())
+	return Response{Content: interaction.Localize(msgCommandUnavailable)}, nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Message keys for the worker's own responses
+const (
+	msgHandlerFailed      = "handler_failed"
+	msgCommandUnavailable = "command_unavailable"
+	msgEchoEmpty          = "echo_empty"
+)
+
+// localeFiles holds one JSON catalog per Discord locale, named <locale>.json
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogMessage is one message in a catalog: plain text, or text per CLDR
+// plural category ("zero", "one", "two", "few", "many", "other") chosen by
+// the count argument
+type catalogMessage struct {
+	text   string
+	plural map[string]string
+}
+
+func (m *catalogMessage) UnmarshalJSON(raw []byte) error {
+	if err := json.Unmarshal(raw, &m.text); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &m.plural); err != nil {
+		return fmt.Errorf("message must be a string or an object of plural forms")
+	}
+	if m.plural["other"] == "" {
+		return fmt.Errorf("plural forms need \"other\"")
+	}
+	return nil
+}
+
+// messageCatalog maps locale -> message key -> message
+type messageCatalog map[string]map[string]catalogMessage
+
+// messages is the catalog handlers render from; defaultLocale is the final fallback
+var (
+	messages      messageCatalog
+	defaultLocale = "en-US"
+)
+
+// loadMessageCatalog reads every embedded locale and checks that fallback is complete
+func loadMessageCatalog(fallback string) (messageCatalog, error) {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := make(messageCatalog, len(entries))
+	for _, entry := range entries {
+		raw, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var msgs map[string]catalogMessage
+		if err := json.Unmarshal(raw, &msgs); err != nil {
+			return nil, fmt.Errorf("invalid locale %s: %w", entry.Name(), err)
+		}
+		catalog[strings.TrimSuffix(entry.Name(), ".json")] = msgs
+	}
+
+	// Regional locales also answer for their base language, so "es-419" can
+	// fall back to "es-ES". ReadDir is sorted, so the first region wins.
+	for _, entry := range entries {
+		locale := strings.TrimSuffix(entry.Name(), ".json")
+		if lang, _, ok := strings.Cut(locale, "-"); ok {
+			if _, exists := catalog[lang]; !exists {
+				catalog[lang] = catalog[locale]
+			}
+		}
+	}
+
+	base, ok := catalog[fallback]
+	if !ok {
+		return nil, fmt.Errorf("no catalog for default locale %q", fallback)
+	}
+	for _, key := range []string{msgHandlerFailed, msgCommandUnavailable, msgEchoEmpty} {
+		if _, ok := base[key]; !ok {
+			return nil, fmt.Errorf("default locale %q is missing %q", fallback, key)
+		}
+	}
+	return catalog, nil
+}
+
+// Localize renders the message for key in the invoking user's language.
+// args are name/value pairs filling {name} placeholders; a "count" argument
+// also picks the plural form:
+//
+//	interaction.Localize("results_found", "count", n, "query", q)
+//
+// The fallback chain is the user's locale, then its base language (so
+// "es-419" uses "es-ES"), then the same for the guild locale, and finally
+// the default locale. A key missing everywhere renders as the key itself.
+func (i Interaction) Localize(key string, args ...any) string {
+	locale, msg := defaultLocale, messages[defaultLocale][key]
+	for _, candidate := range []string{i.Locale, i.GuildLocale} {
+		if candidate == "" {
+			continue
+		}
+		if found, m, ok := messages.lookup(candidate, key); ok {
+			locale, msg = found, m
+			break
+		}
+	}
+	return msg.render(locale, key, args)
+}
+
+// lookup finds key for an exact locale or, failing that, its base language
+func (m messageCatalog) lookup(locale, key string) (string, catalogMessage, bool) {
+	if msg, ok := m[locale][key]; ok {
+		return locale, msg, true
+	}
+	lang, _, _ := strings.Cut(locale, "-")
+	if msg, ok := m[lang][key]; ok {
+		return lang, msg, true
+	}
+	return "", catalogMessage{}, false
+}
+
+// render picks the plural form for the count argument and fills placeholders
+func (m catalogMessage) render(locale, key string, args []any) string {
+	params := make(map[string]any, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		if name, ok := args[i].(string); ok {
+			params[name] = args[i+1]
+		}
+	}
+
+	text := m.text
+	if m.plural != nil {
+		count, _ := toInt64(params["count"])
+		if text = m.plural[pluralCategory(locale, count)]; text == "" {
+			text = m.plural["other"]
+		}
+	}
+	if text == "" {
+		return key
+	}
+	if len(params) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, v := range params {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// pluralCategory returns the CLDR cardinal plural category of n for the
+// locale's language. Languages without a rule here only use "other".
+func pluralCategory(locale string, n int64) string {
+	lang, _, _ := strings.Cut(locale, "-")
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+	switch lang {
+	case "en", "de", "es", "it", "nl", "sv", "da", "no", "fi", "el", "bg", "hu", "tr":
+		if n == 1 {
+			return "one"
+		}
+	case "fr", "pt":
+		if n <= 1 {
+			return "one"
+		}
+	case "ru", "uk":
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "pl":
+		switch {
+		case n == 1:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "cs", "sk":
+		switch {
+		case n == 1:
+			return "one"
+		case n >= 2 && n <= 4:
+			return "few"
+		}
+	}
+	return "other"
+}
+
+// toInt64 converts an integer argument of any integer type
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case uint:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestPluralCategory(t *testing.T) {
+	for _, tc := range []struct {
+		locale string
+		n      int64
+		want   string
+	}{
+		{"en-US", 1, "one"},
+		{"en-US", 0, "other"},
+		{"en-US", 2, "other"},
+		{"fr", 0, "one"},
+		{"pt-BR", 1, "one"},
+		{"fr", 2, "other"},
+
+		{"ru", 1, "one"},
+		{"ru", 21, "one"},
+		{"ru", 11, "many"},
+		{"ru", 2, "few"},
+		{"ru", 24, "few"},
+		{"ru", 12, "many"},
+		{"ru", 14, "many"},
+		{"ru", 5, "many"},
+		{"ru", 0, "many"},
+		{"ru", 111, "many"},
+		{"ru", -1, "one"},
+		{"uk", 22, "few"},
+
+		{"pl", 1, "one"},
+		{"pl", 21, "many"},
+		{"pl", 2, "few"},
+		{"pl", 22, "few"},
+		{"pl", 12, "many"},
+		{"pl", 5, "many"},
+		{"pl", 0, "many"},
+
+		{"cs", 1, "one"},
+		{"cs", 2, "few"},
+		{"cs", 4, "few"},
+		{"cs", 5, "other"},
+		{"cs", 22, "other"},
+		{"sk", 3, "few"},
+
+		{"ja", 1, "other"},
+	} {
+		if got := pluralCategory(tc.locale, tc.n); got != tc.want {
+			t.Errorf("pluralCategory(%q, %d): expected %q, got %q", tc.locale, tc.n, tc.want, got)
+		}
+	}
+}
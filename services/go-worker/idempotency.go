@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Idempotency backends (IDEMPOTENCY_BACKEND)
+const (
+	idempotencyMemory    = "memory"
+	idempotencyRedis     = "redis"
+	idempotencyFirestore = "firestore"
+	idempotencyNone      = "none"
+)
+
+// redisKeyPrefix matches the edge's, so both can share a Redis instance
+const redisKeyPrefix = "discord-bot:"
+
+// memorySweepInterval is how often expired in-memory entries are dropped
+const memorySweepInterval = time.Minute
+
+// claimResult is what a claim on an interaction found
+type claimResult int
+
+const (
+	// claimAcquired: this delivery should handle the interaction
+	claimAcquired claimResult = iota
+	// claimHeld: another delivery is handling it right now
+	claimHeld
+	// claimDone: it has already been handled
+	claimDone
+)
+
+// idempotencyBackend records which interactions are being handled or done
+type idempotencyBackend interface {
+	// Claim marks key as being handled by owner for lease, unless it is
+	// already held or done
+	Claim(ctx context.Context, key, owner string, lease time.Duration) (claimResult, error)
+
+	// Complete marks key as done for ttl
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+
+	// Release drops owner's claim on key, so a redelivery can handle it
+	Release(ctx context.Context, key, owner string) error
+}
+
+// idempotency stops duplicate deliveries of an interaction from answering it
+// twice. Pub/Sub delivers at least once, and the edge may publish a retried
+// interaction again. A delivery claims the interaction ID for a lease while it
+// runs, then marks it done for the TTL; a failed delivery releases its claim
+// so the redelivery can run. The lease expiring frees interactions whose
+// worker died mid-way.
+type idempotency struct {
+	backend idempotencyBackend
+	ttl     time.Duration
+	lease   time.Duration
+}
+
+// newIdempotency creates the configured backend. It returns nil for "none".
+func newIdempotency(ctx context.Context, cfg *Config) (*idempotency, error) {
+	i := &idempotency{ttl: cfg.IdempotencyTTL, lease: cfg.IdempotencyLease}
+	switch cfg.IdempotencyBackend {
+	case idempotencyNone:
+		return nil, nil
+	case idempotencyMemory:
+		i.backend = newMemoryIdempotency()
+	case idempotencyRedis:
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		i.backend = &redisIdempotency{client: redis.NewClient(opts)}
+	case idempotencyFirestore:
+		client, err := firestore.NewClientWithDatabase(ctx, cfg.ProjectID, cfg.FirestoreDatabase)
+		if err != nil {
+			return nil, err
+		}
+		i.backend = &firestoreIdempotency{client: client, collection: client.Collection(cfg.IdempotencyCollection)}
+	default:
+		return nil, fmt.Errorf("unknown IDEMPOTENCY_BACKEND %q", cfg.IdempotencyBackend)
+	}
+	return i, nil
+}
+
+// Claim claims the interaction for this delivery, returning the owner token
+// to complete or release it with. Backend errors fail open: the interaction
+// is handled rather than left unanswered.
+func (i *idempotency) Claim(ctx context.Context, interactionID string) (claimResult, string) {
+	owner := newOwnerToken()
+	result, err := i.backend.Claim(ctx, interactionID, owner, i.lease)
+	if err != nil {
+		loggerFrom(ctx).Error("Idempotency claim failed, handling anyway", "error", err)
+		return claimAcquired, owner
+	}
+	return result, owner
+}
+
+// Finish completes the claim when the delivery succeeded and releases it
+// otherwise. A dead-lettered interaction is released too, so reprocessing it
+// isn't mistaken for a duplicate.
+func (i *idempotency) Finish(ctx context.Context, interactionID, owner string, succeeded bool) {
+	// The message context may be done by now
+	ctx = context.WithoutCancel(ctx)
+	var err error
+	if succeeded {
+		err = i.backend.Complete(ctx, interactionID, i.ttl)
+	} else {
+		err = i.backend.Release(ctx, interactionID, owner)
+	}
+	if err != nil {
+		// The lease expiring frees a claim that couldn't be released; a
+		// claim that couldn't be completed leaves the next duplicate to run
+		loggerFrom(ctx).Error("Failed to record interaction outcome", "succeeded", succeeded, "error", err)
+	}
+}
+
+// newOwnerToken identifies one delivery's claim. Redeliveries of the same
+// message share a message ID, so that can't tell them apart.
+func newOwnerToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// memoryIdempotency only catches duplicates delivered to this instance
+type memoryIdempotency struct {
+	mu        sync.Mutex
+	entries   map[string]memoryClaim
+	lastSweep time.Time
+}
+
+type memoryClaim struct {
+	owner   string // empty once done
+	expires time.Time
+}
+
+func newMemoryIdempotency() *memoryIdempotency {
+	return &memoryIdempotency{entries: make(map[string]memoryClaim), lastSweep: time.Now()}
+}
+
+func (m *memoryIdempotency) Claim(_ context.Context, key, owner string, lease time.Duration) (claimResult, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	if e, ok := m.entries[key]; ok && now.Before(e.expires) {
+		if e.owner == "" {
+			return claimDone, nil
+		}
+		return claimHeld, nil
+	}
+	m.entries[key] = memoryClaim{owner: owner, expires: now.Add(lease)}
+	return claimAcquired, nil
+}
+
+func (m *memoryIdempotency) Complete(_ context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = memoryClaim{expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *memoryIdempotency) Release(_ context.Context, key, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok && e.owner == owner {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// sweep drops expired entries at most once per memorySweepInterval. Callers hold m.mu.
+func (m *memoryIdempotency) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < memorySweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for k, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+}
+
+// redisDone is the value of a completed interaction's key; claimed keys hold
+// their owner token
+const redisDone = "done"
+
+// redisRelease deletes a claim only if it is still the caller's, so a
+// delivery whose lease ran out can't release a later delivery's claim
+var redisRelease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// redisIdempotency shares claims through Redis, across every instance
+type redisIdempotency struct {
+	client *redis.Client
+}
+
+func redisIdempotencyKey(key string) string { return redisKeyPrefix + "worker:idempotency:" + key }
+
+func (r *redisIdempotency) Claim(ctx context.Context, key, owner string, lease time.Duration) (claimResult, error) {
+	k := redisIdempotencyKey(key)
+	set, err := r.client.SetNX(ctx, k, owner, lease).Result()
+	if err != nil || set {
+		return claimAcquired, err
+	}
+	v, err := r.client.Get(ctx, k).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		// Released or expired since SetNX; the redelivery will claim it
+		return claimHeld, nil
+	case err != nil:
+		return claimAcquired, err
+	case v == redisDone:
+		return claimDone, nil
+	default:
+		return claimHeld, nil
+	}
+}
+
+func (r *redisIdempotency) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	return r.client.Set(ctx, redisIdempotencyKey(key), redisDone, ttl).Err()
+}
+
+func (r *redisIdempotency) Release(ctx context.Context, key, owner string) error {
+	return redisRelease.Run(ctx, r.client, []string{redisIdempotencyKey(key)}, owner).Err()
+}
+
+// Status of a firestoreClaim
+const (
+	claimStatusHandling = "handling"
+	claimStatusDone     = "done"
+)
+
+// firestoreClaim is the document kept per claimed interaction, keyed by
+// interaction ID
+type firestoreClaim struct {
+	Status string `firestore:"status"`
+	Owner  string `firestore:"owner,omitempty"`
+
+	// Firestore TTL policies delete documents once this time has passed;
+	// until then, claims check it themselves
+	ExpireAt time.Time `firestore:"expire_at"`
+}
+
+// firestoreIdempotency shares claims through Firestore, across every instance
+type firestoreIdempotency struct {
+	client     *firestore.Client
+	collection *firestore.CollectionRef
+}
+
+func (f *firestoreIdempotency) Claim(ctx context.Context, key, owner string, lease time.Duration) (claimResult, error) {
+	doc := f.collection.Doc(key)
+	result := claimAcquired
+	err := f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		now := time.Now().UTC()
+		snap, err := tx.Get(doc)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil && snap.Exists() {
+			var c firestoreClaim
+			if err := snap.DataTo(&c); err != nil {
+				return err
+			}
+			if now.Before(c.ExpireAt) {
+				result = claimHeld
+				if c.Status == claimStatusDone {
+					result = claimDone
+				}
+				return nil
+			}
+		}
+		result = claimAcquired
+		return tx.Set(doc, firestoreClaim{Status: claimStatusHandling, Owner: owner, ExpireAt: now.Add(lease)})
+	})
+	return result, err
+}
+
+func (f *firestoreIdempotency) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := f.collection.Doc(key).Set(ctx, firestoreClaim{Status: claimStatusDone, ExpireAt: time.Now().UTC().Add(ttl)})
+	return err
+}
+
+func (f *firestoreIdempotency) Release(ctx context.Context, key, owner string) error {
+	doc := f.collection.Doc(key)
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var c firestoreClaim
+		if err := snap.DataTo(&c); err != nil {
+			return err
+		}
+		if c.Status != claimStatusHandling || c.Owner != owner {
+			return nil
+		}
+		return tx.Delete(doc)
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// Interaction types the worker handles
+const (
+	InteractionTypeApplicationCommand = 2
+)
+
+// Interaction is a published interaction as handlers see it. The token
+// stays with the worker and is never handed to handlers.
+type Interaction struct {
+	payload.Interaction
+}
+
+// CommandName returns the invoked command name, or "" if there is none
+func (i Interaction) CommandName() string {
+	return payload.CommandName(i.Data)
+}
+
+// CommandPath returns the command name followed by any subcommand group and
+// subcommand, e.g. "config permission set"
+func (i Interaction) CommandPath() string {
+	return payload.CommandPath(i.Data)
+}
+
+// Options returns the options of the command that ran: the subcommand's
+// options for a subcommand, otherwise the top-level options
+func (i Interaction) Options() Options {
+	raw := i.Data["options"]
+	if subs := payload.Subcommands(i.Data); len(subs) > 0 {
+		raw = subs[len(subs)-1]["options"]
+	}
+	return parseOptions(raw)
+}
+
+// UserID returns the invoking user's ID, from member.user in guilds and
+// user in DMs
+func (i Interaction) UserID() string {
+	return payload.UserID(i.Member, i.User)
+}
+
+// Resolved returns the entities the interaction's options reference, or an
+// empty Resolved if its resolved data doesn't have Discord's shapes
+func (i Interaction) Resolved() *payload.Resolved {
+	resolved, err := payload.ParseResolved(i.Data)
+	if err != nil {
+		return &payload.Resolved{}
+	}
+	return resolved
+}
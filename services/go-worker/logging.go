@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// logger writes structured JSON log entries to stderr using the field names
+// Cloud Logging recognizes (severity, message)
+var logger = newLogger(os.Stderr)
+
+type loggerContextKey struct{}
+
+func newLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{ReplaceAttr: cloudLoggingAttr}))
+}
+
+// cloudLoggingAttr renames slog's built-in keys to Cloud Logging's
+func cloudLoggingAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		level, _ := a.Value.Any().(slog.Level)
+		return slog.String("severity", severity(level))
+	case slog.MessageKey:
+		a.Key = "message"
+	}
+	return a
+}
+
+// severity maps slog levels to Cloud Logging LogSeverity names
+func severity(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARNING"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// fatal logs an error entry and exits
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+// withLogger returns ctx carrying l
+func withLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// loggerFrom returns the message-scoped logger, or the base logger outside a message
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return logger
+}
@@ -0,0 +1,159 @@
+// Discord interaction worker
+//
+// Consumes the interactions the edge service publishes to Pub/Sub, pulled
+// from a subscription or pushed by Pub/Sub or Eventarc (DELIVERY), runs the
+// command's handler, and completes the deferred response through Discord's
+// interaction webhook:
+// - Decodes messages with the edge's payload package (any schema version)
+// - Opens the sealed interaction token with TOKEN_SEAL_PRIVATE_KEY
+// - Skips duplicate deliveries of an interaction (IDEMPOTENCY_BACKEND)
+// - Dispatches to the handler registered for the command
+// - Dead-letters messages that can't be processed
+// - Continues the edge's trace, and serves Prometheus metrics on PORT
+// - On SIGTERM, stops pulling and drains in-flight messages (DRAIN_TIMEOUT)
+//
+// "worker reprocess" republishes dead-lettered messages.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+func main() {
+	slog.SetDefault(logger)
+
+	if len(os.Args) > 1 && os.Args[1] == "reprocess" {
+		os.Exit(reprocess(os.Args[2:]))
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	defaultLocale = cfg.DefaultLocale
+	payload.MaxDepth = cfg.JSONMaxDepth
+	if messages, err = loadMessageCatalog(defaultLocale); err != nil {
+		fatal("Failed to load message catalog", "error", err)
+	}
+	tokens, err := newTokenOpener(cfg.TokenSealPrivateKey)
+	if err != nil {
+		fatal("Invalid TOKEN_SEAL_PRIVATE_KEY", "error", err)
+	}
+	discord = newDiscordClient(cfg.DiscordAPIURL, cfg.DiscordAPITimeout, cfg.DiscordRateLimitRetries)
+
+	if attachments, err = newAttachmentFetcher(context.Background(), cfg.AttachmentBucket, cfg.AttachmentMaxBytes); err != nil {
+		fatal("Failed to create Cloud Storage client", "error", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	shutdownTracing, err := setupTracing(ctx)
+	if err != nil {
+		fatal("Failed to set up tracing", "error", err)
+	}
+	if shutdownTracing != nil {
+		defer func() {
+			// Flush buffered spans; ctx is already cancelled by now
+			sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = shutdownTracing(sctx)
+		}()
+	}
+
+	client, err := pubsub.NewClient(ctx, cfg.ProjectID)
+	if err != nil {
+		fatal("Failed to create Pub/Sub client", "error", err)
+	}
+	defer client.Close()
+
+	// Cloud Run services need a listener; worker pools don't. Pushed
+	// messages arrive on it.
+	if cfg.Port != "" && cfg.Delivery == deliveryPull {
+		go serveHealth(cfg.Port)
+	}
+
+	// Once SIGTERM stops Receive, in-flight handlers get DrainTimeout to
+	// finish before their contexts are cancelled
+	drained, drain := context.WithCancel(context.Background())
+	defer drain()
+	context.AfterFunc(ctx, func() {
+		logger.Info("Shutting down, draining in-flight messages", "timeout", cfg.DrainTimeout.String())
+		time.AfterFunc(cfg.DrainTimeout, drain)
+	})
+
+	w := &worker{
+		tokens:       tokens,
+		handlers:     handlers,
+		maxAttempts:  cfg.MaxDeliveryAttempts,
+		subscription: cfg.Subscription,
+		projectID:    cfg.ProjectID,
+		drained:      drained,
+	}
+	if cfg.DeadLetterTopic != "" {
+		w.deadLetters = newDeadLetterer(client, cfg.DeadLetterTopic, cfg.Subscription)
+		defer w.deadLetters.topic.Stop()
+	}
+	if cfg.ResultsTopic != "" {
+		// Stopped after Receive returns, flushing the last results
+		w.results = newResultPublisher(client, cfg.ResultsTopic, cfg.Subscription)
+		defer w.results.topic.Stop()
+	}
+	if w.idempotency, err = newIdempotency(ctx, cfg); err != nil {
+		fatal("Failed to create idempotency store", "backend", cfg.IdempotencyBackend, "error", err)
+	}
+	if cfg.Delivery == deliveryPush {
+		logger.Info("Worker started", "delivery", cfg.Delivery, "port", cfg.Port, "commands", handlers.Names())
+		servePush(ctx, cfg.Port, cfg.DrainTimeout, w)
+		logger.Info("Worker stopped")
+		return
+	}
+
+	sub := client.Subscription(cfg.Subscription)
+	sub.ReceiveSettings.MaxOutstandingMessages = cfg.MaxConcurrency
+	logger.Info("Worker started", "delivery", cfg.Delivery, "subscription", cfg.Subscription, "commands", handlers.Names())
+	// Receive returns once every callback has, and their acks and nacks
+	// have been sent
+	if err := sub.Receive(ctx, w.process); err != nil {
+		fatal("Pub/Sub receive failed", "error", err)
+	}
+	logger.Info("Worker stopped")
+}
+
+func serveHealth(port string) {
+	if err := newServer(port, healthMux()).ListenAndServe(); err != nil {
+		fatal("Health listener failed", "error", err)
+	}
+}
+
+// healthMux serves GET /health and GET /metrics
+func healthMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	})
+	mux.Handle("GET /metrics", promhttp.Handler())
+	return mux
+}
+
+func newServer(port string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+}
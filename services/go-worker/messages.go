@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// Discord's message limits, in characters. Build checks responses against
+// them so an oversized response fails in the handler's logs rather than as
+// a 400 from Discord.
+const (
+	maxContentLength          = 2000
+	maxEmbeds                 = 10
+	maxEmbedTotalLength       = 6000
+	maxEmbedTitleLength       = 256
+	maxEmbedDescriptionLength = 4096
+	maxEmbedFields            = 25
+	maxEmbedFieldNameLength   = 256
+	maxEmbedFieldValueLength  = 1024
+	maxEmbedFooterLength      = 2048
+	maxEmbedAuthorLength      = 256
+	maxActionRows             = 5
+	maxRowButtons             = 5
+	maxButtonLabelLength      = 80
+	maxCustomIDLength         = 100
+	maxSelectOptions          = 25
+	maxSelectPlaceholder      = 150
+	maxSelectOptionLength     = 100
+)
+
+// MessageBuilder assembles a Response from the message model shared with
+// the edge in the payload package
+type MessageBuilder struct {
+	resp Response
+}
+
+// NewMessage starts an empty message
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{}
+}
+
+// Content sets the message text
+func (b *MessageBuilder) Content(content string) *MessageBuilder {
+	b.resp.Content = content
+	return b
+}
+
+// Embed appends an embed
+func (b *MessageBuilder) Embed(embed *payload.EmbedBuilder) *MessageBuilder {
+	b.resp.Embeds = append(b.resp.Embeds, embed.Embed())
+	return b
+}
+
+// Row appends an action row
+func (b *MessageBuilder) Row(components ...payload.Component) *MessageBuilder {
+	b.resp.Components = append(b.resp.Components, payload.ActionRow{Type: payload.ComponentTypeActionRow, Components: components})
+	return b
+}
+
+// AllowMentions lets the message's mentions notify those m lists
+func (b *MessageBuilder) AllowMentions(m AllowedMentions) *MessageBuilder {
+	b.resp.AllowedMentions = &m
+	return b
+}
+
+// File uploads data as a file named name
+func (b *MessageBuilder) File(name string, data []byte) *MessageBuilder {
+	b.resp.Files = append(b.resp.Files, File{Name: name, Data: data})
+	return b
+}
+
+// Attach uploads a file the user attached, fetched from the edge's offload
+// bucket or Discord's CDN
+func (b *MessageBuilder) Attach(a Attachment) *MessageBuilder {
+	b.resp.Files = append(b.resp.Files, File{Name: a.Filename, ContentType: a.ContentType, Source: a.URL})
+	return b
+}
+
+// FollowUp sends msg as a new message after this one
+func (b *MessageBuilder) FollowUp(msg *MessageBuilder) *MessageBuilder {
+	b.resp.FollowUps = append(b.resp.FollowUps, msg.resp)
+	return b
+}
+
+// Build returns the response, or every way it breaks Discord's limits
+func (b *MessageBuilder) Build() (Response, error) {
+	return b.resp, b.resp.Validate()
+}
+
+// Validate checks the response against Discord's message limits
+func (r Response) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	maxLength := func(field, s string, limit int) {
+		if n := utf8.RuneCountInString(s); n > limit {
+			fail("%s: %d characters, Discord allows %d", field, n, limit)
+		}
+	}
+
+	if r.Content == "" && len(r.Embeds) == 0 && len(r.Files) == 0 {
+		fail("message has no content, embeds, or files")
+	}
+	maxLength("content", r.Content, maxContentLength)
+
+	if len(r.Embeds) > maxEmbeds {
+		fail("embeds: %d, Discord allows %d", len(r.Embeds), maxEmbeds)
+	}
+	total := 0
+	for i, e := range r.Embeds {
+		p := fmt.Sprintf("embeds[%d]", i)
+		maxLength(p+".title", e.Title, maxEmbedTitleLength)
+		maxLength(p+".description", e.Description, maxEmbedDescriptionLength)
+		total += utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+		if len(e.Fields) > maxEmbedFields {
+			fail("%s.fields: %d, Discord allows %d", p, len(e.Fields), maxEmbedFields)
+		}
+		for j, f := range e.Fields {
+			fp := fmt.Sprintf("%s.fields[%d]", p, j)
+			if f.Name == "" || f.Value == "" {
+				fail("%s: name and value are required", fp)
+			}
+			maxLength(fp+".name", f.Name, maxEmbedFieldNameLength)
+			maxLength(fp+".value", f.Value, maxEmbedFieldValueLength)
+			total += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+		}
+		if e.Footer != nil {
+			maxLength(p+".footer.text", e.Footer.Text, maxEmbedFooterLength)
+			total += utf8.RuneCountInString(e.Footer.Text)
+		}
+		if e.Author != nil {
+			maxLength(p+".author.name", e.Author.Name, maxEmbedAuthorLength)
+			total += utf8.RuneCountInString(e.Author.Name)
+		}
+	}
+	if total > maxEmbedTotalLength {
+		fail("embeds: %d characters in total, Discord allows %d", total, maxEmbedTotalLength)
+	}
+
+	if len(r.Components) > maxActionRows {
+		fail("components: %d action rows, Discord allows %d", len(r.Components), maxActionRows)
+	}
+	customIDs := make(map[string]bool)
+	for i, row := range r.Components {
+		p := fmt.Sprintf("components[%d]", i)
+		if len(row.Components) == 0 {
+			fail("%s: empty action row", p)
+		}
+		if len(row.Components) > maxRowButtons {
+			fail("%s: %d components, Discord allows %d", p, len(row.Components), maxRowButtons)
+		}
+		for j, c := range row.Components {
+			cp := fmt.Sprintf("%s.components[%d]", p, j)
+			var customID string
+			switch c := c.(type) {
+			case payload.Button:
+				customID = c.CustomID
+				if c.Label == "" {
+					fail("%s: button has no label", cp)
+				}
+				maxLength(cp+".label", c.Label, maxButtonLabelLength)
+				if c.Style == payload.ButtonStyleLink {
+					if c.URL == "" || c.CustomID != "" {
+						fail("%s: link buttons need a url and no custom_id", cp)
+					}
+				} else if c.CustomID == "" || c.URL != "" {
+					fail("%s: buttons need a custom_id and no url", cp)
+				}
+			case payload.SelectMenu:
+				customID = c.CustomID
+				if len(row.Components) > 1 {
+					fail("%s: a select menu must be alone in its action row", cp)
+				}
+				validateSelect(cp, c, fail, maxLength)
+			default:
+				fail("%s: unsupported component %T", cp, c)
+			}
+			maxLength(cp+".custom_id", customID, maxCustomIDLength)
+			if customID != "" {
+				if customIDs[customID] {
+					fail("%s: custom_id %q is used twice", cp, customID)
+				}
+				customIDs[customID] = true
+			}
+		}
+	}
+
+	if m := r.AllowedMentions; m != nil {
+		for _, kind := range m.Parse {
+			switch {
+			case kind != "users" && kind != "roles" && kind != "everyone":
+				fail("allowed_mentions.parse: unknown mention type %q", kind)
+			case kind == "users" && len(m.Users) > 0, kind == "roles" && len(m.Roles) > 0:
+				fail("allowed_mentions: %s is in parse, so it can't also list IDs", kind)
+			}
+		}
+	}
+
+	if len(r.Files) > maxFiles {
+		fail("files: %d, Discord allows %d", len(r.Files), maxFiles)
+	}
+	for i, f := range r.Files {
+		if f.Name == "" {
+			fail("files[%d]: no name", i)
+		}
+		if (f.Data == nil) == (f.Source == "") {
+			fail("files[%d]: set one of data and source", i)
+		}
+	}
+
+	for i, f := range r.FollowUps {
+		if len(f.FollowUps) > 0 {
+			fail("followups[%d]: follow-ups can't have follow-ups", i)
+		}
+		if err := f.Validate(); err != nil {
+			fail("followups[%d]: %w", i, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateSelect checks one select menu for Validate
+func validateSelect(p string, s payload.SelectMenu, fail func(string, ...any), maxLength func(string, string, int)) {
+	if s.CustomID == "" {
+		fail("%s: select menus need a custom_id", p)
+	}
+	maxLength(p+".placeholder", s.Placeholder, maxSelectPlaceholder)
+	if s.Type == payload.ComponentTypeStringSelect && (len(s.Options) == 0 || len(s.Options) > maxSelectOptions) {
+		fail("%s: %d options, Discord allows 1 to %d", p, len(s.Options), maxSelectOptions)
+	}
+	for k, o := range s.Options {
+		op := fmt.Sprintf("%s.options[%d]", p, k)
+		if o.Label == "" || o.Value == "" {
+			fail("%s: label and value are required", op)
+		}
+		maxLength(op+".label", o.Label, maxSelectOptionLength)
+		maxLength(op+".value", o.Value, maxSelectOptionLength)
+		maxLength(op+".description", o.Description, maxSelectOptionLength)
+	}
+	lo, hi := 1, 1
+	if s.MinValues != nil {
+		lo = *s.MinValues
+	}
+	if s.MaxValues != nil {
+		hi = *s.MaxValues
+	}
+	if lo < 0 || hi < 1 || lo > hi || hi > maxSelectOptions {
+		fail("%s: min_values %d and max_values %d must satisfy 0 <= min <= max <= %d", p, lo, hi, maxSelectOptions)
+	}
+	if s.Type == payload.ComponentTypeStringSelect && hi > len(s.Options) && len(s.Options) > 0 {
+		fail("%s: max_values %d is more than the %d options", p, hi, len(s.Options))
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// TestResponseValidate checks each of Discord's limits Validate enforces,
+// naming the field that breaks it
+func TestResponseValidate(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("é", n) } // counted in characters, not bytes
+	button := func(customID string) payload.Component {
+		return payload.NewButton(payload.ButtonStylePrimary, "Go", customID)
+	}
+	embeds := func(n int) []payload.Embed { return make([]payload.Embed, n) }
+	fields := func(n int) []payload.EmbedField {
+		f := make([]payload.EmbedField, n)
+		for i := range f {
+			f[i] = payload.EmbedField{Name: "n", Value: "v"}
+		}
+		return f
+	}
+	row := func(components ...payload.Component) payload.ActionRow {
+		return payload.ActionRow{Type: payload.ComponentTypeActionRow, Components: components}
+	}
+	options := func(n int) []payload.SelectOption {
+		o := make([]payload.SelectOption, n)
+		for i := range o {
+			o[i] = payload.SelectOption{Label: "l", Value: string(rune('a' + i))}
+		}
+		return o
+	}
+
+	for _, tc := range []struct {
+		name string
+		resp Response
+		want string // in the error, or "" for a valid response
+	}{
+		{"content", Response{Content: "hi"}, ""},
+		{"content at the limit", Response{Content: long(2000)}, ""},
+		{"everything at its limit", Response{
+			Embeds: []payload.Embed{{Title: long(256), Description: long(4096), Fields: fields(25)}},
+			Components: []payload.ActionRow{
+				row(button("a"), button("b"), button("c"), button("d"), button("e")),
+				row(payload.NewSelectMenu("s", options(25)...).Values(0, 25)),
+				row(payload.NewLinkButton("Docs", "https://example.com")),
+			},
+		}, ""},
+		{"empty", Response{}, "message has no content, embeds, or files"},
+		{"content too long", Response{Content: long(2001)}, "content: 2001 characters, Discord allows 2000"},
+		{"too many embeds", Response{Embeds: embeds(11)}, "embeds: 11, Discord allows 10"},
+		{"title too long", Response{Embeds: []payload.Embed{{Title: long(257)}}}, "embeds[0].title: 257 characters"},
+		{"description too long", Response{Embeds: []payload.Embed{{Description: long(4097)}}}, "embeds[0].description: 4097 characters"},
+		{"too many fields", Response{Embeds: []payload.Embed{{Fields: fields(26)}}}, "embeds[0].fields: 26, Discord allows 25"},
+		{"field without a value", Response{Embeds: []payload.Embed{{Fields: []payload.EmbedField{{Name: "n"}}}}}, "embeds[0].fields[0]: name and value are required"},
+		{"field value too long", Response{Embeds: []payload.Embed{{Fields: []payload.EmbedField{{Name: "n", Value: long(1025)}}}}}, "embeds[0].fields[0].value: 1025 characters"},
+		{"footer too long", Response{Embeds: []payload.Embed{{Footer: &payload.EmbedFooter{Text: long(2049)}}}}, "embeds[0].footer.text: 2049 characters"},
+		{"author too long", Response{Embeds: []payload.Embed{{Author: &payload.EmbedAuthor{Name: long(257)}}}}, "embeds[0].author.name: 257 characters"},
+		{"embeds too long in total", Response{Embeds: []payload.Embed{{Description: long(4000)}, {Description: long(2001)}}}, "embeds: 6001 characters in total, Discord allows 6000"},
+		{"too many rows", Response{Content: "c", Components: []payload.ActionRow{
+			row(button("a")), row(button("b")), row(button("c")), row(button("d")), row(button("e")), row(button("f")),
+		}}, "components: 6 action rows, Discord allows 5"},
+		{"empty row", Response{Content: "c", Components: []payload.ActionRow{row()}}, "components[0]: empty action row"},
+		{"too many buttons", Response{Content: "c", Components: []payload.ActionRow{
+			row(button("a"), button("b"), button("c"), button("d"), button("e"), button("f")),
+		}}, "components[0]: 6 components, Discord allows 5"},
+		{"button without a label", Response{Content: "c", Components: []payload.ActionRow{
+			row(payload.NewButton(payload.ButtonStylePrimary, "", "a")),
+		}}, "components[0].components[0]: button has no label"},
+		{"link button with a custom_id", Response{Content: "c", Components: []payload.ActionRow{
+			row(payload.Button{Type: payload.ComponentTypeButton, Style: payload.ButtonStyleLink, Label: "l", URL: "https://example.com", CustomID: "a"}),
+		}}, "link buttons need a url and no custom_id"},
+		{"button without a custom_id", Response{Content: "c", Components: []payload.ActionRow{row(button(""))}}, "buttons need a custom_id and no url"},
+		{"custom_id too long", Response{Content: "c", Components: []payload.ActionRow{row(button(long(101)))}}, "custom_id: 101 characters"},
+		{"custom_id used twice", Response{Content: "c", Components: []payload.ActionRow{row(button("a")), row(button("a"))}}, `custom_id "a" is used twice`},
+		{"select sharing its row", Response{Content: "c", Components: []payload.ActionRow{
+			row(button("a"), payload.NewSelectMenu("s", options(1)...)),
+		}}, "a select menu must be alone in its action row"},
+		{"select without options", Response{Content: "c", Components: []payload.ActionRow{
+			row(payload.NewSelectMenu("s")),
+		}}, "0 options, Discord allows 1 to 25"},
+		{"select with too many options", Response{Content: "c", Components: []payload.ActionRow{
+			row(payload.NewSelectMenu("s", options(26)...)),
+		}}, "26 options, Discord allows 1 to 25"},
+		{"select picking more than it offers", Response{Content: "c", Components: []payload.ActionRow{
+			row(payload.NewSelectMenu("s", options(2)...).Values(1, 3)),
+		}}, "max_values 3 is more than the 2 options"},
+		{"select with min over max", Response{Content: "c", Components: []payload.ActionRow{
+			row(payload.NewEntitySelect(payload.ComponentTypeUserSelect, "u").Values(3, 2)),
+		}}, "min_values 3 and max_values 2"},
+		{"too many files", Response{Files: make([]File, maxFiles+1)}, "files: 11, Discord allows 10"},
+		{"file with data and a source", Response{Files: []File{{Name: "f", Data: []byte("x"), Source: "gs://b/o"}}}, "files[0]: set one of data and source"},
+		{"nested follow-ups", Response{Content: "c", FollowUps: []Response{{Content: "f", FollowUps: []Response{{Content: "g"}}}}}, "followups[0]: follow-ups can't have follow-ups"},
+		{"invalid follow-up", Response{Content: "c", FollowUps: []Response{{}}}, "followups[0]: message has no content"},
+		{"unknown mention type", Response{Content: "c", AllowedMentions: &AllowedMentions{Parse: []string{"channels"}}}, `unknown mention type "channels"`},
+		{"mentions parsed and listed", Response{Content: "c", AllowedMentions: &AllowedMentions{Parse: []string{"users"}, Users: []string{"1"}}}, "users is in parse, so it can't also list IDs"},
+	} {
+		err := tc.resp.Validate()
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: expected no error, got %v", tc.name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
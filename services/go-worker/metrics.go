@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus metrics exposed at /metrics on PORT. Names share the edge's
+// discord_ prefix, with worker_ to keep the two services apart.
+var (
+	messagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_worker_messages_total",
+		Help: "Messages processed by outcome (handled, duplicate, held, retried, dead_lettered, dropped, dead_letter_failed, interrupted).",
+	}, []string{"result"})
+
+	failuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_worker_failures_total",
+		Help: "Failed deliveries by reason (parse_error, token_error, handler_panic, discord_rejected, discord_error).",
+	}, []string{"reason"})
+
+	messageDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "discord_worker_message_duration_seconds",
+		Help:    "Time to process one delivery, from receipt to ack or nack.",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	})
+
+	messageAge = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "discord_worker_message_age_seconds",
+		Help:    "Time from the edge publishing a message to the worker receiving it.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 15, 60, 300, 900},
+	})
+
+	handlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "discord_worker_handler_duration_seconds",
+		Help:    "Command handler latency by registered command (unknown for the fallback) and result.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"command", "result"})
+
+	discordRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "discord_worker_api_request_duration_seconds",
+		Help:    "Discord API request latency by operation and status (error when no response arrived).",
+		Buckets: []float64{.025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation", "status"})
+
+	discordRateLimitedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_worker_api_rate_limited_total",
+		Help: "Discord API 429 responses by scope (user, global, shared).",
+	}, []string{"scope"})
+
+	resultPublishFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "discord_worker_result_publish_failures_total",
+		Help: "Result events that couldn't be published to RESULTS_TOPIC.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		messagesTotal,
+		failuresTotal,
+		messageDuration,
+		messageAge,
+		handlerDuration,
+		discordRequestDuration,
+		discordRateLimitedTotal,
+		resultPublishFailuresTotal,
+	)
+}
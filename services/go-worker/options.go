@@ -0,0 +1,75 @@
+package main
+
+// Option is one option the user supplied
+type Option struct {
+	Name  string
+	Type  int
+	Value any
+}
+
+func newOption(v any) Option {
+	raw, _ := v.(map[string]any)
+	name, _ := raw["name"].(string)
+	t, _ := raw["type"].(float64)
+	return Option{Name: name, Type: int(t), Value: raw["value"]}
+}
+
+// Options are a command's options by name. The getters report false when the
+// option wasn't supplied or has another type, so optional options need no
+// special handling.
+type Options map[string]Option
+
+func parseOptions(raw any) Options {
+	list, _ := raw.([]any)
+	opts := make(Options, len(list))
+	for _, v := range list {
+		opt := newOption(v)
+		opts[opt.Name] = opt
+	}
+	return opts
+}
+
+// String returns a string option
+func (o Options) String(name string) (string, bool) {
+	s, ok := o[name].Value.(string)
+	return s, ok
+}
+
+// StringOr returns a string option, or def when it wasn't supplied
+func (o Options) StringOr(name, def string) string {
+	if s, ok := o.String(name); ok {
+		return s
+	}
+	return def
+}
+
+// Int returns an integer option
+func (o Options) Int(name string) (int64, bool) {
+	// JSON numbers decode as float64; Discord integers fit in 2^53
+	f, ok := o[name].Value.(float64)
+	return int64(f), ok
+}
+
+// Float returns a number option
+func (o Options) Float(name string) (float64, bool) {
+	f, ok := o[name].Value.(float64)
+	return f, ok
+}
+
+// Bool returns a boolean option
+func (o Options) Bool(name string) (bool, bool) {
+	b, ok := o[name].Value.(bool)
+	return b, ok
+}
+
+// ID returns the snowflake of a user, channel, role, mentionable or
+// attachment option
+func (o Options) ID(name string) (string, bool) {
+	return o.String(name)
+}
+
+// Has reports whether the option was supplied
+func (o Options) Has(name string) bool {
+	_, ok := o[name]
+	return ok
+}
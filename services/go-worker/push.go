@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// maxPushBytes bounds a push request. Pub/Sub messages are at most 10 MB,
+// and push bodies carry the data base64-encoded.
+const maxPushBytes = 16 << 20
+
+// pushEnvelope is the body a Pub/Sub push subscription POSTs for each
+// message. Eventarc's Pub/Sub triggers deliver the same body as the data of
+// a messagePublished CloudEvent.
+type pushEnvelope struct {
+	Message struct {
+		ID          string            `json:"messageId"`
+		Data        []byte            `json:"data"` // base64 in JSON
+		Attributes  map[string]string `json:"attributes"`
+		PublishTime time.Time         `json:"publishTime"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+	// Set when the subscription has a dead letter policy
+	DeliveryAttempt *int `json:"deliveryAttempt"`
+}
+
+// push handles a pushed message. A 204 acks it; any other status has Pub/Sub
+// deliver it again.
+func (w *worker) push(rw http.ResponseWriter, r *http.Request) {
+	var env pushEnvelope
+	err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxPushBytes)).Decode(&env)
+	if err == nil && env.Message.ID == "" {
+		err = errors.New("no message ID")
+	}
+	if err != nil {
+		logger.Warn("Rejecting request that isn't a Pub/Sub push", "error", err)
+		http.Error(rw, "body is not a Pub/Sub push message", http.StatusBadRequest)
+		return
+	}
+
+	msg := &pubsub.Message{
+		ID:              env.Message.ID,
+		Data:            env.Message.Data,
+		Attributes:      env.Message.Attributes,
+		PublishTime:     env.Message.PublishTime,
+		DeliveryAttempt: env.DeliveryAttempt,
+	}
+	if !w.deliver(r.Context(), msg) {
+		http.Error(rw, "message not processed, redeliver it", http.StatusServiceUnavailable)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// servePush serves health, metrics, and pushed messages on port until ctx
+// is cancelled. It then stops accepting messages and returns once those in
+// flight have finished, or drainTimeout has passed and the worker's drain
+// has cut them off.
+func servePush(ctx context.Context, port string, drainTimeout time.Duration, w *worker) {
+	mux := healthMux()
+	mux.HandleFunc("POST /", w.push)
+	srv := newServer(port, mux)
+
+	stopped := make(chan struct{})
+	context.AfterFunc(ctx, func() {
+		defer close(stopped)
+		// A little past the drain, so cut-off handlers can answer
+		sctx, cancel := context.WithTimeout(context.Background(), drainTimeout+time.Second)
+		defer cancel()
+		_ = srv.Shutdown(sctx)
+	})
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fatal("Push listener failed", "error", err)
+	}
+	<-stopped
+}
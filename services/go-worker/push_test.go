@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestPush checks pushed messages are acked with a 204, and bodies that
+// aren't push messages are refused rather than redelivered for ever
+func TestPush(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		body string
+		want int
+	}{
+		// {"type":1}, an interaction the worker acks without handling
+		{"not a command", `{"message":{"messageId":"m1","data":"eyJ0eXBlIjoxfQ=="},"subscription":"s"}`, http.StatusNoContent},
+		{"not JSON", `not json`, http.StatusBadRequest},
+		{"no message", `{"subscription":"s"}`, http.StatusBadRequest},
+		{"data not base64", `{"message":{"messageId":"m1","data":"%%"}}`, http.StatusBadRequest},
+	} {
+		w := &worker{drained: context.Background()}
+		rec := httptest.NewRecorder()
+		w.push(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Discord rate limit response headers
+const (
+	headerRateLimitBucket     = "X-RateLimit-Bucket"
+	headerRateLimitRemaining  = "X-RateLimit-Remaining"
+	headerRateLimitResetAfter = "X-RateLimit-Reset-After"
+	headerRateLimitGlobal     = "X-RateLimit-Global"
+	headerRateLimitScope      = "X-RateLimit-Scope"
+)
+
+// maxRateLimitEntries bounds the tracked routes and buckets. Interaction
+// webhook routes include the token, so every interaction adds one; expired
+// entries are dropped when the limit is reached.
+const maxRateLimitEntries = 10000
+
+// rateLimits tracks Discord's per-route buckets and the global limit, so
+// requests wait for a bucket to reset instead of drawing 429s. Repeated 429s
+// get a bot temporarily banned, so throttling happens before sending, not
+// only after a 429.
+type rateLimits struct {
+	mu          sync.Mutex
+	routes      map[string]string // route -> bucket key, once Discord names the bucket
+	buckets     map[string]*rateLimitBucket
+	globalUntil time.Time
+}
+
+// rateLimitBucket is what Discord last reported for a bucket, less the
+// requests sent since
+type rateLimitBucket struct {
+	remaining int
+	reset     time.Time
+}
+
+func newRateLimits() *rateLimits {
+	return &rateLimits{routes: make(map[string]string), buckets: make(map[string]*rateLimitBucket)}
+}
+
+// routeKey identifies a route for bucket tracking: the method and path.
+// Paths carry the major parameter (channel, guild, or webhook ID and token)
+// that Discord keys buckets on.
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// majorParameter returns the part of path Discord's buckets are shared by
+func majorParameter(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	switch {
+	case len(parts) >= 3 && parts[0] == "webhooks":
+		return strings.Join(parts[:3], "/")
+	case len(parts) >= 2 && (parts[0] == "channels" || parts[0] == "guilds"):
+		return strings.Join(parts[:2], "/")
+	default:
+		return ""
+	}
+}
+
+// Wait blocks until a request on route may be sent, then reserves it
+func (l *rateLimits) Wait(ctx context.Context, route string) error {
+	for {
+		delay := l.reserve(route, time.Now())
+		if delay <= 0 {
+			return nil
+		}
+		loggerFrom(ctx).Debug("Waiting for Discord rate limit", "delay", delay.String())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// reserve takes a request from the route's bucket, or returns how long to
+// wait before trying again
+func (l *rateLimits) reserve(route string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Before(l.globalUntil) {
+		return l.globalUntil.Sub(now)
+	}
+	b := l.buckets[l.routes[route]]
+	if b == nil || !now.Before(b.reset) {
+		return 0
+	}
+	if b.remaining <= 0 {
+		return b.reset.Sub(now)
+	}
+	b.remaining--
+	return 0
+}
+
+// Update records the rate limit headers of a response to a request on
+// route. For a 429 it returns how long to wait before retrying.
+func (l *rateLimits) Update(route string, resp *http.Response, body []byte, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if hash := resp.Header.Get(headerRateLimitBucket); hash != "" {
+		key := hash + ":" + majorParameter(strings.SplitN(route, " ", 2)[1])
+		if len(l.routes) >= maxRateLimitEntries || len(l.buckets) >= maxRateLimitEntries {
+			l.sweep(now)
+		}
+		l.routes[route] = key
+		remaining, errRemaining := strconv.Atoi(resp.Header.Get(headerRateLimitRemaining))
+		resetAfter, errReset := strconv.ParseFloat(resp.Header.Get(headerRateLimitResetAfter), 64)
+		if errRemaining == nil && errReset == nil {
+			l.buckets[key] = &rateLimitBucket{
+				remaining: remaining,
+				reset:     now.Add(time.Duration(resetAfter * float64(time.Second))),
+			}
+		}
+	}
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+
+	// The body's retry_after is more precise than the Retry-After header
+	var limited struct {
+		RetryAfter float64 `json:"retry_after"`
+		Global     bool    `json:"global"`
+	}
+	_ = json.Unmarshal(body, &limited)
+	retryAfter := time.Duration(limited.RetryAfter * float64(time.Second))
+	if retryAfter <= 0 {
+		secs, _ := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+		retryAfter = time.Duration(secs * float64(time.Second))
+	}
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+	if limited.Global || resp.Header.Get(headerRateLimitGlobal) == "true" {
+		l.globalUntil = now.Add(retryAfter)
+	} else {
+		// A route Discord hasn't named a bucket for is its own bucket
+		key, ok := l.routes[route]
+		if !ok {
+			key = route
+			l.routes[route] = key
+		}
+		l.buckets[key] = &rateLimitBucket{remaining: 0, reset: now.Add(retryAfter)}
+	}
+	return retryAfter
+}
+
+// sweep drops buckets past their reset, and routes whose bucket is gone
+func (l *rateLimits) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if !now.Before(b.reset) {
+			delete(l.buckets, key)
+		}
+	}
+	for route, key := range l.routes {
+		if _, ok := l.buckets[key]; !ok {
+			delete(l.routes, route)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// rateLimitResponse returns a response with the given status and headers
+func rateLimitResponse(status int, headers map[string]string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: make(http.Header)}
+	for k, v := range headers {
+		resp.Header.Set(k, v)
+	}
+	return resp
+}
+
+func TestMajorParameter(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{"/webhooks/1/token/messages/@original", "webhooks/1/token"},
+		{"/webhooks/1/token", "webhooks/1/token"},
+		{"/webhooks/1", ""},
+		{"/channels/2/messages/3", "channels/2"},
+		{"/guilds/4/members", "guilds/4"},
+		{"/guilds", ""},
+		{"/users/@me", ""},
+		{"/", ""},
+	} {
+		if got := majorParameter(tc.path); got != tc.want {
+			t.Errorf("majorParameter(%q): expected %q, got %q", tc.path, tc.want, got)
+		}
+	}
+}
+
+func TestRateLimits_Reserve(t *testing.T) {
+	now := time.Now()
+	route := routeKey(http.MethodPost, "/webhooks/1/token")
+	for _, tc := range []struct {
+		name   string
+		limits func() *rateLimits
+		want   []time.Duration // one reservation each
+	}{
+		{"unknown route", newRateLimits, []time.Duration{0, 0, 0}},
+		{"requests left", func() *rateLimits {
+			l := newRateLimits()
+			l.routes[route] = "b"
+			l.buckets["b"] = &rateLimitBucket{remaining: 2, reset: now.Add(time.Second)}
+			return l
+		}, []time.Duration{0, 0, time.Second, time.Second}},
+		{"bucket reset", func() *rateLimits {
+			l := newRateLimits()
+			l.routes[route] = "b"
+			l.buckets["b"] = &rateLimitBucket{remaining: 0, reset: now}
+			return l
+		}, []time.Duration{0, 0}},
+		{"global limit", func() *rateLimits {
+			l := newRateLimits()
+			l.globalUntil = now.Add(2 * time.Second)
+			return l
+		}, []time.Duration{2 * time.Second, 2 * time.Second}},
+	} {
+		l := tc.limits()
+		for i, want := range tc.want {
+			if got := l.reserve(route, now); got != want {
+				t.Errorf("%s: reservation %d: expected %v, got %v", tc.name, i+1, want, got)
+			}
+		}
+	}
+}
+
+func TestRateLimits_Update(t *testing.T) {
+	now := time.Now()
+	route := routeKey(http.MethodPatch, "/webhooks/1/token/messages/@original")
+	sibling := routeKey(http.MethodPost, "/webhooks/1/token")
+	other := routeKey(http.MethodPost, "/webhooks/1/other")
+	for _, tc := range []struct {
+		name      string
+		resp      *http.Response
+		body      string
+		wantRetry time.Duration
+		// Waits for a request on each route afterwards. The sibling shares
+		// the route's bucket, and the other route has its own.
+		wantRoute, wantSibling, wantOther time.Duration
+	}{
+		{
+			name: "bucket exhausted",
+			resp: rateLimitResponse(http.StatusOK, map[string]string{
+				headerRateLimitBucket: "abc", headerRateLimitRemaining: "0", headerRateLimitResetAfter: "1.5",
+			}),
+			wantRoute: 1500 * time.Millisecond, wantSibling: 1500 * time.Millisecond,
+		},
+		{
+			name: "bucket with requests left",
+			resp: rateLimitResponse(http.StatusOK, map[string]string{
+				headerRateLimitBucket: "abc", headerRateLimitRemaining: "3", headerRateLimitResetAfter: "1",
+			}),
+		},
+		{
+			name: "retry_after in the body wins over Retry-After",
+			resp: rateLimitResponse(http.StatusTooManyRequests, map[string]string{"Retry-After": "3"}),
+			body: `{"retry_after": 0.25, "global": false}`, wantRetry: 250 * time.Millisecond,
+			wantRoute: 250 * time.Millisecond,
+		},
+		{
+			name:      "Retry-After without a body",
+			resp:      rateLimitResponse(http.StatusTooManyRequests, map[string]string{"Retry-After": "3"}),
+			wantRetry: 3 * time.Second, wantRoute: 3 * time.Second,
+		},
+		{
+			name:      "no retry hint",
+			resp:      rateLimitResponse(http.StatusTooManyRequests, nil),
+			wantRetry: time.Second, wantRoute: time.Second,
+		},
+		{
+			name: "429 on a named bucket",
+			resp: rateLimitResponse(http.StatusTooManyRequests, map[string]string{
+				headerRateLimitBucket: "abc", headerRateLimitRemaining: "0", headerRateLimitResetAfter: "2",
+			}),
+			body: `{"retry_after": 2}`, wantRetry: 2 * time.Second,
+			wantRoute: 2 * time.Second, wantSibling: 2 * time.Second,
+		},
+		{
+			name: "global in the body",
+			resp: rateLimitResponse(http.StatusTooManyRequests, nil),
+			body: `{"retry_after": 1, "global": true}`, wantRetry: time.Second,
+			wantRoute: time.Second, wantSibling: time.Second, wantOther: time.Second,
+		},
+		{
+			name:      "global in the header",
+			resp:      rateLimitResponse(http.StatusTooManyRequests, map[string]string{headerRateLimitGlobal: "true"}),
+			body:      `{"retry_after": 1}`,
+			wantRetry: time.Second, wantRoute: time.Second, wantSibling: time.Second, wantOther: time.Second,
+		},
+	} {
+		l := newRateLimits()
+		// The sibling route shares the bucket once Discord has named it
+		l.Update(sibling, rateLimitResponse(http.StatusOK, map[string]string{
+			headerRateLimitBucket: "abc", headerRateLimitRemaining: "5", headerRateLimitResetAfter: "10",
+		}), nil, now)
+		if got := l.Update(route, tc.resp, []byte(tc.body), now); got != tc.wantRetry {
+			t.Errorf("%s: expected retry after %v, got %v", tc.name, tc.wantRetry, got)
+		}
+		for _, r := range []struct {
+			route string
+			want  time.Duration
+		}{{route, tc.wantRoute}, {sibling, tc.wantSibling}, {other, tc.wantOther}} {
+			if got := l.reserve(r.route, now); got != r.want {
+				t.Errorf("%s: expected %q to wait %v, got %v", tc.name, r.route, r.want, got)
+			}
+		}
+	}
+}
+
+func TestRateLimits_Sweep(t *testing.T) {
+	now := time.Now()
+	l := newRateLimits()
+	l.routes["expired"] = "old"
+	l.buckets["old"] = &rateLimitBucket{reset: now.Add(-time.Second)}
+	l.routes["current"] = "new"
+	l.buckets["new"] = &rateLimitBucket{reset: now.Add(time.Second)}
+	l.routes["dangling"] = "gone"
+
+	l.sweep(now)
+	if len(l.buckets) != 1 || l.buckets["new"] == nil {
+		t.Errorf("expected only the current bucket to remain, got %v", l.buckets)
+	}
+	if len(l.routes) != 1 || l.routes["current"] != "new" {
+		t.Errorf("expected only the current route to remain, got %v", l.routes)
+	}
+}
+
+// TestRateLimits_UpdateSweepsWhenFull checks the tracked routes stay bounded
+// as each interaction's token adds one
+func TestRateLimits_UpdateSweepsWhenFull(t *testing.T) {
+	now := time.Now()
+	l := newRateLimits()
+	for i := range maxRateLimitEntries {
+		l.routes[routeKey(http.MethodPost, "/webhooks/1/"+strconv.Itoa(i))] = "old"
+	}
+	l.buckets["old"] = &rateLimitBucket{reset: now.Add(-time.Second)}
+
+	l.Update(routeKey(http.MethodPost, "/webhooks/1/new"), rateLimitResponse(http.StatusOK, map[string]string{
+		headerRateLimitBucket: "abc", headerRateLimitRemaining: "1", headerRateLimitResetAfter: "1",
+	}), nil, now)
+	if len(l.routes) != 1 || len(l.buckets) != 1 {
+		t.Errorf("expected the expired entries to be swept, got %d routes and %d buckets", len(l.routes), len(l.buckets))
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// resultEvent is published to RESULTS_TOPIC for every delivery the worker
+// settles. Outcome is the discord_worker_messages_total result; retried,
+// held and interrupted deliveries are followed by another event for the
+// same interaction. LatencyMS is the worker's own processing time and
+// EndToEndMS the time since the edge published the message.
+type resultEvent struct {
+	InteractionID string    `json:"interaction_id"`
+	MessageID     string    `json:"message_id"`
+	RequestID     string    `json:"request_id,omitempty"`
+	Command       string    `json:"command,omitempty"`
+	Outcome       string    `json:"outcome"`
+	ErrorClass    string    `json:"error_class,omitempty"`
+	Attempt       int       `json:"attempt"`
+	LatencyMS     int64     `json:"latency_ms"`
+	EndToEndMS    int64     `json:"end_to_end_ms,omitempty"`
+	Subscription  string    `json:"subscription"`
+	Time          time.Time `json:"time"`
+}
+
+// resultPublisher reports how each delivery ended to a results topic, for
+// analytics alongside the edge's own events
+type resultPublisher struct {
+	topic        *pubsub.Topic
+	subscription string
+}
+
+func newResultPublisher(client *pubsub.Client, topic, subscription string) *resultPublisher {
+	return &resultPublisher{topic: client.Topic(topic), subscription: subscription}
+}
+
+// Publish sends the result of msg without waiting: a results outage mustn't
+// hold up acking. Failures are logged and counted; Stop flushes the rest.
+func (r *resultPublisher) Publish(ctx context.Context, msg *pubsub.Message, outcome string, failure *processError, attempt int, start time.Time) {
+	// Interrupted deliveries are reported after ctx is cut off
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	event := resultEvent{
+		InteractionID: msg.Attributes["interaction_id"],
+		MessageID:     msg.ID,
+		RequestID:     msg.Attributes["request_id"],
+		Command:       msg.Attributes["command_path"],
+		Outcome:       outcome,
+		Attempt:       attempt,
+		LatencyMS:     now.Sub(start).Milliseconds(),
+		Subscription:  r.subscription,
+		Time:          now.UTC(),
+	}
+	if failure != nil {
+		event.ErrorClass = failure.reason
+	}
+	if !msg.PublishTime.IsZero() {
+		event.EndToEndMS = now.Sub(msg.PublishTime).Milliseconds()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	attrs := map[string]string{
+		"interaction_id": event.InteractionID,
+		"outcome":        outcome,
+		"attempt":        strconv.Itoa(attempt),
+	}
+	if event.Command != "" {
+		attrs["command_path"] = event.Command
+	}
+	if event.ErrorClass != "" {
+		attrs["error_class"] = event.ErrorClass
+	}
+	if event.RequestID != "" {
+		attrs["request_id"] = event.RequestID
+	}
+	// Carry the trace on, so results join up with the edge's and worker's spans
+	propagator.Inject(ctx, propagation.MapCarrier(attrs))
+
+	res := r.topic.Publish(ctx, &pubsub.Message{Data: data, Attributes: attrs})
+	log := loggerFrom(ctx)
+	go func() {
+		if _, err := res.Get(ctx); err != nil {
+			log.Warn("Failed to publish result", "outcome", outcome, "error", err)
+			resultPublishFailuresTotal.Inc()
+		}
+	}()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// tokenOpener decrypts the sealed_token the edge publishes in place of the
+// interaction token (libsodium crypto_box_seal)
+type tokenOpener struct {
+	public, private [32]byte
+}
+
+// newTokenOpener parses a hex or base64 X25519 private key
+func newTokenOpener(s string) (*tokenOpener, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		if raw, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, errors.New("key is neither hex nor base64")
+		}
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(raw))
+	}
+	o := &tokenOpener{}
+	copy(o.private[:], raw)
+	public, err := curve25519.X25519(o.private[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	copy(o.public[:], public)
+	return o, nil
+}
+
+// Open returns the token sealed in the base64 sealed box
+func (o *tokenOpener) Open(sealed string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	token, ok := box.OpenAnonymous(nil, raw, &o.public, &o.private)
+	if !ok {
+		return "", errors.New("sealed token does not open with this key")
+	}
+	return string(token), nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Cloud Logging special fields, as on the edge
+const (
+	traceKey        = "logging.googleapis.com/trace"
+	spanIDKey       = "logging.googleapis.com/spanId"
+	traceSampledKey = "logging.googleapis.com/trace_sampled"
+)
+
+// tracer starts the worker's spans. Until setupTracing installs an SDK
+// provider it is a no-op that still carries the edge's trace context, so
+// logs correlate either way.
+var tracer = otel.Tracer("github.com/pmgledhill102/discord-bot-test-suite/services/go-worker")
+
+// propagator reads the W3C traceparent and tracestate attributes the edge
+// publishes
+var propagator = propagation.TraceContext{}
+
+// setupTracing exports spans over OTLP/gRPC when OTEL_EXPORTER_OTLP_ENDPOINT
+// or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set. The exporter, sampler
+// (OTEL_TRACES_SAMPLER) and resource (OTEL_SERVICE_NAME,
+// OTEL_RESOURCE_ATTRIBUTES) follow the standard OTEL_ variables. It returns
+// a function flushing spans on shutdown, or nil when tracing is off.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return nil, nil
+	}
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "go-worker")),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// extractTrace returns ctx carrying the trace context from a message's
+// attributes
+func extractTrace(ctx context.Context, attributes map[string]string) context.Context {
+	return propagator.Extract(ctx, propagation.MapCarrier(attributes))
+}
+
+// traceAttrs returns the Cloud Logging fields linking log entries to the
+// span in ctx
+func traceAttrs(ctx context.Context, projectID string) []any {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || projectID == "" {
+		return nil
+	}
+	return []any{
+		traceKey, "projects/" + projectID + "/traces/" + sc.TraceID().String(),
+		spanIDKey, sc.SpanID().String(),
+		traceSampledKey, sc.IsSampled(),
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/services/go-gin/payload"
+)
+
+// heldRetryDelay is how long a duplicate delivery waits before being nacked
+// while another delivery of the interaction is running
+const heldRetryDelay = 5 * time.Second
+
+// Why a message failed, recorded on dead-lettered messages
+const (
+	failureParse          = "parse_error"
+	failureToken          = "token_error"
+	failureHandlerPanic   = "handler_panic"
+	failureDiscordRejects = "discord_rejected"
+	failureDiscordError   = "discord_error"
+)
+
+// worker processes interaction messages
+type worker struct {
+	tokens       *tokenOpener
+	handlers     *Registry
+	maxAttempts  int
+	subscription string
+
+	// projectID qualifies trace IDs in log entries
+	projectID string
+
+	// deadLetters is nil unless DEAD_LETTER_TOPIC is set, in which case
+	// failed messages are logged and dropped
+	deadLetters *deadLetterer
+
+	// results is nil unless RESULTS_TOPIC is set
+	results *resultPublisher
+
+	// idempotency is nil when IDEMPOTENCY_BACKEND is "none"
+	idempotency *idempotency
+
+	// drained is cancelled once the drain timeout after shutdown has passed,
+	// cutting off the handlers still running
+	drained context.Context
+
+	// attempts counts deliveries of messages whose subscription has no dead
+	// letter policy, so Pub/Sub doesn't report the attempt
+	attempts sync.Map // message ID -> int
+}
+
+// processError is why a message couldn't be processed. Permanent failures
+// are dead-lettered at once; others are retried until maxAttempts.
+type processError struct {
+	reason    string
+	permanent bool
+	err       error
+}
+
+func (e *processError) Error() string { return e.reason + ": " + e.err.Error() }
+func (e *processError) Unwrap() error { return e.err }
+
+// process is the Receive callback for pulled messages
+func (w *worker) process(ctx context.Context, msg *pubsub.Message) {
+	if w.deliver(ctx, msg) {
+		msg.Ack()
+	} else {
+		msg.Nack()
+	}
+}
+
+// deliver handles one message, pulled or pushed, and reports whether to ack
+// it: it acks what succeeded or can be ignored, nacks transient failures for
+// redelivery, and dead-letters poison messages so they can't wedge the
+// subscription
+func (w *worker) deliver(ctx context.Context, msg *pubsub.Message) bool {
+	// Receive cancels ctx on shutdown. Handlers run on until the drain
+	// deadline instead, so they don't leave half-sent responses behind.
+	if ctx.Err() != nil {
+		return false
+	}
+	stopping := ctx.Done()
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	defer context.AfterFunc(w.drained, cancel)()
+
+	start := time.Now()
+	if !msg.PublishTime.IsZero() {
+		messageAge.Observe(start.Sub(msg.PublishTime).Seconds())
+	}
+	defer func() { messageDuration.Observe(time.Since(start).Seconds()) }()
+
+	// Continue the edge's trace from the message attributes
+	ctx, span := tracer.Start(extractTrace(ctx, msg.Attributes), "process interaction",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "gcp_pubsub"),
+			attribute.String("messaging.destination.subscription.name", w.subscription),
+			attribute.String("messaging.message.id", msg.ID),
+			attribute.String("discord.interaction_id", msg.Attributes["interaction_id"]),
+			attribute.String("discord.request_id", msg.Attributes["request_id"]),
+		))
+	defer span.End()
+	log := logger.With(traceAttrs(ctx, w.projectID)...).With(
+		"message_id", msg.ID,
+		"interaction_id", msg.Attributes["interaction_id"],
+		"request_id", msg.Attributes["request_id"],
+	)
+	ctx = withLogger(ctx, log)
+
+	// The edge always sets interaction_id; without it there's nothing to
+	// dedupe on
+	if interactionID := msg.Attributes["interaction_id"]; w.idempotency != nil && interactionID != "" {
+		result, owner := w.idempotency.Claim(ctx, interactionID)
+		switch result {
+		case claimDone:
+			log.Info("Skipping duplicate delivery of handled interaction")
+			w.record(ctx, msg, "duplicate", nil, w.currentAttempt(msg), start)
+			w.attempts.Delete(msg.ID)
+			return true
+		case claimHeld:
+			// Not a failure: if that delivery fails, this one is needed.
+			// Pausing first keeps an immediate redelivery from spinning
+			// until the claim is finished.
+			log.Info("Interaction is being handled by another delivery, will retry")
+			select {
+			case <-stopping:
+			case <-time.After(heldRetryDelay):
+			}
+			w.record(ctx, msg, "held", nil, w.currentAttempt(msg), start)
+			return false
+		}
+		err := w.handle(ctx, msg)
+		w.idempotency.Finish(ctx, interactionID, owner, err == nil)
+		return w.settle(ctx, msg, start, err)
+	}
+	return w.settle(ctx, msg, start, w.handle(ctx, msg))
+}
+
+// settle reports whether to ack a handled message: what succeeded or can be
+// ignored is acked, transient failures are nacked for redelivery, and the
+// rest is dead-lettered and acked
+func (w *worker) settle(ctx context.Context, msg *pubsub.Message, start time.Time, err *processError) bool {
+	log := loggerFrom(ctx)
+	if err == nil {
+		w.record(ctx, msg, "handled", nil, w.currentAttempt(msg), start)
+		w.attempts.Delete(msg.ID)
+		return true
+	}
+	if w.drained.Err() != nil {
+		// Cut off by shutdown rather than failed; another instance gets it
+		log.Warn("Drain timeout passed, returning message", "reason", err.reason, "error", err.err)
+		w.record(ctx, msg, "interrupted", err, w.currentAttempt(msg), start)
+		return false
+	}
+	failuresTotal.WithLabelValues(err.reason).Inc()
+	span := trace.SpanFromContext(ctx)
+	span.SetStatus(codes.Error, err.reason)
+	span.SetAttributes(attribute.String("discord.failure_reason", err.reason))
+
+	attempt := w.deliveryAttempt(msg)
+	if !err.permanent && attempt < w.maxAttempts {
+		log.Warn("Processing failed, will retry", "reason", err.reason, "attempt", attempt, "error", err.err)
+		w.record(ctx, msg, "retried", err, attempt, start)
+		return false
+	}
+	w.attempts.Delete(msg.ID)
+	if w.deadLetters == nil {
+		log.Error("Dropping message that can't be processed", "reason", err.reason, "attempt", attempt, "error", err.err)
+		w.record(ctx, msg, "dropped", err, attempt, start)
+		return true
+	}
+	if dlErr := w.deadLetters.Publish(ctx, msg, err, attempt); dlErr != nil {
+		// Keep the message rather than lose it; it comes back after the
+		// ack deadline
+		log.Error("Failed to dead-letter message", "reason", err.reason, "error", dlErr)
+		w.record(ctx, msg, "dead_letter_failed", err, attempt, start)
+		return false
+	}
+	log.Error("Dead-lettered message", "reason", err.reason, "attempt", attempt, "error", err.err)
+	w.record(ctx, msg, "dead_lettered", err, attempt, start)
+	return true
+}
+
+// handle runs the interaction's handler and sends its response
+func (w *worker) handle(ctx context.Context, msg *pubsub.Message) *processError {
+	log := loggerFrom(ctx)
+
+	data, err := payload.Parse(msg.Attributes, msg.Data)
+	if err != nil {
+		return &processError{reason: failureParse, permanent: true, err: err}
+	}
+	interaction := Interaction{Interaction: *data}
+	if interaction.Type != InteractionTypeApplicationCommand {
+		log.Debug("Ignoring interaction type", "interaction_type", interaction.Type)
+		return nil
+	}
+	if interaction.SealedToken == "" {
+		return &processError{reason: failureToken, permanent: true,
+			err: errors.New("no sealed_token; is TOKEN_SEAL_PUBLIC_KEY set on the edge?")}
+	}
+	token, err := w.tokens.Open(interaction.SealedToken)
+	if err != nil {
+		return &processError{reason: failureToken, permanent: true, err: err}
+	}
+
+	resp, err := w.runHandler(ctx, interaction)
+	if perr, ok := err.(*processError); ok {
+		return perr
+	}
+	if err == nil {
+		// Discord would reject it; a retry wouldn't change that
+		if err = resp.Validate(); err != nil {
+			err = fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	if err == nil {
+		err = attachments.Load(ctx, &resp)
+	}
+	if err != nil {
+		log.Error("Command handler failed", "command", interaction.CommandPath(), "error", err)
+		resp = Response{Content: interaction.Localize(msgHandlerFailed)}
+	}
+	if err := discord.EditOriginal(ctx, interaction.ApplicationID, token, resp); err != nil {
+		return discordFailure(err)
+	}
+	for _, followUp := range resp.FollowUps {
+		if err := discord.CreateFollowup(ctx, interaction.ApplicationID, token, followUp); err != nil {
+			return discordFailure(err)
+		}
+	}
+	log.Info("Interaction handled", "command", interaction.CommandPath())
+	return nil
+}
+
+// discordFailure classifies a failed Discord API call. Discord won't accept
+// a retry of a rejected request, e.g. once the token has expired.
+func discordFailure(err error) *processError {
+	var apiErr *discordAPIError
+	if errors.As(err, &apiErr) && apiErr.Status >= 400 && apiErr.Status < 500 && apiErr.Status != 429 {
+		return &processError{reason: failureDiscordRejects, permanent: true, err: err}
+	}
+	return &processError{reason: failureDiscordError, err: err}
+}
+
+// runHandler runs the command's handler, turning a panic into a permanent
+// failure: the same message would panic again
+func (w *worker) runHandler(ctx context.Context, interaction Interaction) (resp Response, err error) {
+	name, h := w.handlers.Route(interaction)
+	if name == "" {
+		name = "unknown"
+	}
+	ctx, span := tracer.Start(ctx, "handle "+name,
+		trace.WithAttributes(attribute.String("discord.command", interaction.CommandPath())))
+	start := time.Now()
+	defer func() {
+		result := "ok"
+		if r := recover(); r != nil {
+			result = "panic"
+			err = &processError{reason: failureHandlerPanic, permanent: true, err: fmt.Errorf("panic: %v", r)}
+		} else if err != nil {
+			result = "error"
+		}
+		if err != nil {
+			span.SetStatus(codes.Error, result)
+		}
+		span.End()
+		handlerDuration.WithLabelValues(name, result).Observe(time.Since(start).Seconds())
+	}()
+	return h.Handle(ctx, interaction)
+}
+
+// record counts how a delivery ended and, with RESULTS_TOPIC set, publishes
+// it as a result event
+func (w *worker) record(ctx context.Context, msg *pubsub.Message, outcome string, failure *processError, attempt int, start time.Time) {
+	messagesTotal.WithLabelValues(outcome).Inc()
+	if w.results != nil {
+		w.results.Publish(ctx, msg, outcome, failure, attempt, start)
+	}
+}
+
+// currentAttempt is deliveryAttempt for deliveries that didn't fail, which
+// aren't counted
+func (w *worker) currentAttempt(msg *pubsub.Message) int {
+	if msg.DeliveryAttempt != nil {
+		return *msg.DeliveryAttempt
+	}
+	if prev, ok := w.attempts.Load(msg.ID); ok {
+		return prev.(int) + 1
+	}
+	return 1
+}
+
+// deliveryAttempt returns which delivery of msg this is. Pub/Sub reports it
+// when the subscription has a dead letter policy; otherwise deliveries to
+// this instance are counted.
+func (w *worker) deliveryAttempt(msg *pubsub.Message) int {
+	if msg.DeliveryAttempt != nil {
+		return *msg.DeliveryAttempt
+	}
+	for {
+		prev, loaded := w.attempts.LoadOrStore(msg.ID, 1)
+		if !loaded {
+			return 1
+		}
+		if w.attempts.CompareAndSwap(msg.ID, prev, prev.(int)+1) {
+			return prev.(int) + 1
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// TestDeliveryAttempt checks attempts are Pub/Sub's when it reports them,
+// and otherwise counted per message, with currentAttempt not counting
+func TestDeliveryAttempt(t *testing.T) {
+	reported := 4
+	for _, tc := range []struct {
+		name string
+		msg  *pubsub.Message
+		// Calls in order: "delivery" or "current", and the attempt each returns
+		calls []string
+		want  []int
+	}{
+		{
+			name:  "reported by Pub/Sub",
+			msg:   &pubsub.Message{ID: "m1", DeliveryAttempt: &reported},
+			calls: []string{"delivery", "current", "delivery"},
+			want:  []int{4, 4, 4},
+		},
+		{
+			name:  "counted",
+			msg:   &pubsub.Message{ID: "m2"},
+			calls: []string{"current", "delivery", "current", "delivery", "delivery", "current"},
+			want:  []int{1, 1, 2, 2, 3, 4},
+		},
+	} {
+		w := &worker{}
+		for i, call := range tc.calls {
+			var got int
+			if call == "delivery" {
+				got = w.deliveryAttempt(tc.msg)
+			} else {
+				got = w.currentAttempt(tc.msg)
+			}
+			if got != tc.want[i] {
+				t.Errorf("%s: call %d (%s): expected attempt %d, got %d", tc.name, i+1, call, tc.want[i], got)
+			}
+		}
+	}
+
+	// Messages are counted separately
+	w := &worker{}
+	w.deliveryAttempt(&pubsub.Message{ID: "a"})
+	if got := w.deliveryAttempt(&pubsub.Message{ID: "b"}); got != 1 {
+		t.Errorf("expected a new message's first attempt to be 1, got %d", got)
+	}
+}
@@ -7,9 +7,13 @@ This service handles Discord interactions webhooks:
 - Publishes sanitized slash command payloads to Pub/Sub
 """
 
+import codecs
 import json
 import os
+import re
+import secrets
 import time
+import zlib
 from functools import wraps
 
 from flask import Flask, g, request
@@ -18,6 +22,7 @@ from nacl.exceptions import BadSignatureError
 from nacl.signing import VerifyKey
 
 app = Flask(__name__)
+app.config["MAX_CONTENT_LENGTH"] = int(os.environ.get("MAX_BODY_BYTES", str(1 << 20)))
 
 PUBLIC_KEY_HEX = os.environ.get("DISCORD_PUBLIC_KEY")
 if not PUBLIC_KEY_HEX:
@@ -48,9 +53,45 @@ def get_pubsub():
     return app.pubsub
 
 
+class BodyError(Exception):
+    """A request body that can't be read, answered with a JSON error."""
+
+    def __init__(self, status: int, code: str, message: str):
+        super().__init__(message)
+        self.status = status
+        self.code = code
+        self.message = message
+
+
+def decode_body(data: bytes) -> bytes:
+    """Undo the request's Content-Encoding.
+
+    Discord never compresses bodies, but proxies in front of the service
+    sometimes do. A gzip body is decompressed, bounded by MAX_CONTENT_LENGTH
+    so a small body can't expand without bound, and the signature is checked
+    against the result: Discord signed the body before it was compressed.
+    """
+    encoding = request.headers.get("Content-Encoding", "").strip().lower()
+    if encoding in ("", "identity"):
+        return data
+    if encoding not in ("gzip", "x-gzip"):
+        raise BodyError(415, "unsupported_content_encoding", "Content-Encoding must be gzip or identity")
+    limit = app.config["MAX_CONTENT_LENGTH"]
+    decompressor = zlib.decompressobj(16 + zlib.MAX_WBITS)
+    try:
+        body = decompressor.decompress(data, limit + 1)
+    except zlib.error:
+        raise BodyError(400, "invalid_body", "failed to read body") from None
+    if len(body) > limit:
+        raise BodyError(413, "body_too_large", "request body too large")
+    if not decompressor.eof:
+        raise BodyError(400, "invalid_body", "failed to read body")
+    return body
+
+
 def get_raw_body() -> bytes:
     if not hasattr(g, "raw_body"):
-        g.raw_body = request.get_data(cache=True) or b""
+        g.raw_body = decode_body(request.get_data(cache=True) or b"")
     return g.raw_body
 
 
@@ -86,11 +127,47 @@ def is_valid_signature() -> bool:
     return True
 
 
+def error_response(status: int, code: str, message: str) -> tuple[dict[str, str], int]:
+    """Return the JSON error body every failed request gets, with a stable code."""
+    return {"error": message, "code": code}, status
+
+
+def reject_duplicate_keys(pairs: list[tuple[str, object]]) -> dict[str, object]:
+    obj = {}
+    for key, value in pairs:
+        if key in obj:
+            raise ValueError("duplicate key in JSON object")
+        obj[key] = value
+    return obj
+
+
+def parse_interaction(raw: bytes) -> dict[str, object] | None:
+    """Parse a body as a single JSON object, or return None.
+
+    Parsers disagree about repeated keys, a byte order mark and data after
+    the document, so those are rejected rather than read one way here and
+    another elsewhere. Nesting too deep to parse is rejected the same way.
+    """
+    if raw.startswith(codecs.BOM_UTF8):
+        return None
+    try:
+        payload = json.loads(raw.decode("utf-8"), object_pairs_hook=reject_duplicate_keys)
+    except (ValueError, RecursionError):
+        return None
+    return payload if isinstance(payload, dict) else None
+
+
+def is_json_content_type() -> bool:
+    """Whether the request is application/json, in UTF-8 if it names a charset."""
+    charset = request.mimetype_params.get("charset")
+    return request.mimetype == "application/json" and (charset is None or charset.lower() == "utf-8")
+
+
 def require_valid_signature(view):
     @wraps(view)
     def wrapper(*args, **kwargs):
         if not is_valid_signature():
-            return {"error": "invalid signature"}, 401
+            return error_response(401, "invalid_signature", "invalid signature")
         return view(*args, **kwargs)
 
     return wrapper
@@ -130,12 +207,67 @@ def publish_interaction(payload: dict) -> None:
     command_name = data_payload.get("name")
     if command_name:
         attributes["command_name"] = str(command_name)
+    if "request_id" in g:
+        attributes["request_id"] = g.request_id
     try:
         client.publish(topic_path, data, **attributes)
     except Exception:
         return
 
 
+@app.errorhandler(BodyError)
+def body_error(error: BodyError):
+    return error_response(error.status, error.code, error.message)
+
+
+@app.errorhandler(404)
+def not_found(_error):
+    return error_response(404, "not_found", "not found")
+
+
+@app.errorhandler(405)
+def method_not_allowed(error):
+    response = app.make_response(error_response(405, "method_not_allowed", "method not allowed"))
+    response.headers["Allow"] = ", ".join(error.valid_methods or [])
+    return response
+
+
+@app.errorhandler(413)
+def body_too_large(_error):
+    return error_response(413, "body_too_large", "request body too large")
+
+
+@app.errorhandler(500)
+def internal_error(_error):
+    return error_response(500, "internal_error", "internal server error")
+
+
+REQUEST_ID_PATTERN = re.compile(r"[A-Za-z0-9_.:/+=-]{1,128}")
+
+
+@app.before_request
+def assign_request_id() -> None:
+    """Take the ingress's X-Request-ID, else the trace ID, else a new one."""
+    request_id = request.headers.get("X-Request-ID", "")
+    if not REQUEST_ID_PATTERN.fullmatch(request_id):
+        request_id = request.headers.get("X-Cloud-Trace-Context", "").split("/")[0]
+    if not REQUEST_ID_PATTERN.fullmatch(request_id):
+        request_id = secrets.token_hex(16)
+    g.request_id = request_id
+
+
+@app.after_request
+def security_headers(response):
+    """Responses are JSON for Discord, never pages to sniff or cache."""
+    response.headers["X-Content-Type-Options"] = "nosniff"
+    response.headers["Cache-Control"] = "no-store"
+    response.headers["Content-Security-Policy"] = "default-src 'none'; frame-ancestors 'none'"
+    response.headers["Referrer-Policy"] = "no-referrer"
+    if "request_id" in g:
+        response.headers["X-Request-ID"] = g.request_id
+    return response
+
+
 @app.get("/health")
 def health() -> tuple[dict[str, str], int]:
     return {"status": "ok"}, 200
@@ -144,18 +276,22 @@ def health() -> tuple[dict[str, str], int]:
 @app.post("/interactions")
 @require_valid_signature
 def interactions() -> tuple[dict[str, str], int]:
-    payload = request.get_json(silent=True)
-    if not isinstance(payload, dict):
-        return {"error": "invalid request body"}, 400
+    # Only JSON is parsed as an interaction: form and multipart bodies are
+    # never parsed, even when signed
+    if not is_json_content_type():
+        return error_response(400, "unsupported_content_type", "Content-Type must be application/json")
+    payload = parse_interaction(get_raw_body())
+    if payload is None:
+        return error_response(400, "invalid_body", "invalid request body")
     interaction_type = payload.get("type")
+    if interaction_type is not None and (not isinstance(interaction_type, int) or isinstance(interaction_type, bool)):
+        return error_response(400, "invalid_body", "type must be an integer")
     if interaction_type == 1:
         return {"type": 1}, 200
     if interaction_type == 2:
         publish_interaction(payload)
         return {"type": 5}, 200
-    if interaction_type is None:
-        return {"error": "missing type"}, 400
-    return {"error": "unsupported interaction type"}, 400
+    return error_response(400, "unsupported_interaction_type", "unsupported interaction type")
 
 
 @app.post("/")
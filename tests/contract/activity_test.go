@@ -0,0 +1,83 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// Activity tests only run against services configured to launch an Activity.
+// CONTRACT_TEST_ACTIVITY_COMMAND names a slash command,
+// CONTRACT_TEST_ACTIVITY_CUSTOM_ID a component custom_id, and
+// CONTRACT_TEST_ENTRY_POINT_COMMAND the app's primary entry point command
+// that the service answers with LAUNCH_ACTIVITY.
+
+// assertLaunchActivity checks the response body is exactly {"type": 12}
+func assertLaunchActivity(t *testing.T, resp *http.Response, respBody []byte) {
+	t.Helper()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", resp.StatusCode)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(respBody, &body); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// LAUNCH_ACTIVITY carries no data; Discord rejects anything else
+	if len(body) != 1 || body["type"] != float64(12) {
+		t.Errorf("Expected exactly {\"type\":12}, got %s", string(respBody))
+	}
+}
+
+func TestActivity_CommandLaunchesActivity(t *testing.T) {
+	command := os.Getenv("CONTRACT_TEST_ACTIVITY_COMMAND")
+	if command == "" {
+		t.Skip("CONTRACT_TEST_ACTIVITY_COMMAND not set")
+	}
+
+	body := toJSON(t, createSlashCommandRequest(command))
+	resp, respBody := sendRequest(t, body)
+
+	assertLaunchActivity(t, resp, respBody)
+}
+
+func TestActivity_ComponentLaunchesActivity(t *testing.T) {
+	customID := os.Getenv("CONTRACT_TEST_ACTIVITY_CUSTOM_ID")
+	if customID == "" {
+		t.Skip("CONTRACT_TEST_ACTIVITY_CUSTOM_ID not set")
+	}
+
+	req := InteractionRequest{
+		Type:          3, // Message Component
+		ID:            fmt.Sprintf("test-interaction-%d", time.Now().UnixNano()),
+		ApplicationID: "test-app-id",
+		Token:         "sensitive-token-should-be-redacted",
+		Data: map[string]interface{}{
+			"custom_id":      customID,
+			"component_type": 2, // Button
+		},
+		GuildID:   "test-guild-id",
+		ChannelID: "test-channel-id",
+	}
+	resp, respBody := sendRequest(t, toJSON(t, req))
+
+	assertLaunchActivity(t, resp, respBody)
+}
+
+func TestActivity_EntryPointLaunchesActivity(t *testing.T) {
+	command := os.Getenv("CONTRACT_TEST_ENTRY_POINT_COMMAND")
+	if command == "" {
+		t.Skip("CONTRACT_TEST_ENTRY_POINT_COMMAND not set")
+	}
+
+	req := createSlashCommandRequest(command)
+	req.Data["type"] = 4 // PRIMARY_ENTRY_POINT
+	resp, respBody := sendRequest(t, toJSON(t, req))
+
+	assertLaunchActivity(t, resp, respBody)
+}
@@ -0,0 +1,189 @@
+package contract
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/testkeys"
+)
+
+// Chunked transfer encoding tests send interactions without a
+// Content-Length, in chunks the service must reassemble before checking the
+// signature. They write to a raw connection so the chunk boundaries are the
+// test's rather than the HTTP client's.
+
+// chunkedRequest is an interaction sent with Transfer-Encoding: chunked
+type chunkedRequest struct {
+	body      []byte
+	chunkSize int
+	signature string
+	timestamp string
+	// extension is appended to every chunk's size line, e.g. ";name=value"
+	extension string
+	// trailer is a header field sent after the last chunk, e.g. "Name: value"
+	trailer string
+}
+
+// signedChunked returns a request for body signed with the test key
+func signedChunked(body []byte, chunkSize int) chunkedRequest {
+	signature, timestamp := testkeys.SignRequest(body)
+	return chunkedRequest{body: body, chunkSize: chunkSize, signature: signature, timestamp: timestamp}
+}
+
+// sendChunked sends a request's body chunkSize bytes at a time, flushing
+// each chunk so the service reads them separately, and returns the response
+func sendChunked(t *testing.T, r chunkedRequest) (*http.Response, []byte) {
+	t.Helper()
+
+	target, err := url.Parse(interactionsURL)
+	if err != nil {
+		t.Fatalf("Invalid interactions URL %q: %v", interactionsURL, err)
+	}
+	conn := dialTarget(t, target)
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatalf("Failed to set connection deadline: %v", err)
+	}
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "POST %s HTTP/1.1\r\nHost: %s\r\nContent-Type: application/json\r\n", target.RequestURI(), target.Host)
+	fmt.Fprintf(w, "Transfer-Encoding: chunked\r\nConnection: close\r\n")
+	fmt.Fprintf(w, "X-Signature-Ed25519: %s\r\nX-Signature-Timestamp: %s\r\n", r.signature, r.timestamp)
+	if r.trailer != "" {
+		name, _, _ := strings.Cut(r.trailer, ":")
+		fmt.Fprintf(w, "Trailer: %s\r\n", name)
+	}
+	fmt.Fprintf(w, "\r\n")
+	for rest := r.body; len(rest) > 0; {
+		chunk := rest[:min(r.chunkSize, len(rest))]
+		rest = rest[len(chunk):]
+		fmt.Fprintf(w, "%x%s\r\n%s\r\n", len(chunk), r.extension, chunk)
+		if err := w.Flush(); err != nil {
+			t.Fatalf("Failed to send chunk: %v", err)
+		}
+	}
+	fmt.Fprintf(w, "0%s\r\n", r.extension)
+	if r.trailer != "" {
+		fmt.Fprintf(w, "%s\r\n", r.trailer)
+	}
+	fmt.Fprintf(w, "\r\n")
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Failed to send last chunk: %v", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	return resp, respBody
+}
+
+// assertResponseType checks a response is 200 with the given interaction
+// response type
+func assertResponseType(t *testing.T, resp *http.Response, respBody []byte, responseType int) {
+	t.Helper()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Type int `json:"type"`
+	}
+	if err := json.Unmarshal(respBody, &body); err != nil {
+		t.Fatalf("Response is not valid JSON: %v", err)
+	}
+	if body.Type != responseType {
+		t.Errorf("Expected response type %d, got %d", responseType, body.Type)
+	}
+}
+
+// chunkedOptionValue has multi-byte characters, so one-byte chunks split
+// them between chunks
+const chunkedOptionValue = "héllo wörld ✓ 🎲"
+
+// chunkedSlashCommand returns a slash command with chunkedOptionValue as
+// an option
+func chunkedSlashCommand() InteractionRequest {
+	req := createSlashCommandRequest("chunked-test")
+	req.Data["options"] = []map[string]interface{}{{"name": "text", "type": 3, "value": chunkedOptionValue}}
+	return req
+}
+
+// TestChunked_Ping sends a ping in several chunks
+func TestChunked_Ping(t *testing.T) {
+	body := toJSON(t, InteractionRequest{Type: 1, ID: "chunked-ping", ApplicationID: "test-app-id"})
+	resp, respBody := sendChunked(t, signedChunked(body, 7))
+	assertResponseType(t, resp, respBody, 1)
+}
+
+// TestChunked_SlashCommand sends a slash command a byte per chunk
+func TestChunked_SlashCommand(t *testing.T) {
+	resp, respBody := sendChunked(t, signedChunked(toJSON(t, chunkedSlashCommand()), 1))
+	assertResponseType(t, resp, respBody, 5)
+}
+
+// TestChunked_SlashCommandPublished checks a slash command sent a byte per
+// chunk is published whole, its multi-byte option value intact
+func TestChunked_SlashCommandPublished(t *testing.T) {
+	sub, cleanup := serviceTopicSubscription(t, "CONTRACT_TEST_TOPIC")
+	defer cleanup()
+
+	req := chunkedSlashCommand()
+	resp, respBody := sendChunked(t, signedChunked(toJSON(t, req), 1))
+	assertResponseType(t, resp, respBody, 5)
+
+	msg := receiveInteraction(t, sub, req.ID, 10*time.Second)
+	var published struct {
+		Data struct {
+			Options []struct {
+				Value string `json:"value"`
+			} `json:"options"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(decodeMessageData(t, msg), &published); err != nil {
+		t.Fatalf("Published data is not valid JSON: %v", err)
+	}
+	if len(published.Data.Options) != 1 || published.Data.Options[0].Value != chunkedOptionValue {
+		t.Errorf("Expected the option value %q published, got options %+v", chunkedOptionValue, published.Data.Options)
+	}
+}
+
+// TestChunked_TamperedChunkRejected signs a ping, then changes its last
+// chunk, so a service that verified anything short of the reassembled body
+// would accept it
+func TestChunked_TamperedChunkRejected(t *testing.T) {
+	body := toJSON(t, InteractionRequest{Type: 1, ID: "chunked-tampered", ApplicationID: "test-app-id"})
+	r := signedChunked(body, 8)
+	tampered := append([]byte(nil), body...)
+	tampered[len(tampered)-3] ^= 0x01 // the application ID's last letter, in the last chunk
+	r.body = tampered
+
+	resp, respBody := sendChunked(t, r)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 for a tampered chunk, got %d", resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "invalid_signature")
+}
+
+// TestChunked_ExtensionsAndTrailer sends a ping with a chunk extension on
+// every chunk and a trailer field, both of which a service must ignore
+func TestChunked_ExtensionsAndTrailer(t *testing.T) {
+	body := toJSON(t, InteractionRequest{Type: 1, ID: "chunked-extensions", ApplicationID: "test-app-id"})
+	r := signedChunked(body, 16)
+	r.extension = ";contract-test=1"
+	r.trailer = "X-Contract-Trailer: 1"
+	resp, respBody := sendChunked(t, r)
+	assertResponseType(t, resp, respBody, 1)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/testkeys"
+)
+
+// servicePort is the port services listen on inside their container
+const servicePort = "8080"
+
+// hostGateway is how a container reaches the host, for an emulator
+// listening on the host's loopback interface
+const hostGateway = "host.docker.internal"
+
+// serviceConfig is how to build and start a service under test
+type serviceConfig struct {
+	Name string // the service directory's name
+	Dir  string
+	// Topic, Project and EmulatorHost configure publishing; without an
+	// emulator the service runs without one. EmulatorHost is as the host
+	// sees it, or on Network if that is set.
+	Topic        string
+	Project      string
+	EmulatorHost string
+	Network      string
+	Env          []string // extra KEY=value settings
+	Verbose      bool     // stream the image build
+}
+
+// container is a service under test running in Docker
+type container struct {
+	name string
+	url  string // base URL the service is published on
+}
+
+// startService builds the service's image and starts it with the contract
+// tests' public key and the Pub/Sub settings, published on a free port of
+// the host's loopback interface. Once it returns a container, the caller
+// removes it, even with an error.
+func startService(ctx context.Context, cfg serviceConfig, stderr io.Writer) (*container, error) {
+	image := "contractctl-" + cfg.Name
+	fmt.Fprintf(stderr, "Building %s from %s\n", image, cfg.Dir)
+	build := exec.CommandContext(ctx, "docker", "build", "-t", image, "-f", filepath.Join(cfg.Dir, "Dockerfile"), cfg.Dir)
+	var buildOutput bytes.Buffer
+	if cfg.Verbose {
+		build.Stdout, build.Stderr = stderr, stderr
+	} else {
+		build.Stdout, build.Stderr = &buildOutput, &buildOutput
+	}
+	if err := build.Run(); err != nil {
+		stderr.Write(buildOutput.Bytes())
+		return nil, fmt.Errorf("building %s: %w", cfg.Name, err)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	c := &container{
+		name: fmt.Sprintf("contractctl-%s-%d", cfg.Name, os.Getpid()),
+		url:  "http://127.0.0.1:" + port,
+	}
+	args := []string{"run", "-d", "--name", c.name,
+		"-p", "127.0.0.1:" + port + ":" + servicePort,
+		"-e", "PORT=" + servicePort,
+		"-e", "DISCORD_PUBLIC_KEY=" + testkeys.TestPublicKeyHex,
+	}
+	if cfg.Network != "" {
+		args = append(args, "--network", cfg.Network)
+	}
+	if cfg.EmulatorHost != "" {
+		host, viaGateway := cfg.EmulatorHost, false
+		if cfg.Network == "" {
+			host, viaGateway = containerEmulatorHost(cfg.EmulatorHost)
+		}
+		if viaGateway {
+			args = append(args, "--add-host", hostGateway+":host-gateway")
+		}
+		args = append(args,
+			"-e", "PUBSUB_EMULATOR_HOST="+host,
+			"-e", "GOOGLE_CLOUD_PROJECT="+cfg.Project,
+			"-e", "PUBSUB_TOPIC="+cfg.Topic,
+		)
+	}
+	for _, kv := range cfg.Env {
+		args = append(args, "-e", kv)
+	}
+	args = append(args, image)
+
+	fmt.Fprintf(stderr, "Starting %s on %s\n", c.name, c.url)
+	if out, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput(); err != nil {
+		// The container may exist without having started
+		return c, fmt.Errorf("starting %s: %w: %s", cfg.Name, err, strings.TrimSpace(string(out)))
+	}
+	return c, nil
+}
+
+// containerEmulatorHost returns the emulator's address as the container
+// sees it: an emulator on the host's loopback interface is reached through
+// the host gateway
+func containerEmulatorHost(host string) (string, bool) {
+	h, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return host, false
+	}
+	if h == "localhost" || net.ParseIP(h).IsLoopback() {
+		return net.JoinHostPort(hostGateway, port), true
+	}
+	return host, false
+}
+
+// freePort returns a port nothing on the loopback interface is listening on
+func freePort() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("finding a free port: %w", err)
+	}
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// waitHealthy polls the service's /health until it answers 200, giving up
+// if the container stops or the timeout passes
+func (c *container) waitHealthy(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := &http.Client{Timeout: 2 * time.Second}
+	tick := time.NewTicker(500 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		resp, err := client.Get(c.url + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		if !c.running(ctx) {
+			return errors.New("the container exited")
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("not healthy after %s", timeout)
+			}
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// running reports whether the container is still running
+func (c *container) running(ctx context.Context) bool {
+	out, err := exec.CommandContext(ctx, "docker", "inspect", "-f", "{{.State.Running}}", c.name).Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// logs writes the container's output so far
+func (c *container) logs(w io.Writer) {
+	fmt.Fprintf(w, "\n--- logs of %s ---\n", c.name)
+	cmd := exec.Command("docker", "logs", c.name)
+	cmd.Stdout, cmd.Stderr = w, w
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(w, "(docker logs: %v)\n", err)
+	}
+	fmt.Fprintf(w, "--- end of logs ---\n\n")
+}
+
+// remove stops and deletes the container. It runs even once the run is
+// interrupted, so it doesn't use the run's context.
+func (c *container) remove() {
+	_ = exec.Command("docker", "rm", "-f", c.name).Run()
+}
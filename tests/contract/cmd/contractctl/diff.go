@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance"
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/report"
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/spec"
+)
+
+// defaultIgnore is what differs between any two runs, however alike the
+// services: when they published
+const defaultIgnore = "published.attributes.timestamp,published.attributes.ce-time"
+
+// diffSpec replays each requirement's request against the baseline and then
+// the candidate, collecting where their responses and published messages
+// diverge. Requirements covered by hand-written tests have no request and
+// are left out. With verbose set, each requirement is written to progress
+// as it is compared.
+func diffSpec(ctx context.Context, s *spec.Spec, baseline, candidate conformance.Target, ignore []string, progress io.Writer, verbose bool) (*report.Diff, error) {
+	d := &report.Diff{SpecVersion: s.Version}
+	for _, r := range s.Requirements {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if r.Request == nil {
+			continue
+		}
+		c, err := conformance.FromRequirement(s, r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.ID, err)
+		}
+
+		b, berr := conformance.Observe(baseline, c)
+		o, cerr := conformance.Observe(candidate, c)
+		var divergences []conformance.Divergence
+		if berr != nil || cerr != nil {
+			divergences = []conformance.Divergence{{Path: "request", Baseline: outcome(berr), Candidate: outcome(cerr)}}
+		} else {
+			divergences = conformance.Diff(b, o, ignore)
+		}
+		d.Compared++
+		if len(divergences) > 0 {
+			d.Requirements = append(d.Requirements, report.RequirementDiff{
+				ID:          r.ID,
+				Category:    r.Category,
+				Title:       r.Title,
+				Divergences: divergences,
+			})
+		}
+
+		if verbose {
+			label := "same"
+			if len(divergences) > 0 {
+				label = "DIFF"
+			}
+			fmt.Fprintf(progress, "%s %s %s\n", label, r.ID, r.Title)
+			for _, dv := range divergences {
+				fmt.Fprintf(progress, "     %s: %s | %s\n", dv.Path, dv.Baseline, dv.Candidate)
+			}
+		}
+	}
+	return d, nil
+}
+
+// outcome describes how sending a request went, for a divergence in
+// whether it could be sent at all
+func outcome(err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return "ok"
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// emulatorImage is the Cloud SDK image with the Pub/Sub emulator, as
+// docker-compose.test.yml uses
+const emulatorImage = "gcr.io/google.com/cloudsdktool/google-cloud-cli:emulators"
+
+// Ways to get a Pub/Sub emulator
+const (
+	emulatorAuto   = "auto"   // PUBSUB_EMULATOR_HOST if set, else start one with docker or gcloud
+	emulatorDocker = "docker" // start the emulator image
+	emulatorGcloud = "gcloud" // start gcloud beta emulators pubsub
+	emulatorOff    = "off"    // none: publish expectations are skipped
+)
+
+// emulatorAlias is the emulator's name on the Docker network it shares
+// with the service
+const emulatorAlias = "pubsub-emulator"
+
+// emulator is a Pub/Sub emulator contractctl started
+type emulator struct {
+	host string // as the host sees it
+	// network is the Docker network a containerized emulator is on, where
+	// the service reaches it at emulatorAlias
+	network string
+	stop    func()
+}
+
+// serviceHost returns the emulator's address as a service container on
+// its network, if it has one, sees it
+func (e *emulator) serviceHost() string {
+	if e.network != "" {
+		return emulatorAlias + ":8085"
+	}
+	return e.host
+}
+
+// startEmulator starts a Pub/Sub emulator on a free port of the loopback
+// interface, with docker or gcloud; waitEmulator waits for it to answer.
+// Auto mode uses whichever is installed, docker first. Once it returns an
+// emulator, the caller stops it, even with an error.
+func startEmulator(ctx context.Context, mode, project string, stderr io.Writer) (*emulator, error) {
+	if mode == emulatorAuto {
+		switch {
+		case installed("docker"):
+			mode = emulatorDocker
+		case installed("gcloud"):
+			mode = emulatorGcloud
+		default:
+			return nil, errors.New("no Pub/Sub emulator: set PUBSUB_EMULATOR_HOST, install docker or gcloud, or use -emulator off")
+		}
+	}
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	e := &emulator{host: "127.0.0.1:" + port}
+
+	fmt.Fprintf(stderr, "Starting the Pub/Sub emulator (%s) on %s\n", mode, e.host)
+	switch mode {
+	case emulatorDocker:
+		// On a network of its own with the service, as in
+		// docker-compose.test.yml, since a container can't reach a port
+		// published only on the host's loopback interface
+		name := fmt.Sprintf("contractctl-pubsub-%d", os.Getpid())
+		e.network = name
+		if out, err := exec.CommandContext(ctx, "docker", "network", "create", e.network).CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("creating a Docker network: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+		e.stop = func() {
+			_ = exec.Command("docker", "rm", "-f", name).Run()
+			_ = exec.Command("docker", "network", "rm", e.network).Run()
+		}
+		out, err := exec.CommandContext(ctx, "docker", "run", "-d", "--name", name,
+			"--network", e.network, "--network-alias", emulatorAlias, "-p", e.host+":8085", emulatorImage,
+			"gcloud", "beta", "emulators", "pubsub", "start", "--host-port=0.0.0.0:8085", "--project="+project,
+		).CombinedOutput()
+		if err != nil {
+			return e, fmt.Errorf("starting the Pub/Sub emulator: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+	case emulatorGcloud:
+		// On every interface, so the service's container can reach it
+		// through the host gateway
+		cmd := exec.Command("gcloud", "beta", "emulators", "pubsub", "start", "--host-port=0.0.0.0:"+port, "--project="+project)
+		setProcessGroup(cmd)
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("starting the Pub/Sub emulator: %w", err)
+		}
+		e.stop = func() {
+			killProcessGroup(cmd)
+			_ = cmd.Wait()
+		}
+	default:
+		return nil, fmt.Errorf("unknown -emulator %q: want auto, docker, gcloud or off", mode)
+	}
+	return e, nil
+}
+
+// waitEmulator polls the emulator through a client connected to it until
+// it answers
+func waitEmulator(ctx context.Context, client *pubsub.Client, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	tick := time.NewTicker(500 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		attempt, cancelAttempt := context.WithTimeout(ctx, 2*time.Second)
+		_, err := client.Topic("contractctl-ready").Exists(attempt)
+		cancelAttempt()
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("the Pub/Sub emulator isn't answering after %s", timeout)
+			}
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// createTopic creates the topic the service publishes to, so the suite can
+// subscribe to it before the service has published anything
+func createTopic(ctx context.Context, client *pubsub.Client, topic string) error {
+	_, err := client.CreateTopic(ctx, topic)
+	if status.Code(err) == codes.AlreadyExists {
+		return nil
+	}
+	return err
+}
+
+// installed reports whether a command is on the PATH
+func installed(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
@@ -0,0 +1,540 @@
+// Command contractctl runs the conformance specification against a service
+// and reports how it did, in one binary that needs neither the Go toolchain
+// nor the contract test sources:
+//
+//	contractctl -target http://localhost:8080
+//	contractctl -service go-gin -category security,protocol -v
+//	PUBSUB_EMULATOR_HOST=localhost:8085 contractctl -service python-flask
+//	contractctl -target https://bot.example.com -path /interactions -format markdown -o SCORECARD.md
+//	contractctl -matrix -format markdown -o MATRIX.md
+//	contractctl -target http://localhost:8080 -diff http://localhost:8081
+//
+// The requirements, their requests and expectations come from the embedded
+// specification, so contractctl checks exactly what the generated contract
+// tests do. Requirements covered by hand-written tests need go test and are
+// reported as skipped.
+//
+// -service builds the image of a directory under services/ and runs it
+// with Docker, configured with the contract tests' public key and the
+// Pub/Sub emulator, instead of testing a service already running at
+// -target. The container is removed afterwards; its logs are printed if it
+// fails to start or a requirement fails. Unless PUBSUB_EMULATOR_HOST names
+// one already running, contractctl also starts a Pub/Sub emulator for the
+// service, with docker or gcloud, and creates its topic, so publish
+// expectations are checked rather than skipped.
+//
+// -matrix does the same for every directory under services/ with a
+// Dockerfile, except those named by -exclude, one after another against a
+// shared emulator, and reports them together as a matrix of services and
+// requirements.
+//
+// -diff replays each requirement's request against -target, the baseline,
+// and a second service, the candidate, and reports every divergence in
+// their status codes, response bodies and published messages, whatever the
+// specification expects. Paths named by -ignore, such as attributes that
+// hold the time, are left out.
+//
+// Against -target, publish expectations are checked through the emulator
+// at PUBSUB_EMULATOR_HOST, on the topic named by -topic.
+//
+// The exit code is 0 when every requirement that ran passed, 1 when any
+// failed (or, with -strict, when any MUST requirement didn't pass), a
+// -matrix service couldn't be tested or -diff found a divergence, and 2
+// when the suite couldn't run.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance"
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/report"
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/spec"
+)
+
+// Exit codes
+const (
+	exitOK     = 0
+	exitFailed = 1 // a requirement failed, with -strict a service isn't conformant, or services diverged
+	exitError  = 2
+)
+
+// stringList is a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("contractctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	target := fs.String("target", envString("CONTRACT_TEST_TARGET", "http://localhost:8080"), "base URL of the service under test")
+	path := fs.String("path", os.Getenv("CONTRACT_TEST_PATH"), "path interactions are posted to, for services not serving them at /")
+	service := fs.String("service", "", "services/ directory to build and run in Docker, by name or path, instead of using -target")
+	matrix := fs.Bool("matrix", false, "build, run and test every services/ directory in turn, reporting them as a matrix")
+	diff := fs.String("diff", "", "base URL of a second service to compare with -target, replaying the same requests to both")
+	diffTopic := fs.String("diff-topic", "", "topic the -diff service publishes to (default: -topic)")
+	ignore := fs.String("ignore", defaultIgnore, "comma-separated paths -diff doesn't compare, with everything under them")
+	exclude := fs.String("exclude", "go-worker", "comma-separated services -matrix leaves out, such as those that aren't interaction endpoints")
+	var env stringList
+	fs.Var(&env, "env", "extra KEY=value setting for the -service container (repeatable)")
+	startupTimeout := fs.Duration("startup-timeout", 2*time.Minute, "how long the -service container and the emulator have to become ready")
+	emulatorMode := fs.String("emulator", emulatorAuto, "Pub/Sub emulator for -service and -matrix: auto (PUBSUB_EMULATOR_HOST, else start one), docker, gcloud or off")
+	categories := fs.String("category", "", "comma-separated categories to run (default: all)")
+	topic := fs.String("topic", os.Getenv("CONTRACT_TEST_TOPIC"), "topic the service publishes to, for publish expectations (default with -service and -matrix: discord-interactions)")
+	project := fs.String("project", envString("GOOGLE_CLOUD_PROJECT", "test-project"), "Pub/Sub emulator project")
+	timeout := fs.Duration("timeout", 10*time.Second, "request timeout")
+	verbose := fs.Bool("v", false, "print each requirement's result to stderr as it runs")
+	format := fs.String("format", "text", "report format: text, markdown or json")
+	out := fs.String("o", "", "write the report to this file instead of stdout")
+	badge := fs.String("badge", "", "also write an SVG badge of the overall score to this file")
+	strict := fs.Bool("strict", false, "exit 1 unless every selected MUST requirement passed")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "contractctl: unexpected argument %q\n", fs.Arg(0))
+		return exitError
+	}
+	switch *format {
+	case "text", "markdown", "json":
+	default:
+		fmt.Fprintf(stderr, "contractctl: unknown format %q\n", *format)
+		return exitError
+	}
+
+	s, err := spec.Load()
+	if err == nil {
+		s, err = s.Select(splitList(*categories))
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "contractctl: spec:", err)
+		return exitError
+	}
+	var name, dir string
+	var services []serviceDir
+	switch {
+	case *diff != "":
+		if *matrix || *service != "" || *badge != "" {
+			fmt.Fprintln(stderr, "contractctl: -diff can't be used with -matrix, -service or -badge")
+			return exitError
+		}
+	case *matrix:
+		if *service != "" || flagSet(fs, "target") || *badge != "" {
+			fmt.Fprintln(stderr, "contractctl: -matrix can't be used with -service, -target or -badge")
+			return exitError
+		}
+		if services, err = discoverServices(splitList(*exclude)); err != nil {
+			fmt.Fprintln(stderr, "contractctl:", err)
+			return exitError
+		}
+	case *service != "":
+		if flagSet(fs, "target") {
+			fmt.Fprintln(stderr, "contractctl: -target and -service can't be used together")
+			return exitError
+		}
+		if name, dir, err = resolveService(*service); err != nil {
+			fmt.Fprintln(stderr, "contractctl:", err)
+			return exitError
+		}
+	}
+	containers := *matrix || *service != ""
+	for _, kv := range env {
+		if !strings.Contains(kv, "=") {
+			fmt.Fprintf(stderr, "contractctl: -env %q: want KEY=value\n", kv)
+			return exitError
+		}
+	}
+
+	// An emulator contractctl starts is only of use to services it starts
+	emulatorHost := os.Getenv("PUBSUB_EMULATOR_HOST")
+	startEmulatorToo := false
+	switch *emulatorMode {
+	case emulatorOff:
+		emulatorHost = ""
+	case emulatorAuto:
+		startEmulatorToo = containers && emulatorHost == "" && publishes(s)
+	case emulatorDocker, emulatorGcloud:
+		if !containers {
+			fmt.Fprintf(stderr, "contractctl: -emulator %s needs -service or -matrix\n", *emulatorMode)
+			return exitError
+		}
+		startEmulatorToo = publishes(s)
+	default:
+		fmt.Fprintf(stderr, "contractctl: unknown -emulator %q: want auto, docker, gcloud or off\n", *emulatorMode)
+		return exitError
+	}
+	serviceEmulatorHost, network := emulatorHost, ""
+	if startEmulatorToo {
+		emu, err := startEmulator(ctx, *emulatorMode, *project, stderr)
+		if emu != nil {
+			defer emu.stop()
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "contractctl:", err)
+			return exitError
+		}
+		emulatorHost = emu.host
+		serviceEmulatorHost, network = emu.serviceHost(), emu.network
+		// The Pub/Sub client finds the emulator through the environment
+		os.Setenv("PUBSUB_EMULATOR_HOST", emulatorHost)
+	}
+	if containers && emulatorHost != "" && *topic == "" {
+		*topic = "discord-interactions"
+	}
+	var pubsubClient *pubsub.Client
+	if emulatorHost != "" {
+		pubsubClient, err = pubsub.NewClient(ctx, *project)
+		if err == nil && startEmulatorToo {
+			if err = waitEmulator(ctx, pubsubClient, *startupTimeout); err == nil {
+				err = createTopic(ctx, pubsubClient, *topic)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "contractctl: Pub/Sub:", err)
+			return exitError
+		}
+		defer pubsubClient.Close()
+	}
+	if publishes(s) && (pubsubClient == nil || *topic == "") {
+		fmt.Fprintln(stderr, "contractctl: publish expectations will be skipped: set PUBSUB_EMULATOR_HOST and -topic to check them")
+	}
+
+	if *diff != "" {
+		if *diffTopic == "" {
+			*diffTopic = *topic
+		}
+		baseline := conformance.Target{
+			URL:    strings.TrimSuffix(*target, "/") + *path,
+			Client: &http.Client{Timeout: *timeout},
+			PubSub: pubsubClient,
+			Topic:  *topic,
+		}
+		candidate := baseline
+		candidate.URL = strings.TrimSuffix(*diff, "/") + *path
+		candidate.Topic = *diffTopic
+		for _, t := range []conformance.Target{baseline, candidate} {
+			if err := checkReachable(t); err != nil {
+				fmt.Fprintln(stderr, "contractctl:", err)
+				return exitError
+			}
+		}
+		d, err := diffSpec(ctx, s, baseline, candidate, splitList(*ignore), stderr, *verbose)
+		if err != nil {
+			fmt.Fprintln(stderr, "contractctl:", err)
+			return exitError
+		}
+		d.Generated, d.Baseline, d.Candidate = time.Now(), *target, *diff
+		if err := writeReport(d, *format, *out, stdout); err != nil {
+			fmt.Fprintln(stderr, "contractctl:", err)
+			return exitError
+		}
+		if d.Diverged() {
+			return exitFailed
+		}
+		return exitOK
+	}
+
+	st := &suite{
+		spec:   s,
+		path:   *path,
+		client: &http.Client{Timeout: *timeout},
+		pubsub: pubsubClient,
+		topic:  *topic,
+		service: serviceConfig{
+			Topic:        *topic,
+			Project:      *project,
+			EmulatorHost: serviceEmulatorHost,
+			Network:      network,
+			Env:          env,
+			Verbose:      *verbose,
+		},
+		startupTimeout: *startupTimeout,
+		verbose:        *verbose,
+		stderr:         stderr,
+	}
+
+	if *matrix {
+		m := &report.Matrix{Generated: time.Now(), SpecVersion: s.Version}
+		for _, svc := range services {
+			fmt.Fprintf(stderr, "=== %s\n", svc.name)
+			sc, err := st.testService(ctx, svc.name, svc.dir)
+			if ctx.Err() != nil {
+				fmt.Fprintln(stderr, "contractctl:", ctx.Err())
+				return exitError
+			}
+			if err != nil {
+				fmt.Fprintln(stderr, "contractctl:", err)
+			}
+			m.Add(svc.name, sc, err)
+		}
+		if err := writeReport(m, *format, *out, stdout); err != nil {
+			fmt.Fprintln(stderr, "contractctl:", err)
+			return exitError
+		}
+		if m.Failed() || (*strict && !m.Conformant()) {
+			return exitFailed
+		}
+		return exitOK
+	}
+
+	var sc *report.Scorecard
+	if *service != "" {
+		sc, err = st.testService(ctx, name, dir)
+	} else {
+		sc, err = st.testTarget(ctx, *target, "")
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "contractctl:", err)
+		return exitError
+	}
+	if err := writeReport(sc, *format, *out, stdout); err != nil {
+		fmt.Fprintln(stderr, "contractctl:", err)
+		return exitError
+	}
+	if *badge != "" {
+		var svg bytes.Buffer
+		err := report.WriteBadge(&svg, "conformance", sc.Score)
+		if err == nil {
+			err = os.WriteFile(*badge, svg.Bytes(), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "contractctl:", err)
+			return exitError
+		}
+	}
+
+	if sc.Failed() || (*strict && !sc.Conformant) {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// suite is what testing each service shares
+type suite struct {
+	spec   *spec.Spec
+	path   string
+	client *http.Client
+	pubsub *pubsub.Client
+	topic  string
+	// service is how to start a service, but for its name and directory
+	service        serviceConfig
+	startupTimeout time.Duration
+	verbose        bool
+	stderr         io.Writer
+}
+
+// testService builds and starts a service, runs the specification against
+// it and removes it. Its logs are printed if it fails to start or a
+// requirement fails. Errors name the service.
+func (st *suite) testService(ctx context.Context, name, dir string) (*report.Scorecard, error) {
+	cfg := st.service
+	cfg.Name, cfg.Dir = name, dir
+	c, err := startService(ctx, cfg, st.stderr)
+	if c != nil {
+		defer c.remove()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.waitHealthy(ctx, st.startupTimeout); err != nil {
+		c.logs(st.stderr)
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	sc, err := st.testTarget(ctx, c.url, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if sc.Failed() {
+		c.logs(st.stderr)
+	}
+	return sc, nil
+}
+
+// testTarget runs the specification against a service listening at
+// baseURL and scores it
+func (st *suite) testTarget(ctx context.Context, baseURL, name string) (*report.Scorecard, error) {
+	t := conformance.Target{
+		URL:    strings.TrimSuffix(baseURL, "/") + st.path,
+		Client: st.client,
+		PubSub: st.pubsub,
+		Topic:  st.topic,
+	}
+	if err := checkReachable(t); err != nil {
+		return nil, err
+	}
+	results, err := runSpec(ctx, st.spec, t, st.stderr, st.verbose)
+	if err != nil {
+		return nil, err
+	}
+	return report.Score(st.spec, results, name, time.Now()), nil
+}
+
+// flagSet reports whether a flag was given on the command line
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
+
+// resolveService finds a service directory, given as a path or as the name
+// of a directory under services/, and returns its name and path
+func resolveService(service string) (name, dir string, err error) {
+	dir = service
+	if _, err := os.Stat(dir); err != nil {
+		root, err := repoRoot()
+		if err != nil {
+			return "", "", fmt.Errorf("service %s: %w", service, err)
+		}
+		dir = filepath.Join(root, "services", service)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", "", fmt.Errorf("service %s: no such service directory", service)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Dockerfile")); err != nil {
+		return "", "", fmt.Errorf("service %s: no Dockerfile in %s", service, dir)
+	}
+	return filepath.Base(filepath.Clean(dir)), dir, nil
+}
+
+// serviceDir is a service directory -matrix tests
+type serviceDir struct {
+	name, dir string
+}
+
+// discoverServices returns the directories under services/ with a
+// Dockerfile, by name, leaving out the excluded ones
+func discoverServices(exclude []string) ([]serviceDir, error) {
+	root, err := repoRoot()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "services"))
+	if err != nil {
+		return nil, err
+	}
+	var services []serviceDir
+	for _, e := range entries {
+		if !e.IsDir() || slices.Contains(exclude, e.Name()) {
+			continue
+		}
+		dir := filepath.Join(root, "services", e.Name())
+		if _, err := os.Stat(filepath.Join(dir, "Dockerfile")); err != nil {
+			continue
+		}
+		services = append(services, serviceDir{name: e.Name(), dir: dir})
+	}
+	if len(services) == 0 {
+		return nil, errors.New("no services with a Dockerfile under services/")
+	}
+	return services, nil
+}
+
+// repoRoot returns the nearest directory above the working directory with
+// a services directory
+func repoRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if info, err := os.Stat(filepath.Join(dir, "services")); err == nil && info.IsDir() {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("not in the repository: no services directory above the working directory")
+		}
+		dir = parent
+	}
+}
+
+// checkReachable fails fast when nothing is listening at the target, which
+// would otherwise fail every requirement
+func checkReachable(t conformance.Target) error {
+	resp, err := t.Client.Get(t.URL)
+	if err != nil {
+		return fmt.Errorf("target not reachable: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// publishes reports whether any requirement checks what is published
+func publishes(s *spec.Spec) bool {
+	for _, r := range s.Requirements {
+		if r.Publish != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// reporter is a report contractctl writes: a scorecard, a matrix of them or
+// a diff
+type reporter interface {
+	WriteText(io.Writer) error
+	WriteMarkdown(io.Writer) error
+	WriteJSON(io.Writer) error
+}
+
+// writeReport writes a report in a format, to a file or stdout
+func writeReport(r reporter, format, out string, stdout io.Writer) error {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "markdown":
+		err = r.WriteMarkdown(&buf)
+	case "json":
+		err = r.WriteJSON(&buf)
+	default:
+		err = r.WriteText(&buf)
+	}
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(out, buf.Bytes(), 0o644)
+}
+
+// splitList splits a comma-separated flag, dropping empty items
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// envString returns the value of an environment variable or a default
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
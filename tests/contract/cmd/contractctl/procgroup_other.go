@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+func setProcessGroup(*exec.Cmd) {}
+
+// killProcessGroup kills the command; what it started may outlive it
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance"
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/report"
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/spec"
+)
+
+// caseT collects what a case reports, standing in for *testing.T
+type caseT struct {
+	failed   bool
+	skipped  bool
+	messages []string // errors, or why it was skipped
+	logs     []string
+}
+
+func (t *caseT) Helper() {}
+
+func (t *caseT) Errorf(format string, args ...any) {
+	t.failed = true
+	t.messages = append(t.messages, fmt.Sprintf(format, args...))
+}
+
+func (t *caseT) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (t *caseT) Skip(args ...any) {
+	t.skipped = true
+	t.messages = append(t.messages, fmt.Sprint(args...))
+	runtime.Goexit()
+}
+
+func (t *caseT) Logf(format string, args ...any) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+// runCase runs a case on its own goroutine, so Fatalf and Skip can end it
+func runCase(target conformance.Target, c conformance.Case) *caseT {
+	t := &caseT{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conformance.Run(t, target, c)
+	}()
+	<-done
+	return t
+}
+
+// runSpec runs each requirement of the specification in turn, returning
+// the results by test name. Requirements covered by hand-written tests need
+// go test and are skipped. It stops early if ctx is cancelled. With verbose set, each result is written to
+// progress as it finishes.
+func runSpec(ctx context.Context, s *spec.Spec, target conformance.Target, progress io.Writer, verbose bool) (map[string]report.Result, error) {
+	results := make(map[string]report.Result, len(s.Requirements))
+	for _, r := range s.Requirements {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if r.Request == nil {
+			res := report.Result{
+				Status: report.StatusSkip,
+				Reason: fmt.Sprintf("hand-written test: run go test -run '^%s$' in tests/contract", r.TestName()),
+			}
+			if r.EnabledBy != "" {
+				res.Reason += " with " + r.EnabledBy + " set"
+			}
+			results[r.TestName()] = res
+			if verbose {
+				fmt.Fprintf(progress, "SKIP %s %s\n     %s\n", r.ID, r.Title, res.Reason)
+			}
+			continue
+		}
+		c, err := conformance.FromRequirement(s, r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.ID, err)
+		}
+
+		start := time.Now()
+		t := runCase(target, c)
+		res := report.Result{Status: report.StatusPass, Elapsed: time.Since(start).Seconds()}
+		switch {
+		case t.failed:
+			res.Status = report.StatusFail
+		case t.skipped:
+			res.Status = report.StatusSkip
+		}
+		if len(t.messages) > 0 {
+			res.Reason = t.messages[0]
+		}
+		results[r.TestName()] = res
+
+		if verbose {
+			label := map[string]string{report.StatusPass: "ok  ", report.StatusFail: "FAIL", report.StatusSkip: "SKIP"}[res.Status]
+			fmt.Fprintf(progress, "%s %s %s (%s)\n", label, r.ID, r.Title, time.Duration(res.Elapsed*float64(time.Second)).Round(time.Microsecond))
+			for _, line := range append(t.messages, t.logs...) {
+				fmt.Fprintf(progress, "     %s\n", strings.TrimPrefix(line, r.ID+": "))
+			}
+		}
+	}
+	return results, nil
+}
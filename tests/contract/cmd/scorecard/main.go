@@ -0,0 +1,115 @@
+// Command scorecard turns a contract test run into a conformance scorecard:
+// a score per category of the conformance specification and overall, and an
+// SVG badge to show it.
+//
+//	go test -json ./... > results.json
+//	go run ./cmd/scorecard -service go-gin -in results.json -format markdown -o SCORECARD.md -badge badge.svg
+//
+// Requirements, their categories and their levels come from the spec
+// package, so the scorecard always matches the specification the tests were
+// generated from. Scoring and formatting are in the report package, which
+// contractctl shares.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/report"
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/spec"
+)
+
+// Exit codes
+const (
+	exitOK            = 0
+	exitNotConformant = 1 // with -strict, a MUST requirement didn't pass
+	exitError         = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("scorecard", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	in := fs.String("in", "-", "go test -json output to score (- for stdin)")
+	service := fs.String("service", "", "name of the service the tests ran against")
+	format := fs.String("format", "text", "scorecard format: text, markdown or json")
+	out := fs.String("o", "", "write the scorecard to this file instead of stdout")
+	badge := fs.String("badge", "", "also write an SVG badge of the overall score to this file")
+	label := fs.String("badge-label", "conformance", "text on the left of the badge")
+	strict := fs.Bool("strict", false, "exit 1 unless every MUST requirement passed")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "scorecard: unexpected argument %q\n", fs.Arg(0))
+		return exitError
+	}
+
+	s, err := spec.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, "scorecard: spec:", err)
+		return exitError
+	}
+	r := stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			fmt.Fprintln(stderr, "scorecard:", err)
+			return exitError
+		}
+		defer f.Close()
+		r = f
+	}
+	results, err := readResults(r)
+	if err != nil {
+		fmt.Fprintf(stderr, "scorecard: %s: %v\n", *in, err)
+		return exitError
+	}
+	sc := report.Score(s, reportResults(results), *service, time.Now())
+
+	var buf bytes.Buffer
+	switch *format {
+	case "text":
+		err = sc.WriteText(&buf)
+	case "markdown":
+		err = sc.WriteMarkdown(&buf)
+	case "json":
+		err = sc.WriteJSON(&buf)
+	default:
+		fmt.Fprintf(stderr, "scorecard: unknown format %q\n", *format)
+		return exitError
+	}
+	if err == nil {
+		err = writeOutput(*out, buf.Bytes(), stdout)
+	}
+	if err == nil && *badge != "" {
+		var svg bytes.Buffer
+		if err = report.WriteBadge(&svg, *label, sc.Score); err == nil {
+			err = os.WriteFile(*badge, svg.Bytes(), 0o644)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "scorecard:", err)
+		return exitError
+	}
+	if *strict && !sc.Conformant {
+		return exitNotConformant
+	}
+	return exitOK
+}
+
+// writeOutput writes to a file, or to stdout without one
+func writeOutput(path string, data []byte, stdout io.Writer) error {
+	if path == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/report"
+)
+
+// testEvent is a line of `go test -json` output
+type testEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output"`
+}
+
+// testResult is how one top-level test ended
+type testResult struct {
+	Status  string
+	Elapsed float64
+	Output  []string // what the test printed, for failures and skips
+}
+
+// readResults reads `go test -json` output, returning each top-level test's
+// result by name. Subtests count towards their parent, which fails if any
+// of them does.
+func readResults(r io.Reader) (map[string]*testResult, error) {
+	results := make(map[string]*testResult)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	lines := 0
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 || line[0] != '{' {
+			continue // go test prints build failures as plain text
+		}
+		var ev testEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lines+1, err)
+		}
+		lines++
+		if ev.Test == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(ev.Test, "/")
+		res, ok := results[name]
+		if !ok {
+			res = &testResult{}
+			results[name] = res
+		}
+		switch ev.Action {
+		case "output":
+			res.Output = append(res.Output, ev.Output)
+		case report.StatusPass, report.StatusFail, report.StatusSkip:
+			if name == ev.Test {
+				res.Elapsed = ev.Elapsed
+				if res.Status != report.StatusFail {
+					res.Status = ev.Action
+				}
+			} else if ev.Action == report.StatusFail {
+				res.Status = report.StatusFail
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if lines == 0 {
+		return nil, fmt.Errorf("no test events: run the contract tests with go test -json")
+	}
+	return results, nil
+}
+
+// reason returns the first message a failed or skipped test printed,
+// without go test's file:line prefix
+func (r *testResult) reason() string {
+	for _, line := range r.Output {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "=== ") || strings.HasPrefix(line, "--- ") {
+			continue
+		}
+		if _, msg, ok := strings.Cut(line, ".go:"); ok {
+			if _, after, ok := strings.Cut(msg, ": "); ok {
+				return after
+			}
+		}
+		return line
+	}
+	return ""
+}
+
+// reportResults returns the results in the form the report scores
+func reportResults(results map[string]*testResult) map[string]report.Result {
+	out := make(map[string]report.Result, len(results))
+	for name, r := range results {
+		out[name] = report.Result{Status: r.Status, Elapsed: r.Elapsed, Reason: r.reason()}
+	}
+	return out
+}
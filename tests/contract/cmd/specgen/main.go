@@ -0,0 +1,263 @@
+// Command specgen generates the contract tests from the conformance
+// specification, spec/conformance.yaml. It is run by go generate in
+// tests/contract:
+//
+//	go generate ./...
+//
+// Each requirement with a request becomes a test named after it that runs
+// the request through runConformanceCase and the conformance package.
+// Requirements without one must name a hand-written test in the package,
+// which specgen checks exists.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance"
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/spec"
+)
+
+func main() {
+	out := flag.String("o", "conformance_gen_test.go", "file to write, in the contract test package")
+	flag.Parse()
+	if err := run(*out); err != nil {
+		fmt.Fprintln(os.Stderr, "specgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(out string) error {
+	s, err := spec.Load()
+	if err != nil {
+		return fmt.Errorf("spec/conformance.yaml: %w", err)
+	}
+	handWritten, err := testFuncs(filepath.Dir(out), filepath.Base(out))
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, r := range s.Requirements {
+		switch {
+		case r.Request == nil && !handWritten[r.TestName()]:
+			missing = append(missing, fmt.Sprintf("%s: no hand-written %s", r.ID, r.TestName()))
+		case r.Request != nil && handWritten[r.TestName()]:
+			missing = append(missing, fmt.Sprintf("%s: %s is generated, but is also written by hand", r.ID, r.TestName()))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s", strings.Join(missing, "\n"))
+	}
+
+	src, err := generate(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
+
+var testFunc = regexp.MustCompile(`(?m)^func (Test[A-Za-z0-9_]+)\(t \*testing\.T\)`)
+
+// testFuncs returns the test functions in a directory's test files, other
+// than the generated one
+func testFuncs(dir, generated string) (map[string]bool, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	funcs := make(map[string]bool)
+	for _, f := range files {
+		if filepath.Base(f) == generated {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range testFunc.FindAllSubmatch(src, -1) {
+			funcs[string(m[1])] = true
+		}
+	}
+	return funcs, nil
+}
+
+// generate returns the formatted test file
+func generate(s *spec.Spec) ([]byte, error) {
+	var tests bytes.Buffer
+	usesTime := false
+	for _, r := range s.Requirements {
+		if r.Request == nil {
+			continue
+		}
+		c, err := conformance.FromRequirement(s, r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.ID, err)
+		}
+		writeTest(&tests, r, c)
+		usesTime = usesTime || c.MaxLatency > 0
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by specgen from spec/conformance.yaml. DO NOT EDIT.\n\n")
+	b.WriteString("package contract\n\nimport (\n\t\"testing\"\n")
+	if usesTime {
+		b.WriteString("\t\"time\"\n")
+	}
+	b.WriteString("\n\t\"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance\"\n)\n")
+	b.Write(tests.Bytes())
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+func writeTest(b *bytes.Buffer, r spec.Requirement, c conformance.Case) {
+	fmt.Fprintf(b, "\n// %s checks %s: %s (%s)\n", r.TestName(), r.ID, r.Title, r.Level)
+	if r.Description != "" {
+		b.WriteString("//\n")
+		for _, line := range wrap(r.Description, 76) {
+			fmt.Fprintf(b, "// %s\n", line)
+		}
+	}
+	fmt.Fprintf(b, "func %s(t *testing.T) {\n", r.TestName())
+	b.WriteString("\trunConformanceCase(t, conformance.Case{\n")
+	fmt.Fprintf(b, "\t\tID: %q,\n", c.ID)
+	fmt.Fprintf(b, "\t\tBody: %s,\n", goString(c.Body))
+	fmt.Fprintf(b, "\t\tSignature: %q,\n", c.Signature)
+	fmt.Fprintf(b, "\t\tTimestamp: %q,\n", c.Timestamp)
+	if c.RequestContentType != "" {
+		fmt.Fprintf(b, "\t\tRequestContentType: %q,\n", c.RequestContentType)
+	}
+	if c.RequestContentEncoding != "" {
+		fmt.Fprintf(b, "\t\tRequestContentEncoding: %q,\n", c.RequestContentEncoding)
+	}
+	fmt.Fprintf(b, "\t\tStatuses: %s,\n", intSlice(c.Statuses))
+	if c.ContentType != "" {
+		fmt.Fprintf(b, "\t\tContentType: %q,\n", c.ContentType)
+	}
+	if len(c.Response) > 0 {
+		fmt.Fprintf(b, "\t\tResponse: %s,\n", jsonMap(c.Response))
+	}
+	if c.Ephemeral != nil {
+		fmt.Fprintf(b, "\t\tEphemeral: boolPtr(%t),\n", *c.Ephemeral)
+	}
+	if c.MaxLatency > 0 {
+		fmt.Fprintf(b, "\t\tMaxLatency: %s,\n", goDuration(c.MaxLatency))
+	}
+	if len(c.ErrorCodes) > 0 {
+		fmt.Fprintf(b, "\t\tErrorCodes: %s,\n", stringSlice(c.ErrorCodes))
+	}
+	if p := c.Publish; p != nil {
+		b.WriteString("\t\tPublish: &conformance.Publish{\n")
+		if p.None {
+			b.WriteString("\t\t\tNone: true,\n")
+		}
+		if len(p.Attributes) > 0 {
+			fmt.Fprintf(b, "\t\t\tAttributes: %s,\n", stringMap(p.Attributes))
+		}
+		if len(p.Data) > 0 {
+			fmt.Fprintf(b, "\t\t\tData: %s,\n", jsonMap(p.Data))
+		}
+		if len(p.Absent) > 0 {
+			fmt.Fprintf(b, "\t\t\tAbsent: %s,\n", stringSlice(p.Absent))
+		}
+		if len(p.NeverContains) > 0 {
+			fmt.Fprintf(b, "\t\t\tNeverContains: %s,\n", stringSlice(p.NeverContains))
+		}
+		b.WriteString("\t\t},\n")
+	}
+	b.WriteString("\t})\n}\n")
+}
+
+// goDuration writes a duration as Go, in the largest whole unit
+func goDuration(d time.Duration) string {
+	for _, u := range []struct {
+		unit time.Duration
+		name string
+	}{{time.Second, "time.Second"}, {time.Millisecond, "time.Millisecond"}} {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d * %s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", d)
+}
+
+// goString quotes s as a raw string where it can be, for readable bodies
+func goString(s string) string {
+	if strings.ContainsAny(s, "`\r") || !strconv.CanBackquote(s) {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+func intSlice(v []int) string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return "[]int{" + strings.Join(parts, ", ") + "}"
+}
+
+func stringSlice(v []string) string {
+	parts := make([]string, len(v))
+	for i, s := range v {
+		parts[i] = strconv.Quote(s)
+	}
+	return "[]string{" + strings.Join(parts, ", ") + "}"
+}
+
+// stringMap writes a map literal with its keys sorted, so the output is the
+// same every run
+func stringMap(m map[string]string) string {
+	return mapLiteral(m, strconv.Quote)
+}
+
+// jsonMap writes a map literal of JSON values, raw quoted where possible
+func jsonMap(m map[string]string) string {
+	return mapLiteral(m, goString)
+}
+
+func mapLiteral(m map[string]string, quote func(string) string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var b strings.Builder
+	b.WriteString("map[string]string{\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%q: %s,\n", k, quote(m[k]))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// wrap splits text into lines of at most width characters
+func wrap(text string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
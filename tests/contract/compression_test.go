@@ -0,0 +1,120 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance"
+)
+
+// Compression tests only run against services configured to compress large
+// messages. CONTRACT_TEST_COMPRESSED_TOPIC names the topic the service
+// publishes to; its compression threshold must be between 2 KiB and 32 KiB.
+
+// largeOptionBytes makes a command larger than the highest allowed threshold
+const largeOptionBytes = 32 << 10
+
+// decodeMessageData returns a message's data decompressed according to its
+// content_encoding attribute
+func decodeMessageData(t *testing.T, msg *pubsub.Message) []byte {
+	t.Helper()
+
+	data, err := conformance.DecodeData(msg)
+	if err != nil {
+		t.Fatalf("Undecodable message data: %v", err)
+	}
+	return data
+}
+
+// compressedTopicSubscription subscribes to the service's compressed topic
+func compressedTopicSubscription(t *testing.T) (*pubsub.Subscription, func()) {
+	t.Helper()
+	return serviceTopicSubscription(t, "CONTRACT_TEST_COMPRESSED_TOPIC")
+}
+
+// receiveInteraction waits for the message published for interactionID
+func receiveInteraction(t *testing.T, sub *pubsub.Subscription, interactionID string, timeout time.Duration) *pubsub.Message {
+	t.Helper()
+
+	msg, ok := findInteractionMessage(t, sub, interactionID, timeout)
+	if !ok {
+		t.Fatalf("No message published for interaction %s", interactionID)
+	}
+	return msg
+}
+
+// sendCommandWithOption sends a slash command with one string option
+func sendCommandWithOption(t *testing.T, value string) InteractionRequest {
+	t.Helper()
+
+	req := createSlashCommandRequest("compression-test")
+	req.Data["options"] = []map[string]interface{}{
+		{"name": "text", "type": 3, "value": value},
+	}
+	resp, _ := sendRequest(t, toJSON(t, req))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Slash command failed with status %d", resp.StatusCode)
+	}
+	return req
+}
+
+func TestCompression_LargeMessageRoundTrips(t *testing.T) {
+	sub, cleanup := compressedTopicSubscription(t)
+	defer cleanup()
+
+	value := strings.Repeat(fmt.Sprintf("compressible-%d ", time.Now().UnixNano()), largeOptionBytes/30)
+	req := sendCommandWithOption(t, value)
+
+	msg := receiveInteraction(t, sub, req.ID, 10*time.Second)
+	encoding := msg.Attributes["content_encoding"]
+	if encoding != "gzip" && encoding != "zstd" {
+		t.Fatalf("Expected content_encoding gzip or zstd for a %d byte option, got %q", len(value), encoding)
+	}
+	if len(msg.Data) >= len(value) {
+		t.Errorf("Compressed data is %d bytes, no smaller than the %d byte option", len(msg.Data), len(value))
+	}
+
+	var published struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+		Data  struct {
+			Options []struct {
+				Value string `json:"value"`
+			} `json:"options"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(decodeMessageData(t, msg), &published); err != nil {
+		t.Fatalf("Decompressed data is not valid JSON: %v", err)
+	}
+	if published.ID != req.ID {
+		t.Errorf("Expected interaction ID %s, got %s", req.ID, published.ID)
+	}
+	if published.Token != "" {
+		t.Error("Token must not be published, even compressed")
+	}
+	if len(published.Data.Options) != 1 || published.Data.Options[0].Value != value {
+		t.Error("Option value did not survive the round trip")
+	}
+}
+
+func TestCompression_SmallMessageUncompressed(t *testing.T) {
+	sub, cleanup := compressedTopicSubscription(t)
+	defer cleanup()
+
+	req := sendCommandWithOption(t, "small")
+
+	msg := receiveInteraction(t, sub, req.ID, 10*time.Second)
+	if encoding, ok := msg.Attributes["content_encoding"]; ok {
+		t.Errorf("Expected no content_encoding below the threshold, got %q", encoding)
+	}
+	var published map[string]interface{}
+	if err := json.Unmarshal(msg.Data, &published); err != nil {
+		t.Errorf("Uncompressed data is not valid JSON: %v", err)
+	}
+}
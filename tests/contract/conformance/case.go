@@ -0,0 +1,93 @@
+// Package conformance runs the requirements of the conformance specification
+// against a service: it sends each requirement's request and checks the
+// response and what was published.
+//
+// The generated contract tests run their cases through it, and so does
+// cmd/contractctl, which runs the specification without the Go toolchain.
+package conformance
+
+import (
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/spec"
+)
+
+// Case is a requirement of the specification in the form it is run
+type Case struct {
+	ID string
+	// Body is sent with spec.InteractionIDPlaceholder replaced by a unique ID
+	Body      string
+	Signature string
+	Timestamp string
+	// RequestContentType is the Content-Type sent, if not application/json,
+	// or spec.ContentTypeNone to send none
+	RequestContentType string
+	// RequestContentEncoding is the Content-Encoding sent, if any; the body
+	// is compressed for spec.ContentEncodingGzip
+	RequestContentEncoding string
+	Statuses               []int
+	ContentType            string
+	// Response maps dotted paths into the JSON response to their values as
+	// compact JSON
+	Response   map[string]string
+	Ephemeral  *bool
+	MaxLatency time.Duration // zero for no limit
+	// ErrorCodes are the acceptable codes of an error body, which every
+	// failed request gets: {"error": "<message>", "code": "<code>"}
+	ErrorCodes []string
+	Publish    *Publish
+}
+
+// Publish is what a case must publish. Data values are compact JSON, and
+// every value may contain spec.InteractionIDPlaceholder.
+type Publish struct {
+	None          bool
+	Attributes    map[string]string
+	Data          map[string]string
+	Absent        []string
+	NeverContains []string
+}
+
+// FromRequirement returns the case for a requirement with a request
+func FromRequirement(s *spec.Spec, r spec.Requirement) (Case, error) {
+	body, err := s.Body(r.Request)
+	if err != nil {
+		return Case{}, err
+	}
+	response, err := spec.JSONValues(r.Expect.Response)
+	if err != nil {
+		return Case{}, err
+	}
+	c := Case{
+		ID:                     r.ID,
+		Body:                   body,
+		Signature:              r.Request.SignatureMode(),
+		Timestamp:              r.Request.TimestampMode(),
+		RequestContentType:     r.Request.ContentType,
+		RequestContentEncoding: r.Request.ContentEncoding,
+		Statuses:               r.Expect.Status,
+		ContentType:            r.Expect.ContentType,
+		Ephemeral:              r.Expect.Ephemeral,
+		MaxLatency:             r.Expect.MaxLatency,
+		ErrorCodes:             r.Expect.Error,
+	}
+	if len(response) > 0 {
+		c.Response = response
+	}
+	if p := r.Publish; p != nil {
+		data, err := spec.JSONValues(p.Data)
+		if err != nil {
+			return Case{}, err
+		}
+		c.Publish = &Publish{
+			None:          p.None,
+			Attributes:    p.Attributes,
+			Absent:        p.Absent,
+			NeverContains: p.NeverContains,
+		}
+		if len(data) > 0 {
+			c.Publish.Data = data
+		}
+	}
+	return c, nil
+}
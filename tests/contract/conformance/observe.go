@@ -0,0 +1,229 @@
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/spec"
+)
+
+// Observation is what a service did with a case's request, whatever the
+// case expects. The interaction ID is replaced by the specification's
+// placeholder throughout, so observations of different services compare.
+type Observation struct {
+	Status      int
+	ContentType string // media type, without parameters
+	// Body is the response decoded from JSON, or as a string if it isn't
+	// JSON
+	Body interface{}
+	// PublishObserved is set when what the service published was
+	// watched for, which is for cases with a publish expectation against
+	// a target with a topic. Published is then the message, or nil if
+	// there was none.
+	PublishObserved bool
+	Published       *Message
+}
+
+// Message is a published message, its data decompressed and decoded as
+// for a response body
+type Message struct {
+	Attributes map[string]string
+	Data       interface{}
+}
+
+// Observe sends a case's request to a target and records the response and
+// what was published, without checking them against the case's
+// expectations
+func Observe(target Target, c Case) (*Observation, error) {
+	var sub *pubsub.Subscription
+	if c.Publish != nil && target.PubSub != nil && target.Topic != "" {
+		var cleanup func()
+		var err error
+		sub, cleanup, err = Subscribe(target.PubSub, target.Topic)
+		if err != nil {
+			return nil, fmt.Errorf("subscribing to %s: %w", target.Topic, err)
+		}
+		defer cleanup()
+	}
+
+	interactionID, body := prepare(c)
+	signature, timestamp := sign(body, c.Signature, c.Timestamp)
+	resp, respBody, err := send(target, c, body, signature, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	o := &Observation{
+		Status: resp.StatusCode,
+		Body:   decodeObserved(respBody, interactionID),
+	}
+	o.ContentType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	if sub != nil {
+		wait := publishWait
+		if c.Publish.None {
+			wait = noPublishWait
+		}
+		msg, err := FindInteractionMessage(sub, interactionID, wait)
+		if err != nil {
+			return nil, fmt.Errorf("receiving: %w", err)
+		}
+		o.PublishObserved = true
+		if msg != nil {
+			data, err := DecodeData(msg)
+			if err != nil {
+				return nil, err
+			}
+			o.Published = &Message{
+				Attributes: make(map[string]string, len(msg.Attributes)),
+				Data:       decodeObserved(data, interactionID),
+			}
+			for name, value := range msg.Attributes {
+				o.Published.Attributes[name] = strings.ReplaceAll(value, interactionID, spec.InteractionIDPlaceholder)
+			}
+		}
+	}
+	return o, nil
+}
+
+// decodeObserved decodes a body as JSON, or keeps it as a string, with the
+// interaction ID replaced by the placeholder
+func decodeObserved(body []byte, interactionID string) interface{} {
+	text := strings.ReplaceAll(string(body), interactionID, spec.InteractionIDPlaceholder)
+	var v interface{}
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		return text
+	}
+	return v
+}
+
+// Divergence is a difference between what two services did with the same
+// request. Path names what differs, such as status, body.data.flags or
+// published.attributes.interaction_type, and the values are compact JSON,
+// or "(absent)".
+type Divergence struct {
+	Path      string `json:"path"`
+	Baseline  string `json:"baseline"`
+	Candidate string `json:"candidate"`
+}
+
+const absent = "(absent)"
+
+// Diff returns every way the candidate's observation of a request differs
+// from the baseline's, leaving out the ignored paths and everything under
+// them. Published messages are only compared when both were observed.
+func Diff(baseline, candidate *Observation, ignore []string) []Divergence {
+	var d differ
+	d.ignore = ignore
+	d.value("status", baseline.Status, candidate.Status)
+	d.value("content_type", baseline.ContentType, candidate.ContentType)
+	d.value("body", baseline.Body, candidate.Body)
+	if baseline.PublishObserved && candidate.PublishObserved {
+		switch b, c := baseline.Published, candidate.Published; {
+		case b == nil && c == nil:
+		case b == nil || c == nil:
+			d.add("published", describeMessage(b), describeMessage(c))
+		default:
+			d.value("published.attributes", attributesValue(b.Attributes), attributesValue(c.Attributes))
+			d.value("published.data", b.Data, c.Data)
+		}
+	}
+	return d.divergences
+}
+
+// differ walks two values, collecting where they differ
+type differ struct {
+	ignore      []string
+	divergences []Divergence
+}
+
+func (d *differ) ignored(path string) bool {
+	for _, p := range d.ignore {
+		if path == p || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *differ) add(path, baseline, candidate string) {
+	if !d.ignored(path) {
+		d.divergences = append(d.divergences, Divergence{Path: path, Baseline: baseline, Candidate: candidate})
+	}
+}
+
+// value compares two decoded JSON values, descending into objects and
+// arrays so each difference is reported at its own path
+func (d *differ) value(path string, baseline, candidate interface{}) {
+	if d.ignored(path) {
+		return
+	}
+	switch b := baseline.(type) {
+	case map[string]interface{}:
+		if c, ok := candidate.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(b)+len(c))
+			for k := range b {
+				keys = append(keys, k)
+			}
+			for k := range c {
+				if _, ok := b[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				bv, bok := b[k]
+				cv, cok := c[k]
+				switch {
+				case !bok:
+					d.add(path+"."+k, absent, compact(cv))
+				case !cok:
+					d.add(path+"."+k, compact(bv), absent)
+				default:
+					d.value(path+"."+k, bv, cv)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if c, ok := candidate.([]interface{}); ok && len(b) == len(c) {
+			for i := range b {
+				d.value(path+"."+strconv.Itoa(i), b[i], c[i])
+			}
+			return
+		}
+	}
+	if bs, cs := compact(baseline), compact(candidate); bs != cs {
+		d.add(path, bs, cs)
+	}
+}
+
+// compact encodes a value as compact JSON
+func compact(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
+
+// attributesValue turns attributes into a value Diff can walk
+func attributesValue(attributes map[string]string) map[string]interface{} {
+	v := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		v[name] = value
+	}
+	return v
+}
+
+func describeMessage(m *Message) string {
+	if m == nil {
+		return "nothing published"
+	}
+	return "a message with attributes " + compact(m.Attributes)
+}
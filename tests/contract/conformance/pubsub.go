@@ -0,0 +1,81 @@
+package conformance
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Subscribe creates a subscription to a topic, returning a function that
+// deletes it
+func Subscribe(client *pubsub.Client, topic string) (*pubsub.Subscription, func(), error) {
+	ctx := context.Background()
+	sub, err := client.CreateSubscription(ctx, fmt.Sprintf("test-sub-%d", time.Now().UnixNano()), pubsub.SubscriptionConfig{
+		Topic:       client.Topic(topic),
+		AckDeadline: 10 * time.Second,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return sub, func() { _ = sub.Delete(ctx) }, nil
+}
+
+// FindInteractionMessage waits for the message published for
+// interactionID, acking any others, and returns nil if none arrives in
+// time. Services publish every interaction to the same topic, so messages
+// are matched by their interaction_id attribute.
+func FindInteractionMessage(sub *pubsub.Subscription, interactionID string, timeout time.Duration) (*pubsub.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var received *pubsub.Message
+	err := sub.Receive(ctx, func(_ context.Context, msg *pubsub.Message) {
+		msg.Ack()
+		if msg.Attributes["interaction_id"] == interactionID {
+			received = msg
+			cancel()
+		}
+	})
+	if err == context.Canceled {
+		err = nil
+	}
+	return received, err
+}
+
+// DecodeData returns a message's data decompressed according to its
+// content_encoding attribute
+func DecodeData(msg *pubsub.Message) ([]byte, error) {
+	switch encoding := msg.Attributes["content_encoding"]; encoding {
+	case "":
+		return msg.Data, nil
+	case "gzip":
+		r, err := gzip.NewReader(bytes.NewReader(msg.Data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		return data, nil
+	case "zstd":
+		r, err := zstd.NewReader(bytes.NewReader(msg.Data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd data: %w", err)
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd data: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unexpected content_encoding %q", encoding)
+	}
+}
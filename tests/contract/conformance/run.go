@@ -0,0 +1,344 @@
+package conformance
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/spec"
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/testkeys"
+)
+
+// publishWait is how long a case waits for a message, or to be sure none is
+// published
+const (
+	publishWait   = 10 * time.Second
+	noPublishWait = 2 * time.Second
+)
+
+// T is the part of *testing.T a case reports through, so cases can run
+// outside go test. Fatalf and Skip must stop the calling goroutine.
+type T interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+	Skip(args ...any)
+	Logf(format string, args ...any)
+}
+
+// Target is the service cases run against
+type Target struct {
+	// URL is where interactions are posted
+	URL    string
+	Client *http.Client
+	// PubSub is connected to the emulator the service publishes to, and
+	// Topic is the topic it publishes to. Without both, cases with a
+	// publish expectation are skipped.
+	PubSub *pubsub.Client
+	Topic  string
+}
+
+// Run sends a case's request and checks the response and what was
+// published
+func Run(t T, target Target, c Case) {
+	t.Helper()
+
+	var sub *pubsub.Subscription
+	if c.Publish != nil {
+		if target.PubSub == nil || target.Topic == "" {
+			t.Skip("no Pub/Sub topic to check what the service publishes")
+		}
+		var err error
+		var cleanup func()
+		sub, cleanup, err = Subscribe(target.PubSub, target.Topic)
+		if err != nil {
+			t.Fatalf("%s: subscribing to %s: %v", c.ID, target.Topic, err)
+		}
+		defer cleanup()
+	}
+
+	interactionID, body := prepare(c)
+	signature, timestamp := sign(body, c.Signature, c.Timestamp)
+	start := time.Now()
+	resp, respBody, err := send(target, c, body, signature, timestamp)
+	latency := time.Since(start)
+	if err != nil {
+		t.Fatalf("%s: request failed: %v", c.ID, err)
+	}
+
+	if !containsStatus(c.Statuses, resp.StatusCode) {
+		t.Fatalf("%s: expected status %s, got %d", c.ID, statusList(c.Statuses), resp.StatusCode)
+	}
+	if c.MaxLatency > 0 && latency > c.MaxLatency {
+		t.Errorf("%s: expected a response within %s, took %s", c.ID, c.MaxLatency, latency.Round(time.Millisecond))
+	}
+	if c.ContentType != "" {
+		if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != c.ContentType {
+			t.Errorf("%s: expected Content-Type %s, got %q", c.ID, c.ContentType, resp.Header.Get("Content-Type"))
+		}
+	}
+	if len(c.ErrorCodes) > 0 {
+		checkError(t, c, resp, respBody)
+	}
+	if len(c.Response) > 0 || c.Ephemeral != nil {
+		var response map[string]interface{}
+		if err := json.Unmarshal(respBody, &response); err != nil {
+			t.Fatalf("%s: response is not a JSON object: %v\nBody: %s", c.ID, err, string(respBody))
+		}
+		checkPaths(t, c.ID, "response", response, c.Response, interactionID)
+		if c.Ephemeral != nil {
+			data, _ := response["data"].(map[string]interface{})
+			flags, _ := data["flags"].(float64)
+			if ephemeral := int(flags)&64 != 0; ephemeral != *c.Ephemeral {
+				t.Errorf("%s: expected ephemeral %t, got flags %d", c.ID, *c.Ephemeral, int(flags))
+			}
+		}
+	}
+
+	if c.Publish != nil {
+		checkPublished(t, c, sub, interactionID)
+	}
+}
+
+// prepare gives a case's request a fresh interaction ID, so what the
+// service publishes for it can be told apart
+func prepare(c Case) (interactionID string, body []byte) {
+	interactionID = fmt.Sprintf("conformance-%s-%d", strings.ToLower(c.ID), time.Now().UnixNano())
+	return interactionID, []byte(strings.ReplaceAll(c.Body, spec.InteractionIDPlaceholder, interactionID))
+}
+
+// send posts a body with the case's Content-Type, application/json by
+// default, and Content-Encoding, compressing the body for gzip, and the
+// signature headers, leaving out empty ones
+func send(target Target, c Case, body []byte, signature, timestamp string) (*http.Response, []byte, error) {
+	if c.RequestContentEncoding == spec.ContentEncodingGzip {
+		var err error
+		if body, err = gzipBody(body); err != nil {
+			return nil, nil, err
+		}
+	}
+	req, err := http.NewRequest("POST", target.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	switch c.RequestContentType {
+	case "":
+		req.Header.Set("Content-Type", "application/json")
+	case spec.ContentTypeNone:
+	default:
+		req.Header.Set("Content-Type", c.RequestContentType)
+	}
+	if c.RequestContentEncoding != "" {
+		req.Header.Set("Content-Encoding", c.RequestContentEncoding)
+	}
+	if signature != "" {
+		req.Header.Set("X-Signature-Ed25519", signature)
+	}
+	if timestamp != "" {
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+	}
+
+	client := target.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading response: %w", err)
+	}
+	return resp, respBody, nil
+}
+
+// gzipBody compresses a request body, as a proxy in front of the service
+// might
+func gzipBody(body []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// sign makes the signature headers a case asks for
+func sign(body []byte, signatureMode, timestampMode string) (signature, timestamp string) {
+	timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+	if timestampMode == spec.TimestampExpired {
+		timestamp = testkeys.ExpiredTimestamp()
+	}
+	switch signatureMode {
+	case spec.SignatureValid:
+		signature = testkeys.SignRequestWithTimestamp(body, timestamp)
+	case spec.SignatureInvalid:
+		signature = testkeys.InvalidSignature()
+	case spec.SignatureMalformed:
+		signature = "not-valid-hex!"
+	case spec.SignatureOtherBody:
+		signature = testkeys.SignRequestWithTimestamp([]byte(`{"type":1,"id":"different"}`), timestamp)
+	}
+	if timestampMode == spec.TimestampMissing {
+		timestamp = ""
+	}
+	return signature, timestamp
+}
+
+// checkError checks a failure's body is the JSON error shape, with one of
+// the expected codes
+func checkError(t T, c Case, resp *http.Response, body []byte) {
+	t.Helper()
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/json" {
+		t.Errorf("%s: expected a JSON error body, got Content-Type %q", c.ID, resp.Header.Get("Content-Type"))
+		return
+	}
+	var e struct {
+		Error *string `json:"error"`
+		Code  *string `json:"code"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		t.Errorf("%s: error body is not a JSON object with string error and code: %v\nBody: %s", c.ID, err, string(body))
+		return
+	}
+	if e.Error == nil || *e.Error == "" {
+		t.Errorf("%s: error body has no error message", c.ID)
+	}
+	switch {
+	case e.Code == nil:
+		t.Errorf("%s: error body has no code, expected %s", c.ID, strings.Join(c.ErrorCodes, " or "))
+	case !slices.Contains(c.ErrorCodes, *e.Code):
+		t.Errorf("%s: expected error code %s, got %q", c.ID, strings.Join(c.ErrorCodes, " or "), *e.Code)
+	}
+}
+
+// checkPublished checks the message published for the interaction, or that
+// there was none
+func checkPublished(t T, c Case, sub *pubsub.Subscription, interactionID string) {
+	t.Helper()
+
+	p := c.Publish
+	if p.None {
+		msg, err := FindInteractionMessage(sub, interactionID, noPublishWait)
+		if err != nil {
+			t.Logf("%s: receive error: %v", c.ID, err)
+		}
+		if msg != nil {
+			t.Errorf("%s: expected nothing to be published, got a message with attributes %v", c.ID, msg.Attributes)
+		}
+		return
+	}
+	msg, err := FindInteractionMessage(sub, interactionID, publishWait)
+	if err != nil {
+		t.Logf("%s: receive error: %v", c.ID, err)
+	}
+	if msg == nil {
+		t.Fatalf("%s: no message published for interaction %s", c.ID, interactionID)
+	}
+
+	for name, want := range p.Attributes {
+		want = strings.ReplaceAll(want, spec.InteractionIDPlaceholder, interactionID)
+		if got, ok := msg.Attributes[name]; !ok || got != want {
+			t.Errorf("%s: expected attribute %s=%q, got %q", c.ID, name, want, got)
+		}
+	}
+
+	data, err := DecodeData(msg)
+	if err != nil {
+		t.Fatalf("%s: %v", c.ID, err)
+	}
+	var published map[string]interface{}
+	if err := json.Unmarshal(data, &published); err != nil {
+		t.Fatalf("%s: published data is not a JSON object: %v", c.ID, err)
+	}
+	checkPaths(t, c.ID, "published data", published, p.Data, interactionID)
+	for _, field := range p.Absent {
+		if _, ok := published[field]; ok {
+			t.Errorf("%s: published data has a %q field", c.ID, field)
+		}
+	}
+	for _, secret := range p.NeverContains {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("%s: published data contains %q", c.ID, secret)
+		}
+		for name, value := range msg.Attributes {
+			if strings.Contains(value, secret) {
+				t.Errorf("%s: attribute %s contains %q", c.ID, name, secret)
+			}
+		}
+	}
+}
+
+// checkPaths checks dotted paths into a JSON object have the expected
+// compact JSON values
+func checkPaths(t T, id, what string, object map[string]interface{}, want map[string]string, interactionID string) {
+	t.Helper()
+
+	for path, value := range want {
+		value = strings.ReplaceAll(value, spec.InteractionIDPlaceholder, interactionID)
+		got, ok := lookupPath(object, path)
+		if !ok {
+			t.Errorf("%s: %s has no %s, expected %s", id, what, path, value)
+			continue
+		}
+		encoded, err := json.Marshal(got)
+		if err != nil || string(encoded) != value {
+			t.Errorf("%s: %s %s is %s, expected %s", id, what, path, string(encoded), value)
+		}
+	}
+}
+
+// lookupPath follows a dotted path of object keys and array indexes
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func containsStatus(statuses []int, status int) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func statusList(statuses []int) string {
+	parts := make([]string, len(statuses))
+	for i, s := range statuses {
+		parts[i] = strconv.Itoa(s)
+	}
+	return strings.Join(parts, " or ")
+}
@@ -0,0 +1,645 @@
+// Code generated by specgen from spec/conformance.yaml. DO NOT EDIT.
+
+package contract
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance"
+)
+
+// TestSignature_ValidSignature checks SEC-001: Accept a valid signature (MUST)
+//
+// A request signed with the application's key over the timestamp and body is
+// accepted.
+func TestSignature_ValidSignature(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "SEC-001",
+		Body:      `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+	})
+}
+
+// TestSignature_MissingSignatureHeader checks SEC-002: Reject a request without X-Signature-Ed25519 (MUST)
+func TestSignature_MissingSignatureHeader(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "SEC-002",
+		Body:       `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:  "missing",
+		Timestamp:  "current",
+		Statuses:   []int{401},
+		ErrorCodes: []string{"invalid_signature"},
+	})
+}
+
+// TestSignature_MissingTimestampHeader checks SEC-003: Reject a request without X-Signature-Timestamp (MUST)
+func TestSignature_MissingTimestampHeader(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "SEC-003",
+		Body:       `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:  "valid",
+		Timestamp:  "missing",
+		Statuses:   []int{401},
+		ErrorCodes: []string{"invalid_signature"},
+	})
+}
+
+// TestSignature_InvalidSignature checks SEC-004: Reject a well-formed signature that doesn't verify (MUST)
+func TestSignature_InvalidSignature(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "SEC-004",
+		Body:       `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:  "invalid",
+		Timestamp:  "current",
+		Statuses:   []int{401},
+		ErrorCodes: []string{"invalid_signature"},
+	})
+}
+
+// TestSignature_ExpiredTimestamp checks SEC-005: Reject a timestamp more than 5 seconds old (MUST)
+//
+// The request is correctly signed, but with a timestamp 10 seconds in the
+// past, as a replay would be.
+func TestSignature_ExpiredTimestamp(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "SEC-005",
+		Body:       `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:  "valid",
+		Timestamp:  "expired",
+		Statuses:   []int{401},
+		ErrorCodes: []string{"invalid_signature"},
+	})
+}
+
+// TestSignature_MalformedSignatureHex checks SEC-006: Reject a signature that isn't hex (MUST)
+func TestSignature_MalformedSignatureHex(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "SEC-006",
+		Body:       `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:  "malformed",
+		Timestamp:  "current",
+		Statuses:   []int{401},
+		ErrorCodes: []string{"invalid_signature"},
+	})
+}
+
+// TestSignature_WrongBodySigned checks SEC-007: Reject a signature over a different body (MUST)
+func TestSignature_WrongBodySigned(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "SEC-007",
+		Body:       `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:  "other_body",
+		Timestamp:  "current",
+		Statuses:   []int{401},
+		ErrorCodes: []string{"invalid_signature"},
+	})
+}
+
+// TestPing_ValidPing checks PRO-001: Answer a ping with a pong (MUST)
+func TestPing_ValidPing(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "PRO-001",
+		Body:      `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+		Response: map[string]string{
+			"type": `1`,
+		},
+	})
+}
+
+// TestPing_ResponseContentType checks PRO-002: Answer a ping as application/json (MUST)
+func TestPing_ResponseContentType(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:          "PRO-002",
+		Body:        `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:   "valid",
+		Timestamp:   "current",
+		Statuses:    []int{200},
+		ContentType: "application/json",
+	})
+}
+
+// TestPing_MinimalRequest checks PRO-003: Answer a ping that has only a type (MUST)
+func TestPing_MinimalRequest(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "PRO-003",
+		Body:      `{"type":1}`,
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+		Response: map[string]string{
+			"type": `1`,
+		},
+	})
+}
+
+// TestSlashCommand_ValidCommand checks PRO-004: Defer a slash command (MUST)
+//
+// Commands are handled asynchronously, so the service answers
+// DEFERRED_CHANNEL_MESSAGE_WITH_SOURCE.
+func TestSlashCommand_ValidCommand(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "PRO-004",
+		Body:      `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+		Response: map[string]string{
+			"type": `5`,
+		},
+	})
+}
+
+// TestSlashCommand_ResponseIsNonEphemeral checks PRO-005: Defer a slash command visibly (MUST)
+//
+// The deferred response doesn't set the EPHEMERAL flag (64).
+func TestSlashCommand_ResponseIsNonEphemeral(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "PRO-005",
+		Body:      `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+		Ephemeral: boolPtr(false),
+	})
+}
+
+// TestSlashCommand_ResponseContentType checks PRO-006: Answer a slash command as application/json (MUST)
+func TestSlashCommand_ResponseContentType(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:          "PRO-006",
+		Body:        `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature:   "valid",
+		Timestamp:   "current",
+		Statuses:    []int{200},
+		ContentType: "application/json",
+	})
+}
+
+// TestSlashCommand_WithOptions checks PRO-007: Defer a slash command with options (MUST)
+func TestSlashCommand_WithOptions(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "PRO-007",
+		Body:      `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command","options":[{"name":"option1","type":3,"value":"test-value"}]},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+		Response: map[string]string{
+			"type": `5`,
+		},
+	})
+}
+
+// TestPing_DoesNotPublishToPubSub checks PUB-001: Don't publish pings (MUST)
+func TestPing_DoesNotPublishToPubSub(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "PUB-001",
+		Body:      `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+		Publish: &conformance.Publish{
+			None: true,
+		},
+	})
+}
+
+// TestSlashCommand_PublishesToPubSub checks PUB-002: Publish a slash command with its attributes (MUST)
+func TestSlashCommand_PublishesToPubSub(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "PUB-002",
+		Body:      `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+		Publish: &conformance.Publish{
+			Attributes: map[string]string{
+				"application_id":   "test-app-id",
+				"channel_id":       "test-channel-id",
+				"command_name":     "test-command",
+				"guild_id":         "test-guild-id",
+				"interaction_id":   "{{interaction_id}}",
+				"interaction_type": "2",
+			},
+			Data: map[string]string{
+				"data.name": `"test-command"`,
+				"id":        `"{{interaction_id}}"`,
+				"type":      `2`,
+			},
+		},
+	})
+}
+
+// TestSlashCommand_TokenRedactedFromPubSub checks PUB-003: Never publish the interaction token (MUST)
+//
+// The published interaction has no token field, and the token appears nowhere
+// in the message.
+func TestSlashCommand_TokenRedactedFromPubSub(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "PUB-003",
+		Body:      `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"SUPER_SECRET_TOKEN_12345","type":2}`,
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+		Publish: &conformance.Publish{
+			Absent:        []string{"token"},
+			NeverContains: []string{"SUPER_SECRET_TOKEN_12345"},
+		},
+	})
+}
+
+// TestError_MalformedJSON checks ROB-001: Reject a body that isn't JSON (MUST)
+func TestError_MalformedJSON(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-001",
+		Body:       `{not valid json}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"invalid_body"},
+	})
+}
+
+// TestError_EmptyBody checks ROB-002: Reject an empty body (MUST)
+//
+// Either as unauthorized or as a bad request, depending on which the service
+// checks first.
+func TestError_EmptyBody(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-002",
+		Body:       ``,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400, 401},
+		ErrorCodes: []string{"invalid_body", "invalid_signature"},
+	})
+}
+
+// TestError_MissingTypeField checks ROB-003: Reject an interaction without a type (MUST)
+func TestError_MissingTypeField(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-003",
+		Body:       `{"id": "test-id", "application_id": "test-app"}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"unsupported_interaction_type"},
+	})
+}
+
+// TestError_UnknownInteractionType checks ROB-004: Reject an unknown interaction type (MUST)
+func TestError_UnknownInteractionType(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-004",
+		Body:       `{"type":99,"id":"test-id","application_id":"test-app"}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"unsupported_interaction_type"},
+	})
+}
+
+// TestError_InvalidTypeValue checks ROB-005: Reject a type that isn't a number (MUST)
+func TestError_InvalidTypeValue(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-005",
+		Body:       `{"type": "invalid"}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"invalid_body"},
+	})
+}
+
+// TestError_NullBody checks ROB-006: Reject a null body (MUST)
+func TestError_NullBody(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-006",
+		Body:       `null`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"invalid_body"},
+	})
+}
+
+// TestError_ArrayBody checks ROB-007: Reject a JSON array (MUST)
+func TestError_ArrayBody(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-007",
+		Body:       `[{"type": 1}]`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"invalid_body"},
+	})
+}
+
+// TestError_NegativeType checks ROB-008: Reject a negative type (MUST)
+func TestError_NegativeType(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-008",
+		Body:       `{"type":-1}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"unsupported_interaction_type"},
+	})
+}
+
+// TestError_ZeroType checks ROB-009: Reject type 0 (MUST)
+func TestError_ZeroType(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-009",
+		Body:       `{"type":0}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"unsupported_interaction_type"},
+	})
+}
+
+// TestError_UnsupportedInteractionType3 checks ROB-010: Reject message components when they aren't supported (SHOULD)
+//
+// Services that handle components answer them instead.
+func TestError_UnsupportedInteractionType3(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-010",
+		Body:       `{"type":3,"id":"test-id","application_id":"test-app"}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"unsupported_interaction_type"},
+	})
+}
+
+// TestError_UnsupportedInteractionType4 checks ROB-011: Reject autocomplete when it isn't supported (SHOULD)
+//
+// Services that handle autocomplete answer it instead.
+func TestError_UnsupportedInteractionType4(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-011",
+		Body:       `{"type":4,"id":"test-id","application_id":"test-app"}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"unsupported_interaction_type"},
+	})
+}
+
+// TestContentType_JSONWithCharset checks ROB-013: Accept application/json with a UTF-8 charset (MUST)
+func TestContentType_JSONWithCharset(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:                 "ROB-013",
+		Body:               `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:          "valid",
+		Timestamp:          "current",
+		RequestContentType: "application/json; charset=UTF-8",
+		Statuses:           []int{200},
+		Response: map[string]string{
+			"type": `1`,
+		},
+	})
+}
+
+// TestContentType_FormRejected checks ROB-014: Never parse a form-encoded body as an interaction (MUST)
+//
+// A signed slash command sent as application/x-www-form-urlencoded is
+// rejected, even though the body is valid JSON.
+func TestContentType_FormRejected(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:                 "ROB-014",
+		Body:               `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature:          "valid",
+		Timestamp:          "current",
+		RequestContentType: "application/x-www-form-urlencoded",
+		Statuses:           []int{400},
+		ErrorCodes:         []string{"unsupported_content_type"},
+	})
+}
+
+// TestContentType_MultipartRejected checks ROB-015: Never parse a multipart body as an interaction (MUST)
+func TestContentType_MultipartRejected(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:                 "ROB-015",
+		Body:               `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature:          "valid",
+		Timestamp:          "current",
+		RequestContentType: "multipart/form-data; boundary=contract-test",
+		Statuses:           []int{400},
+		ErrorCodes:         []string{"unsupported_content_type"},
+	})
+}
+
+// TestContentType_MissingRejected checks ROB-016: Reject a request without a Content-Type (SHOULD)
+//
+// Discord always sends application/json, so a request without one didn't come
+// from Discord.
+func TestContentType_MissingRejected(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:                 "ROB-016",
+		Body:               `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:          "valid",
+		Timestamp:          "current",
+		RequestContentType: "none",
+		Statuses:           []int{400},
+		ErrorCodes:         []string{"unsupported_content_type"},
+	})
+}
+
+// TestError_DuplicateKeys checks ROB-017: Reject a body that repeats a key (MUST)
+//
+// Parsers disagree on which of a repeated key wins, so this signed body is a
+// ping to some and a slash command to others. It is rejected rather than read
+// either way.
+func TestError_DuplicateKeys(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-017",
+		Body:       `{"type":1,"id":"test-id","application_id":"test-app","type":2}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"invalid_body"},
+	})
+}
+
+// TestError_TrailingData checks ROB-018: Reject data after the JSON object (MUST)
+func TestError_TrailingData(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-018",
+		Body:       `{"type":1,"id":"test-id","application_id":"test-app"} trailing`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"invalid_body"},
+	})
+}
+
+// TestError_ConcatenatedObjects checks ROB-019: Reject a body holding two JSON objects (MUST)
+//
+// Parsers that stop after the first document would read only the ping.
+func TestError_ConcatenatedObjects(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-019",
+		Body:       `{"type":1,"id":"test-id","application_id":"test-app"}{"type":2}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"invalid_body"},
+	})
+}
+
+// TestError_ByteOrderMark checks ROB-020: Reject a body that starts with a byte order mark (MUST)
+//
+// JSON sent over a network must not start with one (RFC 8259), and parsers
+// differ on skipping it.
+func TestError_ByteOrderMark(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "ROB-020",
+		Body:       "\ufeff{\"type\":1,\"id\":\"test-id\",\"application_id\":\"test-app\"}",
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{400},
+		ErrorCodes: []string{"invalid_body"},
+	})
+}
+
+// TestPing_TrailingWhitespace checks ROB-021: Accept whitespace after the JSON object (MUST)
+//
+// Whitespace isn't data, so a trailing newline doesn't make a body ambiguous.
+func TestPing_TrailingWhitespace(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:        "ROB-021",
+		Body:      "{\"type\":1,\"id\":\"test-id\",\"application_id\":\"test-app\"}\n",
+		Signature: "valid",
+		Timestamp: "current",
+		Statuses:  []int{200},
+		Response: map[string]string{
+			"type": `1`,
+		},
+	})
+}
+
+// TestEncoding_GzipPing checks ROB-025: Accept a gzip-compressed body (MUST)
+//
+// Discord doesn't compress bodies, but a proxy in front of the service may.
+// The signature covers the body Discord sent, so it is checked after
+// decompressing.
+func TestEncoding_GzipPing(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:                     "ROB-025",
+		Body:                   `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:              "valid",
+		Timestamp:              "current",
+		RequestContentEncoding: "gzip",
+		Statuses:               []int{200},
+		Response: map[string]string{
+			"type": `1`,
+		},
+	})
+}
+
+// TestEncoding_GzipSlashCommandPublished checks ROB-026: Publish a gzip-compressed slash command decompressed (MUST)
+func TestEncoding_GzipSlashCommandPublished(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:                     "ROB-026",
+		Body:                   `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature:              "valid",
+		Timestamp:              "current",
+		RequestContentEncoding: "gzip",
+		Statuses:               []int{200},
+		Response: map[string]string{
+			"type": `5`,
+		},
+		Publish: &conformance.Publish{
+			Attributes: map[string]string{
+				"interaction_id": "{{interaction_id}}",
+			},
+			Data: map[string]string{
+				"data.name": `"test-command"`,
+				"id":        `"{{interaction_id}}"`,
+			},
+		},
+	})
+}
+
+// TestEncoding_UnsupportedRejected checks ROB-027: Reject a body in an encoding other than gzip (MUST)
+//
+// Brotli, deflate and other encodings are rejected before the signature is
+// checked.
+func TestEncoding_UnsupportedRejected(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:                     "ROB-027",
+		Body:                   `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:              "valid",
+		Timestamp:              "current",
+		RequestContentEncoding: "br",
+		Statuses:               []int{415},
+		ErrorCodes:             []string{"unsupported_content_encoding"},
+	})
+}
+
+// TestContentType_OctetStreamRejected checks ROB-034: Never parse an octet-stream body as an interaction (MUST)
+//
+// A signed slash command sent as application/octet-stream is rejected, even
+// though the body is valid JSON.
+func TestContentType_OctetStreamRejected(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:                 "ROB-034",
+		Body:               `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature:          "valid",
+		Timestamp:          "current",
+		RequestContentType: "application/octet-stream",
+		Statuses:           []int{400},
+		ErrorCodes:         []string{"unsupported_content_type"},
+	})
+}
+
+// TestContentType_UnsignedMultipartRejected checks ROB-035: Check the signature before the Content-Type (MUST)
+//
+// A multipart body with a signature that doesn't verify gets 401 rather than
+// 400, so the signature was checked on the raw bytes before anything looked at
+// the media type.
+func TestContentType_UnsignedMultipartRejected(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:                 "ROB-035",
+		Body:               `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature:          "invalid",
+		Timestamp:          "current",
+		RequestContentType: "multipart/form-data; boundary=contract-test",
+		Statuses:           []int{401},
+		ErrorCodes:         []string{"invalid_signature"},
+	})
+}
+
+// TestPerformance_PingWithinDeadline checks PRF-001: Answer a ping within 3 seconds (MUST)
+func TestPerformance_PingWithinDeadline(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "PRF-001",
+		Body:       `{"application_id":"test-app-id","id":"{{interaction_id}}","token":"test-token","type":1}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{200},
+		MaxLatency: 3 * time.Second,
+	})
+}
+
+// TestPerformance_CommandWithinDeadline checks PRF-002: Answer a slash command within 3 seconds (MUST)
+//
+// Publishing the command must not hold up the response; work that takes longer
+// happens after it is deferred.
+func TestPerformance_CommandWithinDeadline(t *testing.T) {
+	runConformanceCase(t, conformance.Case{
+		ID:         "PRF-002",
+		Body:       `{"application_id":"test-app-id","channel_id":"test-channel-id","data":{"id":"cmd-id","name":"test-command"},"guild_id":"test-guild-id","id":"{{interaction_id}}","locale":"en-US","member":{"user":{"id":"user-id","username":"testuser"}},"token":"sensitive-token-should-be-redacted","type":2}`,
+		Signature:  "valid",
+		Timestamp:  "current",
+		Statuses:   []int{200},
+		MaxLatency: 3 * time.Second,
+	})
+}
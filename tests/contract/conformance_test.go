@@ -0,0 +1,51 @@
+package contract
+
+//go:generate go run ./cmd/specgen -o conformance_gen_test.go
+
+import (
+	"os"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance"
+)
+
+// Conformance cases are generated from spec/conformance.yaml into
+// conformance_gen_test.go and run by the conformance package. Publish
+// expectations need the Pub/Sub emulator and CONTRACT_TEST_TOPIC naming the
+// topic the service publishes to; without them, cases that have one are
+// skipped.
+
+func boolPtr(b bool) *bool { return &b }
+
+// runConformanceCase runs a case against the service under test
+func runConformanceCase(t *testing.T, c conformance.Case) {
+	t.Helper()
+
+	target := conformance.Target{URL: interactionsURL, PubSub: pubsubClient, Topic: os.Getenv("CONTRACT_TEST_TOPIC")}
+	if c.Publish != nil {
+		switch {
+		case target.Topic == "":
+			t.Skip("CONTRACT_TEST_TOPIC not set")
+		case pubsubClient == nil:
+			t.Skip("Pub/Sub emulator not available")
+		}
+	}
+	conformance.Run(t, target, c)
+}
+
+// serviceTopicSubscription subscribes to the topic the service publishes
+// to, named by an environment variable, skipping the test without one
+func serviceTopicSubscription(t *testing.T, envVar string) (*pubsub.Subscription, func()) {
+	t.Helper()
+
+	topicName := os.Getenv(envVar)
+	if topicName == "" {
+		t.Skip(envVar + " not set")
+	}
+	if pubsubClient == nil {
+		t.Skip("Pub/Sub emulator not available")
+	}
+	return createTestSubscription(t, pubsubClient.Topic(topicName))
+}
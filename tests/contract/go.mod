@@ -2,7 +2,12 @@ module github.com/pmgledhill102/discord-bot-test-suite/tests/contract
 
 go 1.24.0
 
-require cloud.google.com/go/pubsub v1.50.1
+require (
+	cloud.google.com/go/pubsub v1.50.1
+	github.com/klauspost/compress v1.18.0
+	google.golang.org/grpc v1.74.2
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	cloud.google.com/go v0.121.6 // indirect
@@ -36,6 +41,5 @@ require (
 	google.golang.org/genproto v0.0.0-20250603155806-513f23925822 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20250818200422-3122310a409c // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250811230008-5f3141c8851a // indirect
-	google.golang.org/grpc v1.74.2 // indirect
 	google.golang.org/protobuf v1.36.7 // indirect
 )
@@ -0,0 +1,120 @@
+package contract
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/testkeys"
+)
+
+// Header tests check responses are served as JSON no browser will sniff and
+// no cache will keep, whether they answer an interaction or reject one, and
+// that paths and methods the service doesn't serve get its own JSON errors
+// rather than a framework's default page.
+
+// unknownPath is a path no service serves
+const unknownPath = "/contract-test-no-such-path"
+
+// sendSigned sends a signed body with any method to any URL, with any
+// extra headers, which replace the signature's if they name them
+func sendSigned(t *testing.T, method, target string, body []byte, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	signature, timestamp := testkeys.SignRequest(body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature-Ed25519", signature)
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	return resp, respBody
+}
+
+// headerListHas reports whether a comma-separated header value, such as
+// Cache-Control or Allow, has the named item, any value aside
+func headerListHas(header, name string) bool {
+	for _, item := range strings.Split(header, ",") {
+		item, _, _ = strings.Cut(item, "=")
+		if strings.EqualFold(strings.TrimSpace(item), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// assertHardenedHeaders checks a response can't be sniffed as another
+// content type or stored by a cache
+func assertHardenedHeaders(t *testing.T, resp *http.Response) {
+	t.Helper()
+
+	if got := resp.Header.Get("X-Content-Type-Options"); !strings.EqualFold(got, "nosniff") {
+		t.Errorf("Expected X-Content-Type-Options: nosniff, got %q", got)
+	}
+	if got := resp.Header.Get("Cache-Control"); !headerListHas(got, "no-store") {
+		t.Errorf("Expected Cache-Control with no-store, got %q", got)
+	}
+}
+
+// TestHeaders_InteractionResponse checks the headers on an answered ping
+func TestHeaders_InteractionResponse(t *testing.T) {
+	resp, respBody := sendRequest(t, toJSON(t, InteractionRequest{Type: 1, ID: "headers-ping", ApplicationID: "test-app-id"}))
+	assertResponseType(t, resp, respBody, 1)
+	assertHardenedHeaders(t, resp)
+}
+
+// TestHeaders_ErrorResponse checks the headers on a rejected ping
+func TestHeaders_ErrorResponse(t *testing.T) {
+	body := toJSON(t, InteractionRequest{Type: 1, ID: "headers-bad-signature", ApplicationID: "test-app-id"})
+	_, timestamp := testkeys.SignRequest(body)
+	resp, respBody := sendRequestWithHeaders(t, body, testkeys.InvalidSignature(), timestamp)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %d", resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "invalid_signature")
+	assertHardenedHeaders(t, resp)
+}
+
+// TestHeaders_UnknownPath posts a signed ping to a path the service doesn't
+// serve
+func TestHeaders_UnknownPath(t *testing.T) {
+	body := toJSON(t, InteractionRequest{Type: 1, ID: "headers-unknown-path", ApplicationID: "test-app-id"})
+	resp, respBody := sendSigned(t, "POST", strings.TrimSuffix(targetURL, "/")+unknownPath, body, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status 404 for %s, got %d", unknownPath, resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "not_found")
+	assertHardenedHeaders(t, resp)
+}
+
+// TestHeaders_WrongMethod sends a GET to the interactions endpoint, which
+// only accepts POST
+func TestHeaders_WrongMethod(t *testing.T) {
+	resp, respBody := sendSigned(t, "GET", interactionsURL, nil, nil)
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status 405 for a GET, got %d", resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "method_not_allowed")
+	assertHardenedHeaders(t, resp)
+	if allow := resp.Header.Get("Allow"); !headerListHas(allow, "POST") {
+		t.Errorf("Expected an Allow header listing POST, got %q", allow)
+	}
+}
@@ -0,0 +1,192 @@
+package contract
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/testkeys"
+)
+
+// HTTP version tests send the same interactions over HTTP/1.0, HTTP/1.1
+// and, for an https target, HTTP/2, and expect the same answers over each.
+// Discord itself uses HTTP/1.1 today, but proxies and load balancers in
+// front of a service may speak either of the others to it.
+
+// versionCase is an interaction every HTTP version must answer alike
+type versionCase struct {
+	name string
+	body []byte
+	// badSignature sends a well-formed signature that doesn't verify
+	badSignature bool
+	status       int
+	responseType int    // for a 200
+	errorCode    string // for anything else
+}
+
+func versionCases(t *testing.T) []versionCase {
+	return []versionCase{
+		{
+			name:         "ping",
+			body:         toJSON(t, InteractionRequest{Type: 1, ID: "version-ping", ApplicationID: "test-app-id"}),
+			status:       http.StatusOK,
+			responseType: 1,
+		},
+		{
+			name:         "slash command",
+			body:         toJSON(t, createSlashCommandRequest("version-test")),
+			status:       http.StatusOK,
+			responseType: 5,
+		},
+		{
+			name:         "invalid signature",
+			body:         toJSON(t, InteractionRequest{Type: 1, ID: "version-bad-signature", ApplicationID: "test-app-id"}),
+			badSignature: true,
+			status:       http.StatusUnauthorized,
+			errorCode:    "invalid_signature",
+		},
+	}
+}
+
+// versionSender sends a signed interaction over one HTTP version
+type versionSender func(t *testing.T, body []byte, signature, timestamp string) (*http.Response, []byte)
+
+// checkVersion sends every case with send and checks the answers, and that
+// each response was served over proto unless it is empty
+func checkVersion(t *testing.T, proto string, send versionSender) {
+	t.Helper()
+
+	for _, c := range versionCases(t) {
+		signature, timestamp := testkeys.SignRequest(c.body)
+		if c.badSignature {
+			signature = testkeys.InvalidSignature()
+		}
+		resp, respBody := send(t, c.body, signature, timestamp)
+		if proto != "" && resp.Proto != proto {
+			t.Errorf("%s: expected a response over %s, got %s", c.name, proto, resp.Proto)
+		}
+		if resp.StatusCode != c.status {
+			t.Fatalf("%s: expected status %d, got %d", c.name, c.status, resp.StatusCode)
+		}
+		if c.errorCode != "" {
+			assertErrorResponse(t, resp, respBody, c.errorCode)
+		} else {
+			assertResponseType(t, resp, respBody, c.responseType)
+		}
+	}
+}
+
+// versionClient returns a client for the target that speaks HTTP/2 only if
+// http2 is set
+func versionClient(http2 bool) *http.Client {
+	transport := &http.Transport{
+		ForceAttemptHTTP2: http2,
+		TLSClientConfig:   &tls.Config{},
+	}
+	if !http2 {
+		// A non-nil, empty map turns off HTTP/2 negotiation
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// sendWithClient returns a sender posting interactions with client
+func sendWithClient(client *http.Client) versionSender {
+	return func(t *testing.T, body []byte, signature, timestamp string) (*http.Response, []byte) {
+		t.Helper()
+
+		req, err := http.NewRequest("POST", interactionsURL, bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Signature-Ed25519", signature)
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read response body: %v", err)
+		}
+		return resp, respBody
+	}
+}
+
+// sendHTTP10 posts an interaction as HTTP/1.0 without keep-alive, on a
+// connection of its own. The response must not be chunked, which HTTP/1.0
+// doesn't have, and the service must close the connection after it.
+func sendHTTP10(t *testing.T, body []byte, signature, timestamp string) (*http.Response, []byte) {
+	t.Helper()
+
+	target, err := url.Parse(interactionsURL)
+	if err != nil {
+		t.Fatalf("Invalid interactions URL %q: %v", interactionsURL, err)
+	}
+	conn := dialTarget(t, target)
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatalf("Failed to set connection deadline: %v", err)
+	}
+
+	_, err = fmt.Fprintf(conn, "POST %s HTTP/1.0\r\nHost: %s\r\nContent-Type: application/json\r\nContent-Length: %d\r\n"+
+		"X-Signature-Ed25519: %s\r\nX-Signature-Timestamp: %s\r\n\r\n%s",
+		target.RequestURI(), target.Host, len(body), signature, timestamp, body)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, &http.Request{Method: "POST", Proto: "HTTP/1.0", ProtoMajor: 1, ProtoMinor: 0})
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	defer resp.Body.Close()
+	if len(resp.TransferEncoding) > 0 {
+		t.Errorf("Expected no Transfer-Encoding in a response to HTTP/1.0, got %v", resp.TransferEncoding)
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+
+	// Without keep-alive, the service closes the connection once it has
+	// answered
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("Failed to set read deadline: %v", err)
+	}
+	if n, err := r.Read(make([]byte, 1)); n > 0 || !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the connection closed after an HTTP/1.0 response, got %d more bytes, error %v", n, err)
+	}
+	return resp, respBody
+}
+
+// TestHTTPVersion_HTTP10 sends interactions as HTTP/1.0 without keep-alive
+func TestHTTPVersion_HTTP10(t *testing.T) {
+	// The response may be HTTP/1.0 or 1.1 (RFC 9110 section 6.2)
+	checkVersion(t, "", sendHTTP10)
+}
+
+// TestHTTPVersion_HTTP11 sends interactions over HTTP/1.1, with HTTP/2
+// turned off even for an https target
+func TestHTTPVersion_HTTP11(t *testing.T) {
+	checkVersion(t, "HTTP/1.1", sendWithClient(versionClient(false)))
+}
+
+// TestHTTPVersion_HTTP2 sends interactions over HTTP/2, negotiated with
+// ALPN, which needs an https target
+func TestHTTPVersion_HTTP2(t *testing.T) {
+	if target, err := url.Parse(interactionsURL); err != nil || target.Scheme != "https" {
+		t.Skip("HTTP/2 needs an https CONTRACT_TEST_TARGET")
+	}
+	checkVersion(t, "HTTP/2.0", sendWithClient(versionClient(true)))
+}
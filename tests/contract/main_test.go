@@ -12,11 +12,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance"
 	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/testkeys"
 )
 
@@ -24,6 +26,10 @@ var (
 	// targetURL is the base URL of the service under test
 	targetURL string
 
+	// interactionsURL is where interactions are posted: targetURL plus the
+	// optional CONTRACT_TEST_PATH, for services not serving interactions at "/"
+	interactionsURL string
+
 	// pubsubClient is the Pub/Sub client for verifying published messages
 	pubsubClient *pubsub.Client
 
@@ -37,6 +43,7 @@ func TestMain(m *testing.M) {
 	if targetURL == "" {
 		targetURL = "http://localhost:8080"
 	}
+	interactionsURL = strings.TrimSuffix(targetURL, "/") + os.Getenv("CONTRACT_TEST_PATH")
 
 	// Get project ID
 	projectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
@@ -78,12 +85,6 @@ type InteractionRequest struct {
 	Locale        string                 `json:"locale,omitempty"`
 }
 
-// InteractionResponse represents a Discord interaction response
-type InteractionResponse struct {
-	Type int                    `json:"type"`
-	Data map[string]interface{} `json:"data,omitempty"`
-}
-
 // sendRequest sends a signed request to the service under test
 func sendRequest(t *testing.T, body []byte) (*http.Response, []byte) {
 	t.Helper()
@@ -95,7 +96,7 @@ func sendRequest(t *testing.T, body []byte) (*http.Response, []byte) {
 func sendRequestWithHeaders(t *testing.T, body []byte, signature, timestamp string) (*http.Response, []byte) {
 	t.Helper()
 
-	req, err := http.NewRequest("POST", targetURL, bytes.NewReader(body))
+	req, err := http.NewRequest("POST", interactionsURL, bytes.NewReader(body))
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
@@ -123,16 +124,6 @@ func sendRequestWithHeaders(t *testing.T, body []byte, signature, timestamp stri
 	return resp, respBody
 }
 
-// createPingRequest creates a valid ping interaction request
-func createPingRequest() InteractionRequest {
-	return InteractionRequest{
-		Type:          1, // Ping
-		ID:            "test-interaction-id",
-		ApplicationID: "test-app-id",
-		Token:         "test-token",
-	}
-}
-
 // createSlashCommandRequest creates a valid slash command interaction request
 func createSlashCommandRequest(commandName string) InteractionRequest {
 	return InteractionRequest{
@@ -166,42 +157,6 @@ func toJSON(t *testing.T, v interface{}) []byte {
 	return data
 }
 
-// parseResponse parses a response body as InteractionResponse
-func parseResponse(t *testing.T, body []byte) InteractionResponse {
-	t.Helper()
-	var resp InteractionResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		t.Fatalf("Failed to parse response JSON: %v\nBody: %s", err, string(body))
-	}
-	return resp
-}
-
-// createTestTopic creates a unique topic for a test and returns cleanup function
-func createTestTopic(t *testing.T) (*pubsub.Topic, func()) {
-	t.Helper()
-
-	if pubsubClient == nil {
-		t.Skip("Pub/Sub emulator not available")
-	}
-
-	ctx := context.Background()
-	topicName := fmt.Sprintf("test-topic-%d", time.Now().UnixNano())
-
-	topic, err := pubsubClient.CreateTopic(ctx, topicName)
-	if err != nil {
-		t.Fatalf("Failed to create topic: %v", err)
-	}
-
-	cleanup := func() {
-		topic.Stop()
-		if err := topic.Delete(ctx); err != nil {
-			t.Logf("Warning: Failed to delete topic: %v", err)
-		}
-	}
-
-	return topic, cleanup
-}
-
 // createTestSubscription creates a subscription for a topic
 func createTestSubscription(t *testing.T, topic *pubsub.Topic) (*pubsub.Subscription, func()) {
 	t.Helper()
@@ -226,23 +181,14 @@ func createTestSubscription(t *testing.T, topic *pubsub.Topic) (*pubsub.Subscrip
 	return sub, cleanup
 }
 
-// receiveMessage receives a single message from a subscription with timeout
-func receiveMessage(t *testing.T, sub *pubsub.Subscription, timeout time.Duration) (*pubsub.Message, bool) {
+// findInteractionMessage waits for the message published for
+// interactionID, acking any others
+func findInteractionMessage(t *testing.T, sub *pubsub.Subscription, interactionID string, timeout time.Duration) (*pubsub.Message, bool) {
 	t.Helper()
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	var received *pubsub.Message
-	err := sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-		received = msg
-		msg.Ack()
-		cancel() // Stop receiving after first message
-	})
-
-	if err != nil && err != context.Canceled {
+	msg, err := conformance.FindInteractionMessage(sub, interactionID, timeout)
+	if err != nil {
 		t.Logf("Receive error: %v", err)
 	}
-
-	return received, received != nil
+	return msg, msg != nil
 }
@@ -0,0 +1,121 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// Publish pressure: the emulator is kept busy with large messages from
+// several publishers while slash commands are sent, so a service that waits
+// for its publish before answering is slowed down with it.
+const (
+	pressurePublishers   = 8
+	pressureMessageBytes = 64 << 10
+	pressureWarmup       = time.Second
+	pressureCommands     = 20
+
+	// pressureBudget is well under Discord's 3 second deadline, leaving
+	// room for the round trip from Discord
+	pressureBudget = 2 * time.Second
+)
+
+// publishPressure floods a topic of its own on the emulator until stopped,
+// returning a function that stops it and reports how long its publishes
+// took. It is stopped when the test ends, if not before.
+func publishPressure(t *testing.T) func() []time.Duration {
+	t.Helper()
+
+	topic, err := pubsubClient.CreateTopic(context.Background(), fmt.Sprintf("contract-pressure-%d", time.Now().UnixNano()))
+	if err != nil {
+		t.Fatalf("Failed to create pressure topic: %v", err)
+	}
+	// Messages to a topic without subscriptions are dropped; one makes the
+	// emulator keep them
+	_, cleanup := createTestSubscription(t, topic)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		latencies []time.Duration
+	)
+	data := []byte(strings.Repeat("x", pressureMessageBytes))
+	for i := 0; i < pressurePublishers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				start := time.Now()
+				if _, err := topic.Publish(ctx, &pubsub.Message{Data: data}).Get(ctx); err != nil {
+					continue
+				}
+				mu.Lock()
+				latencies = append(latencies, time.Since(start))
+				mu.Unlock()
+			}
+		}()
+	}
+	stop := sync.OnceValue(func() []time.Duration {
+		cancel()
+		wg.Wait()
+		topic.Stop()
+		cleanup()
+		if err := topic.Delete(context.Background()); err != nil {
+			t.Logf("Warning: Failed to delete pressure topic: %v", err)
+		}
+		return latencies
+	})
+	t.Cleanup(func() { stop() })
+	return stop
+}
+
+// TestPerformance_DeadlineUnderPublishPressure checks that publishing can't
+// hold up the response: slash commands are still answered well within the
+// deadline while the emulator is saturated
+func TestPerformance_DeadlineUnderPublishPressure(t *testing.T) {
+	if pubsubClient == nil {
+		t.Skip("Pub/Sub emulator not available")
+	}
+
+	stop := publishPressure(t)
+	time.Sleep(pressureWarmup)
+
+	var slowest time.Duration
+	var latencies []time.Duration
+	for i := 0; i < pressureCommands; i++ {
+		body := toJSON(t, createSlashCommandRequest("pressure-test"))
+		start := time.Now()
+		resp, _ := sendRequest(t, body)
+		latency := time.Since(start)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Slash command %d failed with status %d", i+1, resp.StatusCode)
+		}
+		latencies = append(latencies, latency)
+		slowest = max(slowest, latency)
+	}
+	publishes := stop()
+
+	t.Logf("Responses under pressure: median %s, slowest %s", median(latencies), slowest)
+	t.Logf("Emulator publishes under pressure: %d, median %s", len(publishes), median(publishes))
+	if slowest > pressureBudget {
+		t.Errorf("Expected every response within %s under publish pressure, slowest took %s", pressureBudget, slowest.Round(time.Millisecond))
+	}
+}
+
+// median returns the middle of some durations, or zero for none
+func median(d []time.Duration) time.Duration {
+	if len(d) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), d...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
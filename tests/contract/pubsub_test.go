@@ -0,0 +1,82 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+const (
+	// pingCount is how many pings are sent to check none is published
+	pingCount = 5
+
+	// pingGrace is how long to keep watching for pings after the command
+	// sent behind them has been published
+	pingGrace = 2 * time.Second
+)
+
+// sendPing sends a ping with its own interaction ID, returning the ID
+func sendPing(t *testing.T, n int) string {
+	t.Helper()
+
+	id := fmt.Sprintf("test-ping-%d-%d", n, time.Now().UnixNano())
+	resp, _ := sendRequest(t, toJSON(t, InteractionRequest{Type: 1, ID: id, ApplicationID: "test-app-id", Token: "test-token"}))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ping %d failed with status %d", n, resp.StatusCode)
+	}
+	return id
+}
+
+// TestPing_SeveralNeverPublished sends pings, then a slash command, and
+// checks only the command is published. The command arriving shows
+// publishing works, so nothing arriving for the pings means they weren't
+// published rather than that nothing was.
+func TestPing_SeveralNeverPublished(t *testing.T) {
+	sub, cleanup := serviceTopicSubscription(t, "CONTRACT_TEST_TOPIC")
+	defer cleanup()
+
+	pings := make(map[string]bool, pingCount)
+	for i := 1; i <= pingCount; i++ {
+		pings[sendPing(t, i)] = true
+	}
+	command := createSlashCommandRequest("after-pings")
+	if resp, _ := sendRequest(t, toJSON(t, command)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Slash command failed with status %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var (
+		mu        sync.Mutex
+		published []map[string]string // the pings' messages
+		grace     *time.Timer
+	)
+	err := sub.Receive(ctx, func(_ context.Context, msg *pubsub.Message) {
+		msg.Ack()
+		mu.Lock()
+		defer mu.Unlock()
+		switch id := msg.Attributes["interaction_id"]; {
+		case pings[id] || msg.Attributes["interaction_type"] == "1":
+			published = append(published, msg.Attributes)
+		case id == command.ID && grace == nil:
+			grace = time.AfterFunc(pingGrace, cancel)
+		}
+	})
+	if err != nil && err != context.Canceled {
+		t.Logf("Receive error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if grace == nil {
+		t.Fatalf("The slash command sent after the pings was not published, so publishing can't be checked")
+	}
+	for _, attributes := range published {
+		t.Errorf("Ping published with attributes %v", attributes)
+	}
+}
@@ -0,0 +1,86 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+)
+
+// Badge colors, by score, as shields.io names them
+const (
+	colorBrightGreen = "#4c1"
+	colorGreen       = "#97ca00"
+	colorYellow      = "#dfb317"
+	colorOrange      = "#fe7d37"
+	colorRed         = "#e05d44"
+	colorGrey        = "#9f9f9f"
+)
+
+// badgeColor picks the color for a score
+func badgeColor(score *float64) string {
+	switch {
+	case score == nil:
+		return colorGrey
+	case *score >= 100:
+		return colorBrightGreen
+	case *score >= 90:
+		return colorGreen
+	case *score >= 75:
+		return colorYellow
+	case *score >= 50:
+		return colorOrange
+	default:
+		return colorRed
+	}
+}
+
+// textWidth estimates the width of text in 11px Verdana, which badges are
+// set in, closely enough to size the badge without the font
+func textWidth(s string) int {
+	var w float64
+	for _, r := range s {
+		switch {
+		case r == ' ' || r == 'i' || r == 'l' || r == '.' || r == ',' || r == ':' || r == '/':
+			w += 3.9
+		case r == 'm' || r == 'w' || r == '%':
+			w += 10.5
+		case r >= 'A' && r <= 'Z':
+			w += 7.6
+		default:
+			w += 6.8
+		}
+	}
+	return int(math.Ceil(w))
+}
+
+// WriteBadge writes a flat badge in the style of shields.io: the label on
+// grey, and the score on its color
+func WriteBadge(w io.Writer, label string, score *float64) error {
+	message := formatScore(score)
+	labelWidth := textWidth(label) + 10
+	messageWidth := textWidth(message) + 10
+	width := labelWidth + messageWidth
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">
+  <title>%[2]s: %[3]s</title>
+  <linearGradient id="s" x2="0" y2="100%%">
+    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+    <stop offset="1" stop-opacity=".1"/>
+  </linearGradient>
+  <clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[4]d" height="20" fill="#555"/>
+    <rect x="%[4]d" width="%[5]d" height="20" fill="%[6]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[2]s</text>
+    <text x="%[7]d" y="14">%[2]s</text>
+    <text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[3]s</text>
+    <text x="%[8]d" y="14">%[3]s</text>
+  </g>
+</svg>
+`, width, html.EscapeString(label), html.EscapeString(message), labelWidth, messageWidth, badgeColor(score),
+		labelWidth/2, labelWidth+messageWidth/2)
+	return err
+}
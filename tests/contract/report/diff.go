@@ -0,0 +1,91 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/conformance"
+)
+
+// Diff is how a candidate service's behavior diverged from a baseline's on
+// the same requests
+type Diff struct {
+	Generated   time.Time `json:"generated"`
+	SpecVersion int       `json:"spec_version"`
+	Baseline    string    `json:"baseline"`
+	Candidate   string    `json:"candidate"`
+	// Compared is how many requirements' requests were replayed against
+	// both
+	Compared     int               `json:"compared"`
+	Requirements []RequirementDiff `json:"requirements"` // those that diverged
+}
+
+// RequirementDiff is how the services diverged on a requirement's request
+type RequirementDiff struct {
+	ID          string                   `json:"id"`
+	Category    string                   `json:"category"`
+	Title       string                   `json:"title"`
+	Divergences []conformance.Divergence `json:"divergences"`
+}
+
+// Diverged reports whether the services behaved differently on any request
+func (d *Diff) Diverged() bool {
+	return len(d.Requirements) > 0
+}
+
+// maxTextValue is the most of a value the text report shows
+const maxTextValue = 60
+
+// WriteText writes the divergences for a terminal
+func (d *Diff) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Baseline:  %s\nCandidate: %s\n", d.Baseline, d.Candidate)
+	fmt.Fprintf(w, "%d of %d requests diverged\n", len(d.Requirements), d.Compared)
+	for _, r := range d.Requirements {
+		fmt.Fprintf(w, "\n%s %s\n", r.ID, r.Title)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, dv := range r.Divergences {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", dv.Path, truncate(dv.Baseline), truncate(dv.Candidate))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes the divergences as a Markdown report, a table for
+// each requirement
+func (d *Diff) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Behavioral diff\n\n")
+	fmt.Fprintf(&b, "Baseline `%s`, candidate `%s`: specification version %d, %s.\n\n",
+		d.Baseline, d.Candidate, d.SpecVersion, d.Generated.Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&b, "%d of %d requests diverged.\n", len(d.Requirements), d.Compared)
+	for _, r := range d.Requirements {
+		fmt.Fprintf(&b, "\n## %s %s\n\n", r.ID, markdownCell(r.Title))
+		b.WriteString("| Path | Baseline | Candidate |\n|------|----------|-----------|\n")
+		for _, dv := range r.Divergences {
+			fmt.Fprintf(&b, "| `%s` | `%s` | `%s` |\n", dv.Path, markdownCell(dv.Baseline), markdownCell(dv.Candidate))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the divergences as JSON
+func (d *Diff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+func truncate(s string) string {
+	if r := []rune(s); len(r) > maxTextValue {
+		return string(r[:maxTextValue-3]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,209 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Matrix is the conformance of several services to the same specification
+type Matrix struct {
+	Generated   time.Time      `json:"generated"`
+	SpecVersion int            `json:"spec_version"`
+	Services    []ServiceEntry `json:"services"`
+}
+
+// ServiceEntry is one service's row of a matrix: its scorecard, or why it
+// couldn't be tested
+type ServiceEntry struct {
+	Service   string     `json:"service"`
+	Error     string     `json:"error,omitempty"`
+	Scorecard *Scorecard `json:"scorecard,omitempty"`
+}
+
+// Add adds a service's scorecard, or the error that kept it from being
+// tested, to the matrix
+func (m *Matrix) Add(service string, sc *Scorecard, err error) {
+	entry := ServiceEntry{Service: service, Scorecard: sc}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	m.Services = append(m.Services, entry)
+}
+
+// Failed reports whether any requirement failed for any service, or a
+// service couldn't be tested
+func (m *Matrix) Failed() bool {
+	for _, e := range m.Services {
+		if e.Error != "" || e.Scorecard.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// Conformant reports whether every service is conformant
+func (m *Matrix) Conformant() bool {
+	for _, e := range m.Services {
+		if e.Error != "" || !e.Scorecard.Conformant {
+			return false
+		}
+	}
+	return true
+}
+
+// categories returns the categories of the services' scorecards, which
+// share a specification
+func (m *Matrix) categories() []string {
+	for _, e := range m.Services {
+		if e.Scorecard != nil {
+			names := make([]string, len(e.Scorecard.Categories))
+			for i, c := range e.Scorecard.Categories {
+				names[i] = c.Name
+			}
+			return names
+		}
+	}
+	return nil
+}
+
+// requirements returns the requirements of the services' scorecards
+func (m *Matrix) requirements() []RequirementResult {
+	for _, e := range m.Services {
+		if e.Scorecard != nil {
+			return e.Scorecard.Requirements
+		}
+	}
+	return nil
+}
+
+// status returns a service's result for a requirement
+func (e ServiceEntry) status(id string) string {
+	if e.Scorecard != nil {
+		for _, r := range e.Scorecard.Requirements {
+			if r.ID == id {
+				return r.Status
+			}
+		}
+	}
+	return StatusNotRun
+}
+
+// WriteText writes the matrix for a terminal: a row per service, then the
+// requirements that failed anywhere and where
+func (m *Matrix) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Conformance matrix, specification version %d\n\n", m.SpecVersion)
+	categories := m.categories()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "SERVICE\tSCORE\tCONFORMANT")
+	for _, c := range categories {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(c))
+	}
+	fmt.Fprintln(tw)
+	for _, e := range m.Services {
+		if e.Scorecard == nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Service, "n/a", "no")
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s", e.Service, formatScore(e.Scorecard.Score), yesNo(e.Scorecard.Conformant))
+		for _, c := range e.Scorecard.Categories {
+			fmt.Fprintf(tw, "\t%s", formatScore(c.Score))
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, e := range m.Services {
+		if e.Error != "" {
+			fmt.Fprintf(w, "\nERROR %s\n", e.Error)
+		}
+	}
+	for _, r := range m.requirements() {
+		var failing []string
+		for _, e := range m.Services {
+			if e.status(r.ID) == StatusFail {
+				failing = append(failing, e.Service)
+			}
+		}
+		if len(failing) > 0 {
+			fmt.Fprintf(w, "\nFAIL %s %s (%s): %s\n", r.ID, r.Title, r.Level, strings.Join(failing, ", "))
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes the matrix as a Markdown report: a row per service,
+// then a row per requirement with each service's result
+func (m *Matrix) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	categories := m.categories()
+	b.WriteString("# Conformance matrix\n\n")
+	fmt.Fprintf(&b, "Specification version %d, %s.\n\n", m.SpecVersion, m.Generated.Format("2006-01-02 15:04 MST"))
+	b.WriteString("| Service | Score | Conformant |")
+	for _, c := range categories {
+		fmt.Fprintf(&b, " %s |", c)
+	}
+	b.WriteString("\n|---------|-------|------------|")
+	b.WriteString(strings.Repeat("---|", len(categories)))
+	b.WriteString("\n")
+	for _, e := range m.Services {
+		if e.Scorecard == nil {
+			fmt.Fprintf(&b, "| %s | n/a | ❌ %s |%s\n", e.Service, markdownCell(e.Error), strings.Repeat(" |", len(categories)))
+			continue
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |", e.Service, formatScore(e.Scorecard.Score), conformantMark(e.Scorecard.Conformant))
+		for _, c := range e.Scorecard.Categories {
+			fmt.Fprintf(&b, " %s |", formatScore(c.Score))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n## Requirements\n\n| ID | Level | Requirement |")
+	for _, e := range m.Services {
+		fmt.Fprintf(&b, " %s |", e.Service)
+	}
+	b.WriteString("\n|----|-------|-------------|")
+	b.WriteString(strings.Repeat("---|", len(m.Services)))
+	b.WriteString("\n")
+	for _, r := range m.requirements() {
+		fmt.Fprintf(&b, "| %s | %s | %s |", r.ID, r.Level, markdownCell(r.Title))
+		for _, e := range m.Services {
+			fmt.Fprintf(&b, " %s |", statusMarks[e.status(r.ID)])
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var statusMarks = map[string]string{
+	StatusPass:   "✅",
+	StatusFail:   "❌",
+	StatusSkip:   "⏭️",
+	StatusNotRun: "–",
+}
+
+// WriteJSON writes the matrix as JSON
+func (m *Matrix) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func conformantMark(conformant bool) string {
+	if conformant {
+		return "✅"
+	}
+	return "❌"
+}
@@ -0,0 +1,284 @@
+// Package report scores a run of the conformance specification: a score
+// per category and overall, as text, Markdown or JSON, and an SVG badge.
+//
+// Results come from go test (see cmd/scorecard) or from contractctl, which
+// runs the specification itself.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/spec"
+)
+
+// Outcomes of a requirement
+const (
+	StatusPass   = "pass"
+	StatusFail   = "fail"
+	StatusSkip   = "skip"
+	StatusNotRun = "not_run" // not in the results at all
+)
+
+// Result is how a requirement's test ended
+type Result struct {
+	Status  string
+	Elapsed float64 // seconds
+	Reason  string  // why it failed or was skipped
+}
+
+// categoryOrder is the order categories are reported in; any others follow
+// alphabetically
+var categoryOrder = []string{"security", "protocol", "pubsub", "robustness", "performance"}
+
+// Scorecard is a service's conformance, by category and requirement
+type Scorecard struct {
+	Service     string    `json:"service"`
+	Generated   time.Time `json:"generated"`
+	SpecVersion int       `json:"spec_version"`
+	// Score is the percentage of the requirements that ran that passed, or
+	// nil if none ran
+	Score *float64 `json:"score"`
+	// Conformant is whether every MUST requirement ran and passed
+	Conformant   bool                `json:"conformant"`
+	Categories   []CategoryScore     `json:"categories"`
+	Requirements []RequirementResult `json:"requirements"`
+}
+
+// CategoryScore is how a service did in one category
+type CategoryScore struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Passed      int      `json:"passed"`
+	Failed      int      `json:"failed"`
+	Skipped     int      `json:"skipped"`
+	NotRun      int      `json:"not_run"`
+	Score       *float64 `json:"score"`
+}
+
+// RequirementResult is the outcome of one requirement's test
+type RequirementResult struct {
+	ID       string  `json:"id"`
+	Test     string  `json:"test"`
+	Category string  `json:"category"`
+	Level    string  `json:"level"`
+	Title    string  `json:"title"`
+	Status   string  `json:"status"`
+	Elapsed  float64 `json:"elapsed_seconds,omitempty"`
+	Reason   string  `json:"reason,omitempty"` // why it failed or was skipped
+}
+
+// Score builds a scorecard from the spec and a run's results, keyed by test
+// name (see spec.Requirement.TestName). Skipped and
+// missing tests don't count against the score, since optional requirements
+// are skipped by services that don't opt in; they do stop a service being
+// conformant if they are MUST requirements.
+func Score(s *spec.Spec, results map[string]Result, service string, now time.Time) *Scorecard {
+	sc := &Scorecard{Service: service, Generated: now.UTC(), SpecVersion: s.Version, Conformant: true}
+	byCategory := make(map[string]*CategoryScore)
+	for _, name := range categoryNames(s) {
+		cs := &CategoryScore{Name: name, Description: s.Categories[name]}
+		byCategory[name] = cs
+	}
+
+	var passed, ran int
+	for _, r := range s.Requirements {
+		res := RequirementResult{
+			ID:       r.ID,
+			Test:     r.TestName(),
+			Category: r.Category,
+			Level:    r.Level,
+			Title:    r.Title,
+			Status:   StatusNotRun,
+		}
+		if tr, ok := results[r.TestName()]; ok && tr.Status != "" {
+			res.Status, res.Elapsed = tr.Status, tr.Elapsed
+			if tr.Status != StatusPass {
+				// Conformance cases lead their messages with the ID
+				res.Reason = strings.TrimPrefix(tr.Reason, r.ID+": ")
+			}
+		}
+		sc.Requirements = append(sc.Requirements, res)
+
+		cs := byCategory[r.Category]
+		switch res.Status {
+		case StatusPass:
+			cs.Passed++
+			passed++
+			ran++
+		case StatusFail:
+			cs.Failed++
+			ran++
+		case StatusSkip:
+			cs.Skipped++
+		default:
+			cs.NotRun++
+		}
+		if r.Level == spec.LevelMust && res.Status != StatusPass {
+			sc.Conformant = false
+		}
+	}
+
+	sc.Score = percent(passed, ran)
+	for _, name := range categoryNames(s) {
+		cs := byCategory[name]
+		cs.Score = percent(cs.Passed, cs.Passed+cs.Failed)
+		sc.Categories = append(sc.Categories, *cs)
+	}
+	return sc
+}
+
+// categoryNames returns the spec's categories in report order
+func categoryNames(s *spec.Spec) []string {
+	var names, rest []string
+	for _, name := range categoryOrder {
+		if _, ok := s.Categories[name]; ok {
+			names = append(names, name)
+		}
+	}
+	for name := range s.Categories {
+		if !contains(categoryOrder, name) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func percent(n, of int) *float64 {
+	if of == 0 {
+		return nil
+	}
+	p := 100 * float64(n) / float64(of)
+	return &p
+}
+
+// formatScore renders a percentage, or n/a when nothing was scored
+func formatScore(p *float64) string {
+	if p == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.0f%%", *p)
+}
+
+// WriteText writes the scorecard for a terminal
+func (sc *Scorecard) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Conformance of %s: %s%s\n\n", serviceName(sc.Service), formatScore(sc.Score), conformantNote(sc.Conformant))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CATEGORY\tSCORE\tPASSED\tFAILED\tSKIPPED\tNOT RUN")
+	for _, c := range sc.Categories {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\n", c.Name, formatScore(c.Score), c.Passed, c.Failed, c.Skipped, c.NotRun)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, r := range sc.Requirements {
+		if r.Status == StatusFail {
+			fmt.Fprintf(w, "\nFAIL %s %s (%s)\n", r.ID, r.Title, r.Level)
+			if r.Reason != "" {
+				fmt.Fprintf(w, "     %s\n", r.Reason)
+			}
+		}
+	}
+	if ids := sc.Unverified(); len(ids) > 0 {
+		fmt.Fprintf(w, "\nMUST requirements that didn't run: %s\n", strings.Join(ids, ", "))
+	}
+	return nil
+}
+
+// Failed reports whether any requirement failed
+func (sc *Scorecard) Failed() bool {
+	for _, r := range sc.Requirements {
+		if r.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Unverified returns the MUST requirements that were skipped or not run,
+// which keep a service from being conformant without failing
+func (sc *Scorecard) Unverified() []string {
+	var ids []string
+	for _, r := range sc.Requirements {
+		if r.Level == spec.LevelMust && (r.Status == StatusSkip || r.Status == StatusNotRun) {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids
+}
+
+// WriteMarkdown writes the scorecard as a Markdown report
+func (sc *Scorecard) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Conformance: %s\n\n", serviceName(sc.Service))
+	fmt.Fprintf(&b, "**Score: %s**%s. Specification version %d, %s.\n\n",
+		formatScore(sc.Score), conformantNote(sc.Conformant), sc.SpecVersion, sc.Generated.Format("2006-01-02 15:04 MST"))
+	b.WriteString("| Category | Score | Passed | Failed | Skipped | Not run |\n")
+	b.WriteString("|----------|-------|--------|--------|---------|---------|\n")
+	for _, c := range sc.Categories {
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %d | %d |\n", c.Name, formatScore(c.Score), c.Passed, c.Failed, c.Skipped, c.NotRun)
+	}
+	if ids := sc.Unverified(); len(ids) > 0 {
+		fmt.Fprintf(&b, "\nMUST requirements that didn't run: %s.\n", strings.Join(ids, ", "))
+	}
+	b.WriteString("\n## Requirements\n\n")
+	b.WriteString("| ID | Level | Requirement | Result |\n")
+	b.WriteString("|----|-------|-------------|--------|\n")
+	for _, r := range sc.Requirements {
+		result := statusLabels[r.Status]
+		if r.Reason != "" {
+			result += ": " + markdownCell(r.Reason)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", r.ID, r.Level, markdownCell(r.Title), result)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var statusLabels = map[string]string{
+	StatusPass:   "✅ pass",
+	StatusFail:   "❌ fail",
+	StatusSkip:   "⏭️ skipped",
+	StatusNotRun: "not run",
+}
+
+// markdownCell escapes text for a table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// WriteJSON writes the scorecard as JSON
+func (sc *Scorecard) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sc)
+}
+
+func serviceName(s string) string {
+	if s == "" {
+		return "service"
+	}
+	return s
+}
+
+func conformantNote(conformant bool) string {
+	if conformant {
+		return ", conformant"
+	}
+	return ", not conformant"
+}
@@ -0,0 +1,87 @@
+package contract
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Request ID tests check a service takes the ingress's X-Request-ID, or
+// makes one up, and hands it back in the response and on to the published
+// message, so a failure a user reports can be followed through every hop.
+
+// contractRequestID is a request ID as an ingress might send it
+const contractRequestID = "contract-test-7f3c9a12-request"
+
+// sendWithRequestID posts a signed interaction with an X-Request-ID header,
+// or none when id is empty
+func sendWithRequestID(t *testing.T, body []byte, id string) (*http.Response, []byte) {
+	t.Helper()
+
+	header := http.Header{}
+	if id != "" {
+		header.Set("X-Request-ID", id)
+	}
+	return sendSigned(t, "POST", interactionsURL, body, header)
+}
+
+// pingBody returns a ping with the given interaction ID
+func pingBody(t *testing.T, id string) []byte {
+	return toJSON(t, InteractionRequest{Type: 1, ID: id, ApplicationID: "test-app-id"})
+}
+
+// TestRequestID_Echoed checks the response carries the request's ID
+func TestRequestID_Echoed(t *testing.T) {
+	resp, respBody := sendWithRequestID(t, pingBody(t, "request-id-echoed"), contractRequestID)
+	assertResponseType(t, resp, respBody, 1)
+	if got := resp.Header.Get("X-Request-ID"); got != contractRequestID {
+		t.Errorf("Expected X-Request-ID %q echoed, got %q", contractRequestID, got)
+	}
+}
+
+// TestRequestID_Generated checks a request without an ID is given one, and
+// that two requests aren't given the same
+func TestRequestID_Generated(t *testing.T) {
+	var ids []string
+	for _, interactionID := range []string{"request-id-generated-1", "request-id-generated-2"} {
+		resp, respBody := sendWithRequestID(t, pingBody(t, interactionID), "")
+		assertResponseType(t, resp, respBody, 1)
+		id := resp.Header.Get("X-Request-ID")
+		if id == "" {
+			t.Fatal("Expected an X-Request-ID generated for a request without one")
+		}
+		ids = append(ids, id)
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("Expected a different X-Request-ID for each request, got %q twice", ids[0])
+	}
+}
+
+// TestRequestID_UnsafeReplaced sends IDs no service should log verbatim and
+// checks each is replaced with one of the service's own
+func TestRequestID_UnsafeReplaced(t *testing.T) {
+	for _, unsafe := range []string{"contract test <script>", strings.Repeat("a", 200)} {
+		resp, respBody := sendWithRequestID(t, pingBody(t, "request-id-unsafe"), unsafe)
+		assertResponseType(t, resp, respBody, 1)
+		if got := resp.Header.Get("X-Request-ID"); got == "" || got == unsafe {
+			t.Errorf("Expected X-Request-ID %.40q replaced with a generated one, got %.40q", unsafe, got)
+		}
+	}
+}
+
+// TestRequestID_Published checks a slash command's request ID is published
+// as the request_id attribute
+func TestRequestID_Published(t *testing.T) {
+	sub, cleanup := serviceTopicSubscription(t, "CONTRACT_TEST_TOPIC")
+	defer cleanup()
+
+	req := createSlashCommandRequest("request-id-test")
+	resp, respBody := sendWithRequestID(t, toJSON(t, req), contractRequestID)
+	assertResponseType(t, resp, respBody, 5)
+
+	msg := receiveInteraction(t, sub, req.ID, 10*time.Second)
+	if got := msg.Attributes["request_id"]; got != contractRequestID {
+		t.Errorf("Expected request_id attribute %q, got %q", contractRequestID, got)
+	}
+}
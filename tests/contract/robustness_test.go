@@ -0,0 +1,370 @@
+package contract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/testkeys"
+)
+
+const (
+	// hostileDepth is far deeper than any interaction, and than a recursive
+	// parser without a guard can safely go
+	hostileDepth = 10000
+
+	// nestingBudget is how long rejecting a hostile body may take; parsing
+	// it at all would take longer, or crash the service
+	nestingBudget = time.Second
+)
+
+// A slow client trickles its body a byte at a time. It must be cut off
+// within slowBodyMargin of the service's read timeout, and other clients
+// answered within slowBodyHealthyBudget meanwhile.
+const (
+	slowBodyInterval      = time.Second
+	slowBodyMargin        = 2 * time.Second
+	slowBodyHealthyBudget = time.Second
+)
+
+// A load shedding test holds up to maxHeldRequests in flight. The service
+// must start shedding within shedBudget of the last one, and stop within
+// shedBudget of their release, probed every shedPollInterval.
+const (
+	maxHeldRequests  = 256
+	shedBudget       = 2 * time.Second
+	shedPollInterval = 50 * time.Millisecond
+)
+
+// assertErrorResponse checks a failed request got the JSON error body,
+// {"error": "<message>", "code": "<code>"}, with the expected code
+func assertErrorResponse(t *testing.T, resp *http.Response, respBody []byte, code string) {
+	t.Helper()
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/json" {
+		t.Fatalf("Expected a JSON error body, got Content-Type %q", resp.Header.Get("Content-Type"))
+	}
+	var body struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if err := json.Unmarshal(respBody, &body); err != nil {
+		t.Fatalf("Error body is not valid JSON: %v", err)
+	}
+	if body.Error == "" {
+		t.Error("Error body has no error message")
+	}
+	if body.Code != code {
+		t.Errorf("Expected error code %q, got %q", code, body.Code)
+	}
+}
+
+// TestError_OversizedBody sends a signed slash command one byte over the
+// service's body size limit, given by CONTRACT_TEST_MAX_BODY_BYTES
+func TestError_OversizedBody(t *testing.T) {
+	raw := os.Getenv("CONTRACT_TEST_MAX_BODY_BYTES")
+	if raw == "" {
+		t.Skip("CONTRACT_TEST_MAX_BODY_BYTES not set")
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		t.Fatalf("CONTRACT_TEST_MAX_BODY_BYTES=%q is not a positive number of bytes", raw)
+	}
+
+	body := oversizedSlashCommand(t, limit)
+	resp, respBody := sendRequest(t, body)
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413 for a %d byte body, got %d", len(body), resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "body_too_large")
+}
+
+// oversizedSlashCommand returns a slash command one byte over limit
+func oversizedSlashCommand(t *testing.T, limit int) []byte {
+	t.Helper()
+
+	req := createSlashCommandRequest("oversized-test")
+	req.Data["options"] = []map[string]interface{}{{"name": "text", "type": 3, "value": ""}}
+	padding := limit + 1 - len(toJSON(t, req))
+	if padding < 0 {
+		t.Fatalf("CONTRACT_TEST_MAX_BODY_BYTES=%d is smaller than a slash command", limit)
+	}
+	req.Data["options"] = []map[string]interface{}{{"name": "text", "type": 3, "value": strings.Repeat("x", padding)}}
+	return toJSON(t, req)
+}
+
+// TestError_OversizedGzipBody sends a signed slash command one byte over
+// CONTRACT_TEST_MAX_BODY_BYTES gzip-compressed, so the limit is only
+// exceeded once the body is decompressed
+func TestError_OversizedGzipBody(t *testing.T) {
+	raw := os.Getenv("CONTRACT_TEST_MAX_BODY_BYTES")
+	if raw == "" {
+		t.Skip("CONTRACT_TEST_MAX_BODY_BYTES not set")
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		t.Fatalf("CONTRACT_TEST_MAX_BODY_BYTES=%q is not a positive number of bytes", raw)
+	}
+
+	body := oversizedSlashCommand(t, limit)
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(body); err != nil {
+		t.Fatalf("Failed to compress body: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to compress body: %v", err)
+	}
+
+	signature, timestamp := testkeys.SignRequest(body)
+	req, err := http.NewRequest("POST", interactionsURL, &compressed)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-Signature-Ed25519", signature)
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413 for a %d byte body, got %d", len(body), resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "body_too_large")
+}
+
+// nestedPing returns a ping with an extra field whose arrays nest depth
+// levels deep, so the whole body nests depth+1 levels
+func nestedPing(depth int) []byte {
+	return []byte(`{"type":1,"id":"nested-test","application_id":"test-app-id","nested":` +
+		strings.Repeat("[", depth) + strings.Repeat("]", depth) + `}`)
+}
+
+// TestError_DeeplyNestedArrays sends a signed ping carrying arrays nested
+// 10,000 deep, which must be rejected quickly rather than parsed
+func TestError_DeeplyNestedArrays(t *testing.T) {
+	start := time.Now()
+	resp, respBody := sendRequest(t, nestedPing(hostileDepth))
+	elapsed := time.Since(start)
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for arrays nested %d deep, got %d", hostileDepth, resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "invalid_body")
+	if elapsed > nestingBudget {
+		t.Errorf("Expected rejection within %s, took %s", nestingBudget, elapsed.Round(time.Millisecond))
+	}
+}
+
+// TestError_NestingOverLimit checks the service's nesting limit, given by
+// CONTRACT_TEST_JSON_MAX_DEPTH, is exact: a body nested to the limit is
+// answered and one a level deeper is rejected
+func TestError_NestingOverLimit(t *testing.T) {
+	raw := os.Getenv("CONTRACT_TEST_JSON_MAX_DEPTH")
+	if raw == "" {
+		t.Skip("CONTRACT_TEST_JSON_MAX_DEPTH not set")
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 2 {
+		t.Fatalf("CONTRACT_TEST_JSON_MAX_DEPTH=%q is not a depth of at least 2", raw)
+	}
+
+	if resp, _ := sendRequest(t, nestedPing(limit-1)); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 for a body nested %d deep, got %d", limit, resp.StatusCode)
+	}
+	resp, respBody := sendRequest(t, nestedPing(limit))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for a body nested %d deep, got %d", limit+1, resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "invalid_body")
+}
+
+// dialTarget opens a raw connection to the service, with TLS for an https
+// target
+func dialTarget(t *testing.T, target *url.URL) net.Conn {
+	t.Helper()
+
+	host := target.Host
+	if target.Port() == "" {
+		port := "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(target.Hostname(), port)
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	var conn net.Conn
+	var err error
+	if target.Scheme == "https" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: target.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		t.Fatalf("Failed to connect to %s: %v", host, err)
+	}
+	return conn
+}
+
+// TestError_SlowBodyCutOff trickles a signed ping's body a byte a second
+// and checks the service closes the connection soon after its read timeout,
+// given by CONTRACT_TEST_READ_TIMEOUT, while still answering other clients
+func TestError_SlowBodyCutOff(t *testing.T) {
+	raw := os.Getenv("CONTRACT_TEST_READ_TIMEOUT")
+	if raw == "" {
+		t.Skip("CONTRACT_TEST_READ_TIMEOUT not set")
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil || timeout <= 0 {
+		t.Fatalf("CONTRACT_TEST_READ_TIMEOUT=%q is not a positive duration", raw)
+	}
+	target, err := url.Parse(interactionsURL)
+	if err != nil {
+		t.Fatalf("Invalid interactions URL %q: %v", interactionsURL, err)
+	}
+
+	// Padded so the body can't all arrive before the connection should be
+	// cut off
+	bound := timeout + slowBodyMargin
+	padding := int(bound/slowBodyInterval) + 1
+	body := []byte(`{"type":1,"id":"slow-body-test","application_id":"test-app-id","padding":"` +
+		strings.Repeat("x", padding) + `"}`)
+	signature, timestamp := testkeys.SignRequest(body)
+
+	conn := dialTarget(t, target)
+	defer conn.Close()
+	_, err = fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: %s\r\nContent-Type: application/json\r\n"+
+		"Content-Length: %d\r\nX-Signature-Ed25519: %s\r\nX-Signature-Timestamp: %s\r\n\r\n",
+		target.RequestURI(), target.Host, len(body), signature, timestamp)
+	if err != nil {
+		t.Fatalf("Failed to send headers: %v", err)
+	}
+	start := time.Now()
+
+	// Cut off means closed: a response alone would leave the connection held
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+
+	deadline := time.NewTimer(bound)
+	defer deadline.Stop()
+	tick := time.NewTicker(slowBodyInterval)
+	defer tick.Stop()
+	sent := 0
+	for {
+		select {
+		case <-closed:
+			t.Logf("Connection closed after %s, with %d of %d body bytes sent",
+				time.Since(start).Round(time.Millisecond), sent, len(body))
+			return
+		case <-deadline.C:
+			t.Fatalf("Connection still open %s after the headers, with %d of %d body bytes sent",
+				bound, sent, len(body))
+		case <-tick.C:
+			if _, err := conn.Write(body[sent : sent+1]); err == nil {
+				sent++
+			}
+			// Once the trickle is under way, another client must still be
+			// answered promptly
+			if sent == 1 {
+				pingStart := time.Now()
+				resp, _ := sendRequest(t, toJSON(t, InteractionRequest{Type: 1, ID: "slow-body-healthy", ApplicationID: "test-app-id"}))
+				if resp.StatusCode != http.StatusOK {
+					t.Errorf("Ping during the trickle failed with status %d", resp.StatusCode)
+				}
+				if elapsed := time.Since(pingStart); elapsed > slowBodyHealthyBudget {
+					t.Errorf("Expected a ping during the trickle answered within %s, took %s",
+						slowBodyHealthyBudget, elapsed.Round(time.Millisecond))
+				}
+			}
+		}
+	}
+}
+
+// TestError_LoadShed holds as many requests in flight as the service's
+// concurrency limit, given by CONTRACT_TEST_MAX_CONCURRENT_REQUESTS, by
+// sending their headers but not their bodies. Another request must be
+// answered 503 with Retry-After rather than queued, and requests must be
+// served again once the held ones are released.
+func TestError_LoadShed(t *testing.T) {
+	raw := os.Getenv("CONTRACT_TEST_MAX_CONCURRENT_REQUESTS")
+	if raw == "" {
+		t.Skip("CONTRACT_TEST_MAX_CONCURRENT_REQUESTS not set")
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 || limit > maxHeldRequests {
+		t.Fatalf("CONTRACT_TEST_MAX_CONCURRENT_REQUESTS=%q is not between 1 and %d", raw, maxHeldRequests)
+	}
+	target, err := url.Parse(interactionsURL)
+	if err != nil {
+		t.Fatalf("Invalid interactions URL %q: %v", interactionsURL, err)
+	}
+
+	body := toJSON(t, InteractionRequest{Type: 1, ID: "load-shed-held", ApplicationID: "test-app-id"})
+	signature, timestamp := testkeys.SignRequest(body)
+	var held []net.Conn
+	release := func() {
+		for _, conn := range held {
+			conn.Close()
+		}
+		held = nil
+	}
+	defer release()
+	for range limit {
+		conn := dialTarget(t, target)
+		held = append(held, conn)
+		_, err := fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: %s\r\nContent-Type: application/json\r\n"+
+			"Content-Length: %d\r\nX-Signature-Ed25519: %s\r\nX-Signature-Timestamp: %s\r\n\r\n",
+			target.RequestURI(), target.Host, len(body), signature, timestamp)
+		if err != nil {
+			t.Fatalf("Failed to send headers: %v", err)
+		}
+	}
+
+	// The held requests reach the service a moment after their headers
+	probe := toJSON(t, InteractionRequest{Type: 1, ID: "load-shed-probe", ApplicationID: "test-app-id"})
+	resp, respBody := sendRequest(t, probe)
+	for deadline := time.Now().Add(shedBudget); resp.StatusCode != http.StatusServiceUnavailable && time.Now().Before(deadline); {
+		time.Sleep(shedPollInterval)
+		resp, respBody = sendRequest(t, probe)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503 with %d requests in flight, got %d", limit, resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "overloaded")
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err != nil || seconds <= 0 {
+		t.Errorf("Expected Retry-After in whole seconds, got %q", resp.Header.Get("Retry-After"))
+	}
+
+	release()
+	resp, _ = sendRequest(t, probe)
+	for deadline := time.Now().Add(shedBudget); resp.StatusCode != http.StatusOK && time.Now().Before(deadline); {
+		time.Sleep(shedPollInterval)
+		resp, _ = sendRequest(t, probe)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected a ping answered once the held requests were released, got status %d", resp.StatusCode)
+	}
+}
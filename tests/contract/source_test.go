@@ -0,0 +1,86 @@
+package contract
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tests/contract/testkeys"
+)
+
+// Source filtering tests need a service that accepts interactions only from
+// some client addresses and trusts the test runner as a proxy, so the
+// X-Forwarded-For the tests send decides the source.
+// CONTRACT_TEST_ALLOWED_SOURCE names an address the service accepts, and
+// CONTRACT_TEST_BLOCKED_SOURCE one it refuses.
+
+// sourceAddress returns the address an environment variable names,
+// skipping the test without one
+func sourceAddress(t *testing.T, envVar string) string {
+	t.Helper()
+
+	addr := os.Getenv(envVar)
+	if addr == "" {
+		t.Skip(envVar + " not set")
+	}
+	if net.ParseIP(addr) == nil {
+		t.Fatalf("%s=%q is not an IP address", envVar, addr)
+	}
+	return addr
+}
+
+// sendFrom posts a signed ping forwarded for the given client addresses,
+// with extra headers
+func sendFrom(t *testing.T, forwardedFor string, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("X-Forwarded-For", forwardedFor)
+	return sendSigned(t, "POST", interactionsURL, pingBody(t, "source-filter"), header)
+}
+
+// TestSource_BlockedRejected checks a ping from a refused address gets 403,
+// and that one with a bad signature does too, so the source was checked
+// before the signature
+func TestSource_BlockedRejected(t *testing.T) {
+	blocked := sourceAddress(t, "CONTRACT_TEST_BLOCKED_SOURCE")
+
+	resp, respBody := sendFrom(t, blocked, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected status 403 from %s, got %d", blocked, resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "source_not_allowed")
+
+	badSignature := http.Header{"X-Signature-Ed25519": {testkeys.InvalidSignature()}}
+	resp, respBody = sendFrom(t, blocked, badSignature)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected status 403 from %s before the signature is checked, got %d", blocked, resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "source_not_allowed")
+}
+
+// TestSource_AllowedAccepted checks a ping from an accepted address is
+// answered
+func TestSource_AllowedAccepted(t *testing.T) {
+	allowed := sourceAddress(t, "CONTRACT_TEST_ALLOWED_SOURCE")
+
+	resp, respBody := sendFrom(t, allowed, nil)
+	assertResponseType(t, resp, respBody, 1)
+}
+
+// TestSource_SpoofedForwardedForRejected sends a ping from a refused
+// address that puts an accepted one first in X-Forwarded-For, as a client
+// can. Only the entry the trusted proxy added counts.
+func TestSource_SpoofedForwardedForRejected(t *testing.T) {
+	blocked := sourceAddress(t, "CONTRACT_TEST_BLOCKED_SOURCE")
+	allowed := sourceAddress(t, "CONTRACT_TEST_ALLOWED_SOURCE")
+
+	resp, respBody := sendFrom(t, allowed+", "+blocked, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for X-Forwarded-For %q, got %d", allowed+", "+blocked, resp.StatusCode)
+	}
+	assertErrorResponse(t, resp, respBody, "source_not_allowed")
+}
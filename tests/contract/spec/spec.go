@@ -0,0 +1,396 @@
+// Package spec loads the conformance specification, conformance.yaml: the
+// requirements every service must meet, with the requests that exercise
+// them and the responses and Pub/Sub messages they must produce.
+//
+// The contract tests are generated from it (see cmd/specgen), and reports
+// of a run are grouped by its categories and requirement IDs.
+package spec
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed conformance.yaml
+var conformanceYAML []byte
+
+// InteractionIDPlaceholder is replaced in request bodies and expectations by
+// an interaction ID unique to each run
+const InteractionIDPlaceholder = "{{interaction_id}}"
+
+// Requirement levels, as in RFC 2119
+const (
+	LevelMust   = "MUST"
+	LevelShould = "SHOULD"
+	LevelMay    = "MAY"
+)
+
+// Signature modes: how a request's X-Signature-Ed25519 is made
+const (
+	SignatureValid     = "valid"
+	SignatureMissing   = "missing"
+	SignatureInvalid   = "invalid"    // well-formed hex that doesn't verify
+	SignatureMalformed = "malformed"  // not hex
+	SignatureOtherBody = "other_body" // a valid signature of a different body
+)
+
+// Timestamp modes: how a request's X-Signature-Timestamp is made
+const (
+	TimestampCurrent = "current"
+	TimestampMissing = "missing"
+	TimestampExpired = "expired" // 10 seconds old
+)
+
+// ContentTypeNone sends a request without a Content-Type header
+const ContentTypeNone = "none"
+
+// ContentEncodingGzip sends a request's body gzip-compressed. Other content
+// encodings are named in the header but the body is sent as is.
+const ContentEncodingGzip = "gzip"
+
+// Spec is the conformance specification
+type Spec struct {
+	Version int `yaml:"version"`
+	// Categories maps each category's name to what it covers
+	Categories map[string]string `yaml:"categories"`
+	// Errors maps each error code a failed request's body may carry to
+	// what it means
+	Errors       map[string]ErrorCode      `yaml:"errors"`
+	Fixtures     map[string]map[string]any `yaml:"fixtures"`
+	Requirements []Requirement             `yaml:"requirements"`
+}
+
+// ErrorCode is a machine-readable reason a request failed, sent as the
+// code of the error body {"error": "<message>", "code": "<code>"}
+type ErrorCode struct {
+	Status      int    `yaml:"status"`
+	Description string `yaml:"description"`
+}
+
+// Requirement is one thing a service must do
+type Requirement struct {
+	ID          string `yaml:"id"`
+	Test        string `yaml:"test"` // the Go test is Test<Test>
+	Category    string `yaml:"category"`
+	Level       string `yaml:"level"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	// EnabledBy is the environment variable that opts a service into an
+	// optional requirement
+	EnabledBy string `yaml:"enabled_by"`
+	// Request is how the requirement is tested. Without one, the
+	// requirement is covered by a hand-written test.
+	Request *Request `yaml:"request"`
+	Expect  Expect   `yaml:"expect"`
+	Publish *Publish `yaml:"publish"`
+}
+
+// Request is the interaction sent to test a requirement
+type Request struct {
+	// Fixture names the body to send, and Patch overrides its fields
+	Fixture string         `yaml:"fixture"`
+	Patch   map[string]any `yaml:"patch"`
+	// Body is sent byte for byte instead of a fixture
+	Body      *string `yaml:"body"`
+	Signature string  `yaml:"signature"` // default: valid
+	Timestamp string  `yaml:"timestamp"` // default: current
+	// ContentType is the Content-Type header sent, or ContentTypeNone for
+	// none; default: application/json
+	ContentType string `yaml:"content_type"`
+	// ContentEncoding is the Content-Encoding header sent; default: none.
+	// The signature is always made over the body before it is encoded.
+	ContentEncoding string `yaml:"content_encoding"`
+}
+
+// Expect is the response a request must get
+type Expect struct {
+	Status      Statuses `yaml:"status"`       // any of these
+	ContentType string   `yaml:"content_type"` // media type, parameters aside
+	// Response maps dotted paths into the JSON response, e.g. data.flags,
+	// to their values
+	Response  map[string]any `yaml:"response"`
+	Ephemeral *bool          `yaml:"ephemeral"` // data.flags has EPHEMERAL (64)
+	// MaxLatency is how long the response may take, e.g. 3s
+	MaxLatency time.Duration `yaml:"max_latency"`
+	// Error is the error body's code, or any of a list of them
+	Error Codes `yaml:"error"`
+}
+
+// Publish is what a request must publish to the service's topic
+type Publish struct {
+	None       bool              `yaml:"none"` // nothing at all
+	Attributes map[string]string `yaml:"attributes"`
+	// Data maps dotted paths into the published interaction to their values
+	Data map[string]any `yaml:"data"`
+	// Absent lists top-level fields the published interaction must not have
+	Absent []string `yaml:"absent"`
+	// NeverContains lists strings that appear nowhere in the message's data
+	// or attributes
+	NeverContains []string `yaml:"never_contains"`
+}
+
+// Statuses is one status code or a list of acceptable ones
+type Statuses []int
+
+func (s *Statuses) UnmarshalYAML(node *yaml.Node) error {
+	codes, err := decodeOneOrMore[int](node)
+	*s = codes
+	return err
+}
+
+// Codes is one error code or a list of acceptable ones
+type Codes []string
+
+func (c *Codes) UnmarshalYAML(node *yaml.Node) error {
+	codes, err := decodeOneOrMore[string](node)
+	*c = codes
+	return err
+}
+
+// decodeOneOrMore decodes a scalar or a list of them
+func decodeOneOrMore[T any](node *yaml.Node) ([]T, error) {
+	if node.Kind == yaml.ScalarNode {
+		var v T
+		if err := node.Decode(&v); err != nil {
+			return nil, err
+		}
+		return []T{v}, nil
+	}
+	var vs []T
+	if err := node.Decode(&vs); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// Load returns the conformance specification
+func Load() (*Spec, error) {
+	return Parse(conformanceYAML)
+}
+
+// Parse reads and checks a specification
+func Parse(raw []byte) (*Spec, error) {
+	var s Spec
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, s.validate()
+}
+
+var (
+	idPattern   = regexp.MustCompile(`^[A-Z]{3}-[0-9]{3}$`)
+	testPattern = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*_[A-Z][A-Za-z0-9]*$`)
+)
+
+func (s *Spec) validate() error {
+	if s.Version != 1 {
+		return fmt.Errorf("unsupported version %d", s.Version)
+	}
+	var errs []error
+	for code, e := range s.Errors {
+		if e.Status < 400 || e.Status > 599 {
+			errs = append(errs, fmt.Errorf("error %s: status %d isn't a failure", code, e.Status))
+		}
+	}
+	ids := make(map[string]bool)
+	tests := make(map[string]bool)
+	for _, r := range s.Requirements {
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("%s: %s", r.ID, fmt.Sprintf(format, args...)))
+		}
+		if !idPattern.MatchString(r.ID) {
+			fail("IDs look like SEC-001")
+		}
+		if ids[r.ID] {
+			fail("duplicate ID")
+		}
+		ids[r.ID] = true
+		if !testPattern.MatchString(r.Test) {
+			fail("test %q should look like Category_Name", r.Test)
+		}
+		if tests[r.Test] {
+			fail("test %s is used twice", r.Test)
+		}
+		tests[r.Test] = true
+		if _, ok := s.Categories[r.Category]; !ok {
+			fail("unknown category %q", r.Category)
+		}
+		if r.Level != LevelMust && r.Level != LevelShould && r.Level != LevelMay {
+			fail("level must be MUST, SHOULD or MAY")
+		}
+		if r.Title == "" {
+			fail("no title")
+		}
+		if r.Request == nil {
+			if len(r.Expect.Status) > 0 || r.Publish != nil {
+				fail("expect and publish need a request")
+			}
+			continue
+		}
+		if err := s.validateRequest(r.Request); err != nil {
+			fail("%v", err)
+		}
+		if len(r.Expect.Status) == 0 {
+			fail("expect.status is required")
+		}
+		if r.Expect.MaxLatency < 0 {
+			fail("expect.max_latency can't be negative")
+		}
+		for _, code := range r.Expect.Error {
+			e, ok := s.Errors[code]
+			switch {
+			case !ok:
+				fail("unknown error code %q", code)
+			case !slices.Contains(r.Expect.Status, e.Status):
+				fail("error code %s is sent with status %d, which expect.status doesn't allow", code, e.Status)
+			}
+		}
+		if p := r.Publish; p != nil && p.None && (len(p.Attributes) > 0 || len(p.Data) > 0 || len(p.Absent) > 0 || len(p.NeverContains) > 0) {
+			fail("publish.none can't be combined with expectations of the message")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Spec) validateRequest(req *Request) error {
+	switch {
+	case req.Body != nil && req.Fixture != "":
+		return errors.New("request has both a body and a fixture")
+	case req.Body != nil && req.Patch != nil:
+		return errors.New("patch applies to fixtures, not bodies")
+	case req.Body == nil:
+		if _, ok := s.Fixtures[req.Fixture]; !ok {
+			return fmt.Errorf("unknown fixture %q", req.Fixture)
+		}
+	}
+	switch req.Signature {
+	case "", SignatureValid, SignatureMissing, SignatureInvalid, SignatureMalformed, SignatureOtherBody:
+	default:
+		return fmt.Errorf("unknown signature mode %q", req.Signature)
+	}
+	switch req.Timestamp {
+	case "", TimestampCurrent, TimestampMissing, TimestampExpired:
+	default:
+		return fmt.Errorf("unknown timestamp mode %q", req.Timestamp)
+	}
+	return nil
+}
+
+// Category returns the requirements in a category, in specification order
+func (s *Spec) Category(name string) []Requirement {
+	var out []Requirement
+	for _, r := range s.Requirements {
+		if r.Category == name {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Select returns the specification with only the requirements in the
+// named categories, or all of them without any names
+func (s *Spec) Select(categories []string) (*Spec, error) {
+	if len(categories) == 0 {
+		return s, nil
+	}
+	out := *s
+	out.Categories = make(map[string]string, len(categories))
+	for _, name := range categories {
+		description, ok := s.Categories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown category %q", name)
+		}
+		out.Categories[name] = description
+	}
+	out.Requirements = nil
+	for _, r := range s.Requirements {
+		if _, ok := out.Categories[r.Category]; ok {
+			out.Requirements = append(out.Requirements, r)
+		}
+	}
+	return &out, nil
+}
+
+// Body returns the body a request sends: its fixture with the patch applied,
+// encoded as JSON, or its literal body
+func (s *Spec) Body(req *Request) (string, error) {
+	if req.Body != nil {
+		return *req.Body, nil
+	}
+	body := merge(s.Fixtures[req.Fixture], req.Patch)
+	out, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("fixture %s: %w", req.Fixture, err)
+	}
+	return string(out), nil
+}
+
+// merge returns base with the patch's fields set over it. Objects are
+// merged field by field; anything else replaces what was there.
+func merge(base, patch map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(patch))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range patch {
+		if sub, ok := v.(map[string]any); ok {
+			if existing, ok := out[k].(map[string]any); ok {
+				out[k] = merge(existing, sub)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// JSONValues renders a path-to-value map's values as compact JSON, the form
+// generated tests compare with
+func JSONValues(m map[string]any) (map[string]string, error) {
+	out := make(map[string]string, len(m))
+	for path, v := range m {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		out[path] = string(b)
+	}
+	return out, nil
+}
+
+// SignatureMode returns the request's signature mode, defaulting to valid
+func (req *Request) SignatureMode() string {
+	if req.Signature == "" {
+		return SignatureValid
+	}
+	return req.Signature
+}
+
+// TimestampMode returns the request's timestamp mode, defaulting to current
+func (req *Request) TimestampMode() string {
+	if req.Timestamp == "" {
+		return TimestampCurrent
+	}
+	return req.Timestamp
+}
+
+// TestName returns the Go test's function name
+func (r Requirement) TestName() string {
+	return "Test" + r.Test
+}
+
+// String identifies the requirement in messages
+func (r Requirement) String() string {
+	return strings.TrimSpace(r.ID + " " + r.Title)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// target is a service to load, named for the comparison table
+type target struct {
+	Name string
+	URL  string
+}
+
+// parseTargets parses a -compare value: comma-separated URLs, each
+// optionally named, e.g. "go-gin=http://localhost:8081,http://localhost:8082"
+func parseTargets(s string) ([]target, error) {
+	var targets []target
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		t := target{Name: part, URL: part}
+		if name, u, ok := strings.Cut(part, "="); ok && !strings.Contains(name, "/") {
+			t = target{Name: name, URL: u}
+		}
+		if parsed, err := url.Parse(t.URL); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid target %q (want [name=]URL)", part)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("target %q is listed twice", t.Name)
+		}
+		seen[t.Name] = true
+		targets = append(targets, t)
+	}
+	if len(targets) < 2 {
+		return nil, errors.New("-compare needs at least two targets")
+	}
+	return targets, nil
+}
+
+// Comparison is the machine-readable result of running one profile against
+// several targets
+type Comparison struct {
+	Label    string    `json:"label,omitempty"`
+	Started  time.Time `json:"started"`
+	Parallel bool      `json:"parallel"`
+	Targets  []*Report `json:"targets"`
+}
+
+// writeText prints each target's report, then a table comparing their totals
+func (c *Comparison) writeText(w io.Writer) {
+	for _, r := range c.Targets {
+		if r.Name == r.Target {
+			fmt.Fprintf(w, "== %s\n", r.Target)
+		} else {
+			fmt.Fprintf(w, "== %s (%s)\n", r.Name, r.Target)
+		}
+		r.writeText(w)
+		fmt.Fprintln(w)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Target\tCompleted\tAchieved\tErrors\tDropped\tp50\tp90\tp99\tp99.9\tMax\t")
+	for _, r := range c.Targets {
+		t := r.Total
+		fmt.Fprintf(tw, "%s\t%d\t%.1f/s\t%.2f%%\t%d\t%s\t%s\t%s\t%s\t%s\t\n",
+			r.Name, t.Completed, t.AchievedRPS, 100*t.ErrorRate, t.Dropped, msLabel(t.Latency.P50),
+			msLabel(t.Latency.P90), msLabel(t.Latency.P99), msLabel(t.Latency.P999), msLabel(t.Latency.Max))
+	}
+	if err := tw.Flush(); err != nil {
+		return
+	}
+	if c.Parallel {
+		fmt.Fprintln(w, "\nTargets were loaded in parallel, so services sharing a host competed for it.")
+	}
+}
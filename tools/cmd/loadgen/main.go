@@ -0,0 +1,238 @@
+// Command loadgen sends signed interactions to a service at controlled
+// rates and reports throughput, latency percentiles and errors:
+//
+//	loadgen -rps 200 -duration 1m
+//	loadgen -profile profiles/ramp.yaml -json results.json -label "$GIT_SHA"
+//
+// A profile runs through stages, such as a warm-up ramp, a steady rate and
+// a spike, and each stage is reported separately. A mix sends several kinds
+// of request, invalid ones included, in set proportions:
+//
+//	loadgen -mix command=70,ping=20,component=5,bad_signature=5
+//
+// Valid interactions are expected to get a 2xx; -expect changes that for
+// services that reject some kinds, such as "-expect component=400".
+//
+// Comparison mode runs the same load against several services, such as
+// each implementation in this repository, and tabulates them side by side:
+//
+//	loadgen -compare go-gin=http://localhost:8081,python-flask=http://localhost:8082
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tools/internal/interaction"
+)
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 2
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	targetURL := fs.String("target", envString("LOADGEN_TARGET", "http://localhost:8080"), "URL interactions are posted to")
+	profilePath := fs.String("profile", "", "profile file of stages to run (default: -rps for -duration)")
+	rps := fs.Float64("rps", 50, "requests per second, without -profile")
+	duration := fs.Duration("duration", 30*time.Second, "how long to run, without -profile")
+	command := fs.String("command", "echo", "slash command to invoke")
+	mixSpec := fs.String("mix", "", `traffic mix as kind=weight pairs, e.g. "command=70,ping=20,bad_signature=10" (default: the profile's, else all command)`)
+	expectSpec := fs.String("expect", "", `responses to expect as kind=status pairs, overriding Discord's, e.g. "component=400" (default: 2xx for valid interactions)`)
+	keySpec := fs.String("key", envString("LOADGEN_KEY", "test"), `signing key: "test", or a hex seed or private key`)
+	timeout := fs.Duration("timeout", 5*time.Second, "request timeout")
+	maxInFlight := fs.Int("max-in-flight", 1000, "requests outstanding at once; more are dropped and reported")
+	jsonPath := fs.String("json", "", "also write the report as JSON to this file (- for stdout instead of the table)")
+	label := fs.String("label", "", "label recorded in the JSON report, e.g. a build or commit")
+	compare := fs.String("compare", "", "comma-separated [name=]URL targets to run the same load against, instead of -target")
+	parallel := fs.Bool("parallel", false, "with -compare, load every target at once rather than one after another")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "loadgen: unexpected argument %q\n", fs.Arg(0))
+		return exitError
+	}
+	if *maxInFlight < 1 {
+		fmt.Fprintln(stderr, "loadgen: -max-in-flight must be at least 1")
+		return exitError
+	}
+
+	targets := []target{{URL: *targetURL}}
+	if *compare != "" {
+		var err error
+		if targets, err = parseTargets(*compare); err != nil {
+			fmt.Fprintln(stderr, "loadgen:", err)
+			return exitError
+		}
+	}
+
+	profile := steadyProfile(*rps, *duration)
+	if *profilePath != "" {
+		var err error
+		if profile, err = loadProfile(*profilePath); err != nil {
+			fmt.Fprintln(stderr, "loadgen:", err)
+			return exitError
+		}
+	} else if err := profile.validate(); err != nil {
+		fmt.Fprintln(stderr, "loadgen:", err)
+		return exitError
+	}
+	if *mixSpec != "" {
+		mix, err := parseMix(*mixSpec)
+		if err != nil {
+			fmt.Fprintln(stderr, "loadgen:", err)
+			return exitError
+		}
+		profile.Mix = mix
+	}
+	if profile.Mix == nil {
+		profile.Mix = Mix{"command": 1}
+	}
+	expect := defaultExpectations()
+	if *expectSpec != "" {
+		if err := expect.parseExpect(*expectSpec); err != nil {
+			fmt.Fprintln(stderr, "loadgen:", err)
+			return exitError
+		}
+	}
+	key, err := interaction.LoadKey(*keySpec)
+	if err != nil {
+		fmt.Fprintln(stderr, "loadgen:", err)
+		return exitError
+	}
+	_, wrongKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		fmt.Fprintln(stderr, "loadgen:", err)
+		return exitError
+	}
+
+	spec := interaction.Spec{
+		Command:       *command,
+		ApplicationID: "100000000000000001",
+		GuildID:       "100000000000000002",
+		ChannelID:     "100000000000000003",
+		UserID:        "100000000000000004",
+		Username:      "loadgen",
+		Permissions:   "2248473465835073",
+		Locale:        "en-US",
+		GuildLocale:   "en-US",
+	}
+	gen := &generator{spec: spec, key: key, wrongKey: wrongKey}
+	// Catch a bad command before sending anything
+	if _, err := gen.build("command"); err != nil {
+		fmt.Fprintln(stderr, "loadgen:", err)
+		return exitError
+	}
+
+	// Every target gets the same sequence of requests
+	seed := mathrand.Uint64()
+	started := time.Now()
+	reports := make([]*Report, len(targets))
+	load := func(i int) {
+		t := targets[i]
+		r := &runner{
+			name:   t.Name,
+			target: t.URL,
+			client: &http.Client{
+				Timeout:   *timeout,
+				Transport: &http.Transport{MaxIdleConnsPerHost: *maxInFlight},
+			},
+			gen:         gen,
+			expect:      expect,
+			maxInFlight: *maxInFlight,
+			progress:    stderr,
+			seed:        seed,
+		}
+		r.logf("Sending to %s for %s\n", r.target, profile.Duration())
+		runStarted := time.Now()
+		reports[i] = newReport(*label, t.Name, r.target, runStarted, r.run(ctx, profile))
+	}
+	if *parallel {
+		var wg sync.WaitGroup
+		for i := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				load(i)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i := range targets {
+			if ctx.Err() == nil {
+				load(i)
+			}
+		}
+	}
+
+	var out interface {
+		writeText(io.Writer)
+	}
+	var doc any
+	if len(targets) == 1 {
+		out, doc = reports[0], reports[0]
+	} else {
+		// Stopping during a sequential comparison leaves later targets unrun
+		var ran []*Report
+		for _, r := range reports {
+			if r != nil {
+				ran = append(ran, r)
+			}
+		}
+		c := &Comparison{Label: *label, Started: started.UTC(), Parallel: *parallel, Targets: ran}
+		out, doc = c, c
+	}
+
+	if *jsonPath == "-" {
+		if err := writeJSON(stdout, doc); err != nil {
+			fmt.Fprintln(stderr, "loadgen:", err)
+			return exitError
+		}
+		return exitOK
+	}
+	out.writeText(stdout)
+	if *jsonPath != "" {
+		if err := writeJSONFile(*jsonPath, doc); err != nil {
+			fmt.Fprintln(stderr, "loadgen:", err)
+			return exitError
+		}
+	}
+	return exitOK
+}
+
+// writeJSONFile writes a report or comparison to path
+func writeJSONFile(path string, v any) error {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, v); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// envString returns the value of an environment variable or a default
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tools/internal/interaction"
+)
+
+// Mix is the share of traffic each kind of request makes up, by weight:
+// {command: 70, ping: 20, bad_signature: 10} sends 70% slash commands
+type Mix map[string]float64
+
+// request is one request ready to sign and send
+type request struct {
+	kind string
+	body []byte
+	key  ed25519.PrivateKey // nil to send it unsigned
+	skew time.Duration      // added to the signature timestamp
+}
+
+// trafficKind is a kind of request a mix can include, and the response a
+// service following Discord's documentation gives it
+type trafficKind struct {
+	expect string // "2xx", or the status a correct service rejects it with
+	build  func(g *generator) (request, error)
+}
+
+// trafficKinds are the requests a mix can be made of. The invalid ones
+// exercise a service's rejection paths at realistic ratios.
+var trafficKinds = map[string]trafficKind{
+	"ping":         {expect: "2xx", build: valid("ping")},
+	"command":      {expect: "2xx", build: valid("command")},
+	"component":    {expect: "2xx", build: valid("component")},
+	"autocomplete": {expect: "2xx", build: valid("autocomplete")},
+	"modal":        {expect: "2xx", build: valid("modal")},
+	// Signed by a key the service doesn't trust
+	"bad_signature": {expect: "401", build: func(g *generator) (request, error) {
+		r, err := g.signed("command")
+		r.key = g.wrongKey
+		return r, err
+	}},
+	// Signed correctly, but ten minutes ago
+	"stale_timestamp": {expect: "401", build: func(g *generator) (request, error) {
+		r, err := g.signed("command")
+		r.skew = -10 * time.Minute
+		return r, err
+	}},
+	"unsigned": {expect: "401", build: func(g *generator) (request, error) {
+		r, err := g.signed("command")
+		r.key = nil
+		return r, err
+	}},
+	// Signed, but not JSON
+	"malformed": {expect: "400", build: func(g *generator) (request, error) {
+		return request{body: []byte(`{"type": 2, "data": {"name": `), key: g.key}, nil
+	}},
+	// Signed JSON of an interaction type Discord doesn't send
+	"unknown_type": {expect: "400", build: func(g *generator) (request, error) {
+		return request{body: []byte(`{"type": 99, "id": "1", "token": "loadgen", "version": 1}`), key: g.key}, nil
+	}},
+}
+
+// valid builds a correctly signed interaction of the given type
+func valid(typ string) func(g *generator) (request, error) {
+	return func(g *generator) (request, error) { return g.signed(typ) }
+}
+
+// generator builds the requests a mix picks
+type generator struct {
+	spec     interaction.Spec // IDs, and the command to invoke
+	key      ed25519.PrivateKey
+	wrongKey ed25519.PrivateKey
+}
+
+// signed builds an interaction of the given type, to sign with the service's key
+func (g *generator) signed(typ string) (request, error) {
+	spec := g.spec
+	spec.Type = typ
+	switch typ {
+	case "autocomplete":
+		spec.Options, spec.Focused = []string{"query=lo"}, "query"
+	case "component":
+		spec.CustomID = "loadgen:button"
+	case "modal":
+		spec.CustomID, spec.Fields = "loadgen:form", []string{"feedback=load test"}
+	}
+	payload, err := spec.Build()
+	if err != nil {
+		return request{}, err
+	}
+	body, err := json.Marshal(payload)
+	return request{body: body, key: g.key}, err
+}
+
+// build returns a request of the named kind
+func (g *generator) build(kind string) (request, error) {
+	r, err := trafficKinds[kind].build(g)
+	r.kind = kind
+	return r, err
+}
+
+// Expectations are the response each kind of request should get: "2xx", or
+// a status code
+type Expectations map[string]string
+
+// defaultExpectations returns the responses a service following Discord's
+// documentation gives each kind
+func defaultExpectations() Expectations {
+	e := make(Expectations, len(trafficKinds))
+	for name, k := range trafficKinds {
+		e[name] = k.expect
+	}
+	return e
+}
+
+// parseExpect overrides expectations with an -expect value such as
+// "component=400,modal=400", for services that reject kinds Discord sends
+func (e Expectations) parseExpect(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		name, want, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("invalid expect entry %q (want kind=status)", part)
+		}
+		if _, known := trafficKinds[name]; !known {
+			return fmt.Errorf("expect: unknown kind %q (want one of %s)", name, strings.Join(kindNames(), ", "))
+		}
+		if code, err := strconv.Atoi(want); want != "2xx" && (err != nil || code < 100 || code > 599) {
+			return fmt.Errorf("expect: %s must be 2xx or a status code, not %q", name, want)
+		}
+		e[name] = want
+	}
+	return nil
+}
+
+// met reports whether a kind's outcome is the one expected
+func (e Expectations) met(kind, outcome string) bool {
+	want := e[kind]
+	if want == "2xx" {
+		code, err := strconv.Atoi(outcome)
+		return err == nil && code >= 200 && code <= 299
+	}
+	return outcome == want
+}
+
+// parseMix parses a -mix value such as "command=70,ping=20,bad_signature=10"
+func parseMix(s string) (Mix, error) {
+	m := make(Mix)
+	for _, part := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q (want kind=weight)", part)
+		}
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight in mix entry %q", part)
+		}
+		m[name] += w
+	}
+	return m, m.validate()
+}
+
+// validate reports unknown kinds and weights that aren't positive
+func (m Mix) validate() error {
+	if len(m) == 0 {
+		return errors.New("mix is empty")
+	}
+	var errs []error
+	for _, name := range m.kinds() {
+		if _, ok := trafficKinds[name]; !ok {
+			errs = append(errs, fmt.Errorf("mix: unknown kind %q (want one of %s)", name, strings.Join(kindNames(), ", ")))
+		} else if m[name] <= 0 {
+			errs = append(errs, fmt.Errorf("mix: %s must have a positive weight", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// kinds returns the mix's kinds in name order
+func (m Mix) kinds() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String describes the mix as percentages, e.g. "command 70%, ping 30%"
+func (m Mix) String() string {
+	var total float64
+	for _, w := range m {
+		total += w
+	}
+	parts := make([]string, 0, len(m))
+	for _, name := range m.kinds() {
+		parts = append(parts, fmt.Sprintf("%s %.4g%%", name, 100*m[name]/total))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// picker returns a function that chooses a kind at random, in proportion
+// to its weight. Pickers seeded alike choose the same sequence of kinds.
+func (m Mix) picker(seed uint64) func() string {
+	rng := mathrand.New(mathrand.NewPCG(seed, 0))
+	names := m.kinds()
+	cumulative := make([]float64, len(names))
+	var total float64
+	for i, name := range names {
+		total += m[name]
+		cumulative[i] = total
+	}
+	return func() string {
+		x := rng.Float64() * total
+		for i, c := range cumulative {
+			if x < c {
+				return names[i]
+			}
+		}
+		return names[len(names)-1]
+	}
+}
+
+// kindNames lists every kind a mix can include
+func kindNames() []string {
+	names := make([]string, 0, len(trafficKinds))
+	for name := range trafficKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
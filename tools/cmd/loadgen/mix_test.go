@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpectations(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		expect  string // -expect, or "" for the defaults
+		kind    string
+		outcome string
+		want    bool
+	}{
+		{"valid kind answered", "", "command", "200", true},
+		{"valid kind answered 204", "", "ping", "204", true},
+		{"valid kind rejected", "", "component", "400", false},
+		{"valid kind timed out", "", "command", "timeout", false},
+		{"invalid kind rejected", "", "bad_signature", "401", true},
+		{"invalid kind accepted", "", "bad_signature", "200", false},
+		{"invalid kind rejected otherwise", "", "malformed", "401", false},
+		{"rejection expected", "component=400", "component", "400", true},
+		{"rejection expected, answered", "component=400", "component", "200", false},
+		{"other kinds unchanged", "component=400", "modal", "200", true},
+		{"several overrides", "component=400, modal=2xx", "modal", "201", true},
+	} {
+		e := defaultExpectations()
+		if tc.expect != "" {
+			if err := e.parseExpect(tc.expect); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+		}
+		if got := e.met(tc.kind, tc.outcome); got != tc.want {
+			t.Errorf("%s: expected %s %s to be met: %t, got %t", tc.name, tc.kind, tc.outcome, tc.want, got)
+		}
+	}
+}
+
+func TestParseExpect_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		expect string
+		want   string
+	}{
+		{"component", "want kind=status"},
+		{"button=400", `unknown kind "button"`},
+		{"component=4xx", "must be 2xx or a status code"},
+		{"component=600", "must be 2xx or a status code"},
+	} {
+		err := defaultExpectations().parseExpect(tc.expect)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("-expect %q: expected an error containing %q, got %v", tc.expect, tc.want, err)
+		}
+	}
+}
+
+func TestParseMix(t *testing.T) {
+	m, err := parseMix("command=70, ping=20,bad_signature=5,bad_signature=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.String(), "bad_signature 10%, command 70%, ping 20%"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	for _, tc := range []struct {
+		mix  string
+		want string
+	}{
+		{"command", "want kind=weight"},
+		{"command=many", "invalid weight"},
+		{"button=1", `unknown kind "button"`},
+		{"command=0", "must have a positive weight"},
+	} {
+		if _, err := parseMix(tc.mix); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("-mix %q: expected an error containing %q, got %v", tc.mix, tc.want, err)
+		}
+	}
+}
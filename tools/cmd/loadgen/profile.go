@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is the sequence of stages a load test runs through
+type Profile struct {
+	Mix    Mix     `yaml:"mix"` // default: every request a slash command
+	Stages []Stage `yaml:"stages"`
+}
+
+// Stage sends requests at a fixed rate, or a rate ramping linearly from RPS
+// to RampTo, for its duration
+type Stage struct {
+	Name     string        `yaml:"name"`
+	Duration time.Duration `yaml:"duration"`
+	RPS      float64       `yaml:"rps"`
+	RampTo   float64       `yaml:"ramp_to"` // 0: hold RPS
+	Mix      Mix           `yaml:"mix"`     // default: the profile's
+}
+
+// rate returns the request rate elapsed into the stage
+func (s Stage) rate(elapsed time.Duration) float64 {
+	if s.RampTo == 0 {
+		return s.RPS
+	}
+	return s.RPS + (s.RampTo-s.RPS)*elapsed.Seconds()/s.Duration.Seconds()
+}
+
+// due returns how many requests the stage should have sent elapsed into
+// it: the integral of its rate
+func (s Stage) due(elapsed time.Duration) float64 {
+	t := elapsed.Seconds()
+	if s.RampTo == 0 {
+		return s.RPS * t
+	}
+	return s.RPS*t + (s.RampTo-s.RPS)*t*t/(2*s.Duration.Seconds())
+}
+
+// rateLabel describes the stage's rate, e.g. "100" or "10→100"
+func (s Stage) rateLabel() string {
+	if s.RampTo == 0 {
+		return fmt.Sprintf("%g", s.RPS)
+	}
+	return fmt.Sprintf("%g→%g", s.RPS, s.RampTo)
+}
+
+// Duration is how long the whole profile takes
+func (p *Profile) Duration() time.Duration {
+	var d time.Duration
+	for _, s := range p.Stages {
+		d += s.Duration
+	}
+	return d
+}
+
+// steadyProfile is the profile for -rps and -duration: a single stage
+func steadyProfile(rps float64, duration time.Duration) *Profile {
+	return &Profile{Stages: []Stage{{Name: "steady", Duration: duration, RPS: rps}}}
+}
+
+// loadProfile reads and checks a profile file
+func loadProfile(path string) (*Profile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Profile
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// validate reports every problem with the profile at once
+func (p *Profile) validate() error {
+	if len(p.Stages) == 0 {
+		return errors.New("no stages")
+	}
+	var errs []error
+	if p.Mix != nil {
+		if err := p.Mix.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	names := make(map[string]bool)
+	for i := range p.Stages {
+		s := &p.Stages[i]
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("stage-%d", i+1)
+		}
+		if names[s.Name] {
+			errs = append(errs, fmt.Errorf("stage %q: name used twice", s.Name))
+		}
+		names[s.Name] = true
+		if s.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("stage %q: duration must be positive", s.Name))
+		}
+		if s.RPS < 0 || s.RampTo < 0 {
+			errs = append(errs, fmt.Errorf("stage %q: rates can't be negative", s.Name))
+		}
+		if s.RPS == 0 && s.RampTo == 0 {
+			errs = append(errs, fmt.Errorf("stage %q: needs rps or ramp_to", s.Name))
+		}
+		if s.Mix != nil {
+			if err := s.Mix.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("stage %q: %w", s.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestStageDue(t *testing.T) {
+	steady := Stage{Duration: 10 * time.Second, RPS: 100}
+	rampUp := Stage{Duration: 10 * time.Second, RPS: 10, RampTo: 110}
+	rampDown := Stage{Duration: 4 * time.Second, RPS: 100, RampTo: 0.5}
+	for _, tc := range []struct {
+		name    string
+		stage   Stage
+		elapsed time.Duration
+		want    float64
+	}{
+		{"steady start", steady, 0, 0},
+		{"steady", steady, 2500 * time.Millisecond, 250},
+		{"steady end", steady, 10 * time.Second, 1000},
+		{"ramp start", rampUp, 0, 0},
+		// 10 rps rising by 10 rps a second averages 15 over the first second
+		{"ramp after a second", rampUp, time.Second, 15},
+		{"ramp halfway", rampUp, 5 * time.Second, 175},
+		// The average of 10 and 110 rps for ten seconds
+		{"ramp end", rampUp, 10 * time.Second, 600},
+		{"ramp down end", rampDown, 4 * time.Second, 201},
+	} {
+		if got := tc.stage.due(tc.elapsed); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: expected %g requests due, got %g", tc.name, tc.want, got)
+		}
+	}
+}
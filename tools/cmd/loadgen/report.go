@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Report is the machine-readable result of a run, for tracking latency and
+// errors from build to build
+type Report struct {
+	Label   string         `json:"label,omitempty"`
+	Name    string         `json:"name,omitempty"` // the target's, when comparing
+	Target  string         `json:"target"`
+	Started time.Time      `json:"started"`
+	Stages  []StageSummary `json:"stages"`
+	Total   StageSummary   `json:"total"`
+}
+
+// StageSummary summarizes the requests sent during a stage, or the whole run
+type StageSummary struct {
+	Name        string                 `json:"name"`
+	DurationSec float64                `json:"duration_seconds"`
+	TargetRPS   float64                `json:"target_rps,omitempty"`
+	RampToRPS   float64                `json:"ramp_to_rps,omitempty"`
+	Sent        int                    `json:"sent"`
+	Dropped     int                    `json:"dropped"`
+	Completed   int                    `json:"completed"`
+	AchievedRPS float64                `json:"achieved_rps"`
+	Errors      int                    `json:"errors"`
+	ErrorRate   float64                `json:"error_rate"`
+	Outcomes    map[string]int         `json:"outcomes"`
+	Latency     Latency                `json:"latency_ms"`
+	Kinds       map[string]KindSummary `json:"kinds"`
+
+	rate string
+}
+
+// KindSummary summarizes the requests of one kind in the mix. Errors are
+// responses other than the one expected: a 2xx for valid interactions, and
+// the rejection a correct service gives invalid ones.
+type KindSummary struct {
+	Expect     string         `json:"expect"`
+	Completed  int            `json:"completed"`
+	Errors     int            `json:"errors"`
+	Outcomes   map[string]int `json:"outcomes"`
+	Unexpected map[string]int `json:"unexpected,omitempty"`
+	Latency    Latency        `json:"latency_ms"`
+}
+
+// Latency holds response time percentiles in milliseconds
+type Latency struct {
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p999"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// newReport summarizes each stage and the run as a whole
+func newReport(label, name, target string, started time.Time, stats []*stageStats) *Report {
+	r := &Report{Label: label, Name: name, Target: target, Started: started.UTC()}
+	total := &stageStats{Stage: Stage{Name: "total"}, kinds: make(map[string]*tally)}
+	for _, s := range stats {
+		r.Stages = append(r.Stages, summarize(s))
+		total.Elapsed += s.Elapsed
+		total.sent += s.sent
+		total.dropped += s.dropped
+		total.expect = s.expect
+		for kind, t := range s.kinds {
+			if total.kinds[kind] == nil {
+				total.kinds[kind] = newTally()
+			}
+			total.kinds[kind].merge(t)
+		}
+	}
+	r.Total = summarize(total)
+	r.Total.rate = ""
+	return r
+}
+
+func summarize(s *stageStats) StageSummary {
+	all := s.total()
+	sum := StageSummary{
+		Name:        s.Stage.Name,
+		DurationSec: s.Elapsed.Seconds(),
+		TargetRPS:   s.Stage.RPS,
+		RampToRPS:   s.Stage.RampTo,
+		Sent:        s.sent,
+		Dropped:     s.dropped,
+		Completed:   len(all.latencies),
+		Outcomes:    all.outcomes,
+		Latency:     percentiles(all.latencies),
+		Kinds:       make(map[string]KindSummary, len(s.kinds)),
+		rate:        s.Stage.rateLabel(),
+	}
+	if s.Elapsed > 0 {
+		sum.AchievedRPS = float64(sum.Completed) / s.Elapsed.Seconds()
+	}
+	for _, n := range all.unexpected {
+		sum.Errors += n
+	}
+	if sum.Completed > 0 {
+		sum.ErrorRate = float64(sum.Errors) / float64(sum.Completed)
+	}
+	for kind, t := range s.kinds {
+		k := KindSummary{
+			Expect:     s.expect[kind],
+			Completed:  len(t.latencies),
+			Outcomes:   t.outcomes,
+			Unexpected: t.unexpected,
+			Latency:    percentiles(t.latencies),
+		}
+		for _, n := range t.unexpected {
+			k.Errors += n
+		}
+		sum.Kinds[kind] = k
+	}
+	return sum
+}
+
+// percentiles returns the nearest-rank percentiles of the latencies
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	at := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return ms(sorted[max(i, 0)])
+	}
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+	return Latency{
+		P50:  at(0.50),
+		P90:  at(0.90),
+		P99:  at(0.99),
+		P999: at(0.999),
+		Max:  ms(sorted[len(sorted)-1]),
+		Mean: ms(total / time.Duration(len(sorted))),
+	}
+}
+
+// ms converts a duration to milliseconds, to the microsecond
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// writeText prints a table of the stages, then what each one's errors were
+func (r *Report) writeText(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Stage\tDuration\tRate\tSent\tAchieved\tErrors\tp50\tp90\tp99\tp99.9\tMax\t")
+	for _, s := range append(slices.Clone(r.Stages), r.Total) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.1f/s\t%.2f%%\t%s\t%s\t%s\t%s\t%s\t\n",
+			s.Name, time.Duration(s.DurationSec*float64(time.Second)).Round(time.Second), s.rate, s.Sent,
+			s.AchievedRPS, 100*s.ErrorRate, msLabel(s.Latency.P50), msLabel(s.Latency.P90),
+			msLabel(s.Latency.P99), msLabel(s.Latency.P999), msLabel(s.Latency.Max))
+	}
+	if err := tw.Flush(); err != nil {
+		return
+	}
+
+	if len(r.Total.Kinds) > 1 {
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Kind\tShare\tCompleted\tExpect\tErrors\tp50\tp99\t")
+		for _, name := range sortedKinds(r.Total.Kinds) {
+			k := r.Total.Kinds[name]
+			fmt.Fprintf(tw, "%s\t%.1f%%\t%d\t%s\t%d\t%s\t%s\t\n", name,
+				100*float64(k.Completed)/float64(r.Total.Completed), k.Completed, k.Expect, k.Errors,
+				msLabel(k.Latency.P50), msLabel(k.Latency.P99))
+		}
+		if err := tw.Flush(); err != nil {
+			return
+		}
+	}
+
+	heading := false
+	for _, s := range append(slices.Clone(r.Stages), r.Total) {
+		if s.Errors == 0 && s.Dropped == 0 {
+			continue
+		}
+		if !heading {
+			fmt.Fprintln(w, "\nErrors by outcome:")
+			heading = true
+		}
+		var parts []string
+		for _, name := range sortedKinds(s.Kinds) {
+			unexpected := s.Kinds[name].Unexpected
+			for _, outcome := range sortedOutcomes(unexpected) {
+				parts = append(parts, fmt.Sprintf("%s %s ×%d", name, outcome, unexpected[outcome]))
+			}
+		}
+		if s.Dropped > 0 {
+			parts = append(parts, fmt.Sprintf("dropped ×%d", s.Dropped))
+		}
+		fmt.Fprintf(w, "  %s: %s\n", s.Name, strings.Join(parts, ", "))
+	}
+}
+
+// writeJSON writes a report or comparison as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// sortedOutcomes orders status codes numerically, then transport failures
+func sortedOutcomes(outcomes map[string]int) []string {
+	keys := make([]string, 0, len(outcomes))
+	for k := range outcomes {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, aErr := strconv.Atoi(keys[i])
+		b, bErr := strconv.Atoi(keys[j])
+		if (aErr == nil) != (bErr == nil) {
+			return aErr == nil
+		}
+		if aErr == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// sortedKinds returns the kinds in name order
+func sortedKinds(kinds map[string]KindSummary) []string {
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func msLabel(v float64) string {
+	return strconv.FormatFloat(v, 'f', 1, 64) + "ms"
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentiles(t *testing.T) {
+	// 1ms to 1000ms
+	thousand := make([]time.Duration, 1000)
+	for i := range thousand {
+		thousand[len(thousand)-1-i] = time.Duration(i+1) * time.Millisecond
+	}
+	for _, tc := range []struct {
+		name      string
+		latencies []time.Duration
+		want      Latency
+	}{
+		{"none", nil, Latency{}},
+		{"one", []time.Duration{1500 * time.Microsecond}, Latency{P50: 1.5, P90: 1.5, P99: 1.5, P999: 1.5, Max: 1.5, Mean: 1.5}},
+		{"two", []time.Duration{3 * time.Millisecond, time.Millisecond}, Latency{P50: 1, P90: 3, P99: 3, P999: 3, Max: 3, Mean: 2}},
+		{"nearest rank", thousand, Latency{P50: 500, P90: 900, P99: 990, P999: 999, Max: 1000, Mean: 500.5}},
+		{"submicrosecond dropped", []time.Duration{1234567 * time.Nanosecond}, Latency{P50: 1.234, P90: 1.234, P99: 1.234, P999: 1.234, Max: 1.234, Mean: 1.234}},
+	} {
+		if got := percentiles(tc.latencies); got != tc.want {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.want, got)
+		}
+	}
+	if thousand[0] != 1000*time.Millisecond {
+		t.Error("percentiles sorted the caller's slice")
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tools/internal/interaction"
+)
+
+// runner sends interactions to the target at the rates and in the mix a
+// profile sets
+type runner struct {
+	name        string // set when comparing targets, to label progress
+	target      string
+	client      *http.Client
+	gen         *generator
+	expect      Expectations
+	maxInFlight int
+	progress    io.Writer
+	seed        uint64 // runners seeded alike send the same sequence of kinds
+}
+
+// tally counts the outcomes of a set of requests
+type tally struct {
+	latencies  []time.Duration
+	outcomes   map[string]int // status code, "timeout" or "connection"
+	unexpected map[string]int // outcomes a correct service wouldn't give
+}
+
+func newTally() *tally {
+	return &tally{outcomes: make(map[string]int), unexpected: make(map[string]int)}
+}
+
+func (t *tally) add(outcome string, latency time.Duration, ok bool) {
+	t.outcomes[outcome]++
+	if !ok {
+		t.unexpected[outcome]++
+	}
+	t.latencies = append(t.latencies, latency)
+}
+
+func (t *tally) merge(o *tally) {
+	t.latencies = append(t.latencies, o.latencies...)
+	for outcome, n := range o.outcomes {
+		t.outcomes[outcome] += n
+	}
+	for outcome, n := range o.unexpected {
+		t.unexpected[outcome] += n
+	}
+}
+
+// stageStats collects the outcome of every request sent during a stage.
+// Requests still in flight when the stage ends count towards it.
+type stageStats struct {
+	Stage   Stage
+	Mix     Mix
+	Elapsed time.Duration
+
+	expect Expectations
+
+	mu      sync.Mutex
+	kinds   map[string]*tally
+	sent    int
+	dropped int // not sent because maxInFlight requests were outstanding
+}
+
+func (s *stageStats) record(kind, outcome string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.kinds[kind]
+	if !ok {
+		t = newTally()
+		s.kinds[kind] = t
+	}
+	t.add(outcome, latency, s.expect.met(kind, outcome))
+}
+
+// total returns the stage's requests of every kind together
+func (s *stageStats) total() *tally {
+	t := newTally()
+	for _, k := range s.kinds {
+		t.merge(k)
+	}
+	return t
+}
+
+// run works through the profile's stages and returns their stats once every
+// request has completed. Load is open-loop: requests are sent on schedule
+// whether or not earlier ones have been answered, up to maxInFlight.
+func (r *runner) run(ctx context.Context, p *Profile) []*stageStats {
+	var wg sync.WaitGroup
+	inFlight := make(chan struct{}, r.maxInFlight)
+	var all []*stageStats
+	for i, stage := range p.Stages {
+		if ctx.Err() != nil {
+			break
+		}
+		mix := p.Mix
+		if stage.Mix != nil {
+			mix = stage.Mix
+		}
+		pick := mix.picker(r.seed + uint64(i))
+		stats := &stageStats{Stage: stage, Mix: mix, expect: r.expect, kinds: make(map[string]*tally)}
+		all = append(all, stats)
+		r.logf("%s: %s at %s rps of %s\n", stage.Name, stage.Duration, stage.rateLabel(), mix)
+
+		start := time.Now()
+		issued := 0
+		for ctx.Err() == nil {
+			elapsed := time.Since(start)
+			if elapsed >= stage.Duration {
+				break
+			}
+			if float64(issued) >= stage.due(elapsed) {
+				// Wait for the next request to fall due. Ramps change
+				// the rate as they go, so don't look too far ahead.
+				wait := 10 * time.Millisecond
+				if rate := stage.rate(elapsed); rate > 0 {
+					wait = min(wait, time.Duration((float64(issued)+1-stage.due(elapsed))/rate*float64(time.Second)))
+				}
+				sleep(ctx, min(wait, stage.Duration-elapsed))
+				continue
+			}
+			issued++
+			kind := pick()
+
+			select {
+			case inFlight <- struct{}{}:
+				stats.sent++
+			default:
+				stats.dropped++
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-inFlight }()
+				// Stopping the run stops new requests, not ones in flight
+				outcome, latency := r.send(context.WithoutCancel(ctx), kind)
+				stats.record(kind, outcome, latency)
+			}()
+		}
+		stats.Elapsed = time.Since(start)
+	}
+	wg.Wait()
+	return all
+}
+
+// logf reports progress, prefixed with the target's name when comparing
+func (r *runner) logf(format string, args ...any) {
+	if r.name != "" {
+		format = "[" + r.name + "] " + format
+	}
+	fmt.Fprintf(r.progress, format, args...)
+}
+
+// send posts one request of the given kind and returns its outcome and
+// latency
+func (r *runner) send(ctx context.Context, kind string) (string, time.Duration) {
+	rq, err := r.gen.build(kind)
+	if err != nil {
+		return "payload", 0
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.target, bytes.NewReader(rq.body))
+	if err != nil {
+		return "connection", 0
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Discord-Interactions/1.0 (+https://discord.com) loadgen")
+	if rq.key != nil {
+		timestamp := strconv.FormatInt(time.Now().Add(rq.skew).Unix(), 10)
+		req.Header.Set("X-Signature-Ed25519", interaction.Sign(rq.key, timestamp, rq.body))
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+	}
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return transportOutcome(err), time.Since(start)
+	}
+	defer resp.Body.Close()
+	// Read the whole body so the connection is reused, and so latency
+	// covers the full response
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return transportOutcome(err), time.Since(start)
+	}
+	return strconv.Itoa(resp.StatusCode), time.Since(start)
+}
+
+// transportOutcome classifies a request that got no response
+func transportOutcome(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return "timeout"
+	}
+	return "connection"
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// discordEpoch is the first millisecond of 2015, which snowflakes count from
+const discordEpoch = 1420070400000
+
+// Discord JSON error codes the mock answers with
+const (
+	codeUnknownMessage     = 10008
+	codeUnknownInteraction = 10062
+	codeEmptyMessage       = 50006
+	codeInvalidForm        = 50035
+	codeInvalidJSON        = 50109
+	codeAlreadyAcked       = 40060
+)
+
+// Interaction callback types, and which of them create the original response
+const (
+	callbackMessage         = 4
+	callbackDeferredMessage = 5
+	callbackDeferredUpdate  = 6
+	callbackUpdateMessage   = 7
+)
+
+var validCallbackTypes = map[int]bool{1: true, 4: true, 5: true, 6: true, 7: true, 8: true, 9: true, 10: true, 12: true}
+
+// apiVersion matches the version prefix clients put on API paths
+var apiVersion = regexp.MustCompile(`^/api(/v[0-9]+)?`)
+
+// maxBodyBytes bounds request bodies, above Discord's 25 MiB upload limit
+const maxBodyBytes = 32 << 20
+
+// api serves the interaction endpoints of the Discord REST API
+type api struct {
+	store  *store
+	limits *rateLimiter
+	faults *faultInjector
+	logger *slog.Logger
+}
+
+// routeNames are the names routes are recorded, filtered and rate limited
+// by
+var routeNames = map[string]bool{
+	"callback":        true,
+	"create_followup": true,
+	"get_message":     true,
+	"edit_message":    true,
+	"delete_message":  true,
+}
+
+// routes registers the Discord endpoints, each under a route name the
+// inspection API filters on
+func (a *api) routes(mux *http.ServeMux) {
+	mux.Handle("POST /interactions/{id}/{token}/callback", a.handle("callback", a.callback))
+	mux.Handle("POST /webhooks/{app}/{token}", a.handle("create_followup", a.createFollowup))
+	mux.Handle("GET /webhooks/{app}/{token}/messages/{message}", a.handle("get_message", a.getMessage))
+	mux.Handle("PATCH /webhooks/{app}/{token}/messages/{message}", a.handle("edit_message", a.editMessage))
+	mux.Handle("DELETE /webhooks/{app}/{token}/messages/{message}", a.handle("delete_message", a.deleteMessage))
+}
+
+// stripVersion serves /api/v10/... and /api/... paths as if they had no
+// prefix, so DISCORD_API_URL can be the mock's address with or without one
+func stripVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if prefix := apiVersion.FindString(r.URL.Path); prefix != "" && !strings.HasPrefix(r.URL.Path, "/_inspect") {
+			r2 := new(http.Request)
+			*r2 = *r
+			u := *r.URL
+			u.Path = strings.TrimPrefix(r.URL.Path, prefix)
+			u.RawPath = ""
+			r2.URL = &u
+			r = r2
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// call is a request to a Discord route being handled
+type call struct {
+	rec     record
+	message map[string]any // the decoded body
+}
+
+// apiError is a Discord JSON error response
+type apiError struct {
+	status  int
+	code    int
+	message string
+}
+
+func (e *apiError) Error() string { return e.message }
+
+// routeFunc handles a route under the store's lock, returning the response
+// body or an error to answer with
+type routeFunc func(c *call, r *http.Request) (status int, body any, err error)
+
+// handle records every request to a route with its outcome. An injected
+// fault delays the request, then answers in place of the route unless it
+// lets the request take effect first. Requests over a rate limit are
+// answered 429 without reaching the route.
+func (a *api) handle(route string, fn routeFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.PathValue("token")
+		c := &call{rec: record{
+			Time:          time.Now().UTC(),
+			Route:         route,
+			Method:        r.Method,
+			Path:          strings.Replace(r.URL.Path, "/"+token, "/{token}", 1),
+			ApplicationID: r.PathValue("app"),
+			InteractionID: r.PathValue("id"),
+			MessageID:     r.PathValue("message"),
+			token:         token,
+		}}
+		err := c.readBody(r)
+
+		f := a.faults.pick(route)
+		if f != nil {
+			c.rec.Fault = f.Kind
+			if !f.delay(r) {
+				c.rec.Error = "the client gave up during the injected latency"
+				a.finish(c, 0)
+				return
+			}
+			if f.Kind != faultSlow && !f.Processed {
+				c.rec.Error = f.String()
+				a.finish(c, f.status())
+				f.respond(w, r)
+				return
+			}
+		}
+
+		var status int
+		var body any
+		if limited := a.limits.take(route, majorParameter(r), w.Header(), time.Now()); limited != nil {
+			err = limited
+		}
+		a.store.mu.Lock()
+		if err == nil {
+			status, body, err = fn(c, r)
+		}
+		a.store.mu.Unlock()
+		var ae *apiError
+		var rl *rateLimited
+		switch {
+		case errors.As(err, &ae):
+			status, body = ae.status, map[string]any{"message": ae.message, "code": ae.code}
+			c.rec.Error = ae.message
+		case errors.As(err, &rl):
+			status, body = http.StatusTooManyRequests, rl.body()
+			c.rec.Error = fmt.Sprintf("rate limited (%s scope) for %s", rl.scope, rl.retryAfter.Round(time.Millisecond))
+		}
+
+		if f != nil && f.Kind != faultSlow {
+			c.rec.Error = fmt.Sprintf("%s after the request took effect with %d", f, status)
+			a.finish(c, f.status())
+			f.respond(w, r)
+			return
+		}
+		a.finish(c, status)
+		if body == nil {
+			w.WriteHeader(status)
+			return
+		}
+		writeJSON(w, status, body)
+	})
+}
+
+// finish records a request with the status the client is sent, 0 for none
+func (a *api) finish(c *call, status int) {
+	a.store.mu.Lock()
+	if conv, ok := a.store.conversations[c.rec.token]; ok && c.rec.InteractionID == "" {
+		c.rec.InteractionID = conv.interactionID
+	}
+	c.rec.Status = status
+	a.store.record(c.rec)
+	a.store.mu.Unlock()
+	attrs := []any{"route", c.rec.Route, "method", c.rec.Method, "path", c.rec.Path, "status", status}
+	if c.rec.Fault != "" {
+		attrs = append(attrs, "fault", c.rec.Fault)
+	}
+	a.logger.Info("request", attrs...)
+}
+
+// readBody decodes a JSON body, or the payload_json and files of a
+// multipart one
+func (c *call) readBody(r *http.Request) error {
+	if r.Method == http.MethodGet || r.Method == http.MethodDelete {
+		return nil
+	}
+	raw, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+	if err != nil {
+		return &apiError{http.StatusRequestEntityTooLarge, 40005, "Request entity too large"}
+	}
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		raw, err = c.readMultipart(raw, params["boundary"])
+		if err != nil {
+			return err
+		}
+	}
+	if err := json.Unmarshal(raw, &c.message); err != nil || c.message == nil {
+		return &apiError{http.StatusBadRequest, codeInvalidJSON, "The request body contains invalid JSON."}
+	}
+	c.rec.Body = c.message
+	return nil
+}
+
+// readMultipart records the files of a multipart body and returns its
+// payload_json
+func (c *call) readMultipart(raw []byte, boundary string) ([]byte, error) {
+	var payload []byte
+	mr := multipart.NewReader(bytes.NewReader(raw), boundary)
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &apiError{http.StatusBadRequest, codeInvalidForm, "Invalid multipart form body"}
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, &apiError{http.StatusBadRequest, codeInvalidForm, "Invalid multipart form body"}
+		}
+		switch name := part.FormName(); {
+		case name == "payload_json":
+			payload = data
+		case part.FileName() != "":
+			c.rec.Files = append(c.rec.Files, file{
+				Field:       name,
+				Filename:    part.FileName(),
+				ContentType: part.Header.Get("Content-Type"),
+				Size:        len(data),
+			})
+		}
+	}
+	if payload == nil {
+		payload = []byte("{}")
+	}
+	return payload, nil
+}
+
+// callback acknowledges an interaction. Only the first callback for an
+// interaction is accepted, as on Discord.
+func (a *api) callback(c *call, _ *http.Request) (int, any, error) {
+	typ, _ := c.message["type"].(float64)
+	if !validCallbackTypes[int(typ)] {
+		return 0, nil, &apiError{http.StatusBadRequest, codeInvalidForm, fmt.Sprintf("Invalid interaction callback type %v", c.message["type"])}
+	}
+	conv := a.store.conversation(c.rec.token)
+	if conv.acknowledged {
+		return 0, nil, &apiError{http.StatusBadRequest, codeAlreadyAcked, "Interaction has already been acknowledged."}
+	}
+	conv.acknowledged = true
+	conv.interactionID = c.rec.InteractionID
+
+	data, _ := c.message["data"].(map[string]any)
+	switch int(typ) {
+	case callbackMessage, callbackDeferredMessage:
+		m := a.newMessage(conv, data)
+		if int(typ) == callbackDeferredMessage {
+			m.Flags |= flagLoading
+		}
+		conv.original = m
+	case callbackUpdateMessage, callbackDeferredUpdate:
+		// These edit the message the component is on, which belongs to an
+		// earlier interaction the mock may not have seen
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// flagLoading marks a deferred response still showing "thinking..."
+const flagLoading = 1 << 7
+
+// createFollowup sends a follow-up message
+func (a *api) createFollowup(c *call, _ *http.Request) (int, any, error) {
+	if err := checkNotEmpty(c.message, c.rec.Files); err != nil {
+		return 0, nil, err
+	}
+	conv := a.store.conversation(c.rec.token)
+	conv.applicationID = c.rec.ApplicationID
+	m := a.newMessage(conv, c.message)
+	a.attach(m, c.rec.Files)
+	conv.followups = append(conv.followups, m)
+	c.rec.MessageID = m.ID
+	return http.StatusOK, m, nil
+}
+
+// getMessage returns the original response or a follow-up
+func (a *api) getMessage(c *call, _ *http.Request) (int, any, error) {
+	m, _, err := a.find(c)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, m, nil
+}
+
+// editMessage edits the original response or a follow-up. Editing
+// @original before anything created it creates it: the service being tested
+// usually deferred the interaction over HTTP, which the mock doesn't see.
+func (a *api) editMessage(c *call, _ *http.Request) (int, any, error) {
+	m, _, err := a.find(c)
+	if err != nil {
+		if c.rec.MessageID != "@original" {
+			return 0, nil, err
+		}
+		conv := a.store.conversation(c.rec.token)
+		conv.applicationID = c.rec.ApplicationID
+		m = a.newMessage(conv, nil)
+		conv.original = m
+		conv.acknowledged = true
+	}
+	edit(m, c.message)
+	a.attach(m, c.rec.Files)
+	if err := checkNotEmpty(messageFields(m), m.Attachments); err != nil {
+		return 0, nil, err
+	}
+	m.Flags &^= flagLoading
+	edited := time.Now().UTC().Format(time.RFC3339Nano)
+	m.EditedTimestamp = &edited
+	c.rec.MessageID = m.ID
+	return http.StatusOK, m, nil
+}
+
+// deleteMessage deletes the original response or a follow-up
+func (a *api) deleteMessage(c *call, _ *http.Request) (int, any, error) {
+	m, conv, err := a.find(c)
+	if err != nil {
+		return 0, nil, err
+	}
+	if m == conv.original {
+		conv.original = nil
+	}
+	for i, f := range conv.followups {
+		if f == m {
+			conv.followups = append(conv.followups[:i], conv.followups[i+1:]...)
+			break
+		}
+	}
+	c.rec.MessageID = m.ID
+	return http.StatusNoContent, nil, nil
+}
+
+// find returns the message a webhook message route names
+func (a *api) find(c *call) (*message, *conversation, error) {
+	unknown := &apiError{http.StatusNotFound, codeUnknownMessage, "Unknown Message"}
+	conv, ok := a.store.conversations[c.rec.token]
+	if !ok {
+		return nil, nil, &apiError{http.StatusNotFound, codeUnknownInteraction, "Unknown interaction"}
+	}
+	if c.rec.MessageID == "@original" {
+		if conv.original == nil {
+			return nil, conv, unknown
+		}
+		return conv.original, conv, nil
+	}
+	for _, m := range conv.followups {
+		if m.ID == c.rec.MessageID {
+			return m, conv, nil
+		}
+	}
+	if conv.original != nil && conv.original.ID == c.rec.MessageID {
+		return conv.original, conv, nil
+	}
+	return nil, conv, unknown
+}
+
+// newMessage creates a message from a message body. Callers hold the
+// store's lock.
+func (a *api) newMessage(conv *conversation, body map[string]any) *message {
+	m := &message{
+		ID:            a.store.newMessageID(),
+		Type:          20, // a reply to a slash command
+		ApplicationID: conv.applicationID,
+		WebhookID:     conv.applicationID,
+		Embeds:        []any{},
+		Components:    []any{},
+		Attachments:   []any{},
+		Timestamp:     time.Now().UTC(),
+	}
+	edit(m, body)
+	return m
+}
+
+// edit applies the fields a message body sets
+func edit(m *message, body map[string]any) {
+	if v, ok := body["content"].(string); ok {
+		m.Content = v
+	}
+	if v, ok := body["embeds"].([]any); ok {
+		m.Embeds = v
+	}
+	if v, ok := body["components"].([]any); ok {
+		m.Components = v
+	}
+	if v, ok := body["attachments"].([]any); ok {
+		m.Attachments = v
+	}
+	if v, ok := body["flags"].(float64); ok {
+		m.Flags = int(v)
+	}
+}
+
+// attach replaces the attachments payload_json describes by the index of
+// their files[n] part with the uploaded files. Attachments with a
+// message's attachment ID, kept from before an edit, stay as they are.
+func (a *api) attach(m *message, files []file) {
+	if len(files) == 0 {
+		return
+	}
+	described := make(map[string]map[string]any)
+	kept := []any{}
+	for _, v := range m.Attachments {
+		att, _ := v.(map[string]any)
+		if id, isIndex := att["id"].(float64); isIndex {
+			described[fmt.Sprintf("files[%d]", int(id))] = att
+		} else {
+			kept = append(kept, v)
+		}
+	}
+	for _, f := range files {
+		att := map[string]any{
+			"id":           a.store.newMessageID(),
+			"filename":     f.Filename,
+			"size":         f.Size,
+			"content_type": f.ContentType,
+		}
+		if d, ok := described[f.Field]; ok {
+			if name, ok := d["filename"].(string); ok {
+				att["filename"] = name
+			}
+			if desc, ok := d["description"].(string); ok {
+				att["description"] = desc
+			}
+		}
+		kept = append(kept, att)
+	}
+	m.Attachments = kept
+}
+
+// messageFields returns the fields of a message that make it non-empty
+func messageFields(m *message) map[string]any {
+	return map[string]any{"content": m.Content, "embeds": m.Embeds, "components": m.Components}
+}
+
+// checkNotEmpty rejects a message with nothing to show, as Discord does
+func checkNotEmpty[T any](body map[string]any, files []T) error {
+	if len(files) > 0 {
+		return nil
+	}
+	if content, _ := body["content"].(string); content != "" {
+		return nil
+	}
+	for _, key := range []string{"embeds", "components", "sticker_ids", "poll"} {
+		if v, ok := body[key]; ok && v != nil {
+			if list, isList := v.([]any); !isList || len(list) > 0 {
+				return nil
+			}
+		}
+	}
+	return &apiError{http.StatusBadRequest, codeEmptyMessage, "Cannot send an empty message"}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Fault kinds
+const (
+	faultSlow    = "slow"    // answer normally after the fault's latency
+	faultError   = "error"   // answer with an error status
+	faultTimeout = "timeout" // never answer, until the client gives up
+	faultReset   = "reset"   // reset the connection without answering
+)
+
+// maxHang bounds how long a timeout fault holds a request open
+const maxHang = 5 * time.Minute
+
+// faultConfig is the faults the mock injects. The zero value injects none.
+type faultConfig struct {
+	// Seed makes which requests a probability picks the same every run
+	Seed   uint64  `json:"seed,omitempty"`
+	Faults []fault `json:"faults,omitempty"`
+}
+
+// fault is misbehaviour injected into requests on some routes
+type fault struct {
+	Routes []string `json:"routes"` // route names; * for every route
+	Kind   string   `json:"kind"`
+	// Status is what an error fault answers with (default 500)
+	Status int `json:"status,omitempty"`
+	// Latency delays the request before anything else happens to it
+	Latency duration `json:"latency,omitempty"`
+	// Probability picks the requests affected (default 1: every one)
+	Probability float64 `json:"probability,omitempty"`
+	// Count stops the fault after it has affected this many requests
+	// (default: no limit)
+	Count int `json:"count,omitempty"`
+	// Processed lets the request take effect before the fault hides the
+	// response, as when Discord creates a message but the response is lost,
+	// so a client retrying it sends a duplicate
+	Processed bool `json:"processed,omitempty"`
+}
+
+func (f fault) validate() error {
+	var errs []error
+	if len(f.Routes) == 0 {
+		errs = append(errs, errors.New("no routes"))
+	}
+	for _, r := range f.Routes {
+		if r != "*" && !routeNames[r] {
+			errs = append(errs, fmt.Errorf("unknown route %q", r))
+		}
+	}
+	switch f.Kind {
+	case faultSlow, faultError, faultTimeout, faultReset:
+	default:
+		errs = append(errs, fmt.Errorf("kind must be %s, %s, %s or %s", faultSlow, faultError, faultTimeout, faultReset))
+	}
+	if f.Kind == faultSlow && f.Latency <= 0 {
+		errs = append(errs, errors.New("slow faults need a latency"))
+	}
+	if f.Status != 0 && (f.Kind != faultError || f.Status < 400 || f.Status > 599) {
+		errs = append(errs, errors.New("status is for error faults, and must be 4xx or 5xx"))
+	}
+	if f.Latency < 0 || f.Probability < 0 || f.Probability > 1 || f.Count < 0 {
+		errs = append(errs, errors.New("latency and count can't be negative, and probability must be between 0 and 1"))
+	}
+	if f.Processed && f.Kind == faultSlow {
+		errs = append(errs, errors.New("processed is for error, timeout and reset faults"))
+	}
+	return errors.Join(errs...)
+}
+
+func (c faultConfig) validate() error {
+	var errs []error
+	for i, f := range c.Faults {
+		if err := f.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("fault %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// faultInjector picks the fault, if any, for each request
+type faultInjector struct {
+	mu       sync.Mutex
+	config   faultConfig
+	rand     *rand.Rand
+	injected []int // requests each fault has affected
+}
+
+func newFaultInjector(config faultConfig) *faultInjector {
+	in := &faultInjector{}
+	in.configure(config)
+	return in
+}
+
+// configure replaces the faults, restarting their counts
+func (in *faultInjector) configure(config faultConfig) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.config = config
+	in.rand = rand.New(rand.NewPCG(config.Seed, config.Seed))
+	in.injected = make([]int, len(config.Faults))
+}
+
+// current returns the faults being injected, with how many requests each
+// has affected
+func (in *faultInjector) current() (faultConfig, []int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.config, append([]int(nil), in.injected...)
+}
+
+// reset restarts the faults' counts and random sequence
+func (in *faultInjector) reset() {
+	config, _ := in.current()
+	in.configure(config)
+}
+
+// pick returns the first fault to affect a request on route, if any
+func (in *faultInjector) pick(route string) *fault {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for i, f := range in.config.Faults {
+		if !covers(f.Routes, route) || (f.Count > 0 && in.injected[i] >= f.Count) {
+			continue
+		}
+		if f.Probability > 0 && f.Probability < 1 && in.rand.Float64() >= f.Probability {
+			continue
+		}
+		in.injected[i]++
+		return &f
+	}
+	return nil
+}
+
+// errorBody is the JSON Discord answers a server error with
+func (f *fault) errorBody() map[string]any {
+	status := f.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	return map[string]any{"message": fmt.Sprintf("%d: %s", status, http.StatusText(status)), "code": 0}
+}
+
+// status is the status the fault answers a request with
+func (f *fault) status() int {
+	switch f.Kind {
+	case faultError:
+		if f.Status == 0 {
+			return http.StatusInternalServerError
+		}
+		return f.Status
+	default:
+		return 0 // the client isn't answered
+	}
+}
+
+// String describes the fault for a request's record
+func (f *fault) String() string {
+	switch f.Kind {
+	case faultError:
+		return fmt.Sprintf("injected %d error", f.status())
+	case faultReset:
+		return "injected connection reset"
+	default:
+		return "injected " + f.Kind
+	}
+}
+
+// delay waits out the fault's latency, or until the client gives up
+func (f *fault) delay(r *http.Request) bool {
+	if f.Latency <= 0 {
+		return true
+	}
+	t := time.NewTimer(time.Duration(f.Latency))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// respond answers the request as the fault dictates, in place of the
+// route's response
+func (f *fault) respond(w http.ResponseWriter, r *http.Request) {
+	switch f.Kind {
+	case faultError:
+		writeJSON(w, f.status(), f.errorBody())
+	case faultTimeout:
+		t := time.NewTimer(maxHang)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			writeJSON(w, http.StatusGatewayTimeout, map[string]any{"message": "504: Gateway Timeout", "code": 0})
+		case <-r.Context().Done():
+		}
+	case faultReset:
+		resetConnection(w)
+	}
+}
+
+// resetConnection closes the request's connection with a TCP reset, so the
+// client sees "connection reset by peer" rather than a response
+func resetConnection(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		// HTTP/2 connections can't be hijacked; aborting the handler resets
+		// the stream instead
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
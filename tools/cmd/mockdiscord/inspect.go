@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxWait bounds how long /_inspect/wait holds a request open
+const maxWait = 5 * time.Minute
+
+// inspector serves the /_inspect API tests assert on
+type inspector struct {
+	store  *store
+	limits *rateLimiter
+	faults *faultInjector
+}
+
+func (in *inspector) routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /_inspect/requests", in.requests)
+	mux.HandleFunc("GET /_inspect/messages", in.messages)
+	mux.HandleFunc("GET /_inspect/wait", in.wait)
+	mux.HandleFunc("GET /_inspect/ratelimits", in.rateLimits)
+	mux.HandleFunc("PUT /_inspect/ratelimits", in.setRateLimits)
+	mux.HandleFunc("GET /_inspect/faults", in.faultsInjected)
+	mux.HandleFunc("PUT /_inspect/faults", in.setFaults)
+	mux.HandleFunc("DELETE /_inspect", in.reset)
+}
+
+// parseFilter reads a request filter from query parameters
+func parseFilter(r *http.Request) (filter, error) {
+	q := r.URL.Query()
+	f := filter{
+		token:         q.Get("token"),
+		route:         q.Get("route"),
+		interactionID: q.Get("interaction_id"),
+	}
+	if s := q.Get("since"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return f, err
+		}
+		f.since = n
+	}
+	return f, nil
+}
+
+// requests lists the recorded requests, oldest first
+func (in *inspector) requests(w http.ResponseWriter, r *http.Request) {
+	f, err := parseFilter(r)
+	if err != nil {
+		inspectError(w, http.StatusBadRequest, "since: "+err.Error())
+		return
+	}
+	records, _ := in.store.matching(f)
+	writeJSON(w, http.StatusOK, map[string]any{"requests": records})
+}
+
+// messages returns an interaction's original response and follow-ups as
+// they stand after every edit
+func (in *inspector) messages(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		inspectError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	original, followups, ok := in.store.messages(token)
+	if !ok {
+		inspectError(w, http.StatusNotFound, "nothing was sent for this token")
+		return
+	}
+	if followups == nil {
+		followups = []*message{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"original": original, "followups": followups})
+}
+
+// wait holds the request until count requests match the filter, so a test
+// needn't poll for an asynchronous worker. It answers 408 with what has
+// matched so far if timeout passes first.
+func (in *inspector) wait(w http.ResponseWriter, r *http.Request) {
+	f, err := parseFilter(r)
+	if err != nil {
+		inspectError(w, http.StatusBadRequest, "since: "+err.Error())
+		return
+	}
+	q := r.URL.Query()
+	count := 1
+	if s := q.Get("count"); s != "" {
+		if count, err = strconv.Atoi(s); err != nil || count < 1 {
+			inspectError(w, http.StatusBadRequest, "count must be a positive integer")
+			return
+		}
+	}
+	timeout := 10 * time.Second
+	if s := q.Get("timeout"); s != "" {
+		if timeout, err = time.ParseDuration(s); err != nil || timeout <= 0 {
+			inspectError(w, http.StatusBadRequest, "timeout must be a positive duration, e.g. 5s")
+			return
+		}
+	}
+	timer := time.NewTimer(min(timeout, maxWait))
+	defer timer.Stop()
+
+	for {
+		records, changed := in.store.matching(f)
+		if len(records) >= count {
+			writeJSON(w, http.StatusOK, map[string]any{"requests": records})
+			return
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			writeJSON(w, http.StatusRequestTimeout, map[string]any{"requests": records})
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// rateLimits returns the rate limits being enforced
+func (in *inspector) rateLimits(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, in.limits.current())
+}
+
+// setRateLimits replaces the rate limits, starting every window afresh. An
+// empty object removes them.
+func (in *inspector) setRateLimits(w http.ResponseWriter, r *http.Request) {
+	var config rateLimitConfig
+	if err := decodeConfig(r.Body, &config); err != nil {
+		inspectError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.validate(); err != nil {
+		inspectError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	in.limits.configure(config)
+	writeJSON(w, http.StatusOK, config)
+}
+
+// faultsInjected returns the faults being injected, with how many requests
+// each has affected
+func (in *inspector) faultsInjected(w http.ResponseWriter, _ *http.Request) {
+	config, injected := in.faults.current()
+	writeJSON(w, http.StatusOK, map[string]any{"seed": config.Seed, "faults": config.Faults, "injected": injected})
+}
+
+// setFaults replaces the faults, restarting their counts. An empty object
+// removes them.
+func (in *inspector) setFaults(w http.ResponseWriter, r *http.Request) {
+	var config faultConfig
+	if err := decodeConfig(r.Body, &config); err != nil {
+		inspectError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.validate(); err != nil {
+		inspectError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	in.faults.configure(config)
+	writeJSON(w, http.StatusOK, config)
+}
+
+// reset forgets every request and message, starts every rate limit window
+// afresh and restarts the faults' counts, for a test to start clean. The
+// limits and faults themselves stay.
+func (in *inspector) reset(w http.ResponseWriter, _ *http.Request) {
+	in.store.reset()
+	in.limits.reset()
+	in.faults.reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeConfig decodes a JSON configuration, rejecting unknown fields so a
+// misspelt one isn't silently ignored
+func decodeConfig(r io.Reader, v any) error {
+	dec := json.NewDecoder(io.LimitReader(r, 1<<20))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func inspectError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,155 @@
+// Command mockdiscord is a stand-in for the Discord REST API's interaction
+// endpoints, for end-to-end tests of services that answer interactions
+// asynchronously:
+//
+//	mockdiscord -addr :8089
+//	DISCORD_API_URL=http://localhost:8089/api/v10 go run .  # in services/go-worker
+//
+// It accepts interaction callbacks, follow-up messages and edits to the
+// original response as Discord would, keeping every request and the
+// resulting messages in memory. Tests read them back through /_inspect:
+//
+//	GET    /_inspect/requests?token=&route=&interaction_id=&since=
+//	GET    /_inspect/messages?token=
+//	GET    /_inspect/wait?token=&route=&count=1&timeout=10s
+//	GET    /_inspect/ratelimits
+//	PUT    /_inspect/ratelimits
+//	GET    /_inspect/faults
+//	PUT    /_inspect/faults
+//	DELETE /_inspect
+//
+// Rate limits, set with -rate-limit and -global-rate-limit or through
+// /_inspect/ratelimits, answer requests over them with 429s and the headers
+// Discord sends, so a client's rate limit handling can be tested. Faults,
+// set with -faults or through /_inspect/faults, make routes slow, fail, hang
+// or reset the connection, for testing retries and timeouts.
+//
+// Interaction tokens are never logged or returned: recorded paths show
+// {token} in their place, and tests filter by the token they sent.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 2
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("mockdiscord", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", envString("MOCKDISCORD_ADDR", ":8089"), "address to listen on")
+	quiet := fs.Bool("quiet", false, "don't log each request")
+	faultsFile := fs.String("faults", "", "JSON file of faults to inject, as PUT to /_inspect/faults")
+	var limits rateLimitConfig
+	fs.Func("rate-limit", "bucket as routes=requests/window, e.g. create_followup,edit_message=5/2s (repeatable)", func(s string) error {
+		b, err := parseBucket(s)
+		limits.Buckets = append(limits.Buckets, b)
+		return err
+	})
+	fs.Func("global-rate-limit", "limit on every route as requests/window, e.g. 50/1s", func(s string) error {
+		g, err := parseLimit(s)
+		limits.Global = &g
+		return err
+	})
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "mockdiscord: unexpected argument %q\n", fs.Arg(0))
+		return exitError
+	}
+	if err := limits.validate(); err != nil {
+		fmt.Fprintln(stderr, "mockdiscord:", err)
+		return exitError
+	}
+	var faults faultConfig
+	if *faultsFile != "" {
+		if err := loadFaults(*faultsFile, &faults); err != nil {
+			fmt.Fprintln(stderr, "mockdiscord:", err)
+			return exitError
+		}
+	}
+
+	level := slog.LevelInfo
+	if *quiet {
+		level = slog.LevelWarn
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(stderr, "mockdiscord:", err)
+		return exitError
+	}
+	srv := &http.Server{Handler: newHandler(newStore(), newRateLimiter(limits), newFaultInjector(faults), logger), ReadHeaderTimeout: 10 * time.Second}
+	logger.Info("listening", "addr", ln.Addr().String())
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		fmt.Fprintln(stderr, "mockdiscord:", err)
+		return exitError
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintln(stderr, "mockdiscord:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// newHandler serves the Discord routes and the inspection API
+func newHandler(st *store, limits *rateLimiter, faults *faultInjector, logger *slog.Logger) http.Handler {
+	mux := http.NewServeMux()
+	(&api{store: st, limits: limits, faults: faults, logger: logger}).routes(mux)
+	(&inspector{store: st, limits: limits, faults: faults}).routes(mux)
+	return stripVersion(mux)
+}
+
+// loadFaults reads and checks a faults file
+func loadFaults(path string, config *faultConfig) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := decodeConfig(f, config); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// envString returns the value of an environment variable or a default
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limit scopes, as Discord reports them in X-RateLimit-Scope
+const (
+	scopeUser   = "user"
+	scopeShared = "shared"
+	scopeGlobal = "global"
+)
+
+// rateLimitConfig is the limits the mock enforces. The zero value enforces
+// none.
+type rateLimitConfig struct {
+	// Global limits requests across every route, like Discord's per-bot
+	// global limit
+	Global *limitConfig `json:"global,omitempty"`
+	// Buckets limit the routes they list. Routes in the same bucket share
+	// its requests, and each interaction token has its own counts.
+	Buckets []bucketConfig `json:"buckets,omitempty"`
+}
+
+// limitConfig is a fixed window of Limit requests every Window
+type limitConfig struct {
+	Limit  int      `json:"limit"`
+	Window duration `json:"window"`
+}
+
+// bucketConfig is a limit on a set of routes
+type bucketConfig struct {
+	Routes []string `json:"routes"` // route names; * for every route
+	limitConfig
+	// Scope is reported on 429s: "user" (the default) or "shared", for a
+	// limit on a resource rather than the caller
+	Scope string `json:"scope,omitempty"`
+}
+
+// duration is a time.Duration written as a string such as "2s" in JSON
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New(`durations are strings such as "2s"`)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// parseLimit parses a limit written as requests/window, e.g. 5/2s
+func parseLimit(s string) (limitConfig, error) {
+	n, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return limitConfig{}, fmt.Errorf("limit %q: want requests/window, e.g. 5/2s", s)
+	}
+	limit, err := strconv.Atoi(n)
+	if err != nil {
+		return limitConfig{}, fmt.Errorf("limit %q: %w", s, err)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil {
+		return limitConfig{}, fmt.Errorf("limit %q: %w", s, err)
+	}
+	l := limitConfig{Limit: limit, Window: duration(d)}
+	return l, l.validate()
+}
+
+// parseBucket parses a bucket written as routes=requests/window, e.g.
+// create_followup,edit_message=5/2s
+func parseBucket(s string) (bucketConfig, error) {
+	routes, limit, ok := strings.Cut(s, "=")
+	if !ok {
+		return bucketConfig{}, fmt.Errorf("bucket %q: want routes=requests/window, e.g. edit_message=5/2s", s)
+	}
+	l, err := parseLimit(limit)
+	if err != nil {
+		return bucketConfig{}, err
+	}
+	return bucketConfig{Routes: strings.Split(routes, ","), limitConfig: l}, nil
+}
+
+func (l limitConfig) validate() error {
+	if l.Limit < 1 || l.Window <= 0 {
+		return errors.New("limits need at least 1 request and a positive window")
+	}
+	return nil
+}
+
+// validate checks the routes and limits are ones the mock knows
+func (c rateLimitConfig) validate() error {
+	var errs []error
+	if c.Global != nil {
+		if err := c.Global.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("global: %w", err))
+		}
+	}
+	for i, b := range c.Buckets {
+		if err := b.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("bucket %d: %w", i+1, err))
+		}
+		if len(b.Routes) == 0 {
+			errs = append(errs, fmt.Errorf("bucket %d: no routes", i+1))
+		}
+		for _, r := range b.Routes {
+			if r != "*" && !routeNames[r] {
+				errs = append(errs, fmt.Errorf("bucket %d: unknown route %q", i+1, r))
+			}
+		}
+		if b.Scope != "" && b.Scope != scopeUser && b.Scope != scopeShared {
+			errs = append(errs, fmt.Errorf("bucket %d: scope must be %q or %q", i+1, scopeUser, scopeShared))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// window is a limit's count since its window started
+type window struct {
+	start time.Time
+	count int
+}
+
+// take counts a request at now, returning the requests left and when the
+// window resets, or false if the limit is used up
+func (w *window) take(l limitConfig, now time.Time) (remaining int, reset time.Time, ok bool) {
+	if w.start.IsZero() || !now.Before(w.start.Add(time.Duration(l.Window))) {
+		w.start, w.count = now, 0
+	}
+	reset = w.start.Add(time.Duration(l.Window))
+	if w.count >= l.Limit {
+		return 0, reset, false
+	}
+	w.count++
+	return l.Limit - w.count, reset, true
+}
+
+// rateLimiter enforces a rateLimitConfig. Windows are fixed and start with
+// the first request, so a test knows exactly which request is limited.
+type rateLimiter struct {
+	mu      sync.Mutex
+	config  rateLimitConfig
+	global  window
+	windows map[string]*window // by bucket hash and major parameter
+}
+
+func newRateLimiter(config rateLimitConfig) *rateLimiter {
+	return &rateLimiter{config: config, windows: make(map[string]*window)}
+}
+
+// configure replaces the limits, starting every window afresh
+func (l *rateLimiter) configure(config rateLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config = config
+	l.global = window{}
+	l.windows = make(map[string]*window)
+}
+
+// current returns the limits being enforced
+func (l *rateLimiter) current() rateLimitConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.config
+}
+
+// reset starts every window afresh
+func (l *rateLimiter) reset() {
+	l.configure(l.current())
+}
+
+// rateLimited is a request refused by a limit
+type rateLimited struct {
+	retryAfter time.Duration
+	scope      string
+}
+
+func (e *rateLimited) Error() string { return "You are being rate limited." }
+
+// body is the JSON Discord answers a 429 with
+func (e *rateLimited) body() map[string]any {
+	return map[string]any{
+		"message":     e.Error(),
+		"retry_after": math.Ceil(e.retryAfter.Seconds()*1000) / 1000,
+		"global":      e.scope == scopeGlobal,
+	}
+}
+
+// take counts a request on route, setting the rate limit headers Discord
+// would on header. major is the part of the path, such as the webhook
+// token, that buckets are counted separately for.
+func (l *rateLimiter) take(route, major string, header http.Header, now time.Time) *rateLimited {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if g := l.config.Global; g != nil {
+		if _, reset, ok := l.global.take(*g, now); !ok {
+			header.Set("X-RateLimit-Global", "true")
+			header.Set("X-RateLimit-Scope", scopeGlobal)
+			return l.limited(header, reset.Sub(now), scopeGlobal)
+		}
+	}
+	for _, b := range l.config.Buckets {
+		if !covers(b.Routes, route) {
+			continue
+		}
+		hash := b.hash()
+		w, ok := l.windows[hash+":"+major]
+		if !ok {
+			w = &window{}
+			l.windows[hash+":"+major] = w
+		}
+		remaining, reset, ok := w.take(b.limitConfig, now)
+		resetAfter := reset.Sub(now)
+		header.Set("X-RateLimit-Bucket", hash)
+		header.Set("X-RateLimit-Limit", strconv.Itoa(b.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatFloat(float64(reset.UnixMilli())/1000, 'f', 3, 64))
+		header.Set("X-RateLimit-Reset-After", strconv.FormatFloat(resetAfter.Seconds(), 'f', 3, 64))
+		if !ok {
+			scope := b.Scope
+			if scope == "" {
+				scope = scopeUser
+			}
+			header.Set("X-RateLimit-Scope", scope)
+			return l.limited(header, resetAfter, scope)
+		}
+		// Discord puts a route in one bucket; the first that covers it is it
+		break
+	}
+	return nil
+}
+
+func (l *rateLimiter) limited(header http.Header, retryAfter time.Duration, scope string) *rateLimited {
+	header.Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return &rateLimited{retryAfter: retryAfter, scope: scope}
+}
+
+// covers reports whether a list of route names includes route
+func covers(routes []string, route string) bool {
+	for _, r := range routes {
+		if r == route || r == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// hash names the bucket the way Discord's opaque bucket hashes do, stable
+// for its routes
+func (b bucketConfig) hash() string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strings.Join(b.Routes, ",")))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// majorParameter returns the part of a request's path its buckets are
+// counted for: the webhook or interaction and its token
+func majorParameter(r *http.Request) string {
+	if id := r.PathValue("id"); id != "" {
+		return "interactions/" + id + "/" + r.PathValue("token")
+	}
+	return "webhooks/" + r.PathValue("app") + "/" + r.PathValue("token")
+}
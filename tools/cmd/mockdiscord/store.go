@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// record is one API request the mock received
+type record struct {
+	Seq           int       `json:"seq"`
+	Time          time.Time `json:"time"`
+	Route         string    `json:"route"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"` // with the token replaced by {token}
+	ApplicationID string    `json:"application_id,omitempty"`
+	InteractionID string    `json:"interaction_id,omitempty"`
+	MessageID     string    `json:"message_id,omitempty"`
+	Status        int       `json:"status"`
+	Body          any       `json:"body,omitempty"` // the JSON body, or payload_json of a multipart one
+	Files         []file    `json:"files,omitempty"`
+	Error         string    `json:"error,omitempty"` // why the mock rejected it
+	Fault         string    `json:"fault,omitempty"` // the kind of fault injected
+
+	token string
+}
+
+// file is an uploaded attachment
+type file struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+}
+
+// message is a message as the mock holds it, returned like Discord's
+// message objects
+type message struct {
+	ID              string    `json:"id"`
+	Type            int       `json:"type"`
+	ApplicationID   string    `json:"application_id,omitempty"`
+	WebhookID       string    `json:"webhook_id,omitempty"`
+	Content         string    `json:"content"`
+	Embeds          []any     `json:"embeds"`
+	Components      []any     `json:"components"`
+	Attachments     []any     `json:"attachments"`
+	Flags           int       `json:"flags"`
+	Timestamp       time.Time `json:"timestamp"`
+	EditedTimestamp *string   `json:"edited_timestamp"`
+}
+
+// conversation is everything sent for one interaction, by its token
+type conversation struct {
+	applicationID string
+	interactionID string
+	acknowledged  bool
+	original      *message
+	followups     []*message // in the order they were created
+}
+
+// store holds every request and message in memory
+type store struct {
+	mu            sync.Mutex
+	records       []record
+	conversations map[string]*conversation
+	nextID        int64
+	changed       chan struct{} // closed and replaced whenever a request is recorded
+}
+
+func newStore() *store {
+	return &store{conversations: make(map[string]*conversation), nextID: 1, changed: make(chan struct{})}
+}
+
+// conversation returns the conversation for token, creating it. Callers
+// hold s.mu.
+func (s *store) conversation(token string) *conversation {
+	c, ok := s.conversations[token]
+	if !ok {
+		c = &conversation{}
+		s.conversations[token] = c
+	}
+	return c
+}
+
+// newMessageID returns a snowflake-shaped ID for a message or attachment.
+// Callers hold s.mu.
+func (s *store) newMessageID() string {
+	ms := time.Now().UnixMilli() - discordEpoch
+	id := ms<<22 | s.nextID&(1<<22-1)
+	s.nextID++
+	return strconv.FormatInt(id, 10)
+}
+
+// record stores a request and wakes anything waiting for one. Callers hold
+// s.mu.
+func (s *store) record(r record) {
+	r.Seq = len(s.records) + 1
+	s.records = append(s.records, r)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// matching returns the requests the filter selects, and a channel closed
+// when the next request is recorded
+func (s *store) matching(f filter) ([]record, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []record{}
+	for _, r := range s.records {
+		if f.matches(r) {
+			out = append(out, r)
+		}
+	}
+	return out, s.changed
+}
+
+// messages returns the original response and follow-ups for token
+func (s *store) messages(token string) (original *message, followups []*message, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.conversations[token]
+	if !ok {
+		return nil, nil, false
+	}
+	return c.original, append([]*message(nil), c.followups...), true
+}
+
+// reset forgets everything
+func (s *store) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = nil
+	s.conversations = make(map[string]*conversation)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// filter selects recorded requests; empty fields match anything
+type filter struct {
+	token         string
+	route         string
+	interactionID string
+	since         int // only requests after this sequence number
+}
+
+func (f filter) matches(r record) bool {
+	return (f.token == "" || r.token == f.token) &&
+		(f.route == "" || r.Route == f.route) &&
+		(f.interactionID == "" || r.InteractionID == f.interactionID) &&
+		r.Seq > f.since
+}
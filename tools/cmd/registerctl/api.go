@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// maxRateLimitRetries is how many times a rate limited request is retried
+const maxRateLimitRetries = 5
+
+// RemoteCommand is a command as registered with Discord
+type RemoteCommand struct {
+	ID string `json:"id"`
+	Command
+}
+
+// apiClient manages an application's commands through the Discord REST API
+type apiClient struct {
+	baseURL       string
+	applicationID string
+	token         string
+	client        *http.Client
+}
+
+func newAPIClient(baseURL, applicationID, token string) *apiClient {
+	return &apiClient{
+		baseURL:       baseURL,
+		applicationID: applicationID,
+		token:         token,
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// scope is where commands are registered: globally, or in one guild
+type scope struct {
+	guildID string // empty for global commands
+}
+
+func (s scope) String() string {
+	if s.guildID == "" {
+		return "global"
+	}
+	return "guild " + s.guildID
+}
+
+// apiError is a request Discord rejected. Body holds Discord's explanation,
+// e.g. which field of the command was invalid.
+type apiError struct {
+	Status int
+	Body   string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("discord API returned %d: %s", e.Status, e.Body)
+}
+
+func (c *apiClient) commandsPath(s scope) string {
+	path := "/applications/" + url.PathEscape(c.applicationID)
+	if s.guildID != "" {
+		path += "/guilds/" + url.PathEscape(s.guildID)
+	}
+	return path + "/commands"
+}
+
+// List returns the commands registered in s
+func (c *apiClient) List(ctx context.Context, s scope) ([]RemoteCommand, error) {
+	var commands []RemoteCommand
+	// Without with_localizations only the bot user's own locale is returned
+	err := c.do(ctx, http.MethodGet, c.commandsPath(s)+"?with_localizations=true", nil, &commands)
+	return commands, err
+}
+
+// Upsert creates cmd in s, or replaces the command with its name and type.
+// Unlike PATCH, it also clears fields the manifest no longer sets.
+func (c *apiClient) Upsert(ctx context.Context, s scope, cmd Command) error {
+	return c.do(ctx, http.MethodPost, c.commandsPath(s), cmd, nil)
+}
+
+// Delete removes a command registered in s
+func (c *apiClient) Delete(ctx context.Context, s scope, id string) error {
+	return c.do(ctx, http.MethodDelete, c.commandsPath(s)+"/"+url.PathEscape(id), nil, nil)
+}
+
+// do sends one request, waiting out rate limits, and decodes the response
+// into out when it isn't nil
+func (c *apiClient) do(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bot "+c.token)
+		req.Header.Set("User-Agent", "DiscordBot (https://github.com/pmgledhill102/discord-bot-test-suite, 1.0) registerctl")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries:
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryAfter(resp.Header, data)):
+			}
+			continue
+		case resp.StatusCode >= 300:
+			return &apiError{Status: resp.StatusCode, Body: string(data)}
+		case out != nil:
+			return json.Unmarshal(data, out)
+		}
+		return nil
+	}
+}
+
+// retryAfter reads how long Discord asked us to wait from a 429 response
+func retryAfter(header http.Header, body []byte) time.Duration {
+	var limited struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &limited) == nil && limited.RetryAfter > 0 {
+		return time.Duration(limited.RetryAfter * float64(time.Second))
+	}
+	if secs, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return time.Second
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// discordLocales are the locales Discord accepts localizations for, see
+// https://discord.com/developers/docs/reference#locales
+var discordLocales = map[string]bool{
+	"id": true, "da": true, "de": true, "en-GB": true, "en-US": true, "es-ES": true, "es-419": true,
+	"fr": true, "hr": true, "it": true, "lt": true, "hu": true, "nl": true, "no": true, "pl": true,
+	"pt-BR": true, "ro": true, "fi": true, "sv-SE": true, "vi": true, "tr": true, "cs": true,
+	"el": true, "bg": true, "ru": true, "uk": true, "hi": true, "th": true, "zh-CN": true,
+	"ja": true, "zh-TW": true, "ko": true,
+}
+
+// Localizations maps a Discord locale to the text shown to users with it
+type Localizations map[string]string
+
+// validate checks each locale is one Discord knows and its text passes
+// check, reporting them in locale order
+func (l Localizations) validate(where, field string, check func(string) error) []error {
+	locales := make([]string, 0, len(l))
+	for locale := range l {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	var errs []error
+	for _, locale := range locales {
+		if !discordLocales[locale] {
+			errs = append(errs, fmt.Errorf("%s: %s has unknown locale %q", where, field, locale))
+			continue
+		}
+		if err := check(l[locale]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s[%s]: %w", where, field, locale, err))
+		}
+	}
+	return errs
+}
+
+// checkName returns the rules a name of a command of type t breaks.
+// Options are named like chat input commands.
+func checkName(t CommandType, name string) error {
+	if t == CommandTypeUser || t == CommandTypeMessage {
+		// Shown in a menu, so mixed case and spaces are allowed
+		if n := utf8.RuneCountInString(name); n < 1 || n > maxNameLength {
+			return fmt.Errorf("name must be 1-%d characters", maxNameLength)
+		}
+		return nil
+	}
+	if !namePattern.MatchString(name) || strings.ToLower(name) != name {
+		return fmt.Errorf("name must be 1-%d lowercase letters, digits, '-' or '_'", maxNameLength)
+	}
+	return nil
+}
+
+// checkLength returns a check that s is 1-limit characters
+func checkLength(limit int) func(string) error {
+	return func(s string) error {
+		if n := utf8.RuneCountInString(s); n < 1 || n > limit {
+			return fmt.Errorf("must be 1-%d characters", limit)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,175 @@
+// Command registerctl reconciles a Discord application's commands with a
+// declarative manifest, like kubectl apply for slash commands:
+//
+//	registerctl diff -f commands.yaml
+//	registerctl apply -f commands.yaml -prune
+//	registerctl apply -f commands.yaml -profile staging
+//
+// The application and bot token come from DISCORD_APPLICATION_ID and
+// DISCORD_BOT_TOKEN, unless the manifest profile being applied names others.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// Exit codes. diff exits with exitChanges when applying would change
+// anything, as kubectl diff does.
+const (
+	exitOK      = 0
+	exitChanges = 1
+	exitError   = 2
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "diff" && args[0] != "apply") {
+		fmt.Fprintln(stderr, "usage: registerctl diff|apply [flags]")
+		return exitError
+	}
+	command := args[0]
+
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	manifestPath := fs.String("f", "commands.yaml", "command manifest (YAML or JSON)")
+	profileName := fs.String("profile", os.Getenv("REGISTERCTL_PROFILE"), "manifest profile to apply (default: global commands)")
+	guilds := fs.String("guild", "", "comma-separated guild IDs to register in, instead of the profile's")
+	prune := fs.Bool("prune", false, "delete registered commands missing from the manifest")
+	dryRun := fs.Bool("dry-run", false, "apply: print the plan without changing anything")
+	applicationID := fs.String("application-id", "", "application whose commands are managed (default: the profile's, or $DISCORD_APPLICATION_ID)")
+	apiURL := fs.String("api-url", envString("DISCORD_API_URL", "https://discord.com/api/v10"), "Discord REST API base URL")
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitError
+	}
+
+	manifest, err := loadManifest(*manifestPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "registerctl:", err)
+		return exitError
+	}
+	var profile Profile
+	if *profileName != "" {
+		var ok bool
+		if profile, ok = manifest.Profiles[*profileName]; !ok {
+			fmt.Fprintf(stderr, "registerctl: %s has no profile %q\n", *manifestPath, *profileName)
+			return exitError
+		}
+	}
+	if *guilds != "" {
+		profile.Guilds = strings.Split(*guilds, ",")
+	}
+	if *applicationID == "" {
+		*applicationID = profile.ApplicationID
+	}
+	if *applicationID == "" {
+		*applicationID = os.Getenv("DISCORD_APPLICATION_ID")
+	}
+	// The token is only read from the environment so it stays out of
+	// manifests, shell history and process listings
+	tokenEnv := profile.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = "DISCORD_BOT_TOKEN"
+	}
+	token := os.Getenv(tokenEnv)
+	if *applicationID == "" || token == "" {
+		fmt.Fprintf(stderr, "registerctl: DISCORD_APPLICATION_ID (or -application-id) and %s are required\n", tokenEnv)
+		return exitError
+	}
+
+	scopes := []scope{{}}
+	if len(profile.Guilds) > 0 {
+		scopes = scopes[:0]
+		for _, guild := range profile.Guilds {
+			if !isSnowflake(guild) {
+				fmt.Fprintf(stderr, "registerctl: guild %q is not an ID\n", guild)
+				return exitError
+			}
+			scopes = append(scopes, scope{guildID: guild})
+		}
+	}
+
+	// Plan every scope before changing any, so the whole plan is seen first
+	api := newAPIClient(*apiURL, *applicationID, token)
+	plans := make([][]change, len(scopes))
+	changed := false
+	for i, s := range scopes {
+		registered, err := api.List(ctx, s)
+		if err != nil {
+			fmt.Fprintf(stderr, "registerctl: listing %s commands: %v\n", s, err)
+			return exitError
+		}
+		commands := manifest.Commands
+		if s.guildID != "" {
+			commands = guildCommands(commands)
+		}
+		plans[i] = plan(commands, registered, s, *prune)
+		if len(scopes) > 1 {
+			fmt.Fprintf(stdout, "%s:\n", capitalize(s.String()))
+		}
+		printPlan(stdout, plans[i])
+		changed = changed || pending(plans[i])
+	}
+	switch {
+	case command == "diff" && changed:
+		return exitChanges
+	case command == "diff", *dryRun, !changed:
+		return exitOK
+	}
+
+	for i, s := range scopes {
+		for _, c := range plans[i] {
+			if err := applyChange(ctx, api, s, c); err != nil {
+				fmt.Fprintf(stderr, "registerctl: %s %s: %v\n", s, c.key(), err)
+				return exitError
+			}
+		}
+	}
+	fmt.Fprintln(stdout, "Applied.")
+	return exitOK
+}
+
+// guildCommands drops the primary entry point command, which Discord only
+// registers globally
+func guildCommands(commands []Command) []Command {
+	out := make([]Command, 0, len(commands))
+	for _, cmd := range commands {
+		if cmd.Type != CommandTypePrimaryEntryPoint {
+			out = append(out, cmd)
+		}
+	}
+	return out
+}
+
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// applyChange makes one planned change
+func applyChange(ctx context.Context, api *apiClient, s scope, c change) error {
+	switch c.kind {
+	case changeCreate, changeUpdate:
+		return api.Upsert(ctx, s, *c.want)
+	case changeDelete:
+		return api.Delete(ctx, s, c.have.ID)
+	}
+	return nil
+}
+
+// envString returns the value of an environment variable or a default
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Discord's limits on application commands
+const (
+	maxChatInputCommands = 100
+	maxContextCommands   = 15 // each of user and message
+	maxNameLength        = 32
+	maxDescriptionLength = 100
+	maxOptions           = 25
+	maxChoices           = 25
+	maxChoiceNameLength  = 100
+	maxChoiceValueLength = 100
+	maxStringLength      = 6000
+)
+
+// namePattern is the set of characters Discord allows in chat input command
+// and option names
+var namePattern = regexp.MustCompile(`^[-_'\p{L}\p{N}\p{Devanagari}\p{Thai}]{1,32}$`)
+
+// CommandType is an application command type. Manifests may use the name
+// (chat_input, user, message, primary_entry_point) or the number.
+type CommandType int
+
+const (
+	CommandTypeChatInput         CommandType = 1
+	CommandTypeUser              CommandType = 2
+	CommandTypeMessage           CommandType = 3
+	CommandTypePrimaryEntryPoint CommandType = 4
+)
+
+var commandTypeNames = map[CommandType]string{
+	CommandTypeChatInput:         "chat_input",
+	CommandTypeUser:              "user",
+	CommandTypeMessage:           "message",
+	CommandTypePrimaryEntryPoint: "primary_entry_point",
+}
+
+func (t CommandType) String() string {
+	if name, ok := commandTypeNames[t]; ok {
+		return name
+	}
+	return strconv.Itoa(int(t))
+}
+
+func (t *CommandType) UnmarshalYAML(node *yaml.Node) error {
+	n, err := enumValue(node, commandTypeNames)
+	*t = CommandType(n)
+	return err
+}
+
+// OptionType is a command option type, by name (string, integer, ...) or
+// number in manifests
+type OptionType int
+
+const (
+	OptionTypeSubCommand      OptionType = 1
+	OptionTypeSubCommandGroup OptionType = 2
+	OptionTypeString          OptionType = 3
+	OptionTypeInteger         OptionType = 4
+	OptionTypeBoolean         OptionType = 5
+	OptionTypeUser            OptionType = 6
+	OptionTypeChannel         OptionType = 7
+	OptionTypeRole            OptionType = 8
+	OptionTypeMentionable     OptionType = 9
+	OptionTypeNumber          OptionType = 10
+	OptionTypeAttachment      OptionType = 11
+)
+
+var optionTypeNames = map[OptionType]string{
+	OptionTypeSubCommand:      "sub_command",
+	OptionTypeSubCommandGroup: "sub_command_group",
+	OptionTypeString:          "string",
+	OptionTypeInteger:         "integer",
+	OptionTypeBoolean:         "boolean",
+	OptionTypeUser:            "user",
+	OptionTypeChannel:         "channel",
+	OptionTypeRole:            "role",
+	OptionTypeMentionable:     "mentionable",
+	OptionTypeNumber:          "number",
+	OptionTypeAttachment:      "attachment",
+}
+
+func (t OptionType) String() string {
+	if name, ok := optionTypeNames[t]; ok {
+		return name
+	}
+	return strconv.Itoa(int(t))
+}
+
+func (t *OptionType) UnmarshalYAML(node *yaml.Node) error {
+	n, err := enumValue(node, optionTypeNames)
+	*t = OptionType(n)
+	return err
+}
+
+// enumValue decodes a YAML scalar holding either an enum's name or its number
+func enumValue[T ~int](node *yaml.Node, names map[T]string) (int, error) {
+	if n, err := strconv.Atoi(node.Value); err == nil {
+		return n, nil
+	}
+	for value, name := range names {
+		if name == node.Value {
+			return int(value), nil
+		}
+	}
+	return 0, fmt.Errorf("line %d: unknown type %q", node.Line, node.Value)
+}
+
+// IntegrationType is an installation context a command is available in:
+// guild_install or user_install
+type IntegrationType int
+
+const (
+	IntegrationTypeGuildInstall IntegrationType = 0
+	IntegrationTypeUserInstall  IntegrationType = 1
+)
+
+var integrationTypeNames = map[IntegrationType]string{
+	IntegrationTypeGuildInstall: "guild_install",
+	IntegrationTypeUserInstall:  "user_install",
+}
+
+func (t *IntegrationType) UnmarshalYAML(node *yaml.Node) error {
+	n, err := enumValue(node, integrationTypeNames)
+	*t = IntegrationType(n)
+	return err
+}
+
+// InteractionContext is where a command can be used: guild, bot_dm or
+// private_channel
+type InteractionContext int
+
+const (
+	InteractionContextGuild          InteractionContext = 0
+	InteractionContextBotDM          InteractionContext = 1
+	InteractionContextPrivateChannel InteractionContext = 2
+)
+
+var interactionContextNames = map[InteractionContext]string{
+	InteractionContextGuild:          "guild",
+	InteractionContextBotDM:          "bot_dm",
+	InteractionContextPrivateChannel: "private_channel",
+}
+
+func (c *InteractionContext) UnmarshalYAML(node *yaml.Node) error {
+	n, err := enumValue(node, interactionContextNames)
+	*c = InteractionContext(n)
+	return err
+}
+
+// Command is an application command as the manifest declares it and
+// Discord returns it. Only the fields registerctl manages are decoded, so
+// comparing the JSON encodings of the two tells whether it needs updating.
+type Command struct {
+	Name        string      `json:"name" yaml:"name"`
+	Type        CommandType `json:"type" yaml:"type"`
+	Description string      `json:"description" yaml:"description"`
+	Options     []Option    `json:"options,omitempty" yaml:"options"`
+	NSFW        bool        `json:"nsfw,omitempty" yaml:"nsfw"`
+
+	NameLocalizations        Localizations `json:"name_localizations,omitempty" yaml:"name_localizations"`
+	DescriptionLocalizations Localizations `json:"description_localizations,omitempty" yaml:"description_localizations"`
+
+	// Who can use the command and where. Unset fields take Discord's
+	// defaults: usable by everyone, installed to guilds, in every context.
+	// DMPermission is the older form of Contexts.
+	DefaultMemberPermissions *Permissions         `json:"default_member_permissions,omitempty" yaml:"default_member_permissions"`
+	DMPermission             *bool                `json:"dm_permission,omitempty" yaml:"dm_permission"`
+	IntegrationTypes         []IntegrationType    `json:"integration_types,omitempty" yaml:"integration_types"`
+	Contexts                 []InteractionContext `json:"contexts,omitempty" yaml:"contexts"`
+}
+
+// Option is a command option, subcommand or subcommand group
+type Option struct {
+	Type         OptionType `json:"type" yaml:"type"`
+	Name         string     `json:"name" yaml:"name"`
+	Description  string     `json:"description" yaml:"description"`
+	Required     bool       `json:"required,omitempty" yaml:"required"`
+	Choices      []Choice   `json:"choices,omitempty" yaml:"choices"`
+	Options      []Option   `json:"options,omitempty" yaml:"options"`
+	ChannelTypes []int      `json:"channel_types,omitempty" yaml:"channel_types"`
+	MinValue     *float64   `json:"min_value,omitempty" yaml:"min_value"`
+	MaxValue     *float64   `json:"max_value,omitempty" yaml:"max_value"`
+	MinLength    *int       `json:"min_length,omitempty" yaml:"min_length"`
+	MaxLength    *int       `json:"max_length,omitempty" yaml:"max_length"`
+	Autocomplete bool       `json:"autocomplete,omitempty" yaml:"autocomplete"`
+
+	NameLocalizations        Localizations `json:"name_localizations,omitempty" yaml:"name_localizations"`
+	DescriptionLocalizations Localizations `json:"description_localizations,omitempty" yaml:"description_localizations"`
+}
+
+// Choice is one predefined value of a string, integer or number option
+type Choice struct {
+	Name              string        `json:"name" yaml:"name"`
+	Value             any           `json:"value" yaml:"value"`
+	NameLocalizations Localizations `json:"name_localizations,omitempty" yaml:"name_localizations"`
+}
+
+// Manifest is the declarative list of commands registerctl reconciles, and
+// the environments it can be applied to
+type Manifest struct {
+	Profiles map[string]Profile `yaml:"profiles"`
+	Commands []Command          `yaml:"commands"`
+}
+
+// Profile is an environment the manifest is applied to, such as a staging
+// application's test guilds. Commands are registered in each of Guilds,
+// where changes show up at once, or globally when there are none.
+// ApplicationID and TokenEnv, the variable holding the bot token, default
+// to DISCORD_APPLICATION_ID and DISCORD_BOT_TOKEN.
+type Profile struct {
+	ApplicationID string   `yaml:"application_id"`
+	TokenEnv      string   `yaml:"token_env"`
+	Guilds        []string `yaml:"guilds"`
+}
+
+// key identifies a command: names are unique per type
+func (c Command) key() string {
+	return c.Type.String() + "/" + c.Name
+}
+
+// loadManifest reads and validates a manifest file
+func loadManifest(path string) (*Manifest, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range m.Commands {
+		if m.Commands[i].Type == 0 {
+			m.Commands[i].Type = CommandTypeChatInput
+		}
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// validate checks the manifest against Discord's rules, so mistakes are
+// reported together before anything is sent
+func (m *Manifest) validate() error {
+	var errs []error
+	seen := make(map[string]bool, len(m.Commands))
+	counts := make(map[CommandType]int)
+	for _, cmd := range m.Commands {
+		if seen[cmd.key()] {
+			errs = append(errs, fmt.Errorf("%s command %q is declared twice", cmd.Type, cmd.Name))
+		}
+		seen[cmd.key()] = true
+		counts[cmd.Type]++
+		errs = append(errs, cmd.validate()...)
+	}
+	if counts[CommandTypeChatInput] > maxChatInputCommands {
+		errs = append(errs, fmt.Errorf("%d chat_input commands, Discord allows %d", counts[CommandTypeChatInput], maxChatInputCommands))
+	}
+	for _, t := range []CommandType{CommandTypeUser, CommandTypeMessage} {
+		if counts[t] > maxContextCommands {
+			errs = append(errs, fmt.Errorf("%d %s commands, Discord allows %d", counts[t], t, maxContextCommands))
+		}
+	}
+	if counts[CommandTypePrimaryEntryPoint] > 1 {
+		errs = append(errs, errors.New("only one primary_entry_point command is allowed"))
+	}
+	for name, profile := range m.Profiles {
+		for _, guild := range profile.Guilds {
+			if !isSnowflake(guild) {
+				errs = append(errs, fmt.Errorf("profile %q: guild %q is not an ID", name, guild))
+			}
+		}
+		if profile.ApplicationID != "" && !isSnowflake(profile.ApplicationID) {
+			errs = append(errs, fmt.Errorf("profile %q: application_id %q is not an ID", name, profile.ApplicationID))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isSnowflake reports whether s looks like a Discord ID
+func isSnowflake(s string) bool {
+	if s == "" || len(s) > 20 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Command) validate() []error {
+	var errs []error
+	where := fmt.Sprintf("%s command %q", c.Type, c.Name)
+	if _, ok := commandTypeNames[c.Type]; !ok {
+		return []error{fmt.Errorf("%s: unknown type %d", where, c.Type)}
+	}
+	if err := checkName(c.Type, c.Name); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", where, err))
+	}
+	errs = append(errs, c.NameLocalizations.validate(where, "name_localizations", func(name string) error {
+		return checkName(c.Type, name)
+	})...)
+
+	if c.DMPermission != nil && c.Contexts != nil {
+		errs = append(errs, fmt.Errorf("%s: use contexts instead of dm_permission, not both", where))
+	}
+	if c.IntegrationTypes != nil && len(c.IntegrationTypes) == 0 {
+		errs = append(errs, fmt.Errorf("%s: integration_types must list at least one type", where))
+	}
+	for _, t := range c.IntegrationTypes {
+		if _, ok := integrationTypeNames[t]; !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown integration type %d", where, t))
+		}
+	}
+	if c.Contexts != nil && len(c.Contexts) == 0 {
+		errs = append(errs, fmt.Errorf("%s: contexts must list at least one context", where))
+	}
+	for _, ctx := range c.Contexts {
+		if _, ok := interactionContextNames[ctx]; !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown context %d", where, ctx))
+		}
+	}
+
+	switch c.Type {
+	case CommandTypeChatInput:
+		errs = append(errs, validateDescription(where, c.Description, c.DescriptionLocalizations)...)
+		errs = append(errs, validateOptions(where, c.Options, 0)...)
+	case CommandTypePrimaryEntryPoint:
+		errs = append(errs, validateDescription(where, c.Description, c.DescriptionLocalizations)...)
+		if len(c.Options) > 0 {
+			errs = append(errs, fmt.Errorf("%s: entry point commands take no options", where))
+		}
+	default:
+		// User and message commands are picked from a menu by name
+		if c.Description != "" || len(c.DescriptionLocalizations) > 0 || len(c.Options) > 0 {
+			errs = append(errs, fmt.Errorf("%s: user and message commands have no description or options", where))
+		}
+	}
+	return errs
+}
+
+// validateDescription checks a description and its translations
+func validateDescription(where, description string, localizations Localizations) []error {
+	var errs []error
+	if err := checkLength(maxDescriptionLength)(description); err != nil {
+		errs = append(errs, fmt.Errorf("%s: description %w", where, err))
+	}
+	return append(errs, localizations.validate(where, "description_localizations", checkLength(maxDescriptionLength))...)
+}
+
+// validateOptions checks one level of options. depth is 1 inside a
+// subcommand group or subcommand and 2 inside a subcommand of a group.
+func validateOptions(where string, options []Option, depth int) []error {
+	var errs []error
+	if len(options) > maxOptions {
+		errs = append(errs, fmt.Errorf("%s: %d options, Discord allows %d", where, len(options), maxOptions))
+	}
+	seen := make(map[string]bool, len(options))
+	optional := false
+	for _, opt := range options {
+		at := fmt.Sprintf("%s option %q", where, opt.Name)
+		if _, ok := optionTypeNames[opt.Type]; !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown type %d", at, opt.Type))
+			continue
+		}
+		if err := checkName(CommandTypeChatInput, opt.Name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", at, err))
+		}
+		errs = append(errs, opt.NameLocalizations.validate(at, "name_localizations", func(name string) error {
+			return checkName(CommandTypeChatInput, name)
+		})...)
+		if seen[opt.Name] {
+			errs = append(errs, fmt.Errorf("%s: declared twice", at))
+		}
+		seen[opt.Name] = true
+		errs = append(errs, validateDescription(at, opt.Description, opt.DescriptionLocalizations)...)
+
+		switch opt.Type {
+		case OptionTypeSubCommandGroup:
+			if depth > 0 {
+				errs = append(errs, fmt.Errorf("%s: subcommand groups can only be top-level options", at))
+			}
+			for _, sub := range opt.Options {
+				if sub.Type != OptionTypeSubCommand {
+					errs = append(errs, fmt.Errorf("%s: groups can only contain subcommands", at))
+					break
+				}
+			}
+			errs = append(errs, validateOptions(at, opt.Options, depth+1)...)
+			continue
+		case OptionTypeSubCommand:
+			if depth > 1 {
+				errs = append(errs, fmt.Errorf("%s: subcommands can't be nested this deep", at))
+			}
+			errs = append(errs, validateOptions(at, opt.Options, depth+1)...)
+			continue
+		}
+
+		// Required options must come first
+		if !opt.Required {
+			optional = true
+		} else if optional {
+			errs = append(errs, fmt.Errorf("%s: required options must come before optional ones", at))
+		}
+		if len(opt.Options) > 0 {
+			errs = append(errs, fmt.Errorf("%s: only subcommands and groups have options", at))
+		}
+		errs = append(errs, validateChoices(at, opt)...)
+		if (opt.MinLength != nil || opt.MaxLength != nil) && opt.Type != OptionTypeString {
+			errs = append(errs, fmt.Errorf("%s: min_length and max_length only apply to string options", at))
+		}
+		for _, n := range []*int{opt.MinLength, opt.MaxLength} {
+			if n != nil && (*n < 0 || *n > maxStringLength) {
+				errs = append(errs, fmt.Errorf("%s: lengths must be 0-%d", at, maxStringLength))
+			}
+		}
+		if (opt.MinValue != nil || opt.MaxValue != nil) && opt.Type != OptionTypeInteger && opt.Type != OptionTypeNumber {
+			errs = append(errs, fmt.Errorf("%s: min_value and max_value only apply to integer and number options", at))
+		}
+		if len(opt.ChannelTypes) > 0 && opt.Type != OptionTypeChannel {
+			errs = append(errs, fmt.Errorf("%s: channel_types only applies to channel options", at))
+		}
+	}
+	if depth == 0 {
+		// Subcommands and plain options can't be mixed at the top level
+		var subs, plain bool
+		for _, opt := range options {
+			if opt.Type == OptionTypeSubCommand || opt.Type == OptionTypeSubCommandGroup {
+				subs = true
+			} else {
+				plain = true
+			}
+		}
+		if subs && plain {
+			errs = append(errs, fmt.Errorf("%s: can't mix subcommands with other options", where))
+		}
+	}
+	return errs
+}
+
+func validateChoices(at string, opt Option) []error {
+	if len(opt.Choices) == 0 {
+		return nil
+	}
+	var errs []error
+	if opt.Type != OptionTypeString && opt.Type != OptionTypeInteger && opt.Type != OptionTypeNumber {
+		return []error{fmt.Errorf("%s: only string, integer and number options have choices", at)}
+	}
+	if opt.Autocomplete {
+		errs = append(errs, fmt.Errorf("%s: autocomplete can't be combined with choices", at))
+	}
+	if len(opt.Choices) > maxChoices {
+		errs = append(errs, fmt.Errorf("%s: %d choices, Discord allows %d", at, len(opt.Choices), maxChoices))
+	}
+	for _, choice := range opt.Choices {
+		if n := utf8.RuneCountInString(choice.Name); n < 1 || n > maxChoiceNameLength {
+			errs = append(errs, fmt.Errorf("%s: choice names must be 1-%d characters", at, maxChoiceNameLength))
+		}
+		errs = append(errs, choice.NameLocalizations.validate(fmt.Sprintf("%s choice %q", at, choice.Name),
+			"name_localizations", checkLength(maxChoiceNameLength))...)
+		switch v := choice.Value.(type) {
+		case string:
+			if opt.Type != OptionTypeString {
+				errs = append(errs, fmt.Errorf("%s: choice %q must have a numeric value", at, choice.Name))
+			} else if n := utf8.RuneCountInString(v); n < 1 || n > maxChoiceValueLength {
+				errs = append(errs, fmt.Errorf("%s: choice values must be 1-%d characters", at, maxChoiceValueLength))
+			}
+		case int:
+			if opt.Type == OptionTypeString {
+				errs = append(errs, fmt.Errorf("%s: choice %q must have a string value", at, choice.Name))
+			}
+		case float64:
+			if opt.Type != OptionTypeNumber {
+				errs = append(errs, fmt.Errorf("%s: choice %q must have a %s value", at, choice.Name, opt.Type))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s: choice %q has no value", at, choice.Name))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Discord permission bits, see
+// https://discord.com/developers/docs/topics/permissions#permissions-bitwise-permission-flags
+var permissionBits = map[string]uint64{
+	"CREATE_INSTANT_INVITE":               1 << 0,
+	"KICK_MEMBERS":                        1 << 1,
+	"BAN_MEMBERS":                         1 << 2,
+	"ADMINISTRATOR":                       1 << 3,
+	"MANAGE_CHANNELS":                     1 << 4,
+	"MANAGE_GUILD":                        1 << 5,
+	"ADD_REACTIONS":                       1 << 6,
+	"VIEW_AUDIT_LOG":                      1 << 7,
+	"PRIORITY_SPEAKER":                    1 << 8,
+	"STREAM":                              1 << 9,
+	"VIEW_CHANNEL":                        1 << 10,
+	"SEND_MESSAGES":                       1 << 11,
+	"SEND_TTS_MESSAGES":                   1 << 12,
+	"MANAGE_MESSAGES":                     1 << 13,
+	"EMBED_LINKS":                         1 << 14,
+	"ATTACH_FILES":                        1 << 15,
+	"READ_MESSAGE_HISTORY":                1 << 16,
+	"MENTION_EVERYONE":                    1 << 17,
+	"USE_EXTERNAL_EMOJIS":                 1 << 18,
+	"VIEW_GUILD_INSIGHTS":                 1 << 19,
+	"CONNECT":                             1 << 20,
+	"SPEAK":                               1 << 21,
+	"MUTE_MEMBERS":                        1 << 22,
+	"DEAFEN_MEMBERS":                      1 << 23,
+	"MOVE_MEMBERS":                        1 << 24,
+	"USE_VAD":                             1 << 25,
+	"CHANGE_NICKNAME":                     1 << 26,
+	"MANAGE_NICKNAMES":                    1 << 27,
+	"MANAGE_ROLES":                        1 << 28,
+	"MANAGE_WEBHOOKS":                     1 << 29,
+	"MANAGE_GUILD_EXPRESSIONS":            1 << 30,
+	"USE_APPLICATION_COMMANDS":            1 << 31,
+	"REQUEST_TO_SPEAK":                    1 << 32,
+	"MANAGE_EVENTS":                       1 << 33,
+	"MANAGE_THREADS":                      1 << 34,
+	"CREATE_PUBLIC_THREADS":               1 << 35,
+	"CREATE_PRIVATE_THREADS":              1 << 36,
+	"USE_EXTERNAL_STICKERS":               1 << 37,
+	"SEND_MESSAGES_IN_THREADS":            1 << 38,
+	"USE_EMBEDDED_ACTIVITIES":             1 << 39,
+	"MODERATE_MEMBERS":                    1 << 40,
+	"VIEW_CREATOR_MONETIZATION_ANALYTICS": 1 << 41,
+	"USE_SOUNDBOARD":                      1 << 42,
+	"CREATE_GUILD_EXPRESSIONS":            1 << 43,
+	"CREATE_EVENTS":                       1 << 44,
+	"USE_EXTERNAL_SOUNDS":                 1 << 45,
+	"SEND_VOICE_MESSAGES":                 1 << 46,
+	"SEND_POLLS":                          1 << 49,
+	"USE_EXTERNAL_APPS":                   1 << 50,
+}
+
+// parsePermissions parses "NAME+NAME" (or a numeric bitfield) into a bitfield
+func parsePermissions(s string) (uint64, error) {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n, nil
+	}
+	var bits uint64
+	for _, name := range strings.Split(s, "+") {
+		bit, ok := permissionBits[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("unknown permission %q", name)
+		}
+		bits |= bit
+	}
+	return bits, nil
+}
+
+// Permissions is a default_member_permissions bitfield. Manifests give flag
+// names joined with "+" (MANAGE_GUILD+BAN_MEMBERS), as the edge's
+// COMMAND_PERMISSIONS does, or a decimal bitfield; "0" hides the command
+// from everyone but administrators.
+type Permissions uint64
+
+func (p *Permissions) UnmarshalYAML(node *yaml.Node) error {
+	bits, err := parsePermissions(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*p = Permissions(bits)
+	return nil
+}
+
+// MarshalJSON encodes the bitfield as the decimal string Discord uses
+func (p Permissions) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(p), 10))
+}
+
+func (p *Permissions) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	bits, err := strconv.ParseUint(s, 10, 64)
+	*p = Permissions(bits)
+	return err
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type changeKind int
+
+const (
+	changeNone changeKind = iota
+	changeCreate
+	changeUpdate
+	changeDelete
+	// changeUnmanaged is a registered command missing from the manifest,
+	// left alone without -prune
+	changeUnmanaged
+)
+
+// change is what applying the manifest does to one command
+type change struct {
+	kind changeKind
+	want *Command       // nil for deletions
+	have *RemoteCommand // nil for creations
+}
+
+func (c change) key() string {
+	if c.want != nil {
+		return c.want.key()
+	}
+	return c.have.key()
+}
+
+// plan compares the manifest with the commands registered in s. The result
+// is in manifest order, followed by commands only registered with Discord.
+// Commands are compared, shown and sent in their normalized form.
+func plan(manifest []Command, registered []RemoteCommand, s scope, prune bool) []change {
+	byKey := make(map[string]*RemoteCommand, len(registered))
+	for i := range registered {
+		registered[i].Command = normalized(registered[i].Command, s)
+		byKey[registered[i].key()] = &registered[i]
+	}
+
+	changes := make([]change, 0, len(manifest)+len(registered))
+	for _, cmd := range manifest {
+		want := new(Command)
+		*want = normalized(cmd, s)
+		have, ok := byKey[want.key()]
+		delete(byKey, want.key())
+		switch {
+		case !ok:
+			changes = append(changes, change{kind: changeCreate, want: want})
+		case !bytes.Equal(canonical(*want), canonical(have.Command)):
+			changes = append(changes, change{kind: changeUpdate, want: want, have: have})
+		default:
+			changes = append(changes, change{kind: changeNone, want: want, have: have})
+		}
+	}
+
+	var extra []change
+	for _, have := range byKey {
+		kind := changeUnmanaged
+		if prune {
+			kind = changeDelete
+		}
+		extra = append(extra, change{kind: kind, have: have})
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i].key() < extra[j].key() })
+	return append(changes, extra...)
+}
+
+// pending reports whether applying changes would change anything
+func pending(changes []change) bool {
+	for _, c := range changes {
+		if c.kind != changeNone && c.kind != changeUnmanaged {
+			return true
+		}
+	}
+	return false
+}
+
+// normalized returns cmd with Discord's defaults left unset and lists in a
+// fixed order, so a command as Discord returns it compares equal to the
+// manifest entry it was created from. Sending it has the same effect as
+// sending cmd.
+func normalized(cmd Command, s scope) Command {
+	if s.guildID != "" {
+		// Guild commands aren't installed to users or usable in DMs
+		cmd.DMPermission, cmd.IntegrationTypes, cmd.Contexts = nil, nil, nil
+		return cmd
+	}
+	// Discord derives dm_permission from contexts when they're set
+	if cmd.Contexts != nil || (cmd.DMPermission != nil && *cmd.DMPermission) {
+		cmd.DMPermission = nil
+	}
+	cmd.IntegrationTypes = withoutDefault(cmd.IntegrationTypes, IntegrationTypeGuildInstall)
+	cmd.Contexts = withoutDefault(cmd.Contexts,
+		InteractionContextGuild, InteractionContextBotDM, InteractionContextPrivateChannel)
+	return cmd
+}
+
+// withoutDefault returns values sorted, or nil when they're the default set
+func withoutDefault[T cmp.Ordered](values []T, def ...T) []T {
+	if values == nil {
+		return nil
+	}
+	sorted := slices.Compact(slices.Sorted(slices.Values(values)))
+	if slices.Equal(sorted, def) {
+		return nil
+	}
+	return sorted
+}
+
+// canonical is the indented JSON encoding of the managed fields, used both to
+// compare commands and to show how they differ
+func canonical(cmd Command) []byte {
+	data, err := json.MarshalIndent(cmd, "", "  ")
+	if err != nil {
+		// Only choice values validate rejects can't be encoded
+		return []byte(err.Error())
+	}
+	return data
+}
+
+// printPlan writes a kubectl-style diff of changes and a summary
+func printPlan(w io.Writer, changes []change) {
+	var created, updated, deleted, unchanged, unmanaged int
+	for _, c := range changes {
+		switch c.kind {
+		case changeCreate:
+			created++
+			fmt.Fprintf(w, "+ create %s command %q\n", c.want.Type, c.want.Name)
+			writeLines(w, "+ ", canonical(*c.want))
+		case changeUpdate:
+			updated++
+			fmt.Fprintf(w, "~ update %s command %q (%s)\n", c.want.Type, c.want.Name, c.have.ID)
+			writeDiff(w, canonical(c.have.Command), canonical(*c.want))
+		case changeDelete:
+			deleted++
+			fmt.Fprintf(w, "- delete %s command %q (%s)\n", c.have.Type, c.have.Name, c.have.ID)
+		case changeUnmanaged:
+			unmanaged++
+			fmt.Fprintf(w, "! %s command %q (%s) is not in the manifest; -prune deletes it\n", c.have.Type, c.have.Name, c.have.ID)
+		default:
+			unchanged++
+		}
+	}
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete, %d unchanged", created, updated, deleted, unchanged)
+	if unmanaged > 0 {
+		fmt.Fprintf(w, ", %d not in the manifest", unmanaged)
+	}
+	fmt.Fprintln(w)
+}
+
+func writeLines(w io.Writer, prefix string, data []byte) {
+	for _, line := range strings.Split(string(data), "\n") {
+		fmt.Fprintln(w, "    "+prefix+line)
+	}
+}
+
+// writeDiff writes a line diff of two JSON documents: unchanged lines
+// indented, removed ones prefixed "- " and added ones "+ "
+func writeDiff(w io.Writer, from, to []byte) {
+	a := strings.Split(string(from), "\n")
+	b := strings.Split(string(to), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintln(w, "      "+a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			fmt.Fprintln(w, "    + "+b[j])
+			j++
+		default:
+			fmt.Fprintln(w, "    - "+a[i])
+			i++
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	cmd := func(name, description string) Command {
+		return Command{Name: name, Type: CommandTypeChatInput, Description: description}
+	}
+	remote := func(id string, c Command) RemoteCommand { return RemoteCommand{ID: id, Command: c} }
+	user := Command{Name: "echo", Type: CommandTypeUser}
+
+	for _, tc := range []struct {
+		name       string
+		manifest   []Command
+		registered []RemoteCommand
+		prune      bool
+		want       []string // kind and key of each change, in order
+	}{
+		{
+			name:     "nothing registered",
+			manifest: []Command{cmd("echo", "Echo"), cmd("ping", "Ping")},
+			want:     []string{"create chat_input/echo", "create chat_input/ping"},
+		},
+		{
+			name:       "create, update and unchanged in manifest order",
+			manifest:   []Command{cmd("ping", "Ping"), cmd("echo", "Echo text"), cmd("help", "Help")},
+			registered: []RemoteCommand{remote("1", cmd("echo", "Echo")), remote("2", cmd("ping", "Ping"))},
+			want:       []string{"none chat_input/ping", "update chat_input/echo", "create chat_input/help"},
+		},
+		{
+			name:       "registered only, without -prune",
+			manifest:   []Command{cmd("ping", "Ping")},
+			registered: []RemoteCommand{remote("3", cmd("zeta", "Z")), remote("2", cmd("ping", "Ping")), remote("1", cmd("alpha", "A"))},
+			want:       []string{"none chat_input/ping", "unmanaged chat_input/alpha", "unmanaged chat_input/zeta"},
+		},
+		{
+			name:       "registered only, with -prune",
+			registered: []RemoteCommand{remote("3", cmd("zeta", "Z")), remote("1", cmd("alpha", "A"))},
+			prune:      true,
+			want:       []string{"delete chat_input/alpha", "delete chat_input/zeta"},
+		},
+		{
+			name:       "same name, another type",
+			manifest:   []Command{cmd("echo", "Echo")},
+			registered: []RemoteCommand{remote("1", user)},
+			prune:      true,
+			want:       []string{"create chat_input/echo", "delete user/echo"},
+		},
+	} {
+		changes := plan(tc.manifest, tc.registered, scope{}, tc.prune)
+		var got []string
+		for _, c := range changes {
+			kind := map[changeKind]string{
+				changeNone: "none", changeCreate: "create", changeUpdate: "update",
+				changeDelete: "delete", changeUnmanaged: "unmanaged",
+			}[c.kind]
+			got = append(got, kind+" "+c.key())
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+// TestPlan_NormalizesDefaults checks a command Discord returns with its
+// defaults filled in is unchanged from the manifest entry that left them out
+func TestPlan_NormalizesDefaults(t *testing.T) {
+	yes := true
+	manifest := []Command{{Name: "echo", Type: CommandTypeChatInput, Description: "Echo"}}
+	registered := []RemoteCommand{{ID: "1", Command: Command{
+		Name: "echo", Type: CommandTypeChatInput, Description: "Echo",
+		DMPermission:     &yes,
+		IntegrationTypes: []IntegrationType{IntegrationTypeGuildInstall},
+		Contexts:         []InteractionContext{InteractionContextPrivateChannel, InteractionContextGuild, InteractionContextBotDM},
+	}}}
+	changes := plan(manifest, registered, scope{}, false)
+	if len(changes) != 1 || changes[0].kind != changeNone {
+		t.Errorf("expected the command to be unchanged, got %+v", changes)
+	}
+	if pending(changes) {
+		t.Error("expected no pending changes")
+	}
+}
+
+func TestNormalized(t *testing.T) {
+	yes, no := true, false
+	allContexts := []InteractionContext{InteractionContextBotDM, InteractionContextGuild, InteractionContextPrivateChannel}
+	for _, tc := range []struct {
+		name  string
+		cmd   Command
+		scope scope
+		want  Command
+	}{
+		{
+			name: "global defaults dropped",
+			cmd: Command{
+				DMPermission:     &yes,
+				IntegrationTypes: []IntegrationType{IntegrationTypeGuildInstall},
+				Contexts:         allContexts,
+			},
+			want: Command{},
+		},
+		{
+			name: "global dm_permission false kept",
+			cmd:  Command{DMPermission: &no},
+			want: Command{DMPermission: &no},
+		},
+		{
+			name: "global dm_permission dropped for contexts",
+			cmd:  Command{DMPermission: &no, Contexts: []InteractionContext{InteractionContextGuild}},
+			want: Command{Contexts: []InteractionContext{InteractionContextGuild}},
+		},
+		{
+			name: "global lists sorted and deduplicated",
+			cmd: Command{
+				IntegrationTypes: []IntegrationType{IntegrationTypeUserInstall, IntegrationTypeGuildInstall, IntegrationTypeUserInstall},
+				Contexts:         []InteractionContext{InteractionContextPrivateChannel, InteractionContextGuild},
+			},
+			want: Command{
+				IntegrationTypes: []IntegrationType{IntegrationTypeGuildInstall, IntegrationTypeUserInstall},
+				Contexts:         []InteractionContext{InteractionContextGuild, InteractionContextPrivateChannel},
+			},
+		},
+		{
+			name: "guild drops install and context settings",
+			cmd: Command{
+				DMPermission:     &no,
+				IntegrationTypes: []IntegrationType{IntegrationTypeUserInstall},
+				Contexts:         []InteractionContext{InteractionContextBotDM},
+			},
+			scope: scope{guildID: "1"},
+			want:  Command{},
+		},
+	} {
+		if got := normalized(tc.cmd, tc.scope); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestWithoutDefault(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		values []int
+		def    []int
+		want   []int
+	}{
+		{"unset", nil, []int{0}, nil},
+		{"empty, which isn't sent either", []int{}, []int{0}, nil},
+		{"the default", []int{0}, []int{0}, nil},
+		{"the default, unordered and repeated", []int{2, 0, 1, 2}, []int{0, 1, 2}, nil},
+		{"part of the default", []int{2, 0}, []int{0, 1, 2}, []int{0, 2}},
+		{"more than the default", []int{1, 0}, []int{0}, []int{0, 1}},
+	} {
+		if got := withoutDefault(tc.values, tc.def...); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{"identical", "a\nb", "a\nb", []string{"      a", "      b"}},
+		{"line changed", "a\nb\nc", "a\nx\nc", []string{"      a", "    - b", "    + x", "      c"}},
+		{"line added", "a\nc", "a\nb\nc", []string{"      a", "    + b", "      c"}},
+		{"line removed", "a\nb\nc", "a\nc", []string{"      a", "    - b", "      c"}},
+		{"all replaced", "a\nb", "c", []string{"    - a", "    - b", "    + c"}},
+		{
+			name: "longest common lines kept",
+			from: "{\n\"a\": 1,\n\"b\": 2,\n\"c\": 3\n}",
+			to:   "{\n\"b\": 2,\n\"c\": 3,\n\"d\": 4\n}",
+			want: []string{"      {", "    - \"a\": 1,", "      \"b\": 2,", "    - \"c\": 3", "    + \"c\": 3,", "    + \"d\": 4", "      }"},
+		},
+	} {
+		var buf bytes.Buffer
+		writeDiff(&buf, []byte(tc.from), []byte(tc.to))
+		got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.name, strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultIgnore are the fields that differ on every run: times, versions,
+// traces, request IDs, and the sealed token, which is encrypted with a fresh
+// nonce
+var defaultIgnore = []string{
+	"published.*.attributes.timestamp",
+	"published.*.attributes.service_version",
+	"published.*.attributes.traceparent",
+	"published.*.attributes.tracestate",
+	"published.*.attributes.request_id",
+	"published.*.attributes.ce-time",
+	"published.*.data.sealed_token",
+}
+
+// loadRun reads a run saved with -out
+func loadRun(path string) (*Run, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(raw, &run); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &run, nil
+}
+
+// flatten maps each leaf of a result to its dotted path, e.g.
+// "published.0.attributes.command_path", leaving out ignored paths
+func flatten(r Result, ignore map[string]bool) map[string]string {
+	out := make(map[string]string)
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch node := v.(type) {
+		case map[string]any:
+			for k, child := range node {
+				walk(path+"."+k, child)
+			}
+		case []any:
+			for i, child := range node {
+				walk(path+"."+strconv.Itoa(i), child)
+			}
+		default:
+			if !ignore[pattern(path)] {
+				out[path] = compact(v)
+			}
+		}
+	}
+	out["status"] = strconv.Itoa(r.Status)
+	if r.Error != "" {
+		out["error"] = compact(r.Error)
+	}
+	walk("response", r.Response)
+	out["published"] = strconv.Itoa(len(r.Published))
+	for i, p := range r.Published {
+		prefix := "published." + strconv.Itoa(i)
+		for k, v := range p.Attributes {
+			walk(prefix+".attributes."+k, v)
+		}
+		walk(prefix+".data", p.Data)
+	}
+	return out
+}
+
+// pattern replaces the array indexes in a path with "*", to match it
+// against the ignore list
+func pattern(path string) string {
+	parts := strings.Split(path, ".")
+	for i, p := range parts {
+		if _, err := strconv.Atoi(p); err == nil {
+			parts[i] = "*"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// diffRuns prints how each interaction's outcome changed from the baseline
+// and returns how many differ, counting ones only in either run
+func diffRuns(w io.Writer, baseline, current *Run, ignore map[string]bool) int {
+	base := make(map[string]Result, len(baseline.Results))
+	for _, r := range baseline.Results {
+		base[r.ID] = r
+	}
+	seen := make(map[string]bool, len(current.Results))
+	var changed, added, removed int
+	for _, cur := range current.Results {
+		seen[cur.ID] = true
+		old, ok := base[cur.ID]
+		if !ok {
+			fmt.Fprintf(w, "+ %s only in this run\n", describe(cur))
+			added++
+			continue
+		}
+		lines := diffResult(flatten(old, ignore), flatten(cur, ignore))
+		if len(lines) == 0 {
+			continue
+		}
+		changed++
+		fmt.Fprintf(w, "~ %s\n", describe(cur))
+		for _, line := range lines {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	for _, old := range baseline.Results {
+		if !seen[old.ID] {
+			fmt.Fprintf(w, "- %s only in the baseline\n", describe(old))
+			removed++
+		}
+	}
+	fmt.Fprintf(w, "Compared %d interactions with the baseline: %d differ, %d only in this run, %d only in the baseline\n",
+		len(current.Results), changed, added, removed)
+	return changed + added + removed
+}
+
+// diffResult lists the paths whose values differ, in path order
+func diffResult(old, cur map[string]string) []string {
+	paths := make(map[string]bool, len(old)+len(cur))
+	for p := range old {
+		paths[p] = true
+	}
+	for p := range cur {
+		paths[p] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	var lines []string
+	for _, p := range sorted {
+		o, inOld := old[p]
+		c, inCur := cur[p]
+		switch {
+		case !inOld:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", p, truncate(c)))
+		case !inCur:
+			lines = append(lines, fmt.Sprintf("- %s: %s", p, truncate(o)))
+		case o != c:
+			lines = append(lines, fmt.Sprintf("%s: %s → %s", p, truncate(o), truncate(c)))
+		}
+	}
+	return lines
+}
+
+// describe names an interaction in the diff
+func describe(r Result) string {
+	if r.Name == "" {
+		return fmt.Sprintf("%s (type %d)", r.ID, r.Type)
+	}
+	return fmt.Sprintf("%s (type %d, %s)", r.ID, r.Type, r.Name)
+}
+
+// truncate shortens long values so a diff stays readable
+func truncate(s string) string {
+	const limit = 80
+	if r := []rune(s); len(r) > limit {
+		return string(r[:limit]) + "…"
+	}
+	return s
+}
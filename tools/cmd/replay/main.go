@@ -0,0 +1,208 @@
+// Command replay sends a recorded session of interactions to a service
+// again, and compares what it did with an earlier replay:
+//
+//	gcloud storage cp -r gs://my-captures/captures/2026/10/10 ./captures
+//	replay -captures ./captures -target http://old-build:8080 -out baseline.json
+//	replay -captures ./captures -target http://new-build:8080 -baseline baseline.json
+//
+// Sessions come from go-gin's payload capture (PAYLOAD_CAPTURE_BUCKET).
+// Interactions keep their captured IDs, and the gaps between them can be
+// replayed in real time, faster, or not at all. With -subscription, the
+// messages the service publishes are collected and compared too.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tools/internal/interaction"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitChanged = 1 // the replay differs from the baseline
+	exitError   = 2
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	capturesDir := fs.String("captures", "", "directory of payload captures to replay (required)")
+	target := fs.String("target", envString("REPLAY_TARGET", "http://localhost:8080"), "URL interactions are posted to")
+	keySpec := fs.String("key", envString("REPLAY_KEY", "test"), `signing key: "test", or a hex seed or private key`)
+	speed := fs.Float64("speed", 1, "replay speed: 1 keeps the captured gaps, 10 is ten times faster, 0 sends back to back")
+	maxGap := fs.Duration("max-gap", 0, "longest wait between interactions, e.g. 5s to skip quiet periods (0: no limit)")
+	idOffset := fs.Uint64("id-offset", 0, "added to every interaction ID, to keep replays apart from the captured traffic")
+	includeInvalid := fs.Bool("include-invalid", false, "also replay captures whose signature failed, re-signed")
+	timeout := fs.Duration("timeout", 10*time.Second, "request timeout")
+	project := fs.String("project", os.Getenv("GOOGLE_CLOUD_PROJECT"), "project of -subscription")
+	subscription := fs.String("subscription", os.Getenv("REPLAY_SUBSCRIPTION"), "subscription to the service's topic, to collect what it publishes")
+	settle := fs.Duration("settle", 10*time.Second, "with -subscription, how long to keep collecting after the last response")
+	out := fs.String("out", "", "write the run's results to this file, to use as a later -baseline")
+	baselinePath := fs.String("baseline", "", "earlier run to compare with; exits 1 if anything differs")
+	ignore := fs.String("ignore", "", "comma-separated extra paths to leave out of the comparison, e.g. response.data.content")
+	label := fs.String("label", "", "label recorded in -out, e.g. a build or commit")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *capturesDir == "" || fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: replay -captures DIR [-target URL] [-out FILE] [-baseline FILE]")
+		return exitError
+	}
+	if *speed < 0 {
+		fmt.Fprintln(stderr, "replay: -speed can't be negative")
+		return exitError
+	}
+
+	fail := func(err error) int {
+		fmt.Fprintln(stderr, "replay:", err)
+		return exitError
+	}
+	var baseline *Run
+	if *baselinePath != "" {
+		var err error
+		if baseline, err = loadRun(*baselinePath); err != nil {
+			return fail(err)
+		}
+	}
+	key, err := interaction.LoadKey(*keySpec)
+	if err != nil {
+		return fail(err)
+	}
+	captures, err := loadCaptures(*capturesDir)
+	if err != nil {
+		return fail(err)
+	}
+	steps, err := newSession(captures, timing{speed: *speed, maxGap: *maxGap}, *idOffset, *includeInvalid)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Start collecting before sending, so nothing published is missed
+	var collected *collector
+	stopCollecting := func() error { return nil }
+	if *subscription != "" {
+		if *project == "" {
+			return fail(errors.New("-subscription needs -project or GOOGLE_CLOUD_PROJECT"))
+		}
+		client, err := pubsub.NewClient(ctx, *project)
+		if err != nil {
+			return fail(err)
+		}
+		defer client.Close()
+		collected = newCollector()
+		collectCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		done := make(chan error, 1)
+		go func() { done <- collected.receive(collectCtx, client.Subscription(*subscription)) }()
+		stopCollecting = func() error {
+			cancel()
+			return <-done
+		}
+	}
+
+	fmt.Fprintf(stderr, "Replaying %d interactions from %d captures over %s to %s\n",
+		len(steps), len(captures), steps[len(steps)-1].Offset.Round(time.Second), *target)
+	r := &replayer{target: *target, client: &http.Client{Timeout: *timeout}, key: key}
+	current := &Run{Label: *label, Target: *target, Started: time.Now().UTC()}
+	current.Results = r.replay(ctx, steps)
+
+	if collected != nil {
+		fmt.Fprintf(stderr, "Collecting published messages for %s\n", *settle)
+		t := time.NewTimer(*settle)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+		if err := stopCollecting(); err != nil {
+			return fail(fmt.Errorf("collecting published messages: %w", err))
+		}
+		collected.attach(current.Results)
+		if collected.other > 0 {
+			fmt.Fprintf(stderr, "Ignored %d published messages without an interaction_id\n", collected.other)
+		}
+	}
+	summarize(stdout, current.Results, collected != nil)
+
+	if *out != "" {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(current); err != nil {
+			return fail(err)
+		}
+		if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+			return fail(err)
+		}
+	}
+	if baseline == nil {
+		return exitOK
+	}
+
+	ignored := make(map[string]bool)
+	for _, p := range defaultIgnore {
+		ignored[p] = true
+	}
+	for _, p := range strings.Split(*ignore, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			ignored[pattern(p)] = true
+		}
+	}
+	fmt.Fprintln(stdout)
+	if diffRuns(stdout, baseline, current, ignored) > 0 {
+		return exitChanged
+	}
+	return exitOK
+}
+
+// summarize prints how many interactions got each status, and how many
+// messages were published
+func summarize(w io.Writer, results []Result, published bool) {
+	statuses := make(map[string]int)
+	var messages int
+	for _, r := range results {
+		status := fmt.Sprint(r.Status)
+		if r.Error != "" {
+			status = "no response"
+		}
+		statuses[status]++
+		messages += len(r.Published)
+	}
+	parts := make([]string, 0, len(statuses))
+	for status, n := range statuses {
+		parts = append(parts, fmt.Sprintf("%s ×%d", status, n))
+	}
+	sort.Strings(parts)
+	fmt.Fprintf(w, "Replayed %d interactions: %s\n", len(results), strings.Join(parts, ", "))
+	if published {
+		fmt.Fprintf(w, "Collected %d published messages\n", messages)
+	}
+}
+
+// envString returns the value of an environment variable or a default
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
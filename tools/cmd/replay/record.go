@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tools/internal/interaction"
+)
+
+// Run is the outcome of a replay, saved with -out and compared against a
+// later one with -baseline
+type Run struct {
+	Label   string    `json:"label,omitempty"`
+	Target  string    `json:"target"`
+	Started time.Time `json:"started"`
+	Results []Result  `json:"results"`
+}
+
+// Result is what the service did with one replayed interaction
+type Result struct {
+	ID        string      `json:"id"`
+	Type      int         `json:"type"`
+	Name      string      `json:"name,omitempty"`
+	Status    int         `json:"status,omitempty"`
+	Error     string      `json:"error,omitempty"`    // why there was no response
+	Response  any         `json:"response,omitempty"` // decoded JSON, or the body as text
+	Published []Published `json:"published"`
+	LatencyMS float64     `json:"latency_ms"`
+}
+
+// Published is a message the service published for an interaction
+type Published struct {
+	Attributes map[string]string `json:"attributes"`
+	Data       any               `json:"data"`
+}
+
+// replayer sends a session's steps to the target on schedule
+type replayer struct {
+	target string
+	client *http.Client
+	key    ed25519.PrivateKey
+}
+
+// replay sends every step at its offset from now, overlapping requests as
+// the captured traffic did, and returns the responses in step order
+func (r *replayer) replay(ctx context.Context, steps []step) []Result {
+	results := make([]Result, len(steps))
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, s := range steps {
+		if wait := time.Until(start.Add(s.Offset)); wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+			}
+		}
+		if ctx.Err() != nil {
+			results = results[:i]
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.send(context.WithoutCancel(ctx), s)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// send signs and posts one step
+func (r *replayer) send(ctx context.Context, s step) Result {
+	res := Result{ID: s.ID, Type: s.Type, Name: s.Name, Published: []Published{}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.target, bytes.NewReader(s.Body))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Discord-Interactions/1.0 (+https://discord.com) replay")
+	req.Header.Set("X-Signature-Ed25519", interaction.Sign(r.key, timestamp, s.Body))
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	res.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = resp.StatusCode
+	if err := json.Unmarshal(body, &res.Response); err != nil {
+		res.Response = string(body)
+	}
+	return res
+}
+
+// collector gathers what the service publishes during a replay, by the
+// interaction_id attribute
+type collector struct {
+	mu    sync.Mutex
+	byID  map[string][]Published
+	other int // messages without an interaction_id
+}
+
+func newCollector() *collector {
+	return &collector{byID: make(map[string][]Published)}
+}
+
+// receive acknowledges and records messages until ctx is done
+func (c *collector) receive(ctx context.Context, sub *pubsub.Subscription) error {
+	return sub.Receive(ctx, func(_ context.Context, m *pubsub.Message) {
+		m.Ack()
+		p := Published{Attributes: m.Attributes}
+		if err := json.Unmarshal(m.Data, &p.Data); err != nil {
+			p.Data = string(m.Data)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		id := m.Attributes["interaction_id"]
+		if id == "" {
+			c.other++
+			return
+		}
+		c.byID[id] = append(c.byID[id], p)
+	})
+}
+
+// attach adds each result's published messages, in a stable order so
+// redeliveries and publish order don't show up as differences
+func (c *collector) attach(results []Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range results {
+		published := c.byID[results[i].ID]
+		sort.SliceStable(published, func(a, b int) bool {
+			return compact(published[a].Data) < compact(published[b].Data)
+		})
+		if published != nil {
+			results[i].Published = published
+		}
+	}
+}
+
+// compact renders a value as JSON
+func compact(v any) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// capture is the part of a go-gin payload capture (PAYLOAD_CAPTURE_BUCKET)
+// replay uses
+type capture struct {
+	ReceivedAt     time.Time `json:"received_at"`
+	Body           string    `json:"body"`
+	SignatureValid bool      `json:"signature_valid"`
+
+	file string
+}
+
+// step is one captured request, ready to replay
+type step struct {
+	ID     string        // interaction ID, after any offset
+	Type   int           // interaction type
+	Name   string        // command path or custom_id, to label diffs
+	Offset time.Duration // when to send it, from the start of the replay
+	Body   []byte
+	Valid  bool // the captured signature verified
+}
+
+// loadCaptures reads every capture file under dir, oldest first
+func loadCaptures(dir string) ([]capture, error) {
+	var captures []capture
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var c capture
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		c.file = path
+		captures = append(captures, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(captures) == 0 {
+		return nil, fmt.Errorf("no captures in %s", dir)
+	}
+	// File names start with the arrival time in nanoseconds, so they
+	// break ties between captures made in the same instant
+	sort.SliceStable(captures, func(i, j int) bool {
+		if !captures[i].ReceivedAt.Equal(captures[j].ReceivedAt) {
+			return captures[i].ReceivedAt.Before(captures[j].ReceivedAt)
+		}
+		return captures[i].file < captures[j].file
+	})
+	return captures, nil
+}
+
+// timing controls when replayed requests are sent
+type timing struct {
+	speed  float64       // 1 replays in real time, 2 twice as fast; 0 sends back to back
+	maxGap time.Duration // longest wait between requests, after scaling; 0 for no limit
+}
+
+// newSession turns captures into steps. Each interaction keeps its captured
+// ID, moved by idOffset, and gets a token derived from it, so a session
+// replays identically every time. Captures whose signature failed are
+// skipped unless includeInvalid is set.
+func newSession(captures []capture, t timing, idOffset uint64, includeInvalid bool) ([]step, error) {
+	var steps []step
+	var offset time.Duration
+	var last time.Time
+	for _, c := range captures {
+		if !c.SignatureValid && !includeInvalid {
+			continue
+		}
+		if !last.IsZero() && t.speed > 0 {
+			gap := time.Duration(float64(c.ReceivedAt.Sub(last)) / t.speed)
+			if t.maxGap > 0 && gap > t.maxGap {
+				gap = t.maxGap
+			}
+			offset += gap
+		}
+		last = c.ReceivedAt
+
+		s := step{Offset: offset, Valid: c.SignatureValid, Body: []byte(c.Body)}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(s.Body, &fields); err != nil {
+			// Replayed as captured: the service should reject it again
+			s.ID = "capture:" + filepath.Base(c.file)
+			steps = append(steps, s)
+			continue
+		}
+		var captured string
+		if err := json.Unmarshal(fields["id"], &captured); err == nil && captured != "" {
+			id, err := strconv.ParseUint(captured, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: interaction ID %q: %w", c.file, captured, err)
+			}
+			s.ID = strconv.FormatUint(id+idOffset, 10)
+			fields["id"] = jsonString(s.ID)
+			if _, ok := fields["token"]; ok {
+				fields["token"] = jsonString("replay-" + s.ID)
+			}
+		} else {
+			s.ID = "capture:" + filepath.Base(c.file)
+		}
+		body, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.file, err)
+		}
+		s.Body = body
+
+		var header struct {
+			Type int `json:"type"`
+			Data struct {
+				Name     string `json:"name"`
+				CustomID string `json:"custom_id"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(s.Body, &header); err == nil {
+			s.Type = header.Type
+			s.Name = header.Data.Name
+			if s.Name == "" {
+				s.Name = header.Data.CustomID
+			}
+		}
+		steps = append(steps, s)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("none of the %d captures has a valid signature; use -include-invalid to replay them", len(captures))
+	}
+	return steps, nil
+}
+
+// jsonString encodes s as a JSON string
+func jsonString(s string) json.RawMessage {
+	out, err := json.Marshal(s)
+	if err != nil {
+		return json.RawMessage(`""`)
+	}
+	return out
+}
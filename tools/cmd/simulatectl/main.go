@@ -0,0 +1,171 @@
+// Command simulatectl sends signed Discord interactions to a service, the
+// manual-testing counterpart to the contract suite:
+//
+//	simulatectl -command echo -option text=hello
+//	simulatectl -type component -custom-id confirm:yes
+//	simulatectl -f interaction.json -target https://bot.example.com/interactions
+//	simulatectl -scenario confirm-flow.yaml
+//
+// A scenario sends a sequence of interactions, such as a command, a click on
+// a button in its reply and a modal submission, checking each response.
+// Interactions are signed with the contract suite's test key unless -key
+// says otherwise, so a service started with the contract tests'
+// DISCORD_PUBLIC_KEY accepts them.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tools/internal/interaction"
+)
+
+// Exit codes
+const (
+	exitOK       = 0
+	exitRejected = 1 // the target answered with a non-2xx status, or a scenario step failed
+	exitError    = 2
+)
+
+// stringList is a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("simulatectl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	target := fs.String("target", envString("SIMULATE_TARGET", "http://localhost:8080"), "URL interactions are posted to")
+	file := fs.String("f", "", "interaction JSON file to send as-is (- for stdin) instead of building one from flags")
+	scenarioFile := fs.String("scenario", "", "scenario file of interactions to send in turn (- for stdin)")
+	keySpec := fs.String("key", envString("SIMULATE_KEY", "test"), `signing key: "test", "generate", or a hex seed or private key`)
+	timeout := fs.Duration("timeout", 10*time.Second, "request timeout")
+	showHeaders := fs.Bool("headers", false, "print the response headers")
+	showRequest := fs.Bool("print-request", false, "print the interaction sent, with its token redacted")
+
+	var spec interaction.Spec
+	var values string
+	fs.StringVar(&spec.Type, "type", "", "interaction type: ping, command, component, autocomplete or modal (default: command with -command, else ping)")
+	fs.StringVar(&spec.Command, "command", "", `command path, e.g. "echo" or "config permission set"`)
+	fs.Var((*stringList)(&spec.Options), "option", "command option as name=value, typed from the value (repeatable)")
+	fs.StringVar(&spec.Focused, "focused", "", "autocomplete: the option being typed")
+	fs.StringVar(&spec.CustomID, "custom-id", "", "component or modal custom_id")
+	fs.StringVar(&values, "values", "", "component: comma-separated select menu values (default: a button click)")
+	fs.Var((*stringList)(&spec.Fields), "field", "modal: text input as custom_id=value (repeatable)")
+	fs.StringVar(&spec.ApplicationID, "application-id", "100000000000000001", "application ID")
+	fs.StringVar(&spec.GuildID, "guild", "100000000000000002", "guild ID")
+	fs.StringVar(&spec.ChannelID, "channel", "100000000000000003", "channel ID")
+	fs.StringVar(&spec.UserID, "user", "100000000000000004", "invoking user's ID")
+	fs.StringVar(&spec.Username, "username", "simulated-user", "invoking user's name")
+	fs.StringVar(&spec.Permissions, "permissions", "2248473465835073", "invoking member's permissions bitfield")
+	fs.StringVar(&spec.Locale, "locale", "en-US", "invoking user's locale")
+	fs.StringVar(&spec.GuildLocale, "guild-locale", "en-US", "guild's locale")
+	fs.BoolVar(&spec.DM, "dm", false, "invoke from a DM with the bot rather than a guild")
+
+	var send sender
+	fs.DurationVar(&send.skew, "timestamp-skew", 0, "shift the signature timestamp, e.g. -10s to send a stale one")
+	fs.BoolVar(&send.badSignature, "bad-signature", false, "sign with a random key the target doesn't trust")
+	fs.BoolVar(&send.unsigned, "unsigned", false, "send without signature headers")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "simulatectl: unexpected argument %q\n", fs.Arg(0))
+		return exitError
+	}
+
+	key, err := interaction.LoadKey(*keySpec)
+	if err != nil {
+		fmt.Fprintln(stderr, "simulatectl:", err)
+		return exitError
+	}
+	if *keySpec == "generate" {
+		fmt.Fprintln(stderr, "Signing with a generated key; start the target with DISCORD_PUBLIC_KEY="+interaction.PublicKeyHex(key))
+	}
+	send.target, send.key = *target, key
+	send.client = &http.Client{Timeout: *timeout}
+	if values != "" {
+		spec.Values = strings.Split(values, ",")
+	}
+
+	if *scenarioFile != "" {
+		if *file != "" {
+			fmt.Fprintln(stderr, "simulatectl: -f and -scenario can't be used together")
+			return exitError
+		}
+		sc, err := loadScenario(*scenarioFile, stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "simulatectl:", err)
+			return exitError
+		}
+		passed, err := runScenario(ctx, sc, spec, &send, stdout, *showRequest)
+		if err != nil {
+			fmt.Fprintln(stderr, "simulatectl:", err)
+			return exitError
+		}
+		if !passed {
+			return exitRejected
+		}
+		return exitOK
+	}
+
+	var body []byte
+	if *file != "" {
+		body, err = readInteraction(*file, stdin)
+	} else {
+		var payload map[string]any
+		if payload, err = spec.Build(); err == nil {
+			body, err = json.Marshal(payload)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "simulatectl:", err)
+		return exitError
+	}
+	if *showRequest {
+		fmt.Fprintf(stdout, "POST %s\n%s\n\n", send.target, interaction.Redacted(body))
+	}
+
+	res, err := send.send(ctx, body)
+	if err != nil {
+		fmt.Fprintln(stderr, "simulatectl:", err)
+		return exitError
+	}
+	res.print(stdout, *showHeaders)
+	if res.Status < 200 || res.Status > 299 {
+		return exitRejected
+	}
+	return exitOK
+}
+
+// readInteraction reads an interaction file, or stdin for "-". It is sent
+// byte for byte, so malformed or unusual JSON can be tried too.
+func readInteraction(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// envString returns the value of an environment variable or a default
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
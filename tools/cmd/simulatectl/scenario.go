@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tools/internal/interaction"
+)
+
+// Interaction response types, by the names scenarios use for them
+var responseTypes = map[string]int{
+	"pong":             1,
+	"message":          4,
+	"deferred_message": 5,
+	"deferred_update":  6,
+	"update_message":   7,
+	"autocomplete":     8,
+	"modal":            9,
+	"premium_required": 10,
+	"launch_activity":  12,
+}
+
+// responseTypeName returns the scenario name for response type n
+func responseTypeName(n int) string {
+	for name, t := range responseTypes {
+		if t == n {
+			return name
+		}
+	}
+	return strconv.Itoa(n)
+}
+
+// scenario is a sequence of interactions sent one after another, each
+// checked before the next is sent
+type scenario struct {
+	Name string `yaml:"name"`
+	// Interaction holds fields shared by every step, such as the user
+	Interaction interaction.Spec `yaml:"interaction"`
+	Steps       []step           `yaml:"steps"`
+}
+
+// step is one interaction in a scenario and what its response must be
+type step struct {
+	Name  string        `yaml:"name"`
+	Delay time.Duration `yaml:"delay"` // wait before sending
+	// Label picks the component to use from the current message by its
+	// label, instead of naming its custom_id
+	Label            string `yaml:"label"`
+	interaction.Spec `yaml:",inline"`
+	Expect           expectation `yaml:"expect"`
+}
+
+// expectation is what a step's response must look like. Unset fields
+// aren't checked.
+type expectation struct {
+	Status     int            `yaml:"status"`     // default: any 2xx
+	Type       string         `yaml:"type"`       // response type name or number
+	Content    string         `yaml:"content"`    // contained in data.content
+	Ephemeral  *bool          `yaml:"ephemeral"`  // data.flags has EPHEMERAL
+	Components []string       `yaml:"components"` // custom_ids the response's components include
+	JSON       map[string]any `yaml:"json"`       // dotted path, e.g. data.embeds.0.title, to its value
+	MaxLatency time.Duration  `yaml:"max_latency"`
+}
+
+// flagEphemeral is the message flag for replies only the invoking user sees
+const flagEphemeral = 1 << 6
+
+// loadScenario reads and checks a scenario file, or stdin for "-"
+func loadScenario(path string, stdin io.Reader) (*scenario, error) {
+	var raw []byte
+	var err error
+	if path == "-" {
+		raw, err = io.ReadAll(stdin)
+	} else {
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sc scenario
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&sc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(sc.Steps) == 0 {
+		return nil, fmt.Errorf("%s: no steps", path)
+	}
+	var errs []error
+	for i, st := range sc.Steps {
+		if st.Expect.Type != "" {
+			if _, err := parseResponseType(st.Expect.Type); err != nil {
+				errs = append(errs, fmt.Errorf("%s: step %d: %w", path, i+1, err))
+			}
+		}
+		if st.Label != "" && st.Spec.CustomID != "" {
+			errs = append(errs, fmt.Errorf("%s: step %d: label and custom_id can't both be set", path, i+1))
+		}
+	}
+	return &sc, errors.Join(errs...)
+}
+
+// parseResponseType accepts a response type name or number
+func parseResponseType(s string) (int, error) {
+	if n, ok := responseTypes[s]; ok {
+		return n, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	return 0, fmt.Errorf("unknown response type %q", s)
+}
+
+// runScenario sends each step in turn, printing a line per step. It stops
+// at the first step that fails, since later steps usually build on its
+// response, and reports whether every step passed.
+func runScenario(ctx context.Context, sc *scenario, base interaction.Spec, send *sender, stdout io.Writer, showRequest bool) (bool, error) {
+	if sc.Name != "" {
+		fmt.Fprintf(stdout, "Scenario: %s\n", sc.Name)
+	}
+	defaults := base.With(sc.Interaction)
+
+	// The message the conversation is on: created by a message response,
+	// replaced by an update, and what components are clicked on
+	var message map[string]any
+	// The last response, which a modal step submits the form of
+	var last map[string]any
+
+	for i, st := range sc.Steps {
+		name := st.Name
+		if name == "" {
+			name = fmt.Sprintf("step %d", i+1)
+		}
+		spec := defaults.With(st.Spec)
+		spec.Message = message
+		if err := resolveStep(&spec, st, message, last); err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		payload, err := spec.Build()
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if st.Delay > 0 {
+			select {
+			case <-time.After(st.Delay):
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
+		}
+		if showRequest {
+			fmt.Fprintf(stdout, "POST %s\n%s\n", send.target, interaction.Redacted(body))
+		}
+		res, err := send.send(ctx, body)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+
+		// A body that isn't JSON has no fields for the checks to find
+		var response map[string]any
+		if err := json.Unmarshal(res.Body, &response); err != nil {
+			response = nil
+		}
+		failures := st.Expect.check(res, response)
+		summary := fmt.Sprintf("%d (%s)", res.Status, res.Latency.Round(time.Millisecond))
+		if typ := intField(response, "type"); typ != 0 {
+			summary = fmt.Sprintf("%d %s (%s)", res.Status, responseTypeName(typ), res.Latency.Round(time.Millisecond))
+		}
+		if len(failures) > 0 {
+			fmt.Fprintf(stdout, "FAIL %d. %s: %s\n", i+1, name, summary)
+			for _, f := range failures {
+				fmt.Fprintf(stdout, "    %s\n", f)
+			}
+			res.printBody(indentWriter{stdout})
+			fmt.Fprintf(stdout, "%d of %d steps passed\n", i, len(sc.Steps))
+			return false, nil
+		}
+		fmt.Fprintf(stdout, "ok   %d. %s: %s\n", i+1, name, summary)
+
+		last = response
+		switch intField(response, "type") {
+		case responseTypes["message"], responseTypes["update_message"]:
+			message = messageFrom(response, spec.ChannelID, message)
+		}
+	}
+	fmt.Fprintf(stdout, "%d of %d steps passed\n", len(sc.Steps), len(sc.Steps))
+	return true, nil
+}
+
+// resolveStep fills in what a step takes from earlier responses: the
+// component picked by label, and the custom_id of the modal being submitted
+func resolveStep(spec *interaction.Spec, st step, message, last map[string]any) error {
+	switch spec.Type {
+	case "component":
+		if st.Label == "" {
+			return nil
+		}
+		if message == nil {
+			return fmt.Errorf("no earlier step created a message to find %q on", st.Label)
+		}
+		id, ok := componentByLabel(message["components"], st.Label)
+		if !ok {
+			return fmt.Errorf("the message has no component labelled %q", st.Label)
+		}
+		spec.CustomID = id
+	case "modal":
+		if spec.CustomID != "" {
+			return nil
+		}
+		if intField(last, "type") != responseTypes["modal"] {
+			return errors.New("needs custom_id, or to follow a step answered with a modal")
+		}
+		data, _ := last["data"].(map[string]any)
+		spec.CustomID, _ = data["custom_id"].(string)
+	}
+	return nil
+}
+
+// check returns how the response falls short of the expectation
+func (e expectation) check(res *result, response map[string]any) []string {
+	var failures []string
+	if e.Status != 0 && res.Status != e.Status {
+		failures = append(failures, fmt.Sprintf("status %d, want %d", res.Status, e.Status))
+	} else if e.Status == 0 && (res.Status < 200 || res.Status > 299) {
+		failures = append(failures, fmt.Sprintf("status %d, want 2xx", res.Status))
+	}
+	if e.MaxLatency > 0 && res.Latency > e.MaxLatency {
+		failures = append(failures, fmt.Sprintf("took %s, want at most %s", res.Latency.Round(time.Millisecond), e.MaxLatency))
+	}
+	if e.Type != "" {
+		want, err := parseResponseType(e.Type)
+		if got := intField(response, "type"); err == nil && got != want {
+			failures = append(failures, fmt.Sprintf("response type %s, want %s", responseTypeName(got), responseTypeName(want)))
+		}
+	}
+
+	data, _ := response["data"].(map[string]any)
+	if e.Content != "" {
+		if content, _ := data["content"].(string); !strings.Contains(content, e.Content) {
+			failures = append(failures, fmt.Sprintf("content %q doesn't contain %q", content, e.Content))
+		}
+	}
+	if e.Ephemeral != nil {
+		if got := intField(data, "flags")&flagEphemeral != 0; got != *e.Ephemeral {
+			failures = append(failures, fmt.Sprintf("ephemeral is %t, want %t", got, *e.Ephemeral))
+		}
+	}
+	for _, id := range e.Components {
+		if !hasComponent(data["components"], id) {
+			failures = append(failures, fmt.Sprintf("no component with custom_id %q", id))
+		}
+	}
+	for path, want := range e.JSON {
+		got, ok := lookup(response, path)
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s is missing, want %s", path, compact(want)))
+		} else if compact(got) != compact(want) {
+			failures = append(failures, fmt.Sprintf("%s is %s, want %s", path, compact(got), compact(want)))
+		}
+	}
+	return failures
+}
+
+// messageFrom returns the message a message or update response leaves the
+// conversation on. An update keeps the message's ID.
+func messageFrom(response map[string]any, channelID string, previous map[string]any) map[string]any {
+	message := map[string]any{"channel_id": channelID, "content": ""}
+	if data, ok := response["data"].(map[string]any); ok {
+		for k, v := range data {
+			message[k] = v
+		}
+	}
+	message["id"] = interaction.Snowflake()
+	if previous != nil && intField(response, "type") == responseTypes["update_message"] {
+		message["id"] = previous["id"]
+	}
+	return message
+}
+
+// components walks a component tree, calling visit on each component
+func components(tree any, visit func(map[string]any)) {
+	list, _ := tree.([]any)
+	for _, c := range list {
+		if component, ok := c.(map[string]any); ok {
+			visit(component)
+			components(component["components"], visit)
+		}
+	}
+}
+
+// componentByLabel returns the custom_id of the component with the label
+func componentByLabel(tree any, label string) (string, bool) {
+	var id string
+	var found bool
+	components(tree, func(c map[string]any) {
+		if l, _ := c["label"].(string); l == label && !found {
+			id, _ = c["custom_id"].(string)
+			found = true
+		}
+	})
+	return id, found
+}
+
+// hasComponent reports whether the tree has a component with the custom_id
+func hasComponent(tree any, customID string) bool {
+	var found bool
+	components(tree, func(c map[string]any) {
+		if id, _ := c["custom_id"].(string); id == customID {
+			found = true
+		}
+	})
+	return found
+}
+
+// lookup follows a dotted path of object keys and array indexes
+func lookup(v any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// compact renders a value as JSON, so YAML and JSON numbers compare equal
+func compact(v any) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(out)
+}
+
+// intField returns a numeric field of a JSON object, or 0
+func intField(object map[string]any, key string) int {
+	n, _ := object[key].(float64)
+	return int(n)
+}
+
+// indentWriter indents each line written through it, to nest a response
+// under its step
+type indentWriter struct{ w io.Writer }
+
+func (iw indentWriter) Write(p []byte) (int, error) {
+	lines := strings.SplitAfter(string(p), "\n")
+	var out strings.Builder
+	for _, line := range lines {
+		if line != "" && line != "\n" {
+			out.WriteString("    ")
+		}
+		out.WriteString(line)
+	}
+	if _, err := io.WriteString(iw.w, out.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
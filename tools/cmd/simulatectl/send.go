@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pmgledhill102/discord-bot-test-suite/tools/internal/interaction"
+)
+
+// sender posts signed interactions to the service under test
+type sender struct {
+	target string
+	key    ed25519.PrivateKey
+	client *http.Client
+
+	// Deliberate faults, to see how the target rejects them
+	skew         time.Duration // added to the signature timestamp
+	badSignature bool          // sign with a random key instead
+	unsigned     bool          // send no signature headers
+}
+
+// result is the target's reply to one interaction
+type result struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Latency time.Duration
+}
+
+// send signs body and posts it to the target
+func (s *sender) send(ctx context.Context, body []byte) (*result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Discord-Interactions/1.0 (+https://discord.com) simulatectl")
+	if !s.unsigned {
+		key := s.key
+		if s.badSignature {
+			// Well-formed, but by a key the target doesn't trust
+			if _, key, err = ed25519.GenerateKey(nil); err != nil {
+				return nil, err
+			}
+		}
+		timestamp := strconv.FormatInt(time.Now().Add(s.skew).Unix(), 10)
+		req.Header.Set("X-Signature-Ed25519", interaction.Sign(key, timestamp, body))
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+	}
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return &result{Status: resp.StatusCode, Header: resp.Header, Body: data, Latency: time.Since(start)}, nil
+}
+
+// print writes the status, headers and body, indenting JSON bodies
+func (r *result) print(w io.Writer, headers bool) {
+	fmt.Fprintf(w, "%d %s (%s)\n", r.Status, http.StatusText(r.Status), r.Latency.Round(time.Millisecond))
+	if headers {
+		names := make([]string, 0, len(r.Header))
+		for name := range r.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range r.Header[name] {
+				fmt.Fprintf(w, "%s: %s\n", name, value)
+			}
+		}
+	}
+	if len(r.Body) > 0 {
+		fmt.Fprintln(w)
+		r.printBody(w)
+	}
+}
+
+// printBody writes the body, indenting JSON
+func (r *result) printBody(w io.Writer) {
+	var indented bytes.Buffer
+	if json.Indent(&indented, r.Body, "", "  ") == nil {
+		fmt.Fprintln(w, indented.String())
+		return
+	}
+	fmt.Fprintln(w, string(r.Body))
+}
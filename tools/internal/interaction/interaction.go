@@ -0,0 +1,255 @@
+// Package interaction builds and signs the Discord interactions the tools
+// send to services under test.
+package interaction
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interaction types
+const (
+	interactionPing         = 1
+	interactionCommand      = 2
+	interactionComponent    = 3
+	interactionAutocomplete = 4
+	interactionModalSubmit  = 5
+)
+
+var interactionTypes = map[string]int{
+	"ping":         interactionPing,
+	"command":      interactionCommand,
+	"component":    interactionComponent,
+	"autocomplete": interactionAutocomplete,
+	"modal":        interactionModalSubmit,
+}
+
+// Option types used when building command options
+const (
+	optionSubCommand      = 1
+	optionSubCommandGroup = 2
+	optionString          = 3
+	optionInteger         = 4
+	optionBoolean         = 5
+	optionNumber          = 10
+)
+
+// Component types used when building component and modal data
+const (
+	componentActionRow    = 1
+	componentButton       = 2
+	componentStringSelect = 3
+	componentTextInput    = 4
+)
+
+// discordEpoch is the first millisecond of 2015, where snowflake timestamps start
+const discordEpoch = 1420070400000
+
+// Spec describes an interaction to build, from flags or a
+// scenario step
+type Spec struct {
+	Type          string   `yaml:"type"`    // default: command with a Command, else ping
+	Command       string   `yaml:"command"` // "name", "name subcommand" or "name group subcommand"
+	Options       []string `yaml:"options"` // name=value
+	Focused       string   `yaml:"focused"` // autocomplete: the option being typed
+	CustomID      string   `yaml:"custom_id"`
+	Values        []string `yaml:"values"` // component: selected values, making it a select menu
+	Fields        []string `yaml:"fields"` // modal: text input custom_id=value
+	ApplicationID string   `yaml:"application_id"`
+	GuildID       string   `yaml:"guild"`
+	ChannelID     string   `yaml:"channel"`
+	UserID        string   `yaml:"user"`
+	Username      string   `yaml:"username"`
+	Permissions   string   `yaml:"permissions"` // member permissions bitfield
+	Locale        string   `yaml:"locale"`
+	GuildLocale   string   `yaml:"guild_locale"`
+	DM            bool     `yaml:"dm"`
+
+	// Message is the message a component is on. Scenarios set it to the
+	// message an earlier step's response created.
+	Message map[string]any `yaml:"-"`
+}
+
+// With returns s with every field set in o replacing its own
+func (s Spec) With(o Spec) Spec {
+	dst, src := reflect.ValueOf(&s).Elem(), reflect.ValueOf(o)
+	for i := range src.NumField() {
+		if !src.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return s
+}
+
+// Build returns the interaction payload, with a fresh ID and token
+func (s Spec) Build() (map[string]any, error) {
+	if s.Type == "" {
+		s.Type = "ping"
+		if s.Command != "" {
+			s.Type = "command"
+		}
+	}
+	typ, ok := interactionTypes[s.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown interaction type %q (want ping, command, component, autocomplete or modal)", s.Type)
+	}
+	interaction := map[string]any{
+		"type":           typ,
+		"id":             Snowflake(),
+		"application_id": s.ApplicationID,
+		"token":          "simulated-" + rand.Text(),
+		"version":        1,
+	}
+	if typ == interactionPing {
+		return interaction, nil
+	}
+
+	var err error
+	switch typ {
+	case interactionCommand, interactionAutocomplete:
+		interaction["data"], err = s.commandData(typ == interactionAutocomplete)
+	case interactionComponent:
+		interaction["data"], err = s.componentData()
+		interaction["message"] = s.Message
+		if s.Message == nil {
+			interaction["message"] = map[string]any{"id": Snowflake(), "channel_id": s.ChannelID, "content": ""}
+		}
+	case interactionModalSubmit:
+		interaction["data"], err = s.modalData()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user := map[string]any{"id": s.UserID, "username": s.Username, "global_name": s.Username}
+	interaction["channel_id"] = s.ChannelID
+	interaction["locale"] = s.Locale
+	if s.DM {
+		interaction["user"] = user
+		interaction["context"] = 1
+		interaction["channel"] = map[string]any{"id": s.ChannelID, "type": 1}
+	} else {
+		interaction["guild_id"] = s.GuildID
+		interaction["guild_locale"] = s.GuildLocale
+		interaction["member"] = map[string]any{"user": user, "roles": []string{}, "permissions": s.Permissions}
+		interaction["context"] = 0
+		interaction["channel"] = map[string]any{"id": s.ChannelID, "type": 0, "guild_id": s.GuildID}
+	}
+	return interaction, nil
+}
+
+// commandData builds application command data, nesting the options under
+// the subcommand group and subcommand in the command path
+func (s Spec) commandData(autocomplete bool) (map[string]any, error) {
+	path := strings.Fields(s.Command)
+	if len(path) == 0 || len(path) > 3 {
+		return nil, errors.New("-command must be a name, optionally followed by a subcommand group and subcommand")
+	}
+	options := make([]any, 0, len(s.Options))
+	for _, opt := range s.Options {
+		name, value, ok := strings.Cut(opt, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid option %q (want name=value)", opt)
+		}
+		option := typedOption(name, value)
+		if autocomplete && name == s.Focused {
+			option["focused"] = true
+		}
+		options = append(options, option)
+	}
+	if autocomplete && s.Focused == "" {
+		return nil, errors.New("autocomplete needs -focused naming the option being typed")
+	}
+
+	// Innermost first: the last path element holds the options
+	for i := len(path) - 1; i > 0; i-- {
+		typ := optionSubCommand
+		if i == 1 && len(path) == 3 {
+			typ = optionSubCommandGroup
+		}
+		options = []any{map[string]any{"type": typ, "name": path[i], "options": options}}
+	}
+	data := map[string]any{"id": Snowflake(), "name": path[0], "type": 1}
+	if len(options) > 0 {
+		data["options"] = options
+	}
+	return data, nil
+}
+
+// typedOption infers the option type from the value: a boolean, integer,
+// number, or otherwise a string
+func typedOption(name, value string) map[string]any {
+	option := map[string]any{"name": name}
+	if value == "true" || value == "false" {
+		option["type"], option["value"] = optionBoolean, value == "true"
+	} else if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		option["type"], option["value"] = optionInteger, n
+	} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+		option["type"], option["value"] = optionNumber, f
+	} else {
+		option["type"], option["value"] = optionString, value
+	}
+	return option
+}
+
+// componentData builds a button click, or a select menu choice when values
+// are given
+func (s Spec) componentData() (map[string]any, error) {
+	if s.CustomID == "" {
+		return nil, errors.New("component interactions need -custom-id")
+	}
+	if len(s.Values) == 0 {
+		return map[string]any{"custom_id": s.CustomID, "component_type": componentButton}, nil
+	}
+	return map[string]any{"custom_id": s.CustomID, "component_type": componentStringSelect, "values": s.Values}, nil
+}
+
+// modalData builds a modal submission with one text input per field
+func (s Spec) modalData() (map[string]any, error) {
+	if s.CustomID == "" {
+		return nil, errors.New("modal interactions need -custom-id")
+	}
+	rows := make([]any, 0, len(s.Fields))
+	for _, field := range s.Fields {
+		id, value, ok := strings.Cut(field, "=")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid field %q (want custom_id=value)", field)
+		}
+		rows = append(rows, map[string]any{
+			"type":       componentActionRow,
+			"components": []any{map[string]any{"type": componentTextInput, "custom_id": id, "value": value}},
+		})
+	}
+	return map[string]any{"custom_id": s.CustomID, "components": rows}, nil
+}
+
+// Snowflake returns an ID in Discord's format for the current time, with
+// random low bits so IDs made in the same millisecond differ
+func Snowflake() string {
+	ms := time.Now().UnixMilli() - discordEpoch
+	return strconv.FormatInt(ms<<22|mathrand.Int64N(1<<22), 10)
+}
+
+// Redacted returns body with the interaction token replaced, for printing.
+// Interaction tokens are credentials and are never written out.
+func Redacted(body []byte) []byte {
+	var v map[string]any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return []byte("(not a JSON object)")
+	}
+	if _, ok := v["token"]; ok {
+		v["token"] = "[REDACTED]"
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return []byte(err.Error())
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package interaction
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// testKeySeed is the seed of the contract suite's key pair
+// (tests/contract/testkeys), so services configured for the contract tests
+// accept simulated interactions too
+const testKeySeed = "discord-bot-test-suite-ed25519-test-key-seed-v1"
+
+// LoadKey returns the signing key named by spec: "test" for the contract
+// suite's key, "generate" for a new random one, or a hex-encoded 32-byte
+// seed or 64-byte private key
+func LoadKey(spec string) (ed25519.PrivateKey, error) {
+	switch spec {
+	case "test":
+		seed := sha256.Sum256([]byte(testKeySeed))
+		return ed25519.NewKeyFromSeed(seed[:]), nil
+	case "generate":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+	raw, err := hex.DecodeString(strings.TrimSpace(spec))
+	if err != nil {
+		return nil, fmt.Errorf("key must be \"test\", \"generate\" or hex: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("key must be a %d-byte seed or %d-byte private key, not %d bytes",
+		ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+}
+
+// PublicKeyHex is the value the target's DISCORD_PUBLIC_KEY must have to
+// accept interactions signed with key
+func PublicKeyHex(key ed25519.PrivateKey) string {
+	return hex.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+// Sign returns the X-Signature-Ed25519 value for body sent at timestamp
+func Sign(key ed25519.PrivateKey, timestamp string, body []byte) string {
+	message := append([]byte(timestamp), body...)
+	return hex.EncodeToString(ed25519.Sign(key, message))
+}